@@ -213,6 +213,12 @@ func (h *openAPI) GetChangefeed(c *gin.Context) {
 		return
 	}
 
+	runningDDLs, err := h.statusProvider().GetRunningDDLs(ctx, changefeedID)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
 	taskStatus := make([]model.CaptureTaskStatus, 0, len(processorInfos))
 	for captureID, status := range processorInfos {
 		tables := make([]int64, 0)
@@ -234,6 +240,7 @@ func (h *openAPI) GetChangefeed(c *gin.Context) {
 		Engine:         info.Engine,
 		FeedState:      info.State,
 		TaskStatus:     taskStatus,
+		RunningDDLs:    runningDDLs,
 	}
 
 	c.IndentedJSON(http.StatusOK, changefeedDetail)
@@ -380,6 +387,7 @@ func (h *openAPI) ResumeChangefeed(c *gin.Context) {
 // @Param ignore_txn_start_ts body integer false "ignore transaction start ts"
 // @Param mounter_worker_num body integer false "mounter worker nums"
 // @Param sink_config body config.SinkConfig false "sink config"
+// @Param rate_limit body config.RateLimitConfig false "rate limit"
 // @Success 202
 // @Failure 500,400 {object} model.HTTPError
 // @Router /api/v1/changefeeds/{changefeed_id} [put]
@@ -401,18 +409,20 @@ func (h *openAPI) UpdateChangefeed(c *gin.Context) {
 		_ = c.Error(err)
 		return
 	}
-	if info.State != model.StateStopped {
-		_ = c.Error(cerror.ErrChangefeedUpdateRefused.GenWithStackByArgs("can only update changefeed config when it is stopped"))
-		return
-	}
 
 	// can only update target-ts, sink-uri
-	// filter_rules, ignore_txn_start_ts, mounter_worker_num, sink_config
+	// filter_rules, ignore_txn_start_ts, mounter_worker_num, sink_config, rate_limit
 	var changefeedConfig model.ChangefeedConfig
 	if err = c.BindJSON(&changefeedConfig); err != nil {
 		_ = c.Error(err)
 		return
 	}
+	// the rate limits are applied by the processors at runtime.
+	if info.State != model.StateStopped && !isRateLimitOnlyUpdate(changefeedConfig) {
+		_ = c.Error(cerror.ErrChangefeedUpdateRefused.GenWithStackByArgs(
+			"can only update changefeed config when it is stopped, except the rate limit"))
+		return
+	}
 
 	newInfo, err := verifyUpdateChangefeedConfig(ctx, changefeedConfig, info)
 	if err != nil {
@@ -636,10 +646,11 @@ func (h *openAPI) GetProcessor(c *gin.Context) {
 	var processorDetail model.ProcessorDetail
 	if exist {
 		processorDetail = model.ProcessorDetail{
-			CheckPointTs: position.CheckPointTs,
-			ResolvedTs:   position.ResolvedTs,
-			Count:        position.Count,
-			Error:        position.Error,
+			CheckPointTs:      position.CheckPointTs,
+			ResolvedTs:        position.ResolvedTs,
+			Count:             position.Count,
+			Error:             position.Error,
+			MemoryConsumption: position.MemoryConsumption,
 		}
 		tables := make([]int64, 0)
 		for tableID := range status.Tables {
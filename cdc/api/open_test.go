@@ -90,6 +90,11 @@ func (p *mockStatusProvider) GetCaptures(ctx context.Context) ([]*model.CaptureI
 	return args.Get(0).([]*model.CaptureInfo), args.Error(1)
 }
 
+func (p *mockStatusProvider) GetRunningDDLs(ctx context.Context, changefeedID model.ChangeFeedID) ([]*model.RunningDDL, error) {
+	args := p.Called(ctx, changefeedID)
+	return args.Get(0).([]*model.RunningDDL), args.Error(1)
+}
+
 func newRouter(c *capture.Capture, p *mockStatusProvider) *gin.Engine {
 	router := gin.New()
 	RegisterOpenAPIRoutes(router, NewOpenAPI4Test(c, p))
@@ -137,6 +142,9 @@ func newStatusProvider() *mockStatusProvider {
 	statusProvider.On("GetCaptures", mock.Anything).
 		Return([]*model.CaptureInfo{{ID: captureID}}, nil)
 
+	statusProvider.On("GetRunningDDLs", mock.Anything, changeFeedID).
+		Return([]*model.RunningDDL{{Query: "ALTER TABLE t ADD INDEX idx(a)", CommitTs: 1}}, nil)
+
 	return statusProvider
 }
 
@@ -189,6 +197,8 @@ func TestGetChangefeed(t *testing.T) {
 	err := json.NewDecoder(w.Body).Decode(&resp)
 	require.Nil(t, err)
 	require.Equal(t, model.StateNormal, resp.FeedState)
+	require.Len(t, resp.RunningDDLs, 1)
+	require.Equal(t, uint64(1), resp.RunningDDLs[0].CommitTs)
 
 	// test get changefeed failed
 	api = testCase{url: fmt.Sprintf("/api/v1/changefeeds/%s", nonExistChangefeedID), method: "GET"}
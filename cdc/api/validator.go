@@ -15,6 +15,7 @@ package api
 
 import (
 	"context"
+	"reflect"
 	"time"
 
 	"github.com/pingcap/errors"
@@ -24,6 +25,7 @@ import (
 	"github.com/pingcap/tiflow/cdc/entry"
 	"github.com/pingcap/tiflow/cdc/kv"
 	"github.com/pingcap/tiflow/cdc/model"
+	"github.com/pingcap/tiflow/cdc/redo"
 	"github.com/pingcap/tiflow/cdc/sink"
 	"github.com/pingcap/tiflow/pkg/config"
 	cerror "github.com/pingcap/tiflow/pkg/errors"
@@ -132,12 +134,18 @@ func verifyCreateChangefeedConfig(
 		CreatorVersion:    version.ReleaseVersion,
 	}
 
-	if !replicaConfig.ForceReplicate && !changefeedConfig.IgnoreIneligibleTable {
+	// verify the storage of redo logs, otherwise the changefeed fails after it's created
+	if err := redo.ValidateConsistentConfig(replicaConfig.Consistent); err != nil {
+		return nil, err
+	}
+
+	checkIneligibleTables := !replicaConfig.ForceReplicate && !changefeedConfig.IgnoreIneligibleTable
+	if checkIneligibleTables || needVerifyTables(replicaConfig) {
 		ineligibleTables, _, err := verifyTables(replicaConfig, capture.Storage, changefeedConfig.StartTS)
 		if err != nil {
 			return nil, err
 		}
-		if len(ineligibleTables) != 0 {
+		if checkIneligibleTables && len(ineligibleTables) != 0 {
 			return nil, cerror.ErrTableIneligible.GenWithStackByArgs(ineligibleTables)
 		}
 	}
@@ -189,6 +197,10 @@ func verifyUpdateChangefeedConfig(ctx context.Context, changefeedConfig model.Ch
 		newInfo.Config.Sink = changefeedConfig.SinkConfig
 	}
 
+	if changefeedConfig.RateLimit != nil {
+		newInfo.Config.RateLimit = changefeedConfig.RateLimit
+	}
+
 	// verify sink_uri
 	if changefeedConfig.SinkURI != "" {
 		newInfo.SinkURI = changefeedConfig.SinkURI
@@ -197,6 +209,13 @@ func verifyUpdateChangefeedConfig(ctx context.Context, changefeedConfig model.Ch
 		}
 	}
 
+	// verify the storage of redo logs if it's changed
+	if !reflect.DeepEqual(oldInfo.Config.Consistent, newInfo.Config.Consistent) {
+		if err := redo.ValidateConsistentConfig(newInfo.Config.Consistent); err != nil {
+			return nil, cerror.ErrChangefeedUpdateRefused.GenWithStackByCause(err)
+		}
+	}
+
 	if !diff.Changed(oldInfo, newInfo) {
 		return nil, cerror.ErrChangefeedUpdateRefused.GenWithStackByArgs("changefeed config is the same with the old one, do nothing")
 	}
@@ -204,11 +223,44 @@ func verifyUpdateChangefeedConfig(ctx context.Context, changefeedConfig model.Ch
 	return newInfo, nil
 }
 
-func verifyTables(replicaConfig *config.ReplicaConfig, storage tidbkv.Storage, startTs uint64) (ineligibleTables, eligibleTables []model.TableName, err error) {
-	filter, err := filter.NewFilter(replicaConfig)
-	if err != nil {
-		return nil, nil, errors.Trace(err)
+// isRateLimitOnlyUpdate returns whether the ChangefeedConfig only updates the
+// rate limit, which can be updated while the changefeed is running.
+func isRateLimitOnlyUpdate(changefeedConfig model.ChangefeedConfig) bool {
+	if changefeedConfig.RateLimit == nil {
+		return false
 	}
+	// The changefeed ID and the time zone aren't updated.
+	others := changefeedConfig
+	others.ID, others.TimeZone, others.RateLimit = "", "", nil
+	return reflect.DeepEqual(others, model.ChangefeedConfig{})
+}
+
+// needVerifyTables returns true if there are rules which must be verified against
+// the table schemas, they are verified even if the ineligible tables are ignored.
+func needVerifyTables(replicaConfig *config.ReplicaConfig) bool {
+	if len(replicaConfig.Sink.ColumnSelectors) != 0 {
+		return true
+	}
+	if replicaConfig.Masking != nil && len(replicaConfig.Masking.Rules) != 0 {
+		return true
+	}
+	for _, rule := range replicaConfig.Sink.DispatchRules {
+		if len(rule.Columns) != 0 {
+			return true
+		}
+	}
+	for _, rule := range replicaConfig.Filter.EventFilters {
+		if rule.IgnoreInsertValueExpr != "" || rule.IgnoreUpdateNewValueExpr != "" ||
+			rule.IgnoreUpdateOldValueExpr != "" || rule.IgnoreDeleteValueExpr != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// verifyTables returns the ineligible and eligible tables of the snapshot at
+// startTs, and verifies the eligible tables against the replica config.
+func verifyTables(replicaConfig *config.ReplicaConfig, storage tidbkv.Storage, startTs uint64) (ineligibleTables, eligibleTables []model.TableName, err error) {
 	meta, err := kv.GetSnapshotMeta(storage, startTs)
 	if err != nil {
 		return nil, nil, errors.Trace(err)
@@ -217,16 +269,5 @@ func verifyTables(replicaConfig *config.ReplicaConfig, storage tidbkv.Storage, s
 	if err != nil {
 		return nil, nil, errors.Trace(err)
 	}
-
-	for _, tableInfo := range snap.Tables() {
-		if filter.ShouldIgnoreTable(tableInfo.TableName.Schema, tableInfo.TableName.Table) {
-			continue
-		}
-		if !tableInfo.IsEligible(false /* forceReplicate */) {
-			ineligibleTables = append(ineligibleTables, tableInfo.TableName)
-		} else {
-			eligibleTables = append(eligibleTables, tableInfo.TableName)
-		}
-	}
-	return
+	return sink.VerifyTables(replicaConfig, snap.Tables())
 }
@@ -50,3 +50,50 @@ func TestVerifyUpdateChangefeedConfig(t *testing.T) {
 	require.Nil(t, err)
 	require.NotNil(t, newInfo)
 }
+
+func TestIsRateLimitOnlyUpdate(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	rateLimit := &config.RateLimitConfig{RowsPerSecond: 1000, BytesPerSecond: 1 << 20}
+	require.False(t, isRateLimitOnlyUpdate(model.ChangefeedConfig{}))
+	require.False(t, isRateLimitOnlyUpdate(model.ChangefeedConfig{RateLimit: rateLimit, TargetTS: 10}))
+	require.True(t, isRateLimitOnlyUpdate(model.ChangefeedConfig{RateLimit: rateLimit}))
+	require.True(t, isRateLimitOnlyUpdate(model.ChangefeedConfig{
+		ID: "test", TimeZone: "UTC", RateLimit: rateLimit,
+	}))
+
+	oldInfo := &model.ChangeFeedInfo{Config: config.GetDefaultReplicaConfig()}
+	newInfo, err := verifyUpdateChangefeedConfig(ctx, model.ChangefeedConfig{RateLimit: rateLimit}, oldInfo)
+	require.Nil(t, err)
+	require.Equal(t, rateLimit, newInfo.Config.RateLimit)
+	require.Nil(t, oldInfo.Config.RateLimit)
+}
+
+func TestNeedVerifyTables(t *testing.T) {
+	t.Parallel()
+
+	cfg := config.GetDefaultReplicaConfig()
+	require.False(t, needVerifyTables(cfg))
+
+	cfg.Sink.DispatchRules = []*config.DispatchRule{{Matcher: []string{"test.*"}, Dispatcher: "ts"}}
+	require.False(t, needVerifyTables(cfg))
+	cfg.Sink.DispatchRules[0].Columns = []string{"id"}
+	require.True(t, needVerifyTables(cfg))
+
+	cfg = config.GetDefaultReplicaConfig()
+	cfg.Sink.ColumnSelectors = []*config.ColumnSelector{{Matcher: []string{"test.*"}, Columns: []string{"id"}}}
+	require.True(t, needVerifyTables(cfg))
+
+	cfg = config.GetDefaultReplicaConfig()
+	cfg.Masking = &config.MaskingConfig{Rules: []*config.MaskingRule{{
+		Matcher: []string{"test.*"}, Columns: []string{"name"}, Transform: config.MaskingRedact,
+	}}}
+	require.True(t, needVerifyTables(cfg))
+
+	cfg = config.GetDefaultReplicaConfig()
+	cfg.Filter.EventFilters = []*config.EventFilterRule{{Matcher: []string{"test.*"}, IgnoreSQL: []string{"^DROP"}}}
+	require.False(t, needVerifyTables(cfg))
+	cfg.Filter.EventFilters[0].IgnoreDeleteValueExpr = "id > 100"
+	require.True(t, needVerifyTables(cfg))
+}
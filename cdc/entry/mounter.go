@@ -33,6 +33,8 @@ import (
 	"github.com/pingcap/tidb/types"
 	"github.com/pingcap/tiflow/cdc/model"
 	cerror "github.com/pingcap/tiflow/pkg/errors"
+	"github.com/pingcap/tiflow/pkg/filter"
+	"github.com/pingcap/tiflow/pkg/masking"
 	"github.com/pingcap/tiflow/pkg/util"
 	"go.uber.org/zap"
 	"golang.org/x/sync/errgroup"
@@ -78,6 +80,8 @@ type Mounter interface {
 
 type mounterImpl struct {
 	schemaStorage    SchemaStorage
+	filter           *filter.Filter
+	masker           *masking.Masker
 	rawRowChangedChs []chan *model.PolymorphicEvent
 	tz               *time.Location
 	workerNum        int
@@ -88,7 +92,7 @@ type mounterImpl struct {
 }
 
 // NewMounter creates a mounter
-func NewMounter(schemaStorage SchemaStorage, workerNum int, enableOldValue bool) Mounter {
+func NewMounter(schemaStorage SchemaStorage, filter *filter.Filter, masker *masking.Masker, workerNum int, enableOldValue bool) Mounter {
 	if workerNum <= 0 {
 		workerNum = defaultMounterWorkerNum
 	}
@@ -98,6 +102,8 @@ func NewMounter(schemaStorage SchemaStorage, workerNum int, enableOldValue bool)
 	}
 	return &mounterImpl{
 		schemaStorage:    schemaStorage,
+		filter:           filter,
+		masker:           masker,
 		rawRowChangedChs: chs,
 		workerNum:        workerNum,
 		enableOldValue:   enableOldValue,
@@ -225,7 +231,28 @@ func (m *mounterImpl) unmarshalAndMountRowChanged(ctx context.Context, raw *mode
 			if rowKV == nil {
 				return nil, nil
 			}
-			return m.mountRowKVEntry(tableInfo, rowKV, raw.ApproximateDataSize())
+			row, err := m.mountRowKVEntry(tableInfo, rowKV, raw.ApproximateDataSize())
+			if err != nil {
+				return nil, errors.Trace(err)
+			}
+			if m.filter != nil {
+				ignore, err := m.filter.ShouldIgnoreDMLByEventType(row)
+				if err == nil && !ignore {
+					ignore, err = m.filter.ShouldIgnoreDMLByExpr(row, tableInfo)
+				}
+				if err != nil {
+					return nil, errors.Trace(err)
+				}
+				if ignore {
+					log.Debug("skip the DML by the event filter", zap.Uint64("ts", raw.CRTs), zap.Stringer("table", row.Table))
+					return nil, nil
+				}
+			}
+			// The row is masked before it's sent to the sink and the redo log.
+			if err := m.masker.Apply(row); err != nil {
+				return nil, errors.Trace(err)
+			}
+			return row, nil
 		}
 		return nil, nil
 	}()
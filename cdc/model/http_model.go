@@ -107,6 +107,7 @@ type ChangefeedDetail struct {
 	ErrorHis       []int64             `json:"error_history"`
 	CreatorVersion string              `json:"creator_version"`
 	TaskStatus     []CaptureTaskStatus `json:"task_status"`
+	RunningDDLs    []*RunningDDL       `json:"running_ddls,omitempty"`
 }
 
 // MarshalJSON use to marshal ChangefeedDetail
@@ -138,6 +139,8 @@ type ChangefeedConfig struct {
 	IgnoreTxnStartTs      []uint64           `json:"ignore_txn_start_ts"`
 	MounterWorkerNum      int                `json:"mounter_worker_num" default:"16"`
 	SinkConfig            *config.SinkConfig `json:"sink_config"`
+	// RateLimit can be updated while the changefeed is running.
+	RateLimit *config.RateLimitConfig `json:"rate_limit"`
 }
 
 // ProcessorCommonInfo holds the common info of a processor
@@ -158,6 +161,8 @@ type ProcessorDetail struct {
 	Count uint64 `json:"count"`
 	// Error code when error happens
 	Error *RunningError `json:"error"`
+	// The estimated memory consumption of the events buffered by the processor.
+	MemoryConsumption uint64 `json:"memory_consumption"`
 }
 
 // CaptureTaskStatus holds TaskStatus of a capture
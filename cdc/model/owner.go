@@ -85,6 +85,10 @@ type TaskPosition struct {
 	Count uint64 `json:"count"`
 	// Error when error happens
 	Error *RunningError `json:"error"`
+	// The estimated memory consumption of the events buffered by the processor,
+	// it's refreshed along with the ts or every 10 seconds, the real-time value
+	// is in the metrics.
+	MemoryConsumption uint64 `json:"memory-consumption,omitempty"`
 }
 
 // Marshal returns the json marshal format of a TaskStatus
@@ -109,9 +113,10 @@ func (tp *TaskPosition) String() string {
 // Clone returns a deep clone of TaskPosition
 func (tp *TaskPosition) Clone() *TaskPosition {
 	ret := &TaskPosition{
-		CheckPointTs: tp.CheckPointTs,
-		ResolvedTs:   tp.ResolvedTs,
-		Count:        tp.Count,
+		CheckPointTs:      tp.CheckPointTs,
+		ResolvedTs:        tp.ResolvedTs,
+		Count:             tp.Count,
+		MemoryConsumption: tp.MemoryConsumption,
 	}
 	if tp.Error != nil {
 		ret.Error = &RunningError{
@@ -428,6 +433,20 @@ type ChangeFeedStatus struct {
 	AdminJobType AdminJobType `json:"admin-job-type"`
 }
 
+// RunningDDL is a DDL being executed asynchronously in the downstream, the
+// checkpoint of the changefeed is held before it until it's finished. It's
+// only kept in the memory of the owner.
+type RunningDDL struct {
+	Query     string `json:"query"`
+	CommitTs  uint64 `json:"commit-ts"`
+	StartTime int64  `json:"start-time"`
+	// JobID, State and RowCount are the progress of the DDL job queried by
+	// `ADMIN SHOW DDL JOBS` from TiDB, they're empty before the job is found.
+	JobID    int64  `json:"job-id,omitempty"`
+	State    string `json:"state,omitempty"`
+	RowCount int64  `json:"row-count,omitempty"`
+}
+
 // Marshal returns json encoded string of ChangeFeedStatus, only contains necessary fields stored in storage
 func (status *ChangeFeedStatus) Marshal() (string, error) {
 	data, err := json.Marshal(status)
@@ -189,7 +189,7 @@ func (c *changefeed) tick(ctx cdcContext.Context, state *orchestrator.Changefeed
 	default:
 	}
 
-	c.sink.emitCheckpointTs(ctx, checkpointTs)
+	c.sink.emitCheckpointTs(ctx, checkpointTs, c.schema.AllTableNames())
 	barrierTs, err := c.handleBarrier(ctx)
 	if err != nil {
 		return errors.Trace(err)
@@ -220,6 +220,14 @@ func (c *changefeed) tick(ctx cdcContext.Context, state *orchestrator.Changefeed
 		if newCheckpointTs > barrierTs {
 			newCheckpointTs = barrierTs
 		}
+		// The async DDLs may still fail after they're emitted, the checkpoint
+		// is held before them, so they're executed again if the changefeed
+		// is restarted.
+		for _, ddl := range c.sink.runningDDLs() {
+			if newCheckpointTs >= ddl.CommitTs {
+				newCheckpointTs = ddl.CommitTs - 1
+			}
+		}
 		c.updateStatus(currentTs, newCheckpointTs, newResolvedTs)
 	}
 	return nil
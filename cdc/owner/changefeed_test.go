@@ -73,6 +73,7 @@ type mockDDLSink struct {
 	checkpointTs model.Ts
 	syncPoint    model.Ts
 	syncPointHis []model.Ts
+	running      []*model.RunningDDL
 
 	wg sync.WaitGroup
 }
@@ -100,10 +101,14 @@ func (m *mockDDLSink) emitSyncPoint(ctx cdcContext.Context, checkpointTs uint64)
 	return nil
 }
 
-func (m *mockDDLSink) emitCheckpointTs(ctx cdcContext.Context, ts uint64) {
+func (m *mockDDLSink) emitCheckpointTs(ctx cdcContext.Context, ts uint64, tableNames []model.TableName) {
 	atomic.StoreUint64(&m.checkpointTs, ts)
 }
 
+func (m *mockDDLSink) runningDDLs() []*model.RunningDDL {
+	return m.running
+}
+
 func (m *mockDDLSink) close(ctx context.Context) error {
 	m.wg.Wait()
 	return nil
@@ -353,6 +358,37 @@ func TestFinished(t *testing.T) {
 	require.Equal(t, state.Info.State, model.StateFinished)
 }
 
+func TestHoldCheckpointForRunningDDLs(t *testing.T) {
+	ctx := cdcContext.NewBackendContext4Test(true)
+	cf, state, captures, tester := createChangefeed4Test(ctx, t)
+	defer cf.Close(ctx)
+
+	// pre check and initialize
+	cf.Tick(ctx, state, captures)
+	tester.MustApplyPatches()
+	cf.Tick(ctx, state, captures)
+	tester.MustApplyPatches()
+
+	startTs := ctx.ChangefeedVars().Info.StartTs
+	mockDDLPuller := cf.ddlPuller.(*mockDDLPuller)
+	mockDDLSink := cf.sink.(*mockDDLSink)
+	mockDDLSink.running = []*model.RunningDDL{{CommitTs: startTs + 500}}
+	mockDDLPuller.resolvedTs += 1000
+	for i := 0; i <= 10; i++ {
+		cf.Tick(ctx, state, captures)
+		tester.MustApplyPatches()
+	}
+	// the checkpoint is held before the running async DDL.
+	require.Equal(t, startTs+499, state.Status.CheckpointTs)
+
+	mockDDLSink.running = nil
+	for i := 0; i <= 10; i++ {
+		cf.Tick(ctx, state, captures)
+		tester.MustApplyPatches()
+	}
+	require.Equal(t, mockDDLPuller.resolvedTs, state.Status.CheckpointTs)
+}
+
 func TestRemoveChangefeed(t *testing.T) {
 	baseCtx, cancel := context.WithCancel(context.Background())
 	ctx := cdcContext.NewContext4Test(baseCtx, true)
@@ -44,12 +44,16 @@ type DDLSink interface {
 	// emitCheckpointTs emits the checkpoint Ts to downstream data source
 	// this function will return after recording the checkpointTs specified in memory immediately
 	// and the recorded checkpointTs will be sent and updated to downstream data source every second
-	emitCheckpointTs(ctx cdcContext.Context, ts uint64)
+	emitCheckpointTs(ctx cdcContext.Context, ts uint64, tableNames []model.TableName)
 	// emitDDLEvent emits DDL event and return true if the DDL is executed
 	// the DDL event will be sent to another goroutine and execute to downstream
 	// the caller of this function can call again and again until a true returned
 	emitDDLEvent(ctx cdcContext.Context, ddl *model.DDLEvent) (bool, error)
 	emitSyncPoint(ctx cdcContext.Context, checkpointTs uint64) error
+	// runningDDLs returns the DDLs executed asynchronously and still running
+	// in the downstream. They're refreshed every second, and before the async
+	// DDL is reported as executed by emitDDLEvent.
+	runningDDLs() []*model.RunningDDL
 	// close the sink, cancel running goroutine.
 	close(ctx context.Context) error
 }
@@ -58,7 +62,13 @@ type ddlSinkImpl struct {
 	lastSyncPoint  model.Ts
 	syncPointStore sink.SyncpointStore
 
-	checkpointTs  model.Ts
+	// mu protects the checkpointTs and the tables replicated at it.
+	mu struct {
+		sync.Mutex
+		checkpointTs      model.Ts
+		currentTableNames []model.TableName
+		runningDDLs       []*model.RunningDDL
+	}
 	ddlFinishedTs model.Ts
 	ddlSentTs     model.Ts
 
@@ -144,12 +154,19 @@ func (s *ddlSinkImpl) run(ctx cdcContext.Context, id model.ChangeFeedID, info *m
 				ctx.Throw(err)
 				return
 			case <-ticker.C:
-				checkpointTs := atomic.LoadUint64(&s.checkpointTs)
+				if err := s.refreshRunningDDLs(ctx); err != nil {
+					ctx.Throw(errors.Trace(err))
+					return
+				}
+				s.mu.Lock()
+				checkpointTs := s.mu.checkpointTs
+				tableNames := s.mu.currentTableNames
+				s.mu.Unlock()
 				if checkpointTs == 0 || checkpointTs <= lastCheckpointTs {
 					continue
 				}
 				lastCheckpointTs = checkpointTs
-				if err := s.sink.EmitCheckpointTs(ctx, checkpointTs); err != nil {
+				if err := s.sink.EmitCheckpointTs(ctx, checkpointTs, tableNames); err != nil {
 					ctx.Throw(errors.Trace(err))
 					return
 				}
@@ -158,6 +175,11 @@ func (s *ddlSinkImpl) run(ctx cdcContext.Context, id model.ChangeFeedID, info *m
 				failpoint.Inject("InjectChangefeedDDLError", func() {
 					err = cerror.ErrExecDDLFailed.GenWithStackByArgs()
 				})
+				if err == nil {
+					// the async DDL must be seen by the changefeed before
+					// it's reported as executed, to hold the checkpoint.
+					err = s.refreshRunningDDLs(ctx)
+				}
 				if err == nil || cerror.ErrDDLEventIgnored.Equal(errors.Cause(err)) {
 					log.Info("Execute DDL succeeded",
 						zap.String("changefeed", ctx.ChangefeedVars().ID),
@@ -178,8 +200,11 @@ func (s *ddlSinkImpl) run(ctx cdcContext.Context, id model.ChangeFeedID, info *m
 	}()
 }
 
-func (s *ddlSinkImpl) emitCheckpointTs(ctx cdcContext.Context, ts uint64) {
-	atomic.StoreUint64(&s.checkpointTs, ts)
+func (s *ddlSinkImpl) emitCheckpointTs(ctx cdcContext.Context, ts uint64, tableNames []model.TableName) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.mu.checkpointTs = ts
+	s.mu.currentTableNames = tableNames
 }
 
 func (s *ddlSinkImpl) emitDDLEvent(ctx cdcContext.Context, ddl *model.DDLEvent) (bool, error) {
@@ -204,6 +229,29 @@ func (s *ddlSinkImpl) emitDDLEvent(ctx cdcContext.Context, ddl *model.DDLEvent)
 	return false, nil
 }
 
+// refreshRunningDDLs queries the running DDLs from the sink if it executes
+// some DDLs asynchronously.
+func (s *ddlSinkImpl) refreshRunningDDLs(ctx context.Context) error {
+	asyncSink, ok := s.sink.(sink.AsyncDDLSink)
+	if !ok {
+		return nil
+	}
+	runningDDLs, err := asyncSink.RunningDDLs(ctx)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	s.mu.Lock()
+	s.mu.runningDDLs = runningDDLs
+	s.mu.Unlock()
+	return nil
+}
+
+func (s *ddlSinkImpl) runningDDLs() []*model.RunningDDL {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.mu.runningDDLs
+}
+
 func (s *ddlSinkImpl) emitSyncPoint(ctx cdcContext.Context, checkpointTs uint64) error {
 	if checkpointTs == s.lastSyncPoint {
 		return nil
@@ -490,6 +490,16 @@ func (o *ownerImpl) handleQueries(query *Query) error {
 			})
 		}
 		query.Data = ret
+	case QueryRunningDDLs:
+		cfReactor, ok := o.changefeeds[query.ChangeFeedID]
+		if !ok {
+			return cerror.ErrChangeFeedNotExists.GenWithStackByArgs(query.ChangeFeedID)
+		}
+		var ret []*model.RunningDDL
+		if cfReactor.sink != nil {
+			ret = cfReactor.sink.runningDDLs()
+		}
+		query.Data = ret
 	}
 	return nil
 }
@@ -72,6 +72,24 @@ func TestAllPhysicalTables(t *testing.T) {
 	require.Equal(t, schema.AllPhysicalTables(), expectedTableIDs)
 }
 
+func TestAllTableNames(t *testing.T) {
+	helper := entry.NewSchemaTestHelper(t)
+	defer helper.Close()
+	ver, err := helper.Storage().CurrentVersion(oracle.GlobalTxnScope)
+	require.Nil(t, err)
+	schema, err := newSchemaWrap4Owner(helper.Storage(), ver.Ver, config.GetDefaultReplicaConfig())
+	require.Nil(t, err)
+	require.Len(t, schema.AllTableNames(), 0)
+	// add normal table
+	require.Nil(t, schema.HandleDDL(helper.DDL2Job("create table test.t1(id int primary key)")))
+	// add ineligible table
+	require.Nil(t, schema.HandleDDL(helper.DDL2Job("create table test.t2(id int)")))
+	tableNames := schema.AllTableNames()
+	require.Len(t, tableNames, 1)
+	require.Equal(t, "test", tableNames[0].Schema)
+	require.Equal(t, "t1", tableNames[0].Table)
+}
+
 func TestIsIneligibleTableID(t *testing.T) {
 	helper := entry.NewSchemaTestHelper(t)
 	defer helper.Close()
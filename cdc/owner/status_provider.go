@@ -48,6 +48,10 @@ type StatusProvider interface {
 
 	// GetCaptures returns the information about all captures.
 	GetCaptures(ctx context.Context) ([]*model.CaptureInfo, error)
+
+	// GetRunningDDLs returns the DDLs of the changefeed which are executed
+	// asynchronously and still running in the downstream.
+	GetRunningDDLs(ctx context.Context, changefeedID model.ChangeFeedID) ([]*model.RunningDDL, error)
 }
 
 // QueryType is the type of different queries.
@@ -66,6 +70,8 @@ const (
 	QueryProcessors
 	// QueryCaptures is the type of query captures info.
 	QueryCaptures
+	// QueryRunningDDLs is the type of query running async DDLs.
+	QueryRunningDDLs
 )
 
 // Query wraps query command and return results.
@@ -171,6 +177,17 @@ func (p *ownerStatusProvider) GetCaptures(ctx context.Context) ([]*model.Capture
 	return query.Data.([]*model.CaptureInfo), nil
 }
 
+func (p *ownerStatusProvider) GetRunningDDLs(ctx context.Context, changefeedID model.ChangeFeedID) ([]*model.RunningDDL, error) {
+	query := &Query{
+		Tp:           QueryRunningDDLs,
+		ChangeFeedID: changefeedID,
+	}
+	if err := p.sendQueryToOwner(ctx, query); err != nil {
+		return nil, errors.Trace(err)
+	}
+	return query.Data.([]*model.RunningDDL), nil
+}
+
 func (p *ownerStatusProvider) sendQueryToOwner(ctx context.Context, query *Query) error {
 	doneCh := make(chan error, 1)
 	p.owner.Query(query, doneCh)
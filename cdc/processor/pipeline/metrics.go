@@ -33,10 +33,18 @@ var (
 			Help:      "estimated memory consumption for a table after the sorter",
 			Buckets:   prometheus.ExponentialBuckets(1*1024*1024 /* mb */, 2, 10),
 		}, []string{"changefeed", "capture"})
+	sinkThrottledDuration = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "ticdc",
+			Subsystem: "processor",
+			Name:      "sink_throttled_duration_seconds",
+			Help:      "total duration of the sink nodes throttled by the rate limits",
+		}, []string{"changefeed", "capture"})
 )
 
 // InitMetrics registers all metrics used in processor
 func InitMetrics(registry *prometheus.Registry) {
 	registry.MustRegister(txnCounter)
 	registry.MustRegister(tableMemoryHistogram)
+	registry.MustRegister(sinkThrottledDuration)
 }
@@ -0,0 +1,159 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pipeline
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/pingcap/errors"
+	"github.com/pingcap/log"
+	"github.com/pingcap/tiflow/cdc/model"
+	"github.com/pingcap/tiflow/pkg/config"
+	"github.com/prometheus/client_golang/prometheus"
+	"go.uber.org/zap"
+	"golang.org/x/time/rate"
+)
+
+// RateLimiter limits the rows and bytes emitted by the sink nodes of a
+// changefeed, it's shared by all the tables of the processor. The limits of
+// the changefeed are split evenly among the captures replicating it.
+type RateLimiter struct {
+	changefeedID string
+	captureAddr  string
+
+	mu       sync.Mutex
+	cfg      config.RateLimitConfig
+	captures int
+	rows     *rate.Limiter
+	bytes    *rate.Limiter
+
+	metricThrottledDuration prometheus.Counter
+}
+
+// NewRateLimiter creates a RateLimiter for a single capture, a nil config
+// means no limit.
+func NewRateLimiter(cfg *config.RateLimitConfig, changefeedID, captureAddr string) *RateLimiter {
+	l := &RateLimiter{
+		changefeedID:            changefeedID,
+		captureAddr:             captureAddr,
+		captures:                1,
+		metricThrottledDuration: sinkThrottledDuration.WithLabelValues(changefeedID, captureAddr),
+	}
+	if cfg != nil {
+		l.cfg = *cfg
+	}
+	l.rows = newLimiter(l.cfg.RowsPerSecond, l.captures)
+	l.bytes = newLimiter(l.cfg.BytesPerSecond, l.captures)
+	return l
+}
+
+// newLimiter creates a limiter of the share of one capture, which allows a
+// burst of one second, nil is returned if there is no limit.
+func newLimiter(perSecond uint64, captures int) *rate.Limiter {
+	if perSecond == 0 {
+		return nil
+	}
+	share := (perSecond + uint64(captures) - 1) / uint64(captures)
+	return rate.NewLimiter(rate.Limit(share), int(share))
+}
+
+// UpdateConfig applies the new limits if they or the number of the captures
+// replicating the changefeed are changed.
+func (l *RateLimiter) UpdateConfig(cfg *config.RateLimitConfig, captures int) {
+	var newCfg config.RateLimitConfig
+	if cfg != nil {
+		newCfg = *cfg
+	}
+	if captures < 1 {
+		captures = 1
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.cfg == newCfg && l.captures == captures {
+		return
+	}
+	l.cfg, l.captures = newCfg, captures
+	l.rows = newLimiter(newCfg.RowsPerSecond, captures)
+	l.bytes = newLimiter(newCfg.BytesPerSecond, captures)
+	log.Info("update the rate limits of the sink",
+		zap.String("changefeed", l.changefeedID),
+		zap.Uint64("rowsPerSecond", newCfg.RowsPerSecond),
+		zap.Uint64("bytesPerSecond", newCfg.BytesPerSecond),
+		zap.Int("captures", captures))
+}
+
+// Wait blocks until the rows are allowed by the limits.
+func (l *RateLimiter) Wait(ctx context.Context, rows []*model.RowChangedEvent) error {
+	if l == nil || len(rows) == 0 {
+		return nil
+	}
+	l.mu.Lock()
+	rowsLimiter, bytesLimiter := l.rows, l.bytes
+	l.mu.Unlock()
+	if rowsLimiter == nil && bytesLimiter == nil {
+		return nil
+	}
+
+	throttled, err := waitN(ctx, rowsLimiter, len(rows))
+	if err == nil && bytesLimiter != nil {
+		size := 0
+		for _, row := range rows {
+			size += row.ApproximateBytes()
+		}
+		var bytesThrottled time.Duration
+		bytesThrottled, err = waitN(ctx, bytesLimiter, size)
+		throttled += bytesThrottled
+	}
+	l.metricThrottledDuration.Add(throttled.Seconds())
+	return errors.Trace(err)
+}
+
+// waitN waits for n tokens, they're acquired in batches of the burst at most.
+// It returns the duration blocked by the limiter.
+func waitN(ctx context.Context, limiter *rate.Limiter, n int) (time.Duration, error) {
+	if limiter == nil {
+		return 0, nil
+	}
+	var throttled time.Duration
+	for n > 0 {
+		batch := n
+		if batch > limiter.Burst() {
+			batch = limiter.Burst()
+		}
+		r := limiter.ReserveN(time.Now(), batch)
+		if delay := r.Delay(); delay > 0 {
+			timer := time.NewTimer(delay)
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				r.Cancel()
+				return throttled, ctx.Err()
+			case <-timer.C:
+			}
+			throttled += delay
+		}
+		n -= batch
+	}
+	return throttled, nil
+}
+
+// Close cleans up the metrics of the RateLimiter.
+func (l *RateLimiter) Close() {
+	if l == nil {
+		return
+	}
+	sinkThrottledDuration.DeleteLabelValues(l.changefeedID, l.captureAddr)
+}
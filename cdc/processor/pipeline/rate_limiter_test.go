@@ -0,0 +1,75 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pipeline
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/pingcap/tiflow/cdc/model"
+	"github.com/pingcap/tiflow/pkg/config"
+	"github.com/stretchr/testify/require"
+)
+
+func newRows4RateLimiterTest(n int) []*model.RowChangedEvent {
+	rows := make([]*model.RowChangedEvent, n)
+	for i := range rows {
+		rows[i] = &model.RowChangedEvent{
+			Table:   &model.TableName{Schema: "test", Table: "t"},
+			Columns: []*model.Column{{Name: "a", Value: i, ApproximateBytes: 100}},
+		}
+	}
+	return rows
+}
+
+func TestRateLimiter(t *testing.T) {
+	ctx := context.Background()
+
+	// a nil limiter and an empty config don't limit the rows.
+	var nilLimiter *RateLimiter
+	require.Nil(t, nilLimiter.Wait(ctx, newRows4RateLimiterTest(10)))
+	limiter := NewRateLimiter(nil, "test-changefeed", "127.0.0.1:8300")
+	defer limiter.Close()
+	start := time.Now()
+	require.Nil(t, limiter.Wait(ctx, newRows4RateLimiterTest(10000)))
+	require.Less(t, time.Since(start), 100*time.Millisecond)
+
+	// the burst is one second, the rows beyond it are throttled.
+	limiter.UpdateConfig(&config.RateLimitConfig{RowsPerSecond: 100}, 1)
+	start = time.Now()
+	require.Nil(t, limiter.Wait(ctx, newRows4RateLimiterTest(150)))
+	require.GreaterOrEqual(t, time.Since(start), 400*time.Millisecond)
+
+	limiter.UpdateConfig(&config.RateLimitConfig{BytesPerSecond: 10000}, 1)
+	start = time.Now()
+	require.Nil(t, limiter.Wait(ctx, newRows4RateLimiterTest(150)))
+	require.GreaterOrEqual(t, time.Since(start), 400*time.Millisecond)
+
+	// the limits are split among the captures.
+	limiter.UpdateConfig(&config.RateLimitConfig{RowsPerSecond: 200}, 2)
+	start = time.Now()
+	require.Nil(t, limiter.Wait(ctx, newRows4RateLimiterTest(150)))
+	require.GreaterOrEqual(t, time.Since(start), 400*time.Millisecond)
+
+	// the wait is canceled with the context.
+	cctx, cancel := context.WithCancel(ctx)
+	cancel()
+	require.Error(t, limiter.Wait(cctx, newRows4RateLimiterTest(300)))
+
+	limiter.UpdateConfig(nil, 1)
+	start = time.Now()
+	require.Nil(t, limiter.Wait(ctx, newRows4RateLimiterTest(10000)))
+	require.Less(t, time.Since(start), 100*time.Millisecond)
+}
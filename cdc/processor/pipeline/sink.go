@@ -78,12 +78,18 @@ type sinkNode struct {
 	rowBuffer []*model.RowChangedEvent
 
 	flowController tableFlowController
+	// rateLimiter is shared by the sink nodes of the changefeed, nil means
+	// no limit.
+	rateLimiter *RateLimiter
 
 	replicaConfig    *config.ReplicaConfig
 	isTableActorMode bool
 }
 
-func newSinkNode(tableID model.TableID, sink sink.Sink, startTs model.Ts, targetTs model.Ts, flowController tableFlowController) *sinkNode {
+func newSinkNode(
+	tableID model.TableID, sink sink.Sink, startTs model.Ts, targetTs model.Ts,
+	flowController tableFlowController, rateLimiter *RateLimiter,
+) *sinkNode {
 	return &sinkNode{
 		tableID:      tableID,
 		sink:         sink,
@@ -94,6 +100,7 @@ func newSinkNode(tableID model.TableID, sink sink.Sink, startTs model.Ts, target
 		barrierTs:    startTs,
 
 		flowController: flowController,
+		rateLimiter:    rateLimiter,
 	}
 }
 
@@ -303,6 +310,9 @@ func (n *sinkNode) emitRowToSink(ctx context.Context) error {
 		time.Sleep(10 * time.Second)
 		panic("ProcessorSyncResolvedPreEmit")
 	})
+	if err := n.rateLimiter.Wait(ctx, n.rowBuffer); err != nil {
+		return errors.Trace(err)
+	}
 	err := n.sink.EmitRowChangedEvents(ctx, n.rowBuffer...)
 	if err != nil {
 		return errors.Trace(err)
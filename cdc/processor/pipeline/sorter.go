@@ -196,6 +196,11 @@ func (n *sorterNode) StartActorNode(ctx pipeline.NodeContext, isTableActorMode b
 						}
 						return errors.Trace(err)
 					}
+					// The row is nil if it's ignored by the mounter, such as
+					// the rows filtered out by the expression filter.
+					if msg.Row == nil {
+						continue
+					}
 					// We calculate memory consumption by RowChangedEvent size.
 					// It's much larger than RawKVEntry.
 					size := uint64(msg.Row.ApproximateBytes())
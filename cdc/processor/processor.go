@@ -32,12 +32,14 @@ import (
 	"github.com/pingcap/tiflow/cdc/puller"
 	"github.com/pingcap/tiflow/cdc/redo"
 	"github.com/pingcap/tiflow/cdc/sink"
+	"github.com/pingcap/tiflow/cdc/sink/common"
 	"github.com/pingcap/tiflow/cdc/sorter/memory"
 	"github.com/pingcap/tiflow/pkg/config"
 	cdcContext "github.com/pingcap/tiflow/pkg/context"
 	"github.com/pingcap/tiflow/pkg/cyclic/mark"
 	cerror "github.com/pingcap/tiflow/pkg/errors"
 	"github.com/pingcap/tiflow/pkg/filter"
+	"github.com/pingcap/tiflow/pkg/masking"
 	"github.com/pingcap/tiflow/pkg/orchestrator"
 	"github.com/pingcap/tiflow/pkg/regionspan"
 	"github.com/pingcap/tiflow/pkg/retry"
@@ -50,6 +52,9 @@ import (
 const (
 	backoffBaseDelayInMs = 5
 	maxTries             = 3
+	// memoryReportInterval is the interval of writing the memory consumption
+	// to the task position if the ts don't change.
+	memoryReportInterval = 10 * time.Second
 )
 
 type processor struct {
@@ -65,8 +70,12 @@ type processor struct {
 	filter        *filter.Filter
 	mounter       entry.Mounter
 	sinkManager   *sink.Manager
+	rateLimiter   *tablepipeline.RateLimiter
+	memoryQuota   *common.ChangefeedMemoryQuota
 	redoManager   redo.LogManager
 	lastRedoFlush time.Time
+	// lastMemoryReport is the last time the memory consumption is written.
+	lastMemoryReport time.Time
 
 	initialized bool
 	errCh       chan error
@@ -91,6 +100,7 @@ type processor struct {
 	metricMinCheckpointTableIDGuage prometheus.Gauge
 	metricSyncTableNumGauge         prometheus.Gauge
 	metricSchemaStorageGcTsGauge    prometheus.Gauge
+	metricMemoryConsumptionGauge    prometheus.Gauge
 	metricProcessorErrorCounter     prometheus.Counter
 	metricProcessorTickDuration     prometheus.Observer
 }
@@ -247,6 +257,7 @@ func newProcessor(ctx cdcContext.Context) *processor {
 		metricSyncTableNumGauge:         syncTableNumGauge.WithLabelValues(changefeedID, advertiseAddr),
 		metricProcessorErrorCounter:     processorErrorCounter.WithLabelValues(changefeedID, advertiseAddr),
 		metricSchemaStorageGcTsGauge:    processorSchemaStorageGcTsGauge.WithLabelValues(changefeedID, advertiseAddr),
+		metricMemoryConsumptionGauge:    processorMemoryConsumptionGauge.WithLabelValues(changefeedID, advertiseAddr),
 		metricProcessorTickDuration:     processorTickDuration.WithLabelValues(changefeedID, advertiseAddr),
 	}
 	p.createTablePipeline = p.createTablePipelineImpl
@@ -348,6 +359,9 @@ func (p *processor) tick(ctx cdcContext.Context, state *orchestrator.ChangefeedR
 	}
 	// sink manager will return this checkpointTs to sink node if sink node resolvedTs flush failed
 	p.sinkManager.UpdateChangeFeedCheckpointTs(state.Info.GetCheckpointTs(state.Status))
+	// the rate limits can be updated while the changefeed is running, they're
+	// split among the captures which have created the task positions.
+	p.rateLimiter.UpdateConfig(state.Info.Config.RateLimit, len(state.TaskPositions))
 	if err := p.handleTableOperation(ctx); err != nil {
 		return nil, errors.Trace(err)
 	}
@@ -464,7 +478,11 @@ func (p *processor) lazyInitImpl(ctx cdcContext.Context) error {
 	stdCtx = util.PutCaptureAddrInCtx(stdCtx, p.captureInfo.AdvertiseAddr)
 	stdCtx = util.PutRoleInCtx(stdCtx, util.RoleProcessor)
 
-	p.mounter = entry.NewMounter(p.schemaStorage, p.changefeed.Info.Config.Mounter.WorkerNum, p.changefeed.Info.Config.EnableOldValue)
+	masker, err := masking.New(p.changefeed.Info.Config)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	p.mounter = entry.NewMounter(p.schemaStorage, p.filter, masker, p.changefeed.Info.Config.Mounter.WorkerNum, p.changefeed.Info.Config.EnableOldValue)
 	p.wg.Add(1)
 	go func() {
 		defer p.wg.Done()
@@ -502,6 +520,10 @@ func (p *processor) lazyInitImpl(ctx cdcContext.Context) error {
 	checkpointTs := p.changefeed.Info.GetCheckpointTs(p.changefeed.Status)
 	captureAddr := ctx.GlobalVars().CaptureInfo.AdvertiseAddr
 	p.sinkManager = sink.NewManager(stdCtx, s, errCh, checkpointTs, captureAddr, p.changefeedID)
+	p.rateLimiter = tablepipeline.NewRateLimiter(p.changefeed.Info.Config.RateLimit, p.changefeedID, captureAddr)
+	// The memory quota of the changefeed is opt-in, the tables are only limited
+	// by the per-table quota if it isn't set, the consumption is tracked anyway.
+	p.memoryQuota = common.NewChangefeedMemoryQuota(p.changefeed.Info.Config.MemoryQuota)
 	redoManagerOpts := &redo.ManagerOptions{EnableBgRunner: true, ErrCh: errCh}
 	p.redoManager, err = redo.NewManager(stdCtx, p.changefeed.Info.Config.Consistent, redoManagerOpts)
 	if err != nil {
@@ -832,6 +854,9 @@ func (p *processor) handlePosition(currentTs int64) {
 	p.metricCheckpointTsGauge.Set(float64(checkpointPhyTs))
 	p.metricMinCheckpointTableIDGuage.Set(float64(minCheckpointTableID))
 
+	memoryConsumption := p.memoryQuota.GetConsumption()
+	p.metricMemoryConsumptionGauge.Set(float64(memoryConsumption))
+
 	if p.newSchedulerEnabled {
 		p.checkpointTs = minCheckpointTs
 		p.resolvedTs = minResolvedTs
@@ -839,8 +864,15 @@ func (p *processor) handlePosition(currentTs int64) {
 	}
 
 	// minResolvedTs and minCheckpointTs may less than global resolved ts and global checkpoint ts when a new table added, the startTs of the new table is less than global checkpoint ts.
-	if minResolvedTs != p.changefeed.TaskPositions[p.captureInfo.ID].ResolvedTs ||
-		minCheckpointTs != p.changefeed.TaskPositions[p.captureInfo.ID].CheckPointTs {
+	position := p.changefeed.TaskPositions[p.captureInfo.ID]
+	tsChanged := minResolvedTs != position.ResolvedTs || minCheckpointTs != position.CheckPointTs
+	// The memory consumption is only for display, it's written along with the
+	// changes of the ts, or at a coarse interval, instead of writing etcd in
+	// every tick.
+	memoryChanged := memoryConsumption != position.MemoryConsumption &&
+		time.Since(p.lastMemoryReport) >= memoryReportInterval
+	if tsChanged || memoryChanged {
+		p.lastMemoryReport = time.Now()
 		p.changefeed.PatchTaskPosition(p.captureInfo.ID, func(position *model.TaskPosition) (*model.TaskPosition, bool, error) {
 			failpoint.Inject("ProcessorUpdatePositionDelaying", nil)
 			if position == nil {
@@ -851,6 +883,7 @@ func (p *processor) handlePosition(currentTs int64) {
 			}
 			position.CheckPointTs = minCheckpointTs
 			position.ResolvedTs = minResolvedTs
+			position.MemoryConsumption = memoryConsumption
 			return position, true, nil
 		})
 	}
@@ -986,6 +1019,8 @@ func (p *processor) createTablePipelineImpl(ctx cdcContext.Context, tableID mode
 		replicaInfo,
 		sink,
 		p.changefeed.Info.GetTargetTs(),
+		p.rateLimiter,
+		p.memoryQuota,
 	)
 
 	if p.redoManager.Enabled() {
@@ -1100,6 +1135,8 @@ func (p *processor) Close() error {
 	syncTableNumGauge.DeleteLabelValues(p.changefeedID, p.captureInfo.AdvertiseAddr)
 	processorErrorCounter.DeleteLabelValues(p.changefeedID, p.captureInfo.AdvertiseAddr)
 	processorSchemaStorageGcTsGauge.DeleteLabelValues(p.changefeedID, p.captureInfo.AdvertiseAddr)
+	processorMemoryConsumptionGauge.DeleteLabelValues(p.changefeedID, p.captureInfo.AdvertiseAddr)
+	p.rateLimiter.Close()
 
 	return nil
 }
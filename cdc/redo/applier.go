@@ -22,10 +22,10 @@ import (
 
 // NewRedoReader creates a new redo log reader
 func NewRedoReader(ctx context.Context, storage string, cfg *reader.LogReaderConfig) (rd reader.RedoLogReader, err error) {
-	switch consistentStorage(storage) {
-	case consistentStorageBlackhole:
+	switch {
+	case consistentStorage(storage) == consistentStorageBlackhole:
 		rd = reader.NewBlackHoleReader()
-	case consistentStorageLocal, consistentStorageNFS, consistentStorageS3:
+	case IsValidConsistentStorage(storage):
 		rd, err = reader.NewLogReader(ctx, cfg)
 	default:
 		err = cerror.ErrConsistentStorage.GenWithStackByArgs(storage)
@@ -0,0 +1,300 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package common
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/hex"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/pierrec/lz4"
+	"github.com/pingcap/errors"
+	cerror "github.com/pingcap/tiflow/pkg/errors"
+)
+
+// The redo log files of version 1 are the frames of raw msgp-encoded records.
+// The files of version 2 start with a header, which records how the records
+// in the file are encoded. The records are still framed one by one, so the
+// torn writes can be detected in the same way.
+const (
+	// LogFileVersion2 is the version of the files with the header.
+	LogFileVersion2 = 2
+	// FileHeaderSize is the size of the file header, it keeps the frames
+	// aligned to 8 bytes.
+	FileHeaderSize = 8
+)
+
+// fileHeaderMagic starts the header, the length field of the first frame of
+// version 1 files can't be the same, as the record would be larger than 1GB.
+var fileHeaderMagic = []byte("REDO")
+
+// The compressions of the redo log records.
+const (
+	CompressionNone = "none"
+	CompressionLZ4  = "lz4"
+	CompressionZstd = "zstd"
+)
+
+// compressionType is the compression recorded in the file header.
+type compressionType byte
+
+const (
+	compressionTypeNone compressionType = iota
+	compressionTypeLZ4
+	compressionTypeZstd
+)
+
+var compressionTypes = map[string]compressionType{
+	"":              compressionTypeNone,
+	CompressionNone: compressionTypeNone,
+	CompressionLZ4:  compressionTypeLZ4,
+	CompressionZstd: compressionTypeZstd,
+}
+
+// IsValidCompression checks whether the compression of redo log records is valid
+func IsValidCompression(compression string) bool {
+	_, ok := compressionTypes[compression]
+	return ok
+}
+
+// encryptionType is the encryption recorded in the file header.
+type encryptionType byte
+
+const (
+	encryptionTypeNone encryptionType = iota
+	encryptionTypeAESGCM
+)
+
+// LogCodec encodes the redo log records, the records are compressed and then
+// encrypted by AES-GCM. A nil LogCodec keeps the records as they are.
+// It's not thread-safe.
+type LogCodec struct {
+	compression compressionType
+	encryption  encryptionType
+	aead        cipher.AEAD
+
+	zstdEncoder *zstd.Encoder
+	zstdDecoder *zstd.Decoder
+}
+
+// NewLogCodec creates a LogCodec with the compression, the records are
+// encrypted if the key is not empty. It returns nil if the records needn't
+// be encoded, so the files are the same as the version 1 ones.
+func NewLogCodec(compression string, key []byte) (*LogCodec, error) {
+	tp, ok := compressionTypes[compression]
+	if !ok {
+		return nil, cerror.WrapError(cerror.ErrRedoConfigInvalid,
+			errors.Errorf("invalid compression %s, should be none, lz4 or zstd", compression))
+	}
+	encryption := encryptionTypeNone
+	if len(key) != 0 {
+		encryption = encryptionTypeAESGCM
+	}
+	return newLogCodec(tp, encryption, key)
+}
+
+func newLogCodec(compression compressionType, encryption encryptionType, key []byte) (*LogCodec, error) {
+	if compression == compressionTypeNone && encryption == encryptionTypeNone {
+		return nil, nil
+	}
+	c := &LogCodec{compression: compression, encryption: encryption}
+	switch compression {
+	case compressionTypeNone, compressionTypeLZ4:
+	case compressionTypeZstd:
+		var err error
+		c.zstdEncoder, err = zstd.NewWriter(nil)
+		if err != nil {
+			return nil, cerror.WrapError(cerror.ErrRedoConfigInvalid, err)
+		}
+	default:
+		return nil, cerror.WrapError(cerror.ErrRedoConfigInvalid,
+			errors.Errorf("unknown compression %d", compression))
+	}
+	switch encryption {
+	case encryptionTypeNone:
+	case encryptionTypeAESGCM:
+		if len(key) == 0 {
+			return nil, cerror.WrapError(cerror.ErrRedoConfigInvalid,
+				errors.New("the redo logs are encrypted, but no encryption key is provided"))
+		}
+		block, err := aes.NewCipher(key)
+		if err != nil {
+			return nil, cerror.WrapError(cerror.ErrRedoConfigInvalid, err)
+		}
+		c.aead, err = cipher.NewGCM(block)
+		if err != nil {
+			return nil, cerror.WrapError(cerror.ErrRedoConfigInvalid, err)
+		}
+	default:
+		return nil, cerror.WrapError(cerror.ErrRedoConfigInvalid,
+			errors.Errorf("unknown encryption %d", encryption))
+	}
+	return c, nil
+}
+
+// IsFileHeader returns whether the data starts with a file header.
+func IsFileHeader(data []byte) bool {
+	return len(data) >= FileHeaderSize && bytes.Equal(data[:len(fileHeaderMagic)], fileHeaderMagic)
+}
+
+// ParseFileHeader creates the LogCodec of the file by the header, the key is
+// used to decrypt the records if the file is encrypted.
+func ParseFileHeader(header []byte, key []byte) (*LogCodec, error) {
+	if !IsFileHeader(header) {
+		return nil, cerror.WrapError(cerror.ErrRedoFileOp, errors.New("invalid redo log file header"))
+	}
+	if version := header[4]; version != LogFileVersion2 {
+		return nil, cerror.WrapError(cerror.ErrRedoFileOp,
+			errors.Errorf("unsupported redo log file version %d", version))
+	}
+	return newLogCodec(compressionType(header[5]), encryptionType(header[6]), key)
+}
+
+// Header returns the file header of the files encoded by the LogCodec.
+func (c *LogCodec) Header() []byte {
+	if c == nil {
+		return nil
+	}
+	header := make([]byte, FileHeaderSize)
+	copy(header, fileHeaderMagic)
+	header[4] = LogFileVersion2
+	header[5] = byte(c.compression)
+	header[6] = byte(c.encryption)
+	return header
+}
+
+// Encode compresses and encrypts the record.
+func (c *LogCodec) Encode(data []byte) ([]byte, error) {
+	if c == nil {
+		return data, nil
+	}
+	switch c.compression {
+	case compressionTypeLZ4:
+		buf := &bytes.Buffer{}
+		w := lz4.NewWriter(buf)
+		if _, err := w.Write(data); err != nil {
+			return nil, cerror.WrapError(cerror.ErrRedoLogCodec, err)
+		}
+		if err := w.Close(); err != nil {
+			return nil, cerror.WrapError(cerror.ErrRedoLogCodec, err)
+		}
+		data = buf.Bytes()
+	case compressionTypeZstd:
+		data = c.zstdEncoder.EncodeAll(data, nil)
+	}
+	if c.aead != nil {
+		nonce := make([]byte, c.aead.NonceSize(), c.aead.NonceSize()+len(data)+c.aead.Overhead())
+		if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+			return nil, cerror.WrapError(cerror.ErrRedoLogCodec, err)
+		}
+		data = c.aead.Seal(nonce, nonce, data, nil)
+	}
+	return data, nil
+}
+
+// Decode decrypts and decompresses the record.
+func (c *LogCodec) Decode(data []byte) ([]byte, error) {
+	if c == nil {
+		return data, nil
+	}
+	if c.aead != nil {
+		if len(data) < c.aead.NonceSize() {
+			return nil, cerror.WrapError(cerror.ErrRedoLogCodec, errors.New("the encrypted record is too short"))
+		}
+		nonce, ciphertext := data[:c.aead.NonceSize()], data[c.aead.NonceSize():]
+		var err error
+		data, err = c.aead.Open(nil, nonce, ciphertext, nil)
+		if err != nil {
+			return nil, cerror.WrapError(cerror.ErrRedoLogCodec,
+				errors.Annotate(err, "decrypt the record failed, the encryption key may be wrong"))
+		}
+	}
+	switch c.compression {
+	case compressionTypeLZ4:
+		decoded, err := ioutil.ReadAll(lz4.NewReader(bytes.NewReader(data)))
+		if err != nil {
+			return nil, cerror.WrapError(cerror.ErrRedoLogCodec, err)
+		}
+		data = decoded
+	case compressionTypeZstd:
+		// the decoder runs background goroutines, so it's only created by
+		// the readers.
+		if c.zstdDecoder == nil {
+			var err error
+			c.zstdDecoder, err = zstd.NewReader(nil, zstd.WithDecoderConcurrency(1))
+			if err != nil {
+				return nil, cerror.WrapError(cerror.ErrRedoLogCodec, err)
+			}
+		}
+		decoded, err := c.zstdDecoder.DecodeAll(data, nil)
+		if err != nil {
+			return nil, cerror.WrapError(cerror.ErrRedoLogCodec, err)
+		}
+		data = decoded
+	}
+	return data, nil
+}
+
+// Close stops the background goroutines of the decoder.
+func (c *LogCodec) Close() {
+	if c == nil || c.zstdDecoder == nil {
+		return
+	}
+	c.zstdDecoder.Close()
+	c.zstdDecoder = nil
+}
+
+// LoadEncryptionKey loads the hex-encoded AES key from the key file or the
+// environment variable, the key must be 16, 24 or 32 bytes. It returns nil
+// if neither of them is set.
+func LoadEncryptionKey(keyFile, keyEnv string) ([]byte, error) {
+	var encoded string
+	switch {
+	case keyFile != "" && keyEnv != "":
+		return nil, cerror.WrapError(cerror.ErrRedoConfigInvalid,
+			errors.New("the encryption key file and the encryption key env can't be both set"))
+	case keyFile != "":
+		data, err := os.ReadFile(keyFile)
+		if err != nil {
+			return nil, cerror.WrapError(cerror.ErrRedoConfigInvalid, err)
+		}
+		encoded = string(data)
+	case keyEnv != "":
+		encoded = os.Getenv(keyEnv)
+		if encoded == "" {
+			return nil, cerror.WrapError(cerror.ErrRedoConfigInvalid,
+				errors.Errorf("the encryption key env %s is not set", keyEnv))
+		}
+	default:
+		return nil, nil
+	}
+	key, err := hex.DecodeString(strings.TrimSpace(encoded))
+	if err != nil {
+		return nil, cerror.WrapError(cerror.ErrRedoConfigInvalid, errors.Annotate(err, "the encryption key should be hex-encoded"))
+	}
+	switch len(key) {
+	case 16, 24, 32:
+	default:
+		return nil, cerror.WrapError(cerror.ErrRedoConfigInvalid,
+			errors.Errorf("invalid encryption key length %d, should be 16, 24 or 32 bytes", len(key)))
+	}
+	return key, nil
+}
@@ -0,0 +1,125 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package common
+
+import (
+	"bytes"
+	"encoding/hex"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLogCodec(t *testing.T) {
+	t.Parallel()
+
+	key := bytes.Repeat([]byte{1}, 16)
+	data := bytes.Repeat([]byte("redo log record "), 64)
+
+	c, err := NewLogCodec(CompressionNone, nil)
+	require.Nil(t, err)
+	require.Nil(t, c)
+	require.Nil(t, c.Header())
+	encoded, err := c.Encode(data)
+	require.Nil(t, err)
+	require.Equal(t, data, encoded)
+
+	_, err = NewLogCodec("gzip", nil)
+	require.Regexp(t, "invalid compression", err)
+	_, err = NewLogCodec(CompressionLZ4, []byte("short"))
+	require.NotNil(t, err)
+
+	for _, compression := range []string{CompressionNone, CompressionLZ4, CompressionZstd} {
+		for _, k := range [][]byte{nil, key} {
+			if compression == CompressionNone && k == nil {
+				continue
+			}
+			c, err := NewLogCodec(compression, k)
+			require.Nil(t, err)
+			header := c.Header()
+			require.Len(t, header, FileHeaderSize)
+			require.True(t, IsFileHeader(header))
+
+			encoded, err := c.Encode(data)
+			require.Nil(t, err)
+			require.NotEqual(t, data, encoded)
+
+			decoder, err := ParseFileHeader(header, k)
+			require.Nil(t, err)
+			decoded, err := decoder.Decode(encoded)
+			require.Nil(t, err)
+			require.Equal(t, data, decoded)
+			decoder.Close()
+			c.Close()
+		}
+	}
+
+	c, err = NewLogCodec(CompressionZstd, key)
+	require.Nil(t, err)
+	encoded, err = c.Encode(data)
+	require.Nil(t, err)
+	_, err = ParseFileHeader(c.Header(), nil)
+	require.Regexp(t, "no encryption key is provided", err)
+	wrongKey, err := ParseFileHeader(c.Header(), bytes.Repeat([]byte{2}, 16))
+	require.Nil(t, err)
+	_, err = wrongKey.Decode(encoded)
+	require.Regexp(t, "the encryption key may be wrong", err)
+
+	require.False(t, IsFileHeader([]byte{1, 0, 0, 0, 0, 0, 0, 0}))
+	header := c.Header()
+	header[4] = 3
+	_, err = ParseFileHeader(header, key)
+	require.Regexp(t, "unsupported redo log file version 3", err)
+}
+
+func TestLoadEncryptionKey(t *testing.T) {
+	key, err := LoadEncryptionKey("", "")
+	require.Nil(t, err)
+	require.Nil(t, key)
+
+	dir, err := ioutil.TempDir("", "redo-key")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	expected := bytes.Repeat([]byte{0xab}, 32)
+	keyFile := filepath.Join(dir, "redo.key")
+	err = ioutil.WriteFile(keyFile, []byte(hex.EncodeToString(expected)+"\n"), 0o600)
+	require.Nil(t, err)
+	key, err = LoadEncryptionKey(keyFile, "")
+	require.Nil(t, err)
+	require.Equal(t, expected, key)
+
+	defer os.Unsetenv("TEST_REDO_ENCRYPTION_KEY")
+	require.Nil(t, os.Setenv("TEST_REDO_ENCRYPTION_KEY", hex.EncodeToString(expected[:24])))
+	key, err = LoadEncryptionKey("", "TEST_REDO_ENCRYPTION_KEY")
+	require.Nil(t, err)
+	require.Equal(t, expected[:24], key)
+
+	_, err = LoadEncryptionKey(keyFile, "TEST_REDO_ENCRYPTION_KEY")
+	require.Regexp(t, "can't be both set", err)
+	_, err = LoadEncryptionKey("", "TEST_REDO_ENCRYPTION_KEY_NOT_SET")
+	require.Regexp(t, "is not set", err)
+	_, err = LoadEncryptionKey(filepath.Join(dir, "not-exist"), "")
+	require.NotNil(t, err)
+
+	require.Nil(t, os.Setenv("TEST_REDO_ENCRYPTION_KEY", "not hex"))
+	_, err = LoadEncryptionKey("", "TEST_REDO_ENCRYPTION_KEY")
+	require.Regexp(t, "hex-encoded", err)
+	require.Nil(t, os.Setenv("TEST_REDO_ENCRYPTION_KEY", hex.EncodeToString(expected[:10])))
+	_, err = LoadEncryptionKey("", "TEST_REDO_ENCRYPTION_KEY")
+	require.Regexp(t, "invalid encryption key length 10", err)
+}
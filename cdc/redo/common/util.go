@@ -17,44 +17,38 @@ import (
 	"context"
 	"fmt"
 	"net/url"
+	"os"
 	"path/filepath"
 	"strings"
 
 	"github.com/pingcap/errors"
-	backuppb "github.com/pingcap/kvproto/pkg/brpb"
 	"github.com/pingcap/tidb/br/pkg/storage"
 	cerror "github.com/pingcap/tiflow/pkg/errors"
 )
 
-// InitS3storage init a storage used for s3,
-// s3URI should be like s3URI="s3://logbucket/test-changefeed?endpoint=http://$S3_ENDPOINT/"
-var InitS3storage = func(ctx context.Context, uri url.URL) (storage.ExternalStorage, error) {
-	if len(uri.Host) == 0 {
-		return nil, cerror.WrapError(cerror.ErrS3StorageInitialize, errors.Errorf("please specify the bucket for s3 in %v", uri))
-	}
-
-	prefix := strings.Trim(uri.Path, "/")
-	s3 := &backuppb.S3{Bucket: uri.Host, Prefix: prefix}
-	options := &storage.BackendOptions{}
-	storage.ExtractQueryParameters(&uri, &options.S3)
-	if err := options.S3.Apply(s3); err != nil {
-		return nil, cerror.WrapError(cerror.ErrS3StorageInitialize, err)
+// InitExternalStorage init an external storage used for redo logs, which can be
+// any backend supported by BR, such as S3, GCS, Azure Blob and local directory,
+// uri should be like uri="s3://logbucket/test-changefeed?endpoint=http://$S3_ENDPOINT/"
+var InitExternalStorage = func(ctx context.Context, uri url.URL) (storage.ExternalStorage, error) {
+	// the backend of s3 is path-style by default, the same as br.
+	backend, err := storage.ParseBackend(uri.String(), nil)
+	if err != nil {
+		return nil, cerror.WrapError(cerror.ErrRedoStorageInitialize, err)
 	}
-
-	// we should set this to true, since br set it by default in parseBackend
-	s3.ForcePathStyle = true
-	backend := &backuppb.StorageBackend{
-		Backend: &backuppb.StorageBackend_S3{S3: s3},
+	if local := backend.GetLocal(); local != nil {
+		if err := os.MkdirAll(local.GetPath(), DefaultDirMode); err != nil {
+			return nil, cerror.WrapError(cerror.ErrRedoStorageInitialize, err)
+		}
 	}
-	s3storage, err := storage.New(ctx, backend, &storage.ExternalStorageOptions{
+	extStorage, err := storage.New(ctx, backend, &storage.ExternalStorageOptions{
 		SendCredentials: false,
 		HTTPClient:      nil,
 	})
 	if err != nil {
-		return nil, cerror.WrapError(cerror.ErrS3StorageInitialize, err)
+		return nil, cerror.WrapError(cerror.ErrRedoStorageInitialize, err)
 	}
 
-	return s3storage, nil
+	return extStorage, nil
 }
 
 // ParseLogFileName extract the commitTs, fileType from log fileName
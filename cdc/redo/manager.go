@@ -22,9 +22,11 @@ import (
 	"sync/atomic"
 	"time"
 
+	"github.com/pingcap/errors"
 	"github.com/pingcap/log"
 	"github.com/pingcap/tidb/br/pkg/storage"
 	"github.com/pingcap/tiflow/cdc/model"
+	"github.com/pingcap/tiflow/cdc/redo/common"
 	"github.com/pingcap/tiflow/cdc/redo/writer"
 	"github.com/pingcap/tiflow/pkg/config"
 	cerror "github.com/pingcap/tiflow/pkg/errors"
@@ -49,10 +51,22 @@ type consistentStorage string
 const (
 	consistentStorageLocal     consistentStorage = "local"
 	consistentStorageNFS       consistentStorage = "nfs"
-	consistentStorageS3        consistentStorage = "s3"
 	consistentStorageBlackhole consistentStorage = "blackhole"
 )
 
+// The redo logs are written to a local directory first, and then uploaded to
+// the external storages by the storage interface of BR. The `file` storage is
+// a local directory accessed by the same interface, which stands in for the
+// remote storages in tests.
+const (
+	consistentStorageS3     consistentStorage = "s3"
+	consistentStorageGCS    consistentStorage = "gcs"
+	consistentStorageGS     consistentStorage = "gs"
+	consistentStorageAzure  consistentStorage = "azure"
+	consistentStorageAzblob consistentStorage = "azblob"
+	consistentStorageFile   consistentStorage = "file"
+)
+
 const (
 	// supposing to replicate 10k tables, each table has one cached changce averagely.
 	logBufferChanSize = 10000
@@ -72,11 +86,10 @@ func IsValidConsistentLevel(level string) bool {
 // IsValidConsistentStorage checks whether a give consistent storage is valid
 func IsValidConsistentStorage(storage string) bool {
 	switch consistentStorage(storage) {
-	case consistentStorageLocal, consistentStorageNFS,
-		consistentStorageS3, consistentStorageBlackhole:
+	case consistentStorageLocal, consistentStorageNFS, consistentStorageBlackhole:
 		return true
 	default:
-		return false
+		return IsExternalStorage(storage)
 	}
 }
 
@@ -85,9 +98,48 @@ func IsConsistentEnabled(level string) bool {
 	return IsValidConsistentLevel(level) && ConsistentLevelType(level) != ConsistentLevelNone
 }
 
-// IsS3StorageEnabled returns whether s3 storage is enabled
-func IsS3StorageEnabled(storage string) bool {
-	return consistentStorage(storage) == consistentStorageS3
+// IsExternalStorage returns whether the redo logs are uploaded to an external
+// storage
+func IsExternalStorage(storage string) bool {
+	switch consistentStorage(storage) {
+	case consistentStorageS3, consistentStorageGCS, consistentStorageGS,
+		consistentStorageAzure, consistentStorageAzblob, consistentStorageFile:
+		return true
+	default:
+		return false
+	}
+}
+
+// ValidateConsistentConfig checks the consistent config of a changefeed. The
+// parameters of the external storages are checked without accessing them,
+// so it can be called when the changefeed is created.
+func ValidateConsistentConfig(cfg *config.ConsistentConfig) error {
+	if cfg == nil {
+		return nil
+	}
+	if !IsValidConsistentLevel(cfg.Level) {
+		return cerror.ErrConsistentLevel.GenWithStackByArgs(cfg.Level)
+	}
+	if !IsConsistentEnabled(cfg.Level) {
+		return nil
+	}
+	uri, err := storage.ParseRawURL(cfg.Storage)
+	if err != nil {
+		return cerror.WrapError(cerror.ErrConsistentStorage, err, cfg.Storage)
+	}
+	if !IsValidConsistentStorage(uri.Scheme) {
+		return cerror.ErrConsistentStorage.GenWithStackByArgs(uri.Scheme)
+	}
+	if IsExternalStorage(uri.Scheme) {
+		if _, err := storage.ParseBackend(cfg.Storage, nil); err != nil {
+			return cerror.WrapError(cerror.ErrRedoStorageInitialize, err)
+		}
+	}
+	if !common.IsValidCompression(cfg.Compression) {
+		return cerror.WrapError(cerror.ErrRedoConfigInvalid,
+			errors.Errorf("invalid compression %s, should be none, lz4 or zstd", cfg.Compression))
+	}
+	return nil
 }
 
 // LogManager defines an interface that is used to manage redo log
@@ -148,6 +200,9 @@ func NewManager(ctx context.Context, cfg *config.ConsistentConfig, opts *Manager
 	if cfg == nil || ConsistentLevelType(cfg.Level) == ConsistentLevelNone {
 		return &ManagerImpl{enabled: false}, nil
 	}
+	if err := ValidateConsistentConfig(cfg); err != nil {
+		return nil, err
+	}
 	uri, err := storage.ParseRawURL(cfg.Storage)
 	if err != nil {
 		return nil, err
@@ -160,30 +215,36 @@ func NewManager(ctx context.Context, cfg *config.ConsistentConfig, opts *Manager
 		logBuffer:   make(chan cacheRows, logBufferChanSize),
 	}
 
-	switch m.storageType {
-	case consistentStorageBlackhole:
+	switch {
+	case m.storageType == consistentStorageBlackhole:
 		m.writer = writer.NewBlackHoleWriter()
-	case consistentStorageLocal, consistentStorageNFS, consistentStorageS3:
+	case IsValidConsistentStorage(string(m.storageType)):
 		globalConf := config.GetGlobalServerConfig()
 		changeFeedID := util.ChangefeedIDFromCtx(ctx)
-		// We use a temporary dir to storage redo logs before flushing to other backends, such as S3
+		// We use a temporary dir to storage redo logs before flushing to external storages, such as S3
 		redoDir := filepath.Join(globalConf.DataDir, config.DefaultRedoDir, changeFeedID)
 		if m.storageType == consistentStorageLocal || m.storageType == consistentStorageNFS {
 			// When using local or nfs as backend, store redo logs to redoDir directly.
 			redoDir = uri.Path
 		}
+		key, err := common.LoadEncryptionKey(cfg.EncryptionKeyFile, cfg.EncryptionKeyEnv)
+		if err != nil {
+			return nil, err
+		}
 
 		writerCfg := &writer.LogWriterConfig{
-			Dir:               redoDir,
-			CaptureID:         util.CaptureAddrFromCtx(ctx),
-			ChangeFeedID:      changeFeedID,
-			CreateTime:        time.Now(),
-			MaxLogSize:        cfg.MaxLogSize,
-			FlushIntervalInMs: cfg.FlushIntervalInMs,
-			S3Storage:         m.storageType == consistentStorageS3,
+			Dir:                redoDir,
+			CaptureID:          util.CaptureAddrFromCtx(ctx),
+			ChangeFeedID:       changeFeedID,
+			CreateTime:         time.Now(),
+			MaxLogSize:         cfg.MaxLogSize,
+			FlushIntervalInMs:  cfg.FlushIntervalInMs,
+			UseExternalStorage: IsExternalStorage(uri.Scheme),
+			Compression:        cfg.Compression,
+			EncryptionKey:      key,
 		}
-		if writerCfg.S3Storage {
-			writerCfg.S3URI = *uri
+		if writerCfg.UseExternalStorage {
+			writerCfg.ExternalStorageURI = *uri
 		}
 		writer, err := writer.NewLogWriter(ctx, writerCfg)
 		if err != nil {
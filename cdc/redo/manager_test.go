@@ -57,25 +57,62 @@ func TestConsistentConfig(t *testing.T) {
 		{"local", true},
 		{"nfs", true},
 		{"s3", true},
+		{"gcs", true},
+		{"azure", true},
+		{"file", true},
 		{"blackhole", true},
 		{"Local", false},
+		{"hdfs", false},
 		{"", false},
 	}
 	for _, sc := range storageCases {
 		require.Equal(t, sc.valid, IsValidConsistentStorage(sc.storage))
 	}
 
-	s3StorageCases := []struct {
-		storage   string
-		s3Enabled bool
+	externalStorageCases := []struct {
+		storage  string
+		external bool
 	}{
 		{"local", false},
 		{"nfs", false},
 		{"s3", true},
+		{"gs", true},
+		{"azblob", true},
+		{"file", true},
 		{"blackhole", false},
 	}
-	for _, sc := range s3StorageCases {
-		require.Equal(t, sc.s3Enabled, IsS3StorageEnabled(sc.storage))
+	for _, sc := range externalStorageCases {
+		require.Equal(t, sc.external, IsExternalStorage(sc.storage))
+	}
+}
+
+func TestValidateConsistentConfig(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		cfg     *config.ConsistentConfig
+		wantErr string
+	}{
+		{nil, ""},
+		{&config.ConsistentConfig{Level: "none", Storage: "hdfs://"}, ""},
+		{&config.ConsistentConfig{Level: "strong"}, "ErrConsistentLevel"},
+		{&config.ConsistentConfig{Level: "eventual", Storage: "blackhole://"}, ""},
+		{&config.ConsistentConfig{Level: "eventual", Storage: "local:///tmp/redo"}, ""},
+		{&config.ConsistentConfig{Level: "eventual", Storage: "file:///tmp/redo"}, ""},
+		{&config.ConsistentConfig{Level: "eventual", Storage: "s3://logbucket/cf?endpoint=http://127.0.0.1:9000/"}, ""},
+		{&config.ConsistentConfig{Level: "eventual", Storage: "gcs://logbucket/cf"}, ""},
+		{&config.ConsistentConfig{Level: "eventual", Storage: "hdfs://127.0.0.1/redo"}, "ErrConsistentStorage"},
+		{&config.ConsistentConfig{Level: "eventual", Storage: "s3:///cf"}, "ErrRedoStorageInitialize.*bucket"},
+		{&config.ConsistentConfig{Level: "eventual", Storage: "s3://logbucket/cf?endpoint=http://"}, "host not found in endpoint"},
+		{&config.ConsistentConfig{Level: "eventual", Storage: "blackhole://", Compression: "gzip"}, "invalid compression gzip"},
+	}
+	for _, tc := range cases {
+		err := ValidateConsistentConfig(tc.cfg)
+		if tc.wantErr == "" {
+			require.Nil(t, err)
+		} else {
+			require.Regexp(t, tc.wantErr, err)
+		}
 	}
 }
 
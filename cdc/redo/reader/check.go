@@ -0,0 +1,203 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package reader
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/pingcap/errors"
+	"github.com/pingcap/tiflow/cdc/model"
+	"github.com/pingcap/tiflow/cdc/redo/common"
+	cerror "github.com/pingcap/tiflow/pkg/errors"
+)
+
+// LogFileCheck is the result of checking a redo log file.
+type LogFileCheck struct {
+	Name     string `json:"name"`
+	FileType string `json:"file-type"`
+	// CommitTs is the max commit ts of the events recorded in the file name.
+	CommitTs    uint64 `json:"commit-ts"`
+	Events      int    `json:"events"`
+	MinCommitTs uint64 `json:"min-commit-ts,omitempty"`
+	MaxCommitTs uint64 `json:"max-commit-ts,omitempty"`
+	// Error is the reason why the file is broken, the events after it can't
+	// be read.
+	Error string `json:"error,omitempty"`
+}
+
+// LogFilesReport is the result of checking the redo log files of a
+// changefeed against the meta.
+type LogFilesReport struct {
+	CheckpointTs uint64          `json:"checkpoint-ts"`
+	ResolvedTs   uint64          `json:"resolved-ts"`
+	Files        []*LogFileCheck `json:"files"`
+	// Issues are the broken files and the gaps between the meta and the log
+	// files, the redo logs can be applied safely if it's empty.
+	Issues []string `json:"issues,omitempty"`
+}
+
+// CheckLogFiles reads all the events of the redo log files in the dir, and
+// reports the broken files and the gaps between the meta and the files.
+// The sorted files are skipped, as they're generated by the readers. The key
+// is used to decrypt the encrypted files.
+func CheckLogFiles(dir string, checkpointTs, resolvedTs uint64, key []byte) (*LogFilesReport, error) {
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, cerror.WrapError(cerror.ErrRedoFileOp, errors.Annotatef(err, "can't read log file directory: %s", dir))
+	}
+	report := &LogFilesReport{CheckpointTs: checkpointTs, ResolvedTs: resolvedTs}
+	if checkpointTs > resolvedTs {
+		report.addIssue("the checkpoint ts %d of the meta is larger than the resolved ts %d", checkpointTs, resolvedTs)
+	}
+
+	// the files of each capture and file type are in the order of commit ts.
+	captureFiles := make(map[string][]*LogFileCheck)
+	for _, file := range files {
+		name := file.Name()
+		ext := filepath.Ext(name)
+		if ext != common.LogEXT && ext != common.TmpEXT {
+			continue
+		}
+		commitTs, fileType, err := common.ParseLogFileName(name)
+		if err != nil {
+			report.addIssue("%s: %s", name, err)
+			continue
+		}
+		check, err := checkLogFile(filepath.Join(dir, name), key)
+		if err != nil {
+			return nil, err
+		}
+		check.Name, check.FileType, check.CommitTs = name, fileType, commitTs
+		report.Files = append(report.Files, check)
+
+		if ext == common.TmpEXT {
+			// the temporary files are being written, so the commit ts in the
+			// file name and the broken tail are expected.
+			continue
+		}
+		if check.Error != "" {
+			report.addIssue("%s is broken after %d events: %s", name, check.Events, check.Error)
+		}
+		if check.MaxCommitTs > commitTs {
+			report.addIssue("%s has events with commit ts %d larger than the one in the file name",
+				name, check.MaxCommitTs)
+		}
+		capture := strings.SplitN(name, "_", 2)[0] + "_" + fileType
+		captureFiles[capture] = append(captureFiles[capture], check)
+	}
+
+	for _, checks := range captureFiles {
+		sort.Slice(checks, func(i, j int) bool { return checks[i].CommitTs < checks[j].CommitTs })
+		for i := 1; i < len(checks); i++ {
+			if checks[i].Events != 0 && checks[i].MinCommitTs <= checks[i-1].CommitTs {
+				report.addIssue("%s has events with commit ts %d overlapping the previous file %s",
+					checks[i].Name, checks[i].MinCommitTs, checks[i-1].Name)
+			}
+		}
+	}
+
+	var maxCommitTs uint64
+	for _, check := range report.Files {
+		if check.MaxCommitTs > maxCommitTs {
+			maxCommitTs = check.MaxCommitTs
+		}
+	}
+	if maxCommitTs > resolvedTs {
+		report.addIssue("the log files have events with commit ts %d beyond the resolved ts %d of the meta, "+
+			"they won't be applied", maxCommitTs, resolvedTs)
+	}
+	return report, nil
+}
+
+func (r *LogFilesReport) addIssue(format string, args ...interface{}) {
+	r.Issues = append(r.Issues, fmt.Sprintf(format, args...))
+}
+
+// checkLogFile reads all the events of the file, it's the same as
+// reader.Read, except that the broken records are reported instead of
+// being taken as the end of the file.
+func checkLogFile(path string, key []byte) (*LogFileCheck, error) {
+	file, err := openReadFile(path)
+	if err != nil {
+		return nil, cerror.WrapError(cerror.ErrRedoFileOp, errors.Annotate(err, "can't open redo logfile"))
+	}
+	r := &reader{br: bufio.NewReader(file), fileName: file.Name(), closer: file, key: key}
+	defer r.Close() //nolint:errcheck
+	check := &LogFileCheck{}
+	if err := r.readHeader(); err != nil {
+		check.Error = err.Error()
+		return check, nil
+	}
+	for {
+		lenField, err := readInt64(r.br)
+		if err != nil {
+			if err == io.EOF {
+				return check, nil
+			}
+			check.Error = fmt.Sprintf("truncated frame at offset %d", r.lastValidOff)
+			if err != io.ErrUnexpectedEOF {
+				check.Error = err.Error()
+			}
+			return check, nil
+		}
+
+		recBytes, padBytes := decodeFrameSize(lenField)
+		data := make([]byte, recBytes+padBytes)
+		if _, err := io.ReadFull(r.br, data); err != nil {
+			check.Error = fmt.Sprintf("truncated record at offset %d", r.lastValidOff)
+			if err != io.EOF && err != io.ErrUnexpectedEOF {
+				check.Error = err.Error()
+			}
+			return check, nil
+		}
+
+		redoLog := &model.RedoLog{}
+		payload, err := r.codec.Decode(data[:recBytes])
+		if err == nil {
+			_, err = redoLog.UnmarshalMsg(payload)
+		}
+		if err != nil {
+			if r.isTornEntry(data) {
+				check.Error = fmt.Sprintf("torn write at offset %d", r.lastValidOff)
+			} else {
+				check.Error = fmt.Sprintf("corrupted record at offset %d: %s", r.lastValidOff, err)
+			}
+			return check, nil
+		}
+		r.lastValidOff += frameSizeBytes + recBytes + padBytes
+
+		var commitTs uint64
+		switch {
+		case redoLog.RedoRow != nil && redoLog.RedoRow.Row != nil:
+			commitTs = redoLog.RedoRow.Row.CommitTs
+		case redoLog.RedoDDL != nil && redoLog.RedoDDL.DDL != nil:
+			commitTs = redoLog.RedoDDL.DDL.CommitTs
+		default:
+			continue
+		}
+		if check.Events == 0 || commitTs < check.MinCommitTs {
+			check.MinCommitTs = commitTs
+		}
+		if commitTs > check.MaxCommitTs {
+			check.MaxCommitTs = commitTs
+		}
+		check.Events++
+	}
+}
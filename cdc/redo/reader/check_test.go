@@ -0,0 +1,90 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package reader
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/pingcap/tiflow/cdc/model"
+	"github.com/pingcap/tiflow/cdc/redo/common"
+	"github.com/pingcap/tiflow/cdc/redo/writer"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckLogFiles(t *testing.T) {
+	dir := t.TempDir()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	cfg := &writer.FileWriterConfig{
+		MaxLogSize:   100000,
+		Dir:          dir,
+		ChangeFeedID: "test-cf",
+		CaptureID:    "cp",
+		FileType:     common.DefaultRowLogFileType,
+		CreateTime:   time.Date(2000, 1, 1, 1, 1, 1, 1, &time.Location{}),
+	}
+	w, err := writer.NewWriter(ctx, cfg)
+	require.Nil(t, err)
+	w.AdvanceTs(11)
+	for _, commitTs := range []uint64{10, 11} {
+		log := &model.RedoLog{
+			RedoRow: &model.RedoRowChangedEvent{Row: &model.RowChangedEvent{CommitTs: commitTs}},
+			Type:    model.RedoLogTypeRow,
+		}
+		data, err := log.MarshalMsg(nil)
+		require.Nil(t, err)
+		_, err = w.Write(data)
+		require.Nil(t, err)
+	}
+	require.Nil(t, w.Close())
+	fileName := fmt.Sprintf("%s_%s_%d_%s_%d%s", cfg.CaptureID, cfg.ChangeFeedID, cfg.CreateTime.Unix(), cfg.FileType, 11, common.LogEXT)
+
+	report, err := CheckLogFiles(dir, 5, 20, nil)
+	require.Nil(t, err)
+	require.Empty(t, report.Issues)
+	require.Equal(t, []*LogFileCheck{{
+		Name:        fileName,
+		FileType:    common.DefaultRowLogFileType,
+		CommitTs:    11,
+		Events:      2,
+		MinCommitTs: 10,
+		MaxCommitTs: 11,
+	}}, report.Files)
+
+	// the meta is behind the log files.
+	report, err = CheckLogFiles(dir, 5, 10, nil)
+	require.Nil(t, err)
+	require.Len(t, report.Issues, 1)
+	require.Contains(t, report.Issues[0], "beyond the resolved ts 10")
+
+	// a truncated copy of the file overlaps the original one.
+	data, err := os.ReadFile(filepath.Join(dir, fileName))
+	require.Nil(t, err)
+	brokenName := fmt.Sprintf("%s_%s_%d_%s_%d%s", cfg.CaptureID, cfg.ChangeFeedID, cfg.CreateTime.Unix(), cfg.FileType, 12, common.LogEXT)
+	require.Nil(t, os.WriteFile(filepath.Join(dir, brokenName), data[:len(data)-1], common.DefaultFileMode))
+	report, err = CheckLogFiles(dir, 5, 20, nil)
+	require.Nil(t, err)
+	require.Len(t, report.Files, 2)
+	require.Equal(t, 1, report.Files[1].Events)
+	require.Contains(t, report.Files[1].Error, "truncated record")
+	require.Len(t, report.Issues, 2)
+	require.Contains(t, report.Issues[0], brokenName+" is broken after 1 events")
+	require.Contains(t, report.Issues[1], "overlapping the previous file "+fileName)
+}
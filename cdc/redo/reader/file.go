@@ -57,13 +57,16 @@ type fileReader interface {
 }
 
 type readerConfig struct {
-	dir        string
-	fileType   string
-	startTs    uint64
-	endTs      uint64
-	s3Storage  bool
-	s3URI      url.URL
-	workerNums int
+	dir                string
+	fileType           string
+	startTs            uint64
+	endTs              uint64
+	useExternalStorage bool
+	externalStorageURI url.URL
+	workerNums         int
+	// encryptionKey decrypts the encrypted files, and encrypts the sorted
+	// files of them.
+	encryptionKey []byte
 }
 
 type reader struct {
@@ -74,6 +77,11 @@ type reader struct {
 	closer   io.Closer
 	// lastValidOff file offset following the last valid decoded record
 	lastValidOff int64
+
+	key        []byte
+	headerRead bool
+	// codec decodes the records, it's parsed from the file header.
+	codec *common.LogCodec
 }
 
 func newReader(ctx context.Context, cfg *readerConfig) ([]fileReader, error) {
@@ -81,13 +89,13 @@ func newReader(ctx context.Context, cfg *readerConfig) ([]fileReader, error) {
 		return nil, cerror.WrapError(cerror.ErrRedoConfigInvalid, errors.New("readerConfig can not be nil"))
 	}
 
-	if cfg.s3Storage {
-		s3storage, err := common.InitS3storage(ctx, cfg.s3URI)
+	if cfg.useExternalStorage {
+		extStorage, err := common.InitExternalStorage(ctx, cfg.externalStorageURI)
 		if err != nil {
 			return nil, err
 		}
 
-		err = downLoadToLocal(ctx, cfg.dir, s3storage, cfg.fileType)
+		err = downLoadToLocal(ctx, cfg.dir, extStorage, cfg.fileType)
 		if err != nil {
 			return nil, cerror.WrapError(cerror.ErrRedoDownloadFailed, err)
 		}
@@ -96,7 +104,7 @@ func newReader(ctx context.Context, cfg *readerConfig) ([]fileReader, error) {
 		cfg.workerNums = defaultWorkerNum
 	}
 
-	rr, err := openSelectedFiles(ctx, cfg.dir, cfg.fileType, cfg.startTs, cfg.workerNums)
+	rr, err := openSelectedFiles(ctx, cfg.dir, cfg.fileType, cfg.startTs, cfg.workerNums, cfg.encryptionKey)
 	if err != nil {
 		return nil, err
 	}
@@ -109,15 +117,16 @@ func newReader(ctx context.Context, cfg *readerConfig) ([]fileReader, error) {
 				br:       bufio.NewReader(rr[i]),
 				fileName: rr[i].(*os.File).Name(),
 				closer:   rr[i],
+				key:      cfg.encryptionKey,
 			})
 	}
 
 	return readers, nil
 }
 
-func selectDownLoadFile(ctx context.Context, s3storage storage.ExternalStorage, fixedType string) ([]string, error) {
+func selectDownLoadFile(ctx context.Context, extStorage storage.ExternalStorage, fixedType string) ([]string, error) {
 	files := []string{}
-	err := s3storage.WalkDir(ctx, &storage.WalkOption{}, func(path string, size int64) error {
+	err := extStorage.WalkDir(ctx, &storage.WalkOption{}, func(path string, size int64) error {
 		fileName := filepath.Base(path)
 		_, fileType, err := common.ParseLogFileName(fileName)
 		if err != nil {
@@ -130,14 +139,14 @@ func selectDownLoadFile(ctx context.Context, s3storage storage.ExternalStorage,
 		return nil
 	})
 	if err != nil {
-		return nil, cerror.WrapError(cerror.ErrS3StorageAPI, err)
+		return nil, cerror.WrapError(cerror.ErrExternalStorageAPI, err)
 	}
 
 	return files, nil
 }
 
-func downLoadToLocal(ctx context.Context, dir string, s3storage storage.ExternalStorage, fixedType string) error {
-	files, err := selectDownLoadFile(ctx, s3storage, fixedType)
+func downLoadToLocal(ctx context.Context, dir string, extStorage storage.ExternalStorage, fixedType string) error {
+	files, err := selectDownLoadFile(ctx, extStorage, fixedType)
 	if err != nil {
 		return err
 	}
@@ -146,9 +155,9 @@ func downLoadToLocal(ctx context.Context, dir string, s3storage storage.External
 	for _, file := range files {
 		f := file
 		eg.Go(func() error {
-			data, err := s3storage.ReadFile(eCtx, f)
+			data, err := extStorage.ReadFile(eCtx, f)
 			if err != nil {
-				return cerror.WrapError(cerror.ErrS3StorageAPI, err)
+				return cerror.WrapError(cerror.ErrExternalStorageAPI, err)
 			}
 
 			err = os.MkdirAll(dir, common.DefaultDirMode)
@@ -164,7 +173,7 @@ func downLoadToLocal(ctx context.Context, dir string, s3storage storage.External
 	return eg.Wait()
 }
 
-func openSelectedFiles(ctx context.Context, dir, fixedType string, startTs uint64, workerNum int) ([]io.ReadCloser, error) {
+func openSelectedFiles(ctx context.Context, dir, fixedType string, startTs uint64, workerNum int, key []byte) ([]io.ReadCloser, error) {
 	files, err := ioutil.ReadDir(dir)
 	if err != nil {
 		return nil, cerror.WrapError(cerror.ErrRedoFileOp, errors.Annotatef(err, "can't read log file directory: %s", dir))
@@ -212,7 +221,7 @@ func openSelectedFiles(ctx context.Context, dir, fixedType string, startTs uint6
 		}
 	}
 
-	sortFiles, err := createSortedFiles(ctx, dir, unSortedFile, workerNum)
+	sortFiles, err := createSortedFiles(ctx, dir, unSortedFile, workerNum, key)
 	if err != nil {
 		return nil, err
 	}
@@ -224,11 +233,12 @@ func openReadFile(name string) (*os.File, error) {
 	return os.OpenFile(name, os.O_RDONLY, common.DefaultFileMode)
 }
 
-func readFile(file *os.File) (logHeap, error) {
+func readFile(file *os.File, key []byte) (logHeap, error) {
 	r := &reader{
 		br:       bufio.NewReader(file),
 		fileName: file.Name(),
 		closer:   file,
+		key:      key,
 	}
 	defer r.Close()
 
@@ -249,10 +259,13 @@ func readFile(file *os.File) (logHeap, error) {
 }
 
 // writFile if not safely closed, the sorted file will end up with .sort.tmp as the file name suffix
-func writFile(ctx context.Context, dir, name string, h logHeap) error {
+// the sorted file is encrypted with the key if it's not empty, but it's not compressed, as it's
+// only a temporary local file.
+func writFile(ctx context.Context, dir, name string, h logHeap, key []byte) error {
 	cfg := &writer.FileWriterConfig{
-		Dir:        dir,
-		MaxLogSize: math.MaxInt32,
+		Dir:           dir,
+		MaxLogSize:    math.MaxInt32,
+		EncryptionKey: key,
 	}
 	w, err := writer.NewWriter(ctx, cfg, writer.WithLogFileName(func() string { return name }))
 	if err != nil {
@@ -274,7 +287,7 @@ func writFile(ctx context.Context, dir, name string, h logHeap) error {
 	return w.Close()
 }
 
-func createSortedFiles(ctx context.Context, dir string, names []string, workerNum int) ([]io.ReadCloser, error) {
+func createSortedFiles(ctx context.Context, dir string, names []string, workerNum int, key []byte) ([]io.ReadCloser, error) {
 	logFiles := []io.ReadCloser{}
 	errCh := make(chan error)
 	retCh := make(chan io.ReadCloser)
@@ -293,7 +306,7 @@ func createSortedFiles(ctx context.Context, dir string, names []string, workerNu
 		}
 
 		for i := 0; i < len(nn); i++ {
-			go createSortedFile(ctx, dir, nn[i], errCh, retCh)
+			go createSortedFile(ctx, dir, nn[i], key, errCh, retCh)
 		}
 		for i := 0; i < len(nn); i++ {
 			select {
@@ -313,7 +326,7 @@ func createSortedFiles(ctx context.Context, dir string, names []string, workerNu
 	return logFiles, nil
 }
 
-func createSortedFile(ctx context.Context, dir string, name string, errCh chan error, retCh chan io.ReadCloser) {
+func createSortedFile(ctx context.Context, dir string, name string, key []byte, errCh chan error, retCh chan io.ReadCloser) {
 	path := filepath.Join(dir, name)
 	file, err := openReadFile(path)
 	if err != nil {
@@ -321,7 +334,7 @@ func createSortedFile(ctx context.Context, dir string, name string, errCh chan e
 		return
 	}
 
-	h, err := readFile(file)
+	h, err := readFile(file, key)
 	if err != nil {
 		errCh <- err
 		return
@@ -334,7 +347,7 @@ func createSortedFile(ctx context.Context, dir string, name string, errCh chan e
 	}
 
 	sortFileName := name + common.SortLogEXT
-	err = writFile(ctx, dir, sortFileName, h)
+	err = writFile(ctx, dir, sortFileName, h, key)
 	if err != nil {
 		errCh <- err
 		return
@@ -372,6 +385,9 @@ func (r *reader) Read(redoLog *model.RedoLog) error {
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
+	if err := r.readHeader(); err != nil {
+		return err
+	}
 	lenField, err := readInt64(r.br)
 	if err != nil {
 		if err == io.EOF {
@@ -393,7 +409,14 @@ func (r *reader) Read(redoLog *model.RedoLog) error {
 		return cerror.WrapError(cerror.ErrRedoFileOp, err)
 	}
 
-	_, err = redoLog.UnmarshalMsg(data[:recBytes])
+	payload, err := r.codec.Decode(data[:recBytes])
+	if err != nil {
+		if r.isTornEntry(data) {
+			return io.EOF
+		}
+		return err
+	}
+	_, err = redoLog.UnmarshalMsg(payload)
 	if err != nil {
 		if r.isTornEntry(data) {
 			// just return io.EOF, since if torn write it is the last redoLog entry
@@ -407,6 +430,27 @@ func (r *reader) Read(redoLog *model.RedoLog) error {
 	return nil
 }
 
+// readHeader parses the file header, the files of version 1 have no header.
+func (r *reader) readHeader() error {
+	if r.headerRead {
+		return nil
+	}
+	r.headerRead = true
+	header, err := r.br.Peek(common.FileHeaderSize)
+	if err != nil || !common.IsFileHeader(header) {
+		return nil
+	}
+	r.codec, err = common.ParseFileHeader(header, r.key)
+	if err != nil {
+		return errors.Annotatef(err, "redo log file: %s", r.fileName)
+	}
+	if _, err := r.br.Discard(common.FileHeaderSize); err != nil {
+		return cerror.WrapError(cerror.ErrRedoFileOp, err)
+	}
+	r.lastValidOff += common.FileHeaderSize
+	return nil
+}
+
 func readInt64(r io.Reader) (int64, error) {
 	var n int64
 	err := binary.Read(r, binary.LittleEndian, &n)
@@ -466,6 +510,7 @@ func (r *reader) Close() error {
 	if r == nil || r.closer == nil {
 		return nil
 	}
+	r.codec.Close()
 
 	return cerror.WrapError(cerror.ErrRedoFileOp, r.closer.Close())
 }
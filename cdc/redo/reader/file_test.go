@@ -15,6 +15,7 @@ package reader
 
 import (
 	"bufio"
+	"bytes"
 	"fmt"
 	"io"
 	"io/ioutil"
@@ -97,6 +98,70 @@ func TestReaderRead(t *testing.T) {
 	time.Sleep(1001 * time.Millisecond)
 }
 
+func TestReaderReadEncoded(t *testing.T) {
+	dir, err := ioutil.TempDir("", "redo-reader-encoded")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	key := bytes.Repeat([]byte{1}, 16)
+	cfg := &writer.FileWriterConfig{
+		MaxLogSize:    100000,
+		Dir:           dir,
+		ChangeFeedID:  "test-cf",
+		CaptureID:     "cp",
+		FileType:      common.DefaultRowLogFileType,
+		CreateTime:    time.Date(2000, 1, 1, 1, 1, 1, 1, &time.Location{}),
+		Compression:   common.CompressionZstd,
+		EncryptionKey: key,
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	w, err := writer.NewWriter(ctx, cfg)
+	require.Nil(t, err)
+	// the file is named by the max commit ts, which is advanced before the writes.
+	w.AdvanceTs(1125)
+	for _, commitTs := range []uint64{1125, 1123} {
+		log := &model.RedoLog{
+			RedoRow: &model.RedoRowChangedEvent{Row: &model.RowChangedEvent{CommitTs: commitTs}},
+		}
+		data, err := log.MarshalMsg(nil)
+		require.Nil(t, err)
+		_, err = w.Write(data)
+		require.Nil(t, err)
+	}
+	require.Nil(t, w.Close())
+
+	// the unsorted files can't be read without the key.
+	_, err = newReader(ctx, &readerConfig{
+		dir:      dir,
+		startTs:  1,
+		endTs:    1200,
+		fileType: common.DefaultRowLogFileType,
+	})
+	require.Regexp(t, "no encryption key is provided", err)
+
+	r, err := newReader(ctx, &readerConfig{
+		dir:           dir,
+		startTs:       1,
+		endTs:         1200,
+		fileType:      common.DefaultRowLogFileType,
+		encryptionKey: key,
+	})
+	require.Nil(t, err)
+	require.Equal(t, 1, len(r))
+	defer r[0].Close() //nolint:errcheck
+	for _, commitTs := range []uint64{1123, 1125} {
+		log := &model.RedoLog{}
+		err = r[0].Read(log)
+		require.Nil(t, err)
+		require.EqualValues(t, commitTs, log.RedoRow.Row.CommitTs)
+	}
+	err = r[0].Read(&model.RedoLog{})
+	require.Equal(t, io.EOF, err)
+	time.Sleep(1001 * time.Millisecond)
+}
+
 func TestReaderOpenSelectedFiles(t *testing.T) {
 	dir, err := ioutil.TempDir("", "redo-openSelectedFiles")
 	require.Nil(t, err)
@@ -210,7 +275,7 @@ func TestReaderOpenSelectedFiles(t *testing.T) {
 	}
 
 	for _, tt := range tests {
-		ret, err := openSelectedFiles(ctx, tt.args.dir, tt.args.fixedName, tt.args.startTs, 100)
+		ret, err := openSelectedFiles(ctx, tt.args.dir, tt.args.fixedName, tt.args.startTs, 100, nil)
 		if tt.wantErr == "" {
 			require.Nil(t, err, tt.name)
 			require.Equal(t, len(tt.wantRet), len(ret), tt.name)
@@ -52,17 +52,19 @@ type RedoLogReader interface {
 // LogReaderConfig is the config for LogReader
 type LogReaderConfig struct {
 	// Dir is the folder contains the redo logs need to apply when OP environment or
-	// the folder used to download redo logs to if s3 enabled
-	Dir       string
-	S3Storage bool
-	// S3URI should be like S3URI="s3://logbucket/test-changefeed?endpoint=http://$S3_ENDPOINT/"
-	S3URI url.URL
+	// the folder used to download redo logs to if the external storage is used
+	Dir                string
+	UseExternalStorage bool
+	// ExternalStorageURI should be like ExternalStorageURI="s3://logbucket/test-changefeed?endpoint=http://$S3_ENDPOINT/"
+	ExternalStorageURI url.URL
 	// WorkerNums is the num of workers used to sort the log file to sorted file,
 	// will load the file to memory first then write the sorted file to disk
 	// the memory used is WorkerNums * defaultMaxLogSize (64 * megabyte) total
 	WorkerNums int
-	startTs    uint64
-	endTs      uint64
+	// EncryptionKey is the key to decrypt the encrypted redo logs.
+	EncryptionKey []byte
+	startTs       uint64
+	endTs         uint64
 }
 
 // LogReader implement RedoLogReader interface
@@ -81,7 +83,7 @@ type LogReader struct {
 
 // NewLogReader creates a LogReader instance. Need the client to guarantee only one LogReader per changefeed
 // currently support rewind operation by ResetReader api
-// if s3 will download logs first, if OP environment need fetch the redo logs to local dir first
+// if the external storage is used, will download logs first, if OP environment need fetch the redo logs to local dir first
 func NewLogReader(ctx context.Context, cfg *LogReaderConfig) (*LogReader, error) {
 	if cfg == nil {
 		return nil, cerror.WrapError(cerror.ErrRedoConfigInvalid, errors.New("LogReaderConfig can not be nil"))
@@ -90,8 +92,8 @@ func NewLogReader(ctx context.Context, cfg *LogReaderConfig) (*LogReader, error)
 	logReader := &LogReader{
 		cfg: cfg,
 	}
-	if cfg.S3Storage {
-		s3storage, err := common.InitS3storage(ctx, cfg.S3URI)
+	if cfg.UseExternalStorage {
+		extStorage, err := common.InitExternalStorage(ctx, cfg.ExternalStorageURI)
 		if err != nil {
 			return nil, err
 		}
@@ -100,7 +102,7 @@ func NewLogReader(ctx context.Context, cfg *LogReaderConfig) (*LogReader, error)
 		if err != nil {
 			return nil, cerror.WrapError(cerror.ErrRedoFileOp, err)
 		}
-		err = downLoadToLocal(ctx, cfg.Dir, s3storage, common.DefaultMetaFileType)
+		err = downLoadToLocal(ctx, cfg.Dir, extStorage, common.DefaultMetaFileType)
 		if err != nil {
 			return nil, cerror.WrapError(cerror.ErrRedoDownloadFailed, err)
 		}
@@ -151,13 +153,15 @@ func (l *LogReader) setUpRowReader(ctx context.Context, startTs, endTs uint64) e
 	}
 
 	rowCfg := &readerConfig{
-		dir:        l.cfg.Dir,
-		fileType:   common.DefaultRowLogFileType,
-		startTs:    startTs,
-		endTs:      endTs,
-		s3Storage:  l.cfg.S3Storage,
-		s3URI:      l.cfg.S3URI,
-		workerNums: l.cfg.WorkerNums,
+		dir:                l.cfg.Dir,
+		fileType:           common.DefaultRowLogFileType,
+		startTs:            startTs,
+		endTs:              endTs,
+		useExternalStorage: l.cfg.UseExternalStorage,
+		externalStorageURI: l.cfg.ExternalStorageURI,
+		workerNums:         l.cfg.WorkerNums,
+
+		encryptionKey: l.cfg.EncryptionKey,
 	}
 	l.rowReader, err = newReader(ctx, rowCfg)
 	if err != nil {
@@ -180,13 +184,15 @@ func (l *LogReader) setUpDDLReader(ctx context.Context, startTs, endTs uint64) e
 	}
 
 	ddlCfg := &readerConfig{
-		dir:        l.cfg.Dir,
-		fileType:   common.DefaultDDLLogFileType,
-		startTs:    startTs,
-		endTs:      endTs,
-		s3Storage:  l.cfg.S3Storage,
-		s3URI:      l.cfg.S3URI,
-		workerNums: l.cfg.WorkerNums,
+		dir:                l.cfg.Dir,
+		fileType:           common.DefaultDDLLogFileType,
+		startTs:            startTs,
+		endTs:              endTs,
+		useExternalStorage: l.cfg.UseExternalStorage,
+		externalStorageURI: l.cfg.ExternalStorageURI,
+		workerNums:         l.cfg.WorkerNums,
+
+		encryptionKey: l.cfg.EncryptionKey,
 	}
 	l.ddlReader, err = newReader(ctx, ddlCfg)
 	if err != nil {
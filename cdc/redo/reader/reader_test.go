@@ -47,32 +47,93 @@ func TestNewLogReader(t *testing.T) {
 	require.Nil(t, err)
 	defer os.RemoveAll(dir)
 
-	s3URI, err := url.Parse("s3://logbucket/test-changefeed?endpoint=http://111/")
+	externalStorageURI, err := url.Parse("s3://logbucket/test-changefeed?endpoint=http://111/")
 	require.Nil(t, err)
 
-	origin := common.InitS3storage
+	origin := common.InitExternalStorage
 	defer func() {
-		common.InitS3storage = origin
+		common.InitExternalStorage = origin
 	}()
 	controller := gomock.NewController(t)
 	mockStorage := mockstorage.NewMockExternalStorage(controller)
 	// no file to download
 	mockStorage.EXPECT().WalkDir(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil)
-	common.InitS3storage = func(ctx context.Context, uri url.URL) (storage.ExternalStorage, error) {
+	common.InitExternalStorage = func(ctx context.Context, uri url.URL) (storage.ExternalStorage, error) {
 		return mockStorage, nil
 	}
 
 	// after init should rm the dir
 	_, err = NewLogReader(context.Background(), &LogReaderConfig{
-		S3Storage: true,
-		Dir:       dir,
-		S3URI:     *s3URI,
+		UseExternalStorage: true,
+		Dir:                dir,
+		ExternalStorageURI: *externalStorageURI,
 	})
 	require.Nil(t, err)
 	_, err = os.Stat(dir)
 	require.True(t, os.IsNotExist(err))
 }
 
+// TestLogReaderWithFileStorage uploads the redo logs to a local directory by
+// the external storage interface, and reads them back.
+func TestLogReaderWithFileStorage(t *testing.T) {
+	storageDir, err := ioutil.TempDir("", "redo-file-storage")
+	require.Nil(t, err)
+	defer os.RemoveAll(storageDir)
+	writeDir, err := ioutil.TempDir("", "redo-file-storage-write")
+	require.Nil(t, err)
+	defer os.RemoveAll(writeDir)
+	downloadDir, err := ioutil.TempDir("", "redo-file-storage-download")
+	require.Nil(t, err)
+	defer os.RemoveAll(downloadDir)
+
+	uri, err := url.Parse("file://" + storageDir)
+	require.Nil(t, err)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	w, err := writer.NewLogWriter(ctx, &writer.LogWriterConfig{
+		Dir:                writeDir,
+		ChangeFeedID:       "test-file-storage",
+		CaptureID:          "cp",
+		CreateTime:         time.Now(),
+		MaxLogSize:         10,
+		FlushIntervalInMs:  1000,
+		UseExternalStorage: true,
+		ExternalStorageURI: *uri,
+	})
+	require.Nil(t, err)
+	rows := []*model.RedoRowChangedEvent{
+		{Row: &model.RowChangedEvent{CommitTs: 15, Table: &model.TableName{TableID: 1}}},
+		{Row: &model.RowChangedEvent{CommitTs: 12, Table: &model.TableName{TableID: 1}}},
+	}
+	_, err = w.WriteLog(ctx, 1, rows)
+	require.Nil(t, err)
+	require.Nil(t, w.FlushLog(ctx, 1, 15))
+	require.Nil(t, w.EmitCheckpointTs(ctx, 10))
+	require.Nil(t, w.EmitResolvedTs(ctx, 20))
+	require.Nil(t, w.Close())
+
+	r, err := NewLogReader(ctx, &LogReaderConfig{
+		Dir:                downloadDir,
+		UseExternalStorage: true,
+		ExternalStorageURI: *uri,
+	})
+	require.Nil(t, err)
+	defer r.Close() //nolint:errcheck
+	checkpointTs, resolvedTs, err := r.ReadMeta(ctx)
+	require.Nil(t, err)
+	require.EqualValues(t, 10, checkpointTs)
+	require.EqualValues(t, 20, resolvedTs)
+
+	require.Nil(t, r.ResetReader(ctx, checkpointTs, resolvedTs))
+	logs, err := r.ReadNextLog(ctx, 10)
+	require.Nil(t, err)
+	require.Len(t, logs, 2)
+	require.EqualValues(t, 12, logs[0].Row.CommitTs)
+	require.EqualValues(t, 15, logs[1].Row.CommitTs)
+	time.Sleep(1001 * time.Millisecond)
+}
+
 func TestLogReaderResetReader(t *testing.T) {
 	dir, err := ioutil.TempDir("", "redo-ResetReader")
 	require.Nil(t, err)
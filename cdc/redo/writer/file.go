@@ -15,6 +15,7 @@
 package writer
 
 import (
+	"bytes"
 	"context"
 	"encoding/binary"
 	"fmt"
@@ -47,7 +48,7 @@ const (
 
 const (
 	defaultFlushIntervalInMs = 1000
-	defaultS3Timeout         = 3 * time.Second
+	defaultStorageTimeout    = 3 * time.Second
 )
 
 var (
@@ -81,10 +82,14 @@ type FileWriterConfig struct {
 	FileType     string
 	CreateTime   time.Time
 	// MaxLogSize is the maximum size of log in megabyte, defaults to defaultMaxLogSize.
-	MaxLogSize        int64
-	FlushIntervalInMs int64
-	S3Storage         bool
-	S3URI             url.URL
+	MaxLogSize         int64
+	FlushIntervalInMs  int64
+	UseExternalStorage bool
+	ExternalStorageURI url.URL
+	// Compression and EncryptionKey are used to encode the records, the
+	// files start with a header if either of them is set.
+	Compression   string
+	EncryptionKey []byte
 }
 
 // Option define the writerOptions
@@ -120,6 +125,7 @@ type Writer struct {
 	bw            *pioutil.PageWriter
 	uint64buf     []byte
 	storage       storage.ExternalStorage
+	codec         *common.LogCodec
 	sync.RWMutex
 
 	metricFsyncDuration    prometheus.Observer
@@ -140,15 +146,20 @@ func NewWriter(ctx context.Context, cfg *FileWriterConfig, opts ...Option) (*Wri
 	if cfg.MaxLogSize == 0 {
 		cfg.MaxLogSize = defaultMaxLogSize
 	}
-	var s3storage storage.ExternalStorage
-	if cfg.S3Storage {
+	var extStorage storage.ExternalStorage
+	if cfg.UseExternalStorage {
 		var err error
-		s3storage, err = common.InitS3storage(ctx, cfg.S3URI)
+		extStorage, err = common.InitExternalStorage(ctx, cfg.ExternalStorageURI)
 		if err != nil {
 			return nil, err
 		}
 	}
 
+	codec, err := common.NewLogCodec(cfg.Compression, cfg.EncryptionKey)
+	if err != nil {
+		return nil, err
+	}
+
 	op := &writerOptions{}
 	for _, opt := range opts {
 		opt(op)
@@ -157,7 +168,8 @@ func NewWriter(ctx context.Context, cfg *FileWriterConfig, opts ...Option) (*Wri
 		cfg:       cfg,
 		op:        op,
 		uint64buf: make([]byte, 8),
-		storage:   s3storage,
+		storage:   extStorage,
+		codec:     codec,
 
 		metricFsyncDuration:    redoFsyncDurationHistogram.WithLabelValues(cfg.CaptureID, cfg.ChangeFeedID),
 		metricFlushAllDuration: redoFlushAllDurationHistogram.WithLabelValues(cfg.CaptureID, cfg.ChangeFeedID),
@@ -200,6 +212,10 @@ func (w *Writer) Write(rawData []byte) (int, error) {
 	w.Lock()
 	defer w.Unlock()
 
+	rawData, err := w.codec.Encode(rawData)
+	if err != nil {
+		return 0, err
+	}
 	writeLen := int64(len(rawData))
 	if writeLen > w.cfg.MaxLogSize {
 		return 0, cerror.ErrFileSizeExceed.GenWithStackByArgs(writeLen, w.cfg.MaxLogSize)
@@ -275,6 +291,7 @@ func (w *Writer) Close() error {
 	redoFsyncDurationHistogram.DeleteLabelValues(w.cfg.CaptureID, w.cfg.ChangeFeedID)
 	redoWriteBytesGauge.DeleteLabelValues(w.cfg.CaptureID, w.cfg.ChangeFeedID)
 
+	defer w.codec.Close()
 	return w.close()
 }
 
@@ -304,13 +321,13 @@ func (w *Writer) close() error {
 		return cerror.WrapError(cerror.ErrRedoFileOp, err)
 	}
 
-	if w.cfg.S3Storage {
-		ctx, cancel := context.WithTimeout(context.Background(), defaultS3Timeout)
+	if w.cfg.UseExternalStorage {
+		ctx, cancel := context.WithTimeout(context.Background(), defaultStorageTimeout)
 		defer cancel()
 
-		err = w.renameInS3(ctx, w.file.Name(), w.filePath())
+		err = w.renameInExternalStorage(ctx, w.file.Name(), w.filePath())
 		if err != nil {
-			return cerror.WrapError(cerror.ErrS3StorageAPI, err)
+			return cerror.WrapError(cerror.ErrExternalStorageAPI, err)
 		}
 	}
 
@@ -319,12 +336,16 @@ func (w *Writer) close() error {
 	return cerror.WrapError(cerror.ErrRedoFileOp, err)
 }
 
-func (w *Writer) renameInS3(ctx context.Context, oldPath, newPath string) error {
-	err := w.writeToS3(ctx, newPath)
+func (w *Writer) renameInExternalStorage(ctx context.Context, oldPath, newPath string) error {
+	err := w.writeToExternalStorage(ctx, newPath)
 	if err != nil {
-		return cerror.WrapError(cerror.ErrS3StorageAPI, err)
+		return cerror.WrapError(cerror.ErrExternalStorageAPI, err)
 	}
-	return cerror.WrapError(cerror.ErrS3StorageAPI, w.storage.DeleteFile(ctx, filepath.Base(oldPath)))
+	err = w.storage.DeleteFile(ctx, filepath.Base(oldPath))
+	if isNotExistInStorage(err) {
+		return nil
+	}
+	return cerror.WrapError(cerror.ErrExternalStorageAPI, err)
 }
 
 func (w *Writer) getLogFileName() string {
@@ -378,6 +399,17 @@ func (w *Writer) openOrNew(writeLen int) error {
 	if info.Size()+int64(writeLen) >= w.cfg.MaxLogSize {
 		return w.rotate()
 	}
+	// the records appended must be encoded in the same way as the existing
+	// ones, which is recorded by the header of the file.
+	matched, err := w.headerMatches(path, info.Size())
+	if err != nil {
+		return err
+	}
+	if !matched {
+		log.Warn("the header of the redo log file doesn't match, rotate to a new file",
+			zap.String("changefeed", w.cfg.ChangeFeedID), zap.String("path", path))
+		return w.rotate()
+	}
 
 	file, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, common.DefaultFileMode)
 	if err != nil {
@@ -394,6 +426,31 @@ func (w *Writer) openOrNew(writeLen int) error {
 	return nil
 }
 
+// headerMatches returns whether the existing file has the header of the
+// codec, or has no header if the codec writes none. An empty file gets the
+// header once it's opened.
+func (w *Writer) headerMatches(path string, size int64) (bool, error) {
+	if size == 0 {
+		return true, nil
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return false, cerror.WrapError(cerror.ErrRedoFileOp, err)
+	}
+	defer f.Close()
+	header := make([]byte, common.FileHeaderSize)
+	n, err := io.ReadFull(f, header)
+	if err != nil && err != io.ErrUnexpectedEOF {
+		return false, cerror.WrapError(cerror.ErrRedoFileOp, err)
+	}
+	header = header[:n]
+	expected := w.codec.Header()
+	if expected == nil {
+		return !common.IsFileHeader(header), nil
+	}
+	return bytes.Equal(header, expected), nil
+}
+
 func (w *Writer) newPageWriter() error {
 	offset, err := w.file.Seek(0, io.SeekCurrent)
 	if err != nil {
@@ -401,6 +458,14 @@ func (w *Writer) newPageWriter() error {
 	}
 	w.bw = pioutil.NewPageWriter(w.file, pageBytes, int(offset))
 
+	// the header is written once the file is created, so the appended files
+	// keep the header of their own.
+	if header := w.codec.Header(); header != nil && w.size == 0 {
+		if _, err := w.bw.Write(header); err != nil {
+			return cerror.WrapError(cerror.ErrRedoFileOp, err)
+		}
+		w.size += int64(len(header))
+	}
 	return nil
 }
 
@@ -435,8 +500,8 @@ func (w *Writer) GC(checkPointTs uint64) error {
 		return cerror.WrapError(cerror.ErrRedoFileOp, errs)
 	}
 
-	if w.cfg.S3Storage {
-		// since if fail delete in s3, do not block any path, so just log the error if any
+	if w.cfg.UseExternalStorage {
+		// since if fail delete in the external storage, do not block any path, so just log the error if any
 		go func() {
 			var errs error
 			for _, f := range remove {
@@ -444,8 +509,8 @@ func (w *Writer) GC(checkPointTs uint64) error {
 				errs = multierr.Append(errs, err)
 			}
 			if errs != nil {
-				errs = cerror.WrapError(cerror.ErrS3StorageAPI, errs)
-				log.Warn("delete redo log in s3 fail", zap.Error(errs))
+				errs = cerror.WrapError(cerror.ErrExternalStorageAPI, errs)
+				log.Warn("delete redo log in external storage fail", zap.Error(errs))
 			}
 		}()
 	}
@@ -506,14 +571,14 @@ func (w *Writer) flushAll() error {
 	if err != nil {
 		return err
 	}
-	if !w.cfg.S3Storage {
+	if !w.cfg.UseExternalStorage {
 		return nil
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), defaultS3Timeout)
+	ctx, cancel := context.WithTimeout(context.Background(), defaultStorageTimeout)
 	defer cancel()
 
-	err = w.writeToS3(ctx, w.file.Name())
+	err = w.writeToExternalStorage(ctx, w.file.Name())
 	w.metricFlushAllDuration.Observe(time.Since(start).Seconds())
 
 	return err
@@ -545,12 +610,12 @@ func (w *Writer) flush() error {
 	return cerror.WrapError(cerror.ErrRedoFileOp, err)
 }
 
-func (w *Writer) writeToS3(ctx context.Context, name string) error {
+func (w *Writer) writeToExternalStorage(ctx context.Context, name string) error {
 	fileData, err := os.ReadFile(name)
 	if err != nil {
 		return cerror.WrapError(cerror.ErrRedoFileOp, err)
 	}
 
 	// Key in s3: aws.String(rs.options.Prefix + name), prefix should be changefeed name
-	return cerror.WrapError(cerror.ErrS3StorageAPI, w.storage.WriteFile(ctx, filepath.Base(name), fileData))
+	return cerror.WrapError(cerror.ErrExternalStorageAPI, w.storage.WriteFile(ctx, filepath.Base(name), fileData))
 }
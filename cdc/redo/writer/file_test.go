@@ -14,6 +14,7 @@
 package writer
 
 import (
+	"bytes"
 	"context"
 	"fmt"
 	"io/ioutil"
@@ -136,6 +137,61 @@ func TestWriterWrite(t *testing.T) {
 	require.False(t, w1.IsRunning())
 }
 
+func TestWriterAppendHeader(t *testing.T) {
+	dir, err := ioutil.TempDir("", "redo-writer")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	newWriter := func(changefeedID string) *Writer {
+		codec, err := common.NewLogCodec(common.CompressionLZ4, nil)
+		require.Nil(t, err)
+		return &Writer{
+			cfg: &FileWriterConfig{
+				MaxLogSize:   1024,
+				Dir:          dir,
+				ChangeFeedID: changefeedID,
+				CaptureID:    "cp",
+				FileType:     common.DefaultRowLogFileType,
+				CreateTime:   time.Date(2000, 1, 1, 1, 1, 1, 1, &time.Location{}),
+			},
+			codec:                  codec,
+			uint64buf:              make([]byte, 8),
+			running:                *atomic.NewBool(true),
+			metricWriteBytes:       redoWriteBytesGauge.WithLabelValues("cp", changefeedID),
+			metricFsyncDuration:    redoFsyncDurationHistogram.WithLabelValues("cp", changefeedID),
+			metricFlushAllDuration: redoFlushAllDurationHistogram.WithLabelValues("cp", changefeedID),
+		}
+	}
+
+	// the existing file has no header, the writer rotates to a new file
+	// instead of appending the records encoded in another way.
+	w := newWriter("test-cf")
+	legacy := []byte("legacy00")
+	require.Nil(t, ioutil.WriteFile(w.filePath(), legacy, common.DefaultFileMode))
+	w.eventCommitTS.Store(1)
+	_, err = w.Write([]byte("t"))
+	require.Nil(t, err)
+	require.Nil(t, w.Close())
+	data, err := ioutil.ReadFile(filepath.Join(dir, fmt.Sprintf("%s_%s_%d_%s_%d%s",
+		w.cfg.CaptureID, w.cfg.ChangeFeedID, w.cfg.CreateTime.Unix(), w.cfg.FileType, 0, common.LogEXT)))
+	require.Nil(t, err)
+	require.Equal(t, legacy, data)
+	data, err = ioutil.ReadFile(w.filePath())
+	require.Nil(t, err)
+	require.True(t, bytes.HasPrefix(data, w.codec.Header()))
+
+	// the existing file has the same header, the records are appended.
+	w = newWriter("test-cf1")
+	path := w.filePath()
+	require.Nil(t, ioutil.WriteFile(path, w.codec.Header(), common.DefaultFileMode))
+	w.eventCommitTS.Store(1)
+	_, err = w.Write([]byte("t"))
+	require.Nil(t, err)
+	require.Equal(t, path, w.file.Name())
+	require.Greater(t, w.size, int64(common.FileHeaderSize))
+	require.Nil(t, w.Close())
+}
+
 func TestWriterGC(t *testing.T) {
 	dir, err := ioutil.TempDir("", "redo-GC")
 	require.Nil(t, err)
@@ -160,14 +216,14 @@ func TestWriterGC(t *testing.T) {
 
 	megabyte = 1
 	cfg := &FileWriterConfig{
-		Dir:               dir,
-		ChangeFeedID:      "test",
-		CaptureID:         "cp",
-		MaxLogSize:        10,
-		FileType:          common.DefaultRowLogFileType,
-		CreateTime:        time.Date(2000, 1, 1, 1, 1, 1, 1, &time.Location{}),
-		FlushIntervalInMs: 5,
-		S3Storage:         true,
+		Dir:                dir,
+		ChangeFeedID:       "test",
+		CaptureID:          "cp",
+		MaxLogSize:         10,
+		FileType:           common.DefaultRowLogFileType,
+		CreateTime:         time.Date(2000, 1, 1, 1, 1, 1, 1, &time.Location{}),
+		FlushIntervalInMs:  5,
+		UseExternalStorage: true,
 	}
 	w := &Writer{
 		cfg:                    cfg,
@@ -229,7 +285,7 @@ func TestNewWriter(t *testing.T) {
 	_, err := NewWriter(context.Background(), nil)
 	require.NotNil(t, err)
 
-	s3URI, err := url.Parse("s3://logbucket/test-changefeed?endpoint=http://111/")
+	externalStorageURI, err := url.Parse("s3://logbucket/test-changefeed?endpoint=http://111/")
 	require.Nil(t, err)
 
 	dir, err := ioutil.TempDir("", "redo-NewWriter")
@@ -237,9 +293,9 @@ func TestNewWriter(t *testing.T) {
 	defer os.RemoveAll(dir)
 
 	w, err := NewWriter(context.Background(), &FileWriterConfig{
-		Dir:       "sdfsf",
-		S3Storage: true,
-		S3URI:     *s3URI,
+		Dir:                "sdfsf",
+		UseExternalStorage: true,
+		ExternalStorageURI: *externalStorageURI,
 	})
 	require.Nil(t, err)
 	time.Sleep(time.Duration(defaultFlushIntervalInMs+1) * time.Millisecond)
@@ -255,13 +311,13 @@ func TestNewWriter(t *testing.T) {
 
 	w = &Writer{
 		cfg: &FileWriterConfig{
-			Dir:          dir,
-			CaptureID:    "cp",
-			ChangeFeedID: "test",
-			FileType:     common.DefaultDDLLogFileType,
-			CreateTime:   time.Date(2000, 1, 1, 1, 1, 1, 1, &time.Location{}),
-			S3Storage:    true,
-			MaxLogSize:   defaultMaxLogSize,
+			Dir:                dir,
+			CaptureID:          "cp",
+			ChangeFeedID:       "test",
+			FileType:           common.DefaultDDLLogFileType,
+			CreateTime:         time.Date(2000, 1, 1, 1, 1, 1, 1, &time.Location{}),
+			UseExternalStorage: true,
+			MaxLogSize:         defaultMaxLogSize,
 		},
 		uint64buf:              make([]byte, 8),
 		storage:                mockStorage,
@@ -15,6 +15,7 @@ package writer
 
 import (
 	"context"
+	"crypto/sha256"
 	"fmt"
 	"io"
 	"io/ioutil"
@@ -88,11 +89,14 @@ type LogWriterConfig struct {
 	CaptureID    string
 	CreateTime   time.Time
 	// MaxLogSize is the maximum size of log in megabyte, defaults to defaultMaxLogSize.
-	MaxLogSize        int64
-	FlushIntervalInMs int64
-	S3Storage         bool
-	// S3URI should be like S3URI="s3://logbucket/test-changefeed?endpoint=http://$S3_ENDPOINT/"
-	S3URI url.URL
+	MaxLogSize         int64
+	FlushIntervalInMs  int64
+	UseExternalStorage bool
+	// ExternalStorageURI should be like ExternalStorageURI="s3://logbucket/test-changefeed?endpoint=http://$S3_ENDPOINT/"
+	ExternalStorageURI url.URL
+	// Compression and EncryptionKey are used to encode the records, see common.LogCodec.
+	Compression   string
+	EncryptionKey []byte
 }
 
 // LogWriter implement the RedoLogWriter interface
@@ -126,26 +130,30 @@ func NewLogWriter(ctx context.Context, cfg *LogWriterConfig) (*LogWriter, error)
 	var err error
 	var logWriter *LogWriter
 	rowCfg := &FileWriterConfig{
-		Dir:               cfg.Dir,
-		ChangeFeedID:      cfg.ChangeFeedID,
-		CaptureID:         cfg.CaptureID,
-		FileType:          common.DefaultRowLogFileType,
-		CreateTime:        cfg.CreateTime,
-		MaxLogSize:        cfg.MaxLogSize,
-		FlushIntervalInMs: cfg.FlushIntervalInMs,
-		S3Storage:         cfg.S3Storage,
-		S3URI:             cfg.S3URI,
+		Dir:                cfg.Dir,
+		ChangeFeedID:       cfg.ChangeFeedID,
+		CaptureID:          cfg.CaptureID,
+		FileType:           common.DefaultRowLogFileType,
+		CreateTime:         cfg.CreateTime,
+		MaxLogSize:         cfg.MaxLogSize,
+		FlushIntervalInMs:  cfg.FlushIntervalInMs,
+		UseExternalStorage: cfg.UseExternalStorage,
+		ExternalStorageURI: cfg.ExternalStorageURI,
+		Compression:        cfg.Compression,
+		EncryptionKey:      cfg.EncryptionKey,
 	}
 	ddlCfg := &FileWriterConfig{
-		Dir:               cfg.Dir,
-		ChangeFeedID:      cfg.ChangeFeedID,
-		CaptureID:         cfg.CaptureID,
-		FileType:          common.DefaultDDLLogFileType,
-		CreateTime:        cfg.CreateTime,
-		MaxLogSize:        cfg.MaxLogSize,
-		FlushIntervalInMs: cfg.FlushIntervalInMs,
-		S3Storage:         cfg.S3Storage,
-		S3URI:             cfg.S3URI,
+		Dir:                cfg.Dir,
+		ChangeFeedID:       cfg.ChangeFeedID,
+		CaptureID:          cfg.CaptureID,
+		FileType:           common.DefaultDDLLogFileType,
+		CreateTime:         cfg.CreateTime,
+		MaxLogSize:         cfg.MaxLogSize,
+		FlushIntervalInMs:  cfg.FlushIntervalInMs,
+		UseExternalStorage: cfg.UseExternalStorage,
+		ExternalStorageURI: cfg.ExternalStorageURI,
+		Compression:        cfg.Compression,
+		EncryptionKey:      cfg.EncryptionKey,
 	}
 	logWriter = &LogWriter{
 		cfg: cfg,
@@ -166,8 +174,8 @@ func NewLogWriter(ctx context.Context, cfg *LogWriterConfig) (*LogWriter, error)
 			zap.String("changefeed", cfg.ChangeFeedID),
 			zap.Error(err))
 	}
-	if cfg.S3Storage {
-		logWriter.storage, err = common.InitS3storage(ctx, cfg.S3URI)
+	if cfg.UseExternalStorage {
+		logWriter.storage, err = common.InitExternalStorage(ctx, cfg.ExternalStorageURI)
 		if err != nil {
 			return nil, err
 		}
@@ -179,9 +187,9 @@ func NewLogWriter(ctx context.Context, cfg *LogWriterConfig) (*LogWriter, error)
 			return nil, err
 		}
 	} else {
-		if cfg.S3Storage {
+		if cfg.UseExternalStorage {
 			// since other process get the remove changefeed job async, may still write some logs after owner delete the log
-			err = logWriter.preCleanUpS3(ctx)
+			err = logWriter.preCleanUpStorage(ctx)
 			if err != nil {
 				return nil, err
 			}
@@ -194,16 +202,16 @@ func NewLogWriter(ctx context.Context, cfg *LogWriterConfig) (*LogWriter, error)
 	return logWriter, nil
 }
 
-func (l *LogWriter) preCleanUpS3(ctx context.Context) error {
+func (l *LogWriter) preCleanUpStorage(ctx context.Context) error {
 	ret, err := l.storage.FileExists(ctx, l.getDeletedChangefeedMarker())
 	if err != nil {
-		return cerror.WrapError(cerror.ErrS3StorageAPI, err)
+		return cerror.WrapError(cerror.ErrExternalStorageAPI, err)
 	}
 	if !ret {
 		return nil
 	}
 
-	files, err := getAllFilesInS3(ctx, l)
+	files, err := getAllFilesInStorage(ctx, l)
 	if err != nil {
 		return err
 	}
@@ -214,13 +222,13 @@ func (l *LogWriter) preCleanUpS3(ctx context.Context) error {
 			ff = append(ff, file)
 		}
 	}
-	err = l.deleteFilesInS3(ctx, ff)
+	err = l.deleteFilesInStorage(ctx, ff)
 	if err != nil {
 		return err
 	}
 	err = l.storage.DeleteFile(ctx, l.getDeletedChangefeedMarker())
-	if !isNotExistInS3(err) {
-		return cerror.WrapError(cerror.ErrS3StorageAPI, err)
+	if !isNotExistInStorage(err) {
+		return cerror.WrapError(cerror.ErrExternalStorageAPI, err)
 	}
 
 	return nil
@@ -460,7 +468,7 @@ func (l *LogWriter) DeleteAllLogs(ctx context.Context) error {
 		return err
 	}
 
-	if !l.cfg.S3Storage {
+	if !l.cfg.UseExternalStorage {
 		err = os.RemoveAll(l.cfg.Dir)
 		if err != nil {
 			return cerror.WrapError(cerror.ErrRedoFileOp, err)
@@ -470,29 +478,29 @@ func (l *LogWriter) DeleteAllLogs(ctx context.Context) error {
 		return nil
 	}
 
-	files, err := getAllFilesInS3(ctx, l)
+	files, err := getAllFilesInStorage(ctx, l)
 	if err != nil {
 		return err
 	}
 
-	err = l.deleteFilesInS3(ctx, files)
+	err = l.deleteFilesInStorage(ctx, files)
 	if err != nil {
 		return err
 	}
 	// after delete logs, rm the LogWriter since it is already closed
 	l.cleanUpLogWriter()
 
-	// write a marker to s3, since other process get the remove changefeed job async,
+	// write a marker to the external storage, since other process get the remove changefeed job async,
 	// may still write some logs after owner delete the log
-	return l.writeDeletedMarkerToS3(ctx)
+	return l.writeDeletedMarkerToStorage(ctx)
 }
 
 func (l *LogWriter) getDeletedChangefeedMarker() string {
 	return fmt.Sprintf("delete_%s", l.cfg.ChangeFeedID)
 }
 
-func (l *LogWriter) writeDeletedMarkerToS3(ctx context.Context) error {
-	return cerror.WrapError(cerror.ErrS3StorageAPI, l.storage.WriteFile(ctx, l.getDeletedChangefeedMarker(), []byte("D")))
+func (l *LogWriter) writeDeletedMarkerToStorage(ctx context.Context) error {
+	return cerror.WrapError(cerror.ErrExternalStorageAPI, l.storage.WriteFile(ctx, l.getDeletedChangefeedMarker(), []byte("D")))
 }
 
 func (l *LogWriter) cleanUpLogWriter() {
@@ -501,7 +509,7 @@ func (l *LogWriter) cleanUpLogWriter() {
 	delete(logWriters, l.cfg.ChangeFeedID)
 }
 
-func (l *LogWriter) deleteFilesInS3(ctx context.Context, files []string) error {
+func (l *LogWriter) deleteFilesInStorage(ctx context.Context, files []string) error {
 	eg, eCtx := errgroup.WithContext(ctx)
 	for _, f := range files {
 		name := f
@@ -509,8 +517,8 @@ func (l *LogWriter) deleteFilesInS3(ctx context.Context, files []string) error {
 			err := l.storage.DeleteFile(eCtx, name)
 			if err != nil {
 				// if fail then retry, may end up with notExit err, ignore the error
-				if !isNotExistInS3(err) {
-					return cerror.WrapError(cerror.ErrS3StorageAPI, err)
+				if !isNotExistInStorage(err) {
+					return cerror.WrapError(cerror.ErrExternalStorageAPI, err)
 				}
 			}
 			return nil
@@ -519,7 +527,7 @@ func (l *LogWriter) deleteFilesInS3(ctx context.Context, files []string) error {
 	return eg.Wait()
 }
 
-func isNotExistInS3(err error) bool {
+func isNotExistInStorage(err error) bool {
 	if err != nil {
 		if aerr, ok := errors.Cause(err).(awserr.Error); ok { // nolint:errorlint
 			switch aerr.Code() {
@@ -527,18 +535,20 @@ func isNotExistInS3(err error) bool {
 				return true
 			}
 		}
+		// the local directory used by the file storage
+		return os.IsNotExist(errors.Cause(err))
 	}
 	return false
 }
 
-var getAllFilesInS3 = func(ctx context.Context, l *LogWriter) ([]string, error) {
+var getAllFilesInStorage = func(ctx context.Context, l *LogWriter) ([]string, error) {
 	files := []string{}
 	err := l.storage.WalkDir(ctx, &storage.WalkOption{}, func(path string, _ int64) error {
 		files = append(files, path)
 		return nil
 	})
 	if err != nil {
-		return nil, cerror.WrapError(cerror.ErrS3StorageAPI, err)
+		return nil, cerror.WrapError(cerror.ErrExternalStorageAPI, err)
 	}
 
 	return files, nil
@@ -632,23 +642,23 @@ func (l *LogWriter) flushLogMeta(checkPointTs, resolvedTs uint64) error {
 		return cerror.WrapError(cerror.ErrRedoFileOp, err)
 	}
 
-	if !l.cfg.S3Storage {
+	if !l.cfg.UseExternalStorage {
 		return nil
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), defaultS3Timeout)
+	ctx, cancel := context.WithTimeout(context.Background(), defaultStorageTimeout)
 	defer cancel()
-	return l.writeMetaToS3(ctx)
+	return l.writeMetaToStorage(ctx)
 }
 
-func (l *LogWriter) writeMetaToS3(ctx context.Context) error {
+func (l *LogWriter) writeMetaToStorage(ctx context.Context) error {
 	name := l.filePath()
 	fileData, err := os.ReadFile(name)
 	if err != nil {
 		return cerror.WrapError(cerror.ErrRedoFileOp, err)
 	}
 
-	return cerror.WrapError(cerror.ErrS3StorageAPI, l.storage.WriteFile(ctx, l.getMetafileName(), fileData))
+	return cerror.WrapError(cerror.ErrExternalStorageAPI, l.storage.WriteFile(ctx, l.getMetafileName(), fileData))
 }
 
 func (l *LogWriter) filePath() string {
@@ -656,5 +666,8 @@ func (l *LogWriter) filePath() string {
 }
 
 func (cfg LogWriterConfig) String() string {
-	return fmt.Sprintf("%s:%s:%s:%d:%d:%s:%t", cfg.ChangeFeedID, cfg.CaptureID, cfg.Dir, cfg.MaxLogSize, cfg.FlushIntervalInMs, cfg.S3URI.String(), cfg.S3Storage)
+	// the key itself is not printed, its digest is enough to tell the changes.
+	keyDigest := sha256.Sum256(cfg.EncryptionKey)
+	return fmt.Sprintf("%s:%s:%s:%d:%d:%s:%t:%s:%x", cfg.ChangeFeedID, cfg.CaptureID, cfg.Dir, cfg.MaxLogSize, cfg.FlushIntervalInMs, cfg.ExternalStorageURI.String(), cfg.UseExternalStorage,
+		cfg.Compression, keyDigest[:4])
 }
@@ -315,13 +315,13 @@ func TestLogWriterFlushLog(t *testing.T) {
 		mockWriter.On("Flush", mock.Anything).Return(tt.flushErr)
 		mockWriter.On("IsRunning").Return(tt.isRunning)
 		cfg := &LogWriterConfig{
-			Dir:               dir,
-			ChangeFeedID:      "test-cf",
-			CaptureID:         "cp",
-			MaxLogSize:        10,
-			CreateTime:        time.Date(2000, 1, 1, 1, 1, 1, 1, &time.Location{}),
-			FlushIntervalInMs: 5,
-			S3Storage:         true,
+			Dir:                dir,
+			ChangeFeedID:       "test-cf",
+			CaptureID:          "cp",
+			MaxLogSize:         10,
+			CreateTime:         time.Date(2000, 1, 1, 1, 1, 1, 1, &time.Location{}),
+			FlushIntervalInMs:  5,
+			UseExternalStorage: true,
 		}
 		writer := LogWriter{
 			rowWriter: mockWriter,
@@ -404,13 +404,13 @@ func TestLogWriterEmitCheckpointTs(t *testing.T) {
 		mockWriter := &mockFileWriter{}
 		mockWriter.On("IsRunning").Return(tt.isRunning)
 		cfg := &LogWriterConfig{
-			Dir:               dir,
-			ChangeFeedID:      "test-cf",
-			CaptureID:         "cp",
-			MaxLogSize:        10,
-			CreateTime:        time.Date(2000, 1, 1, 1, 1, 1, 1, &time.Location{}),
-			FlushIntervalInMs: 5,
-			S3Storage:         true,
+			Dir:                dir,
+			ChangeFeedID:       "test-cf",
+			CaptureID:          "cp",
+			MaxLogSize:         10,
+			CreateTime:         time.Date(2000, 1, 1, 1, 1, 1, 1, &time.Location{}),
+			FlushIntervalInMs:  5,
+			UseExternalStorage: true,
 		}
 		writer := LogWriter{
 			rowWriter: mockWriter,
@@ -493,13 +493,13 @@ func TestLogWriterEmitResolvedTs(t *testing.T) {
 		mockWriter := &mockFileWriter{}
 		mockWriter.On("IsRunning").Return(tt.isRunning)
 		cfg := &LogWriterConfig{
-			Dir:               dir,
-			ChangeFeedID:      "test-cf",
-			CaptureID:         "cp",
-			MaxLogSize:        10,
-			CreateTime:        time.Date(2000, 1, 1, 1, 1, 1, 1, &time.Location{}),
-			FlushIntervalInMs: 5,
-			S3Storage:         true,
+			Dir:                dir,
+			ChangeFeedID:       "test-cf",
+			CaptureID:          "cp",
+			MaxLogSize:         10,
+			CreateTime:         time.Date(2000, 1, 1, 1, 1, 1, 1, &time.Location{}),
+			FlushIntervalInMs:  5,
+			UseExternalStorage: true,
 		}
 		writer := LogWriter{
 			rowWriter: mockWriter,
@@ -679,25 +679,25 @@ func TestNewLogWriter(t *testing.T) {
 	require.Equal(t, map[int64]uint64{}, l.meta.ResolvedTsList)
 	time.Sleep(time.Millisecond * time.Duration(math.Max(float64(defaultFlushIntervalInMs), float64(defaultGCIntervalInMs))+1))
 
-	origin := common.InitS3storage
+	origin := common.InitExternalStorage
 	defer func() {
-		common.InitS3storage = origin
+		common.InitExternalStorage = origin
 	}()
 	controller := gomock.NewController(t)
 	mockStorage := mockstorage.NewMockExternalStorage(controller)
 	// skip pre cleanup
 	mockStorage.EXPECT().FileExists(gomock.Any(), gomock.Any()).Return(false, nil)
-	common.InitS3storage = func(ctx context.Context, uri url.URL) (storage.ExternalStorage, error) {
+	common.InitExternalStorage = func(ctx context.Context, uri url.URL) (storage.ExternalStorage, error) {
 		return mockStorage, nil
 	}
 	cfg3 := &LogWriterConfig{
-		Dir:               dir,
-		ChangeFeedID:      "test-cf112232",
-		CaptureID:         "cp",
-		MaxLogSize:        10,
-		CreateTime:        time.Date(2000, 1, 1, 1, 1, 1, 1, &time.Location{}),
-		FlushIntervalInMs: 5,
-		S3Storage:         true,
+		Dir:                dir,
+		ChangeFeedID:       "test-cf112232",
+		CaptureID:          "cp",
+		MaxLogSize:         10,
+		CreateTime:         time.Date(2000, 1, 1, 1, 1, 1, 1, &time.Location{}),
+		FlushIntervalInMs:  5,
+		UseExternalStorage: true,
 	}
 	l3, err := NewLogWriter(ctx, cfg3)
 	require.Nil(t, err)
@@ -773,13 +773,13 @@ func TestDeleteAllLogs(t *testing.T) {
 	}
 
 	tests := []struct {
-		name               string
-		args               args
-		closeErr           error
-		getAllFilesInS3Err error
-		deleteFileErr      error
-		writeFileErr       error
-		wantErr            string
+		name                    string
+		args                    args
+		closeErr                error
+		getAllFilesInStorageErr error
+		deleteFileErr           error
+		writeFileErr            error
+		wantErr                 string
 	}{
 		{
 			name: "happy local",
@@ -796,16 +796,16 @@ func TestDeleteAllLogs(t *testing.T) {
 			wantErr:  ".*xx*.",
 		},
 		{
-			name:               "getAllFilesInS3 err",
-			args:               args{enableS3: true},
-			getAllFilesInS3Err: errors.New("xx"),
-			wantErr:            ".*xx*.",
+			name:                    "getAllFilesInStorage err",
+			args:                    args{enableS3: true},
+			getAllFilesInStorageErr: errors.New("xx"),
+			wantErr:                 ".*xx*.",
 		},
 		{
 			name:          "deleteFile normal err",
 			args:          args{enableS3: true},
 			deleteFileErr: errors.New("xx"),
-			wantErr:       ".*ErrS3StorageAPI*.",
+			wantErr:       ".*ErrExternalStorageAPI*.",
 		},
 		{
 			name:          "deleteFile notExist err",
@@ -830,9 +830,9 @@ func TestDeleteAllLogs(t *testing.T) {
 		_, err = os.Create(path)
 		require.Nil(t, err)
 
-		origin := getAllFilesInS3
-		getAllFilesInS3 = func(ctx context.Context, l *LogWriter) ([]string, error) {
-			return []string{fileName, fileName1}, tt.getAllFilesInS3Err
+		origin := getAllFilesInStorage
+		getAllFilesInStorage = func(ctx context.Context, l *LogWriter) ([]string, error) {
+			return []string{fileName, fileName1}, tt.getAllFilesInStorageErr
 		}
 		controller := gomock.NewController(t)
 		mockStorage := mockstorage.NewMockExternalStorage(controller)
@@ -843,13 +843,13 @@ func TestDeleteAllLogs(t *testing.T) {
 		mockWriter := &mockFileWriter{}
 		mockWriter.On("Close").Return(tt.closeErr)
 		cfg := &LogWriterConfig{
-			Dir:               dir,
-			ChangeFeedID:      "test-cf",
-			CaptureID:         "cp",
-			MaxLogSize:        10,
-			CreateTime:        time.Date(2000, 1, 1, 1, 1, 1, 1, &time.Location{}),
-			FlushIntervalInMs: 5,
-			S3Storage:         tt.args.enableS3,
+			Dir:                dir,
+			ChangeFeedID:       "test-cf",
+			CaptureID:          "cp",
+			MaxLogSize:         10,
+			CreateTime:         time.Date(2000, 1, 1, 1, 1, 1, 1, &time.Location{}),
+			FlushIntervalInMs:  5,
+			UseExternalStorage: tt.args.enableS3,
 		}
 		writer := LogWriter{
 			rowWriter: mockWriter,
@@ -874,18 +874,18 @@ func TestDeleteAllLogs(t *testing.T) {
 			}
 		}
 		os.RemoveAll(dir)
-		getAllFilesInS3 = origin
+		getAllFilesInStorage = origin
 	}
 }
 
 func TestPreCleanUpS3(t *testing.T) {
 	testCases := []struct {
-		name               string
-		fileExistsErr      error
-		fileExists         bool
-		getAllFilesInS3Err error
-		deleteFileErr      error
-		wantErr            string
+		name                    string
+		fileExistsErr           error
+		fileExists              bool
+		getAllFilesInStorageErr error
+		deleteFileErr           error
+		wantErr                 string
 	}{
 		{
 			name:       "happy no marker",
@@ -897,16 +897,16 @@ func TestPreCleanUpS3(t *testing.T) {
 			wantErr:       ".*xx*.",
 		},
 		{
-			name:               "getAllFilesInS3 err",
-			fileExists:         true,
-			getAllFilesInS3Err: errors.New("xx"),
-			wantErr:            ".*xx*.",
+			name:                    "getAllFilesInStorage err",
+			fileExists:              true,
+			getAllFilesInStorageErr: errors.New("xx"),
+			wantErr:                 ".*xx*.",
 		},
 		{
 			name:          "deleteFile normal err",
 			fileExists:    true,
 			deleteFileErr: errors.New("xx"),
-			wantErr:       ".*ErrS3StorageAPI*.",
+			wantErr:       ".*ErrExternalStorageAPI*.",
 		},
 		{
 			name:          "deleteFile notExist err",
@@ -916,9 +916,9 @@ func TestPreCleanUpS3(t *testing.T) {
 	}
 
 	for _, tc := range testCases {
-		origin := getAllFilesInS3
-		getAllFilesInS3 = func(ctx context.Context, l *LogWriter) ([]string, error) {
-			return []string{"1", "11", "delete_test-cf"}, tc.getAllFilesInS3Err
+		origin := getAllFilesInStorage
+		getAllFilesInStorage = func(ctx context.Context, l *LogWriter) ([]string, error) {
+			return []string{"1", "11", "delete_test-cf"}, tc.getAllFilesInStorageErr
 		}
 		controller := gomock.NewController(t)
 		mockStorage := mockstorage.NewMockExternalStorage(controller)
@@ -938,12 +938,12 @@ func TestPreCleanUpS3(t *testing.T) {
 			cfg:     cfg,
 			storage: mockStorage,
 		}
-		ret := writer.preCleanUpS3(context.Background())
+		ret := writer.preCleanUpStorage(context.Background())
 		if tc.wantErr != "" {
 			require.Regexp(t, tc.wantErr, ret.Error(), tc.name)
 		} else {
 			require.Nil(t, ret, tc.name)
 		}
-		getAllFilesInS3 = origin
+		getAllFilesInStorage = origin
 	}
 }
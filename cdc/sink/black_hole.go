@@ -66,7 +66,7 @@ func (b *blackHoleSink) FlushRowChangedEvents(ctx context.Context, _ model.Table
 	return resolvedTs, err
 }
 
-func (b *blackHoleSink) EmitCheckpointTs(ctx context.Context, ts uint64) error {
+func (b *blackHoleSink) EmitCheckpointTs(ctx context.Context, ts uint64, tables []model.TableName) error {
 	log.Debug("BlockHoleSink: Checkpoint Event", zap.Uint64("ts", ts))
 	return nil
 }
@@ -26,6 +26,7 @@ import (
 
 	"github.com/pingcap/errors"
 	"github.com/pingcap/log"
+	timodel "github.com/pingcap/tidb/parser/model"
 	"github.com/pingcap/tidb/parser/mysql"
 	"github.com/pingcap/tidb/types"
 	"github.com/pingcap/tiflow/cdc/model"
@@ -43,6 +44,9 @@ type AvroEventBatchEncoder struct {
 	resultBuf          []*MQMessage
 
 	tz *time.Location
+	// enableTiDBExtension makes the encoder send DDL and watermark events,
+	// which are not part of the Confluent Avro format.
+	enableTiDBExtension bool
 }
 
 type avroEncodeResult struct {
@@ -90,8 +94,15 @@ func (a *AvroEventBatchEncoder) SetTimeZone(tz *time.Location) {
 func (a *AvroEventBatchEncoder) AppendRowChangedEvent(e *model.RowChangedEvent) (EncoderResult, error) {
 	mqMessage := NewMQMessage(config.ProtocolAvro, nil, nil, e.CommitTs, model.MqMessageTypeRow, &e.Table.Schema, &e.Table.Table)
 
+	// The commit ts is added to both the keys and the values, since a deletion
+	// is sent as a key without value.
+	var extension *avroTiDBExtension
+	if a.enableTiDBExtension {
+		extension = &avroTiDBExtension{commitTs: &e.CommitTs}
+	}
+
 	if !e.IsDelete() {
-		res, err := avroEncode(e.Table, a.valueSchemaManager, e.TableInfoVersion, e.Columns, a.tz)
+		res, err := avroEncode(e.Table, a.valueSchemaManager, e.TableInfoVersion, e.Columns, a.tz, extension)
 		if err != nil {
 			log.Warn("AppendRowChangedEvent: avro encoding failed", zap.String("table", e.Table.String()))
 			return EncoderNoOperation, errors.Annotate(err, "AppendRowChangedEvent could not encode to Avro")
@@ -110,7 +121,7 @@ func (a *AvroEventBatchEncoder) AppendRowChangedEvent(e *model.RowChangedEvent)
 
 	pkeyCols := e.HandleKeyColumns()
 
-	res, err := avroEncode(e.Table, a.keySchemaManager, e.TableInfoVersion, pkeyCols, a.tz)
+	res, err := avroEncode(e.Table, a.keySchemaManager, e.TableInfoVersion, pkeyCols, a.tz, extension)
 	if err != nil {
 		log.Warn("AppendRowChangedEvent: avro encoding failed", zap.String("table", e.Table.String()))
 		return EncoderNoOperation, errors.Annotate(err, "AppendRowChangedEvent could not encode to Avro")
@@ -129,19 +140,72 @@ func (a *AvroEventBatchEncoder) AppendRowChangedEvent(e *model.RowChangedEvent)
 	return EncoderNeedAsyncWrite, nil
 }
 
-// AppendResolvedEvent is no-op for Avro
+// AppendResolvedEvent is no-op for Avro, the watermark is sent by EncodeCheckpointEvent
 func (a *AvroEventBatchEncoder) AppendResolvedEvent(ts uint64) (EncoderResult, error) {
 	return EncoderNoOperation, nil
 }
 
-// EncodeCheckpointEvent is no-op for now
+// EncodeCheckpointEvent encodes a watermark event if the TiDB extension is enabled
 func (a *AvroEventBatchEncoder) EncodeCheckpointEvent(ts uint64) (*MQMessage, error) {
-	return nil, nil
+	if !a.enableTiDBExtension {
+		return nil, nil
+	}
+
+	value, err := a.encodeExtensionEvent(avroWatermarkSchemaName, avroWatermarkSchema, map[string]interface{}{
+		"resolvedTs": int64(ts),
+	})
+	if err != nil {
+		log.Warn("EncodeCheckpointEvent: avro encoding failed", zap.Uint64("ts", ts))
+		return nil, errors.Annotate(err, "EncodeCheckpointEvent could not encode to Avro")
+	}
+	return newResolvedMQMessage(config.ProtocolAvro, nil, value, ts), nil
 }
 
-// EncodeDDLEvent is no-op now
+// EncodeDDLEvent encodes a DDL event if the TiDB extension is enabled
 func (a *AvroEventBatchEncoder) EncodeDDLEvent(e *model.DDLEvent) (*MQMessage, error) {
-	return nil, nil
+	if !a.enableTiDBExtension {
+		return nil, nil
+	}
+
+	value, err := a.encodeExtensionEvent(avroDDLSchemaName, avroDDLSchema, map[string]interface{}{
+		"schema":   e.TableInfo.Schema,
+		"table":    e.TableInfo.Table,
+		"query":    e.Query,
+		"type":     e.Type.String(),
+		"commitTs": int64(e.CommitTs),
+	})
+	if err != nil {
+		log.Warn("EncodeDDLEvent: avro encoding failed", zap.String("query", e.Query))
+		return nil, errors.Annotate(err, "EncodeDDLEvent could not encode to Avro")
+	}
+	return newDDLMQMessage(config.ProtocolAvro, nil, value, e), nil
+}
+
+// encodeExtensionEvent encodes an event whose schema does not belong to any table,
+// the schema is registered to the value schema subject of the given name.
+func (a *AvroEventBatchEncoder) encodeExtensionEvent(name string, schema string, native map[string]interface{}) ([]byte, error) {
+	schemaGen := func() (string, error) {
+		return schema, nil
+	}
+
+	// TODO pass ctx from the upper function. Need to modify the EventBatchEncoder interface.
+	avroCodec, registryID, err := a.valueSchemaManager.getCachedOrRegisterSubject(
+		context.Background(), a.valueSchemaManager.nameToSchemaSubject(name), avroExtensionSchemaVersion, schemaGen)
+	if err != nil {
+		return nil, errors.Annotate(err, "AvroEventBatchEncoder: get-or-register failed")
+	}
+
+	bin, err := avroCodec.BinaryFromNative(nil, native)
+	if err != nil {
+		return nil, errors.Annotate(
+			cerror.WrapError(cerror.ErrAvroEncodeToBinary, err), "AvroEventBatchEncoder: converting to Avro binary failed")
+	}
+
+	res := &avroEncodeResult{
+		data:       bin,
+		registryID: registryID,
+	}
+	return res.toEnvelope()
 }
 
 // Build MQ Messages
@@ -174,15 +238,36 @@ func (a *AvroEventBatchEncoder) Size() int {
 	return sum
 }
 
-// SetParams is no-op for now
+// SetParams reads the relevant parameters for Avro Protocol
 func (a *AvroEventBatchEncoder) SetParams(params map[string]string) error {
-	// no op
+	if s, ok := params["enable-tidb-extension"]; ok {
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return cerror.WrapError(cerror.ErrSinkInvalidConfig, err)
+		}
+		a.enableTiDBExtension = b
+	}
 	return nil
 }
 
-func avroEncode(table *model.TableName, manager *AvroSchemaManager, tableVersion uint64, cols []*model.Column, tz *time.Location) (*avroEncodeResult, error) {
+// avroTiDBExtension is the TiDB specific information added to the records when
+// the TiDB extension is enabled, which allows the decoder to restore the events.
+type avroTiDBExtension struct {
+	commitTs *uint64
+}
+
+const (
+	avroTiDBTypeKey       = "tidb_type"
+	avroTiDBFlagKey       = "tidb_flag"
+	avroCommitTsFieldName = "_tidb_commit_ts"
+)
+
+func avroEncode(
+	table *model.TableName, manager *AvroSchemaManager, tableVersion uint64,
+	cols []*model.Column, tz *time.Location, extension *avroTiDBExtension,
+) (*avroEncodeResult, error) {
 	schemaGen := func() (string, error) {
-		schema, err := ColumnInfoToAvroSchema(table.Table, cols)
+		schema, err := columnInfoToAvroSchema(table, cols, extension)
 		if err != nil {
 			return "", errors.Annotate(err, "AvroEventBatchEncoder: generating schema failed")
 		}
@@ -199,6 +284,9 @@ func avroEncode(table *model.TableName, manager *AvroSchemaManager, tableVersion
 	if err != nil {
 		return nil, errors.Annotate(err, "AvroEventBatchEncoder: converting to native failed")
 	}
+	if extension != nil && extension.commitTs != nil {
+		native.(map[string]interface{})[avroCommitTsFieldName] = int64(*extension.commitTs)
+	}
 
 	bin, err := avroCodec.BinaryFromNative(nil, native)
 	if err != nil {
@@ -216,6 +304,8 @@ type avroSchemaTop struct {
 	Tp     string                   `json:"type"`
 	Name   string                   `json:"name"`
 	Fields []map[string]interface{} `json:"fields"`
+	// TiDBSchema is only set if the TiDB extension is enabled.
+	TiDBSchema string `json:"tidb_schema,omitempty"`
 }
 
 type logicalType string
@@ -235,11 +325,18 @@ const (
 
 // ColumnInfoToAvroSchema generates the Avro schema JSON for the corresponding columns
 func ColumnInfoToAvroSchema(name string, columnInfo []*model.Column) (string, error) {
+	return columnInfoToAvroSchema(&model.TableName{Table: name}, columnInfo, nil)
+}
+
+func columnInfoToAvroSchema(table *model.TableName, columnInfo []*model.Column, extension *avroTiDBExtension) (string, error) {
 	top := avroSchemaTop{
 		Tp:     "record",
-		Name:   name,
+		Name:   table.Table,
 		Fields: nil,
 	}
+	if extension != nil {
+		top.TiDBSchema = table.Schema
+	}
 
 	for _, col := range columnInfo {
 		avroType, err := getAvroDataTypeFromColumn(col)
@@ -254,9 +351,22 @@ func ColumnInfoToAvroSchema(name string, columnInfo []*model.Column) (string, er
 			field["type"] = []interface{}{"null", avroType}
 			field["default"] = nil
 		}
+		if extension != nil {
+			// the Avro types are not enough to restore the column, e.g. both the
+			// char and the varchar columns are encoded as "string".
+			field[avroTiDBTypeKey] = col.Type
+			field[avroTiDBFlagKey] = col.Flag
+		}
 
 		top.Fields = append(top.Fields, field)
 	}
+	if extension != nil && extension.commitTs != nil {
+		top.Fields = append(top.Fields, map[string]interface{}{
+			"name":    avroCommitTsFieldName,
+			"type":    "long",
+			"default": 0,
+		})
+	}
 
 	str, err := json.Marshal(&top)
 	if err != nil {
@@ -514,6 +624,37 @@ func columnToAvroNativeData(col *model.Column, tz *time.Location) (interface{},
 
 const magicByte = uint8(0)
 
+// The schemas of the DDL and watermark events. Their subjects don't contain "_",
+// so they never conflict with the subjects of the tables.
+const (
+	avroDDLSchemaName       = "ticdc-ddl"
+	avroWatermarkSchemaName = "ticdc-watermark"
+	// avroExtensionSchemaVersion is used as the tiSchemaID of the schemas above,
+	// which never change.
+	avroExtensionSchemaVersion = 1
+
+	avroDDLSchema = `{
+  "type": "record",
+  "name": "ddl",
+  "namespace": "com.pingcap.ticdc",
+  "fields": [
+    {"name": "schema", "type": "string"},
+    {"name": "table", "type": "string"},
+    {"name": "query", "type": "string"},
+    {"name": "type", "type": "string"},
+    {"name": "commitTs", "type": "long"}
+  ]
+}`
+	avroWatermarkSchema = `{
+  "type": "record",
+  "name": "watermark",
+  "namespace": "com.pingcap.ticdc",
+  "fields": [
+    {"name": "resolvedTs", "type": "long"}
+  ]
+}`
+)
+
 func (r *avroEncodeResult) toEnvelope() ([]byte, error) {
 	buf := new(bytes.Buffer)
 	data := []interface{}{magicByte, int32(r.registryID), r.data}
@@ -574,3 +715,312 @@ func (b *avroEventBatchEncoderBuilder) Build(ctx context.Context) (EventBatchEnc
 
 	return encoder, nil
 }
+
+// AvroEventBatchDecoder decodes the Avro messages encoded with the TiDB extension,
+// the schemas are resolved through the schema registry by the IDs in the envelopes.
+type AvroEventBatchDecoder struct {
+	key   []byte
+	value []byte
+
+	schemaManager *AvroSchemaManager
+	tz            *time.Location
+
+	nextType model.MqMessageType
+	isDelete bool
+	schema   *avroDecodingSchema
+	record   map[string]interface{}
+}
+
+// avroDecodingSchema is the part of the Avro schema used by the decoder.
+type avroDecodingSchema struct {
+	Name       string              `json:"name"`
+	Namespace  string              `json:"namespace"`
+	TiDBSchema string              `json:"tidb_schema"`
+	Fields     []avroDecodingField `json:"fields"`
+}
+
+type avroDecodingField struct {
+	Name     string               `json:"name"`
+	TiDBType *byte                `json:"tidb_type"`
+	TiDBFlag model.ColumnFlagType `json:"tidb_flag"`
+}
+
+const (
+	avroExtensionNamespace  = "com.pingcap.ticdc"
+	avroDDLRecordName       = "ddl"
+	avroWatermarkRecordName = "watermark"
+)
+
+// NewAvroEventBatchDecoder creates a new AvroEventBatchDecoder.
+// The key and the value are those of a Kafka message, the value is nil for a deletion.
+func NewAvroEventBatchDecoder(
+	key []byte, value []byte, schemaManager *AvroSchemaManager, tz *time.Location,
+) (EventBatchDecoder, error) {
+	if err := checkClaimCheckResolved(value); err != nil {
+		return nil, errors.Trace(err)
+	}
+	if schemaManager == nil {
+		return nil, cerror.ErrAvroDecodeFailed.GenWithStack("schema manager is required")
+	}
+	return &AvroEventBatchDecoder{
+		key:           key,
+		value:         value,
+		schemaManager: schemaManager,
+		tz:            tz,
+	}, nil
+}
+
+// HasNext implements the EventBatchDecoder interface
+func (d *AvroEventBatchDecoder) HasNext() (model.MqMessageType, bool, error) {
+	if d.nextType != model.MqMessageTypeUnknown {
+		return d.nextType, true, nil
+	}
+
+	data := d.value
+	d.isDelete = len(data) == 0
+	if d.isDelete {
+		data = d.key
+	}
+	d.key, d.value = nil, nil
+	if len(data) == 0 {
+		return model.MqMessageTypeUnknown, false, nil
+	}
+
+	schema, record, err := d.decodeEnvelope(data)
+	if err != nil {
+		return model.MqMessageTypeUnknown, false, errors.Trace(err)
+	}
+	d.schema, d.record = schema, record
+
+	switch {
+	case schema.Namespace == avroExtensionNamespace && schema.Name == avroDDLRecordName:
+		d.nextType = model.MqMessageTypeDDL
+	case schema.Namespace == avroExtensionNamespace && schema.Name == avroWatermarkRecordName:
+		d.nextType = model.MqMessageTypeResolved
+	default:
+		d.nextType = model.MqMessageTypeRow
+	}
+	return d.nextType, true, nil
+}
+
+// NextResolvedEvent implements the EventBatchDecoder interface
+func (d *AvroEventBatchDecoder) NextResolvedEvent() (uint64, error) {
+	if d.nextType != model.MqMessageTypeResolved {
+		return 0, cerror.ErrAvroDecodeFailed.GenWithStack("not found resolved event message")
+	}
+	d.nextType = model.MqMessageTypeUnknown
+
+	ts, ok := d.record["resolvedTs"].(int64)
+	if !ok {
+		return 0, cerror.ErrAvroDecodeFailed.GenWithStack("invalid watermark event %v", d.record)
+	}
+	return uint64(ts), nil
+}
+
+// NextRowChangedEvent implements the EventBatchDecoder interface
+func (d *AvroEventBatchDecoder) NextRowChangedEvent() (*model.RowChangedEvent, error) {
+	if d.nextType != model.MqMessageTypeRow {
+		return nil, cerror.ErrAvroDecodeFailed.GenWithStack("not found row changed event message")
+	}
+	d.nextType = model.MqMessageTypeUnknown
+
+	if d.schema.TiDBSchema == "" {
+		return nil, cerror.ErrAvroDecodeFailed.GenWithStack(
+			"schema %s has no TiDB extension, please enable `enable-tidb-extension`", d.schema.Name)
+	}
+
+	row := &model.RowChangedEvent{
+		Table: &model.TableName{
+			Schema: d.schema.TiDBSchema,
+			Table:  d.schema.Name,
+		},
+	}
+	cols := make([]*model.Column, 0, len(d.schema.Fields))
+	for _, field := range d.schema.Fields {
+		if field.Name == avroCommitTsFieldName {
+			commitTs, _ := d.record[field.Name].(int64)
+			row.CommitTs = uint64(commitTs)
+			continue
+		}
+		if field.TiDBType == nil {
+			return nil, cerror.ErrAvroDecodeFailed.GenWithStack("field %s has no TiDB type", field.Name)
+		}
+		col := &model.Column{
+			Name: field.Name,
+			Type: *field.TiDBType,
+			Flag: field.TiDBFlag,
+		}
+		value, err := avroNativeToColumnValue(d.record[field.Name], col, d.tz)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		col.Value = value
+		cols = append(cols, col)
+	}
+
+	// the value of a deletion is nil, so only the handle key columns are known.
+	if d.isDelete {
+		row.PreColumns = cols
+	} else {
+		row.Columns = cols
+	}
+	return row, nil
+}
+
+// NextDDLEvent implements the EventBatchDecoder interface
+func (d *AvroEventBatchDecoder) NextDDLEvent() (*model.DDLEvent, error) {
+	if d.nextType != model.MqMessageTypeDDL {
+		return nil, cerror.ErrAvroDecodeFailed.GenWithStack("not found ddl event message")
+	}
+	d.nextType = model.MqMessageTypeUnknown
+
+	schema, _ := d.record["schema"].(string)
+	table, _ := d.record["table"].(string)
+	query, _ := d.record["query"].(string)
+	tp, _ := d.record["type"].(string)
+	commitTs, _ := d.record["commitTs"].(int64)
+	return &model.DDLEvent{
+		CommitTs: uint64(commitTs),
+		TableInfo: &model.SimpleTableInfo{
+			Schema: schema,
+			Table:  table,
+		},
+		Query: query,
+		Type:  avroDDLType(tp),
+	}, nil
+}
+
+func (d *AvroEventBatchDecoder) decodeEnvelope(data []byte) (*avroDecodingSchema, map[string]interface{}, error) {
+	if len(data) < 5 || data[0] != magicByte {
+		return nil, nil, cerror.ErrAvroDecodeFailed.GenWithStack("invalid Avro envelope")
+	}
+	registryID := int(binary.BigEndian.Uint32(data[1:5]))
+
+	// TODO pass ctx from the upper function. Need to modify the EventBatchDecoder interface.
+	avroCodec, err := d.schemaManager.LookupByID(context.Background(), registryID)
+	if err != nil {
+		return nil, nil, errors.Annotate(err, "AvroEventBatchDecoder: lookup schema failed")
+	}
+
+	native, _, err := avroCodec.NativeFromBinary(data[5:])
+	if err != nil {
+		return nil, nil, cerror.WrapError(cerror.ErrAvroDecodeFailed, err)
+	}
+	record, ok := native.(map[string]interface{})
+	if !ok {
+		return nil, nil, cerror.ErrAvroDecodeFailed.GenWithStack("schema %d is not a record", registryID)
+	}
+
+	schema := new(avroDecodingSchema)
+	if err := json.Unmarshal([]byte(avroCodec.Schema()), schema); err != nil {
+		return nil, nil, cerror.WrapError(cerror.ErrAvroDecodeFailed, err)
+	}
+	return schema, record, nil
+}
+
+// avroDDLType converts the string representation of a DDL type back.
+func avroDDLType(tp string) timodel.ActionType {
+	for i := 1; i <= math.MaxUint8; i++ {
+		if timodel.ActionType(i).String() == tp {
+			return timodel.ActionType(i)
+		}
+	}
+	return timodel.ActionNone
+}
+
+// avroNativeToColumnValue is the reverse of columnToAvroNativeData, the precision
+// of the time types is milliseconds in Avro.
+func avroNativeToColumnValue(native interface{}, col *model.Column, tz *time.Location) (interface{}, error) {
+	// the nullable columns are encoded as unions.
+	if union, ok := native.(map[string]interface{}); ok {
+		for _, v := range union {
+			native = v
+		}
+	}
+	if native == nil {
+		return nil, nil
+	}
+
+	invalid := func() (interface{}, error) {
+		return nil, cerror.ErrAvroDecodeFailed.GenWithStack(
+			"invalid value %v(%T) for column %s", native, native, col.Name)
+	}
+
+	switch col.Type {
+	case mysql.TypeDate, mysql.TypeDatetime, mysql.TypeNewDate, mysql.TypeTimestamp:
+		t, ok := native.(time.Time)
+		if !ok {
+			return invalid()
+		}
+		if col.Type == mysql.TypeTimestamp {
+			t = t.In(tz)
+		} else {
+			t = t.UTC()
+		}
+		if col.Type == mysql.TypeDate {
+			return t.Format(types.DateFormat), nil
+		}
+		if t.Nanosecond() == 0 {
+			return t.Format(types.TimeFormat), nil
+		}
+		return t.Format(types.TimeFormat + ".000"), nil
+	case mysql.TypeDuration:
+		d, ok := native.(time.Duration)
+		if !ok {
+			return invalid()
+		}
+		sign := ""
+		if d < 0 {
+			sign = "-"
+			d = -d
+		}
+		str := fmt.Sprintf("%s%02d:%02d:%02d", sign,
+			d/time.Hour, d%time.Hour/time.Minute, d%time.Minute/time.Second)
+		if ms := d % time.Second / time.Millisecond; ms != 0 {
+			str += fmt.Sprintf(".%03d", ms)
+		}
+		return str, nil
+	case mysql.TypeVarchar, mysql.TypeString, mysql.TypeVarString:
+		switch v := native.(type) {
+		case string:
+			return []byte(v), nil
+		case []byte:
+			return v, nil
+		}
+		return invalid()
+	case mysql.TypeEnum, mysql.TypeSet, mysql.TypeBit:
+		r, ok := native.(*big.Rat)
+		if !ok {
+			return invalid()
+		}
+		return r.Num().Uint64(), nil
+	case mysql.TypeTiny, mysql.TypeShort, mysql.TypeInt24, mysql.TypeLong, mysql.TypeLonglong, mysql.TypeYear:
+		var v int64
+		switch n := native.(type) {
+		case int32:
+			v = int64(n)
+		case int64:
+			v = n
+		case *big.Rat:
+			// the unsigned bigint is encoded as a decimal.
+			return n.Num().Uint64(), nil
+		default:
+			return invalid()
+		}
+		if col.Flag.IsUnsigned() && col.Type != mysql.TypeYear {
+			return uint64(v), nil
+		}
+		return v, nil
+	case mysql.TypeFloat:
+		switch v := native.(type) {
+		case float32:
+			return float64(v), nil
+		case float64:
+			return v, nil
+		}
+		return invalid()
+	default:
+		// the decimal, JSON and blob columns, which are kept as they are.
+		return native, nil
+	}
+}
@@ -15,6 +15,7 @@ package codec
 
 import (
 	"context"
+	"encoding/binary"
 	"time"
 
 	"github.com/linkedin/goavro/v2"
@@ -89,7 +90,7 @@ func (s *avroBatchEncoderSuite) TestAvroEncodeOnly(c *check.C) {
 		{Name: "mybytes", Value: []byte("Hello World"), Type: mysql.TypeBlob},
 		{Name: "ts", Value: time.Now().Format(types.TimeFSPFormat), Type: mysql.TypeTimestamp},
 		{Name: "myjson", Value: "{\"foo\": \"bar\"}", Type: mysql.TypeJSON},
-	}, time.Local)
+	}, time.Local, nil)
 	c.Assert(err, check.IsNil)
 
 	res, _, err := avroCodec.NativeFromBinary(r.data)
@@ -135,7 +136,7 @@ func (s *avroBatchEncoderSuite) TestAvroTimeZone(c *check.C) {
 		{Name: "myfloat", Value: float64(3.14), Type: mysql.TypeFloat},
 		{Name: "mybytes", Value: []byte("Hello World"), Type: mysql.TypeBlob},
 		{Name: "ts", Value: timestamp.In(location).Format(types.TimeFSPFormat), Type: mysql.TypeTimestamp},
-	}, location)
+	}, location, nil)
 	c.Assert(err, check.IsNil)
 
 	res, _, err := avroCodec.NativeFromBinary(r.data)
@@ -240,3 +241,183 @@ func (s *avroBatchEncoderSuite) TestAvroEncode(c *check.C) {
 	_, err = s.encoder.AppendRowChangedEvent(testCaseUpdate)
 	c.Check(err, check.IsNil)
 }
+
+func (s *avroBatchEncoderSuite) TestAvroDDLAndCheckpoint(c *check.C) {
+	defer testleak.AfterTest(c)()
+	encoder := &AvroEventBatchEncoder{
+		valueSchemaManager: s.encoder.valueSchemaManager,
+		keySchemaManager:   s.encoder.keySchemaManager,
+		resultBuf:          make([]*MQMessage, 0, 4096),
+	}
+	ddl := &model.DDLEvent{
+		CommitTs: 417318403368288260,
+		TableInfo: &model.SimpleTableInfo{
+			Schema: "test", Table: "person",
+		},
+		Query: "create table person(id int primary key)",
+		Type:  model2.ActionCreateTable,
+	}
+
+	// the events are dropped if the TiDB extension is disabled.
+	msg, err := encoder.EncodeDDLEvent(ddl)
+	c.Assert(err, check.IsNil)
+	c.Assert(msg, check.IsNil)
+	msg, err = encoder.EncodeCheckpointEvent(417318403368288261)
+	c.Assert(err, check.IsNil)
+	c.Assert(msg, check.IsNil)
+
+	c.Assert(encoder.SetParams(map[string]string{"enable-tidb-extension": "abc"}), check.NotNil)
+	c.Assert(encoder.SetParams(map[string]string{"enable-tidb-extension": "true"}), check.IsNil)
+
+	decode := func(name string, msg *MQMessage) interface{} {
+		c.Assert(msg.Key, check.IsNil)
+		c.Assert(msg.Value[0], check.Equals, magicByte)
+		manager := encoder.valueSchemaManager
+		entry := manager.cache[manager.nameToSchemaSubject(name)]
+		c.Assert(int(binary.BigEndian.Uint32(msg.Value[1:5])), check.Equals, entry.registryID)
+		native, _, err := entry.codec.NativeFromBinary(msg.Value[5:])
+		c.Assert(err, check.IsNil)
+		return native
+	}
+
+	msg, err = encoder.EncodeDDLEvent(ddl)
+	c.Assert(err, check.IsNil)
+	c.Assert(msg.Type, check.Equals, model.MqMessageTypeDDL)
+	c.Assert(decode(avroDDLSchemaName, msg), check.DeepEquals, map[string]interface{}{
+		"schema":   "test",
+		"table":    "person",
+		"query":    "create table person(id int primary key)",
+		"type":     "create table",
+		"commitTs": int64(417318403368288260),
+	})
+
+	msg, err = encoder.EncodeCheckpointEvent(417318403368288261)
+	c.Assert(err, check.IsNil)
+	c.Assert(msg.Type, check.Equals, model.MqMessageTypeResolved)
+	c.Assert(decode(avroWatermarkSchemaName, msg), check.DeepEquals, map[string]interface{}{
+		"resolvedTs": int64(417318403368288261),
+	})
+}
+
+func (s *avroBatchEncoderSuite) TestAvroDecode(c *check.C) {
+	defer testleak.AfterTest(c)()
+	encoder := &AvroEventBatchEncoder{
+		valueSchemaManager: s.encoder.valueSchemaManager,
+		keySchemaManager:   s.encoder.keySchemaManager,
+		resultBuf:          make([]*MQMessage, 0, 4096),
+		tz:                 time.UTC,
+	}
+	table := &model.TableName{Schema: "test", Table: "decoder"}
+	cols := []*model.Column{
+		{Name: "id", Type: mysql.TypeLong, Flag: model.HandleKeyFlag, Value: int64(1)},
+		{Name: "name", Type: mysql.TypeVarchar, Value: "Bob"},
+		{Name: "utiny", Type: mysql.TypeTiny, Flag: model.UnsignedFlag, Value: uint64(100)},
+		{Name: "ubig", Type: mysql.TypeLonglong, Flag: model.UnsignedFlag, Value: uint64(12345)},
+		{Name: "price", Type: mysql.TypeNewDecimal, Value: "12.34"},
+		{Name: "created", Type: mysql.TypeDatetime, Value: "2022-01-02 03:04:05"},
+		{Name: "data", Type: mysql.TypeBlob, Flag: model.BinaryFlag, Value: []byte{0, 1, 2}},
+		{Name: "note", Type: mysql.TypeVarchar, Flag: model.NullableFlag, Value: nil},
+	}
+	insert := &model.RowChangedEvent{CommitTs: 417318403368288260, Table: table, Columns: cols}
+	del := &model.RowChangedEvent{CommitTs: 417318403368288261, Table: table, PreColumns: cols}
+
+	// the rows can not be decoded without the TiDB extension.
+	_, err := encoder.AppendRowChangedEvent(insert)
+	c.Assert(err, check.IsNil)
+	msgs := encoder.Build()
+	c.Assert(msgs, check.HasLen, 1)
+	decoder, err := NewAvroEventBatchDecoder(msgs[0].Key, msgs[0].Value, encoder.valueSchemaManager, time.UTC)
+	c.Assert(err, check.IsNil)
+	tp, hasNext, err := decoder.HasNext()
+	c.Assert(err, check.IsNil)
+	c.Assert(hasNext, check.IsTrue)
+	c.Assert(tp, check.Equals, model.MqMessageTypeRow)
+	_, err = decoder.NextRowChangedEvent()
+	c.Assert(err, check.ErrorMatches, ".*TiDB extension.*")
+
+	c.Assert(encoder.SetParams(map[string]string{"enable-tidb-extension": "true"}), check.IsNil)
+	// use another table version to refresh the cached schemas.
+	insert.TableInfoVersion, del.TableInfoVersion = 1, 1
+	_, err = encoder.AppendRowChangedEvent(insert)
+	c.Assert(err, check.IsNil)
+	_, err = encoder.AppendRowChangedEvent(del)
+	c.Assert(err, check.IsNil)
+	msgs = encoder.Build()
+	c.Assert(msgs, check.HasLen, 2)
+
+	expected := []*model.Column{
+		{Name: "id", Type: mysql.TypeLong, Flag: model.HandleKeyFlag, Value: int64(1)},
+		{Name: "name", Type: mysql.TypeVarchar, Value: []byte("Bob")},
+		{Name: "utiny", Type: mysql.TypeTiny, Flag: model.UnsignedFlag, Value: uint64(100)},
+		{Name: "ubig", Type: mysql.TypeLonglong, Flag: model.UnsignedFlag, Value: uint64(12345)},
+		{Name: "price", Type: mysql.TypeNewDecimal, Value: "12.34"},
+		{Name: "created", Type: mysql.TypeDatetime, Value: "2022-01-02 03:04:05"},
+		{Name: "data", Type: mysql.TypeBlob, Flag: model.BinaryFlag, Value: []byte{0, 1, 2}},
+		{Name: "note", Type: mysql.TypeVarchar, Flag: model.NullableFlag, Value: nil},
+	}
+	decoder, err = NewAvroEventBatchDecoder(msgs[0].Key, msgs[0].Value, encoder.valueSchemaManager, time.UTC)
+	c.Assert(err, check.IsNil)
+	tp, hasNext, err = decoder.HasNext()
+	c.Assert(err, check.IsNil)
+	c.Assert(hasNext, check.IsTrue)
+	c.Assert(tp, check.Equals, model.MqMessageTypeRow)
+	row, err := decoder.NextRowChangedEvent()
+	c.Assert(err, check.IsNil)
+	c.Assert(row.CommitTs, check.Equals, insert.CommitTs)
+	c.Assert(row.Table, check.DeepEquals, table)
+	c.Assert(row.Columns, check.DeepEquals, expected)
+	c.Assert(row.PreColumns, check.IsNil)
+	_, hasNext, err = decoder.HasNext()
+	c.Assert(err, check.IsNil)
+	c.Assert(hasNext, check.IsFalse)
+
+	// only the handle key columns of a deletion are known.
+	decoder, err = NewAvroEventBatchDecoder(msgs[1].Key, msgs[1].Value, encoder.keySchemaManager, time.UTC)
+	c.Assert(err, check.IsNil)
+	row, err = decoder.NextRowChangedEvent()
+	c.Assert(err, check.NotNil)
+	_, _, err = decoder.HasNext()
+	c.Assert(err, check.IsNil)
+	row, err = decoder.NextRowChangedEvent()
+	c.Assert(err, check.IsNil)
+	c.Assert(row.CommitTs, check.Equals, del.CommitTs)
+	c.Assert(row.Table, check.DeepEquals, table)
+	c.Assert(row.Columns, check.IsNil)
+	c.Assert(row.PreColumns, check.DeepEquals, expected[:1])
+
+	ddl := &model.DDLEvent{
+		CommitTs:  417318403368288262,
+		TableInfo: &model.SimpleTableInfo{Schema: "test", Table: "decoder"},
+		Query:     "create table decoder(id int primary key)",
+		Type:      model2.ActionCreateTable,
+	}
+	msg, err := encoder.EncodeDDLEvent(ddl)
+	c.Assert(err, check.IsNil)
+	decoder, err = NewAvroEventBatchDecoder(msg.Key, msg.Value, encoder.valueSchemaManager, time.UTC)
+	c.Assert(err, check.IsNil)
+	tp, hasNext, err = decoder.HasNext()
+	c.Assert(err, check.IsNil)
+	c.Assert(hasNext, check.IsTrue)
+	c.Assert(tp, check.Equals, model.MqMessageTypeDDL)
+	decodedDDL, err := decoder.NextDDLEvent()
+	c.Assert(err, check.IsNil)
+	c.Assert(decodedDDL, check.DeepEquals, ddl)
+
+	msg, err = encoder.EncodeCheckpointEvent(417318403368288263)
+	c.Assert(err, check.IsNil)
+	decoder, err = NewAvroEventBatchDecoder(msg.Key, msg.Value, encoder.valueSchemaManager, time.UTC)
+	c.Assert(err, check.IsNil)
+	tp, _, err = decoder.HasNext()
+	c.Assert(err, check.IsNil)
+	c.Assert(tp, check.Equals, model.MqMessageTypeResolved)
+	ts, err := decoder.NextResolvedEvent()
+	c.Assert(err, check.IsNil)
+	c.Assert(ts, check.Equals, uint64(417318403368288263))
+
+	_, err = NewAvroEventBatchDecoder(nil, []byte{1, 2, 3}, nil, time.UTC)
+	c.Assert(err, check.NotNil)
+	decoder, err = NewAvroEventBatchDecoder(nil, []byte{1, 2, 3}, encoder.valueSchemaManager, time.UTC)
+	c.Assert(err, check.IsNil)
+	_, _, err = decoder.HasNext()
+	c.Assert(err, check.NotNil)
+}
@@ -25,6 +25,7 @@ import (
 	"github.com/pingcap/log"
 	mm "github.com/pingcap/tidb/parser/model"
 	"github.com/pingcap/tidb/parser/mysql"
+	parsertypes "github.com/pingcap/tidb/parser/types"
 	"github.com/pingcap/tidb/types"
 	"github.com/pingcap/tiflow/cdc/model"
 	"github.com/pingcap/tiflow/pkg/config"
@@ -557,3 +558,137 @@ func (b *canalEventBatchEncoderBuilder) Build(ctx context.Context) (EventBatchEn
 func newCanalEventBatchEncoderBuilder(opts map[string]string) EncoderBuilder {
 	return &canalEventBatchEncoderBuilder{opts: opts}
 }
+
+// CanalEventBatchDecoder decodes the canal protobuf messages.
+// The commit ts is in milliseconds in canal, so the logical part is lost.
+type CanalEventBatchDecoder struct {
+	entries []*canal.Entry
+
+	nextRowChange *canal.RowChange
+}
+
+// NewCanalEventBatchDecoder creates a new CanalEventBatchDecoder.
+func NewCanalEventBatchDecoder(data []byte) (EventBatchDecoder, error) {
+	if err := checkClaimCheckResolved(data); err != nil {
+		return nil, errors.Trace(err)
+	}
+	packet := new(canal.Packet)
+	if err := proto.Unmarshal(data, packet); err != nil {
+		return nil, cerror.WrapError(cerror.ErrCanalDecodeFailed, err)
+	}
+	messages := new(canal.Messages)
+	if err := proto.Unmarshal(packet.GetBody(), messages); err != nil {
+		return nil, cerror.WrapError(cerror.ErrCanalDecodeFailed, err)
+	}
+	entries := make([]*canal.Entry, 0, len(messages.GetMessages()))
+	for _, msg := range messages.GetMessages() {
+		entry := new(canal.Entry)
+		if err := proto.Unmarshal(msg, entry); err != nil {
+			return nil, cerror.WrapError(cerror.ErrCanalDecodeFailed, err)
+		}
+		entries = append(entries, entry)
+	}
+	return &CanalEventBatchDecoder{entries: entries}, nil
+}
+
+// HasNext implements the EventBatchDecoder interface
+func (d *CanalEventBatchDecoder) HasNext() (model.MqMessageType, bool, error) {
+	if len(d.entries) == 0 {
+		return model.MqMessageTypeUnknown, false, nil
+	}
+	if d.nextRowChange == nil {
+		rc := new(canal.RowChange)
+		if err := proto.Unmarshal(d.entries[0].GetStoreValue(), rc); err != nil {
+			return model.MqMessageTypeUnknown, false, cerror.WrapError(cerror.ErrCanalDecodeFailed, err)
+		}
+		d.nextRowChange = rc
+	}
+	if d.nextRowChange.GetIsDdl() {
+		return model.MqMessageTypeDDL, true, nil
+	}
+	return model.MqMessageTypeRow, true, nil
+}
+
+// NextResolvedEvent implements the EventBatchDecoder interface
+func (d *CanalEventBatchDecoder) NextResolvedEvent() (uint64, error) {
+	return 0, cerror.ErrCanalDecodeFailed.GenWithStack("canal protocol has no resolved event")
+}
+
+// NextRowChangedEvent implements the EventBatchDecoder interface
+func (d *CanalEventBatchDecoder) NextRowChangedEvent() (*model.RowChangedEvent, error) {
+	header, rc, err := d.next(model.MqMessageTypeRow)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	if len(rc.GetRowDatas()) != 1 {
+		return nil, cerror.ErrCanalDecodeFailed.GenWithStack(
+			"expect one row in the entry, got %d", len(rc.GetRowDatas()))
+	}
+
+	rowData := rc.GetRowDatas()[0]
+	row := &model.RowChangedEvent{
+		CommitTs: uint64(header.GetExecuteTime()) << 18,
+		Table: &model.TableName{
+			Schema: header.GetSchemaName(),
+			Table:  header.GetTableName(),
+		},
+	}
+	row.PreColumns = canalColumns2SinkColumns(rowData.GetBeforeColumns())
+	row.Columns = canalColumns2SinkColumns(rowData.GetAfterColumns())
+	return row, nil
+}
+
+// NextDDLEvent implements the EventBatchDecoder interface
+func (d *CanalEventBatchDecoder) NextDDLEvent() (*model.DDLEvent, error) {
+	header, rc, err := d.next(model.MqMessageTypeDDL)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	// the DDL type is lost, only the event type of canal is known.
+	return &model.DDLEvent{
+		CommitTs: uint64(header.GetExecuteTime()) << 18,
+		TableInfo: &model.SimpleTableInfo{
+			Schema: header.GetSchemaName(),
+			Table:  header.GetTableName(),
+		},
+		Query: rc.GetSql(),
+	}, nil
+}
+
+func (d *CanalEventBatchDecoder) next(tp model.MqMessageType) (*canal.Header, *canal.RowChange, error) {
+	nextType, hasNext, err := d.HasNext()
+	if err != nil {
+		return nil, nil, errors.Trace(err)
+	}
+	if !hasNext || nextType != tp {
+		return nil, nil, cerror.ErrCanalDecodeFailed.GenWithStack("not found %v message", tp)
+	}
+	header, rc := d.entries[0].GetHeader(), d.nextRowChange
+	d.entries = d.entries[1:]
+	d.nextRowChange = nil
+	return header, rc, nil
+}
+
+func canalColumns2SinkColumns(cols []*canal.Column) []*model.Column {
+	if len(cols) == 0 {
+		return nil
+	}
+	result := make([]*model.Column, 0, len(cols))
+	for _, c := range cols {
+		mysqlType := c.GetMysqlType()
+		var value interface{}
+		if !c.GetIsNull() {
+			value = c.GetValue()
+		}
+		col := NewColumn(value, parsertypes.StrToType(trimUnsignedFromMySQLType(mysqlType)))
+		if mysqlType != trimUnsignedFromMySQLType(mysqlType) {
+			col.Flag.SetIsUnsigned()
+		}
+		if c.GetIsKey() {
+			col.Flag.SetIsPrimaryKey()
+			col.Flag.SetIsHandleKey()
+		}
+		result = append(result, col.decodeCanalJSONColumn(c.GetName(), JavaSQLType(c.GetSqlType())))
+	}
+	return result
+}
@@ -415,6 +415,32 @@ func NewCanalFlatEventBatchDecoder(data []byte, enableTiDBExtension bool) EventB
 	}
 }
 
+// NewCanalJSONEventBatchDecoder creates a decoder from the value of a canal-json
+// Kafka message, the type of the message is detected from its content.
+func NewCanalJSONEventBatchDecoder(value []byte, enableTiDBExtension bool) (EventBatchDecoder, error) {
+	if err := checkClaimCheckResolved(value); err != nil {
+		return nil, errors.Trace(err)
+	}
+	var header struct {
+		IsDDL     bool   `json:"isDdl"`
+		EventType string `json:"type"`
+	}
+	if err := json.Unmarshal(value, &header); err != nil {
+		return nil, cerrors.WrapError(cerrors.ErrCanalDecodeFailed, err)
+	}
+	tp := model.MqMessageTypeRow
+	if header.IsDDL {
+		tp = model.MqMessageTypeDDL
+	} else if header.EventType == tidbWaterMarkType {
+		tp = model.MqMessageTypeResolved
+	}
+	data, err := json.Marshal(&MQMessage{Value: value, Type: tp})
+	if err != nil {
+		return nil, cerrors.WrapError(cerrors.ErrCanalDecodeFailed, err)
+	}
+	return NewCanalFlatEventBatchDecoder(data, enableTiDBExtension), nil
+}
+
 // HasNext implements the EventBatchDecoder interface
 func (b *CanalFlatEventBatchDecoder) HasNext() (model.MqMessageType, bool, error) {
 	if len(b.data) == 0 {
@@ -289,6 +289,49 @@ func (s *canalFlatSuite) TestNewCanalFlatEventBatchDecoder4DDLMessage(c *check.C
 	}
 }
 
+func (s *canalFlatSuite) TestNewCanalJSONEventBatchDecoder(c *check.C) {
+	defer testleak.AfterTest(c)()
+	encoder := &CanalFlatEventBatchEncoder{builder: NewCanalEntryBuilder(), enableTiDBExtension: true}
+
+	_, err := encoder.AppendRowChangedEvent(testCaseInsert)
+	c.Assert(err, check.IsNil)
+	_, err = encoder.AppendResolvedEvent(testCaseInsert.CommitTs)
+	c.Assert(err, check.IsNil)
+	msgs := encoder.Build()
+	c.Assert(msgs, check.HasLen, 1)
+	ddlMsg, err := encoder.EncodeDDLEvent(testCaseDDL)
+	c.Assert(err, check.IsNil)
+	resolvedMsg, err := encoder.EncodeCheckpointEvent(testCaseDDL.CommitTs)
+	c.Assert(err, check.IsNil)
+
+	for i, msg := range []*MQMessage{msgs[0], ddlMsg, resolvedMsg} {
+		// only the value of the message is used.
+		decoder, err := NewCanalJSONEventBatchDecoder(msg.Value, true)
+		c.Assert(err, check.IsNil)
+		ty, hasNext, err := decoder.HasNext()
+		c.Assert(err, check.IsNil)
+		c.Assert(hasNext, check.IsTrue)
+		c.Assert(ty, check.Equals, msg.Type)
+		switch i {
+		case 0:
+			row, err := decoder.NextRowChangedEvent()
+			c.Assert(err, check.IsNil)
+			c.Assert(row.CommitTs, check.Equals, testCaseInsert.CommitTs)
+		case 1:
+			ddl, err := decoder.NextDDLEvent()
+			c.Assert(err, check.IsNil)
+			c.Assert(ddl.Query, check.Equals, testCaseDDL.Query)
+		case 2:
+			ts, err := decoder.NextResolvedEvent()
+			c.Assert(err, check.IsNil)
+			c.Assert(ts, check.Equals, testCaseDDL.CommitTs)
+		}
+	}
+
+	_, err = NewCanalJSONEventBatchDecoder([]byte("{"), true)
+	c.Assert(err, check.NotNil)
+}
+
 func (s *canalFlatSuite) TestBatching(c *check.C) {
 	defer testleak.AfterTest(c)()
 	encoder := &CanalFlatEventBatchEncoder{builder: NewCanalEntryBuilder()}
@@ -132,6 +132,86 @@ func (s *canalBatchSuite) TestCanalEventBatchEncoder(c *check.C) {
 	}
 }
 
+func (s *canalBatchSuite) TestCanalEventBatchDecoder(c *check.C) {
+	defer testleak.AfterTest(c)()
+	table := &model.TableName{Schema: "test", Table: "t1"}
+	newColumns := func(name string) []*model.Column {
+		return []*model.Column{
+			{Name: "id", Type: mysql.TypeLong, Flag: model.HandleKeyFlag | model.PrimaryKeyFlag, Value: int64(1)},
+			{Name: "name", Type: mysql.TypeVarchar, Value: []byte(name)},
+			{Name: "ubig", Type: mysql.TypeLonglong, Flag: model.UnsignedFlag, Value: uint64(12345)},
+			{Name: "data", Type: mysql.TypeBlob, Flag: model.BinaryFlag, Value: []byte{0xff, 0x1}},
+			{Name: "note", Type: mysql.TypeVarchar, Flag: model.NullableFlag, Value: nil},
+		}
+	}
+	// all the values are strings in canal, and the binary flags are lost.
+	newDecodedColumns := func(name string) []*model.Column {
+		return []*model.Column{
+			{Name: "id", Type: mysql.TypeLong, Flag: model.HandleKeyFlag | model.PrimaryKeyFlag, Value: "1"},
+			{Name: "name", Type: mysql.TypeVarchar, Value: name},
+			{Name: "ubig", Type: mysql.TypeLonglong, Flag: model.UnsignedFlag, Value: "12345"},
+			{Name: "data", Type: mysql.TypeBlob, Value: "\xff\x01"},
+			{Name: "note", Type: mysql.TypeVarchar, Value: nil},
+		}
+	}
+	rows := []*model.RowChangedEvent{
+		{CommitTs: 417318403368288260, Table: table, Columns: newColumns("a")},
+		{CommitTs: 417318403368288261, Table: table, PreColumns: newColumns("a"), Columns: newColumns("b")},
+	}
+
+	encoder := NewCanalEventBatchEncoder()
+	for _, row := range rows {
+		_, err := encoder.AppendRowChangedEvent(row)
+		c.Assert(err, check.IsNil)
+	}
+	msgs := encoder.Build()
+	c.Assert(msgs, check.HasLen, 1)
+
+	decoder, err := NewCanalEventBatchDecoder(msgs[0].Value)
+	c.Assert(err, check.IsNil)
+	_, err = decoder.NextDDLEvent()
+	c.Assert(err, check.NotNil)
+	for i, expected := range []*model.RowChangedEvent{
+		{Table: table, Columns: newDecodedColumns("a")},
+		{Table: table, PreColumns: newDecodedColumns("a"), Columns: newDecodedColumns("b")},
+	} {
+		tp, hasNext, err := decoder.HasNext()
+		c.Assert(err, check.IsNil)
+		c.Assert(hasNext, check.IsTrue)
+		c.Assert(tp, check.Equals, model.MqMessageTypeRow)
+		row, err := decoder.NextRowChangedEvent()
+		c.Assert(err, check.IsNil, check.Commentf("row %d", i))
+		// the commit ts is in milliseconds in canal.
+		c.Assert(row.CommitTs, check.Equals, uint64(417318403368288256))
+		c.Assert(row.Table, check.DeepEquals, expected.Table)
+		c.Assert(row.PreColumns, check.DeepEquals, expected.PreColumns)
+		c.Assert(row.Columns, check.DeepEquals, expected.Columns)
+	}
+	_, hasNext, err := decoder.HasNext()
+	c.Assert(err, check.IsNil)
+	c.Assert(hasNext, check.IsFalse)
+
+	ddl := &model.DDLEvent{
+		CommitTs:  417318403368288256,
+		TableInfo: &model.SimpleTableInfo{Schema: "test", Table: "t1"},
+		Query:     "create table t1(id int primary key)",
+	}
+	msg, err := encoder.EncodeDDLEvent(ddl)
+	c.Assert(err, check.IsNil)
+	decoder, err = NewCanalEventBatchDecoder(msg.Value)
+	c.Assert(err, check.IsNil)
+	tp, hasNext, err := decoder.HasNext()
+	c.Assert(err, check.IsNil)
+	c.Assert(hasNext, check.IsTrue)
+	c.Assert(tp, check.Equals, model.MqMessageTypeDDL)
+	decodedDDL, err := decoder.NextDDLEvent()
+	c.Assert(err, check.IsNil)
+	c.Assert(decodedDDL, check.DeepEquals, ddl)
+
+	_, err = NewCanalEventBatchDecoder([]byte("invalid"))
+	c.Assert(err, check.NotNil)
+}
+
 type canalEntrySuite struct{}
 
 var _ = check.Suite(&canalEntrySuite{})
@@ -0,0 +1,160 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package codec
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"hash/crc32"
+	"os"
+
+	"github.com/google/uuid"
+	"github.com/pingcap/errors"
+	"github.com/pingcap/log"
+	"github.com/pingcap/tidb/br/pkg/storage"
+	cerror "github.com/pingcap/tiflow/pkg/errors"
+	"go.uber.org/zap"
+)
+
+// ClaimCheckStorageURIKey is the sink-uri parameter of the external storage
+// used by the claim check, the claim check is enabled only if it's set.
+const ClaimCheckStorageURIKey = "claim-check-storage-uri"
+
+// claimCheckMagic is the prefix of the value of a claim check message, it
+// never appears at the beginning of the value encoded by any protocol.
+var claimCheckMagic = []byte("\xffticdc-claim-check\xff")
+
+// claimCheckReference is the value of a claim check message, which refers to
+// the value of the original message in the external storage.
+type claimCheckReference struct {
+	Location string `json:"location"`
+	Length   int    `json:"length"`
+	Checksum uint32 `json:"checksum"`
+}
+
+// ClaimCheck writes the values of the oversized messages into an external
+// storage, and sends the small references of them instead, so that the
+// messages are not rejected by the broker. The consumers resolve the
+// references back to the original values by the decoders created by
+// NewDecoderFactory, the decoders of all protocols reject the references
+// which are not resolved.
+type ClaimCheck struct {
+	storage      storage.ExternalStorage
+	changefeedID string
+}
+
+// NewClaimCheck creates a ClaimCheck with the external storage of storageURI.
+func NewClaimCheck(ctx context.Context, storageURI string, changefeedID string) (*ClaimCheck, error) {
+	backend, err := storage.ParseBackend(storageURI, nil)
+	if err != nil {
+		return nil, cerror.WrapError(cerror.ErrStorageInitialize, err)
+	}
+	if local := backend.GetLocal(); local != nil {
+		if err := os.MkdirAll(local.GetPath(), 0o755); err != nil {
+			return nil, cerror.WrapError(cerror.ErrStorageInitialize, err)
+		}
+	}
+	extStorage, err := storage.New(ctx, backend, &storage.ExternalStorageOptions{
+		SendCredentials: false,
+	})
+	if err != nil {
+		return nil, cerror.WrapError(cerror.ErrStorageInitialize, err)
+	}
+	return &ClaimCheck{storage: extStorage, changefeedID: changefeedID}, nil
+}
+
+// IsClaimCheckMessage returns whether the value is a claim check reference.
+func IsClaimCheckMessage(value []byte) bool {
+	return bytes.HasPrefix(value, claimCheckMagic)
+}
+
+// WriteMessage writes the value of the message into the external storage, and
+// returns the claim check message of it, which has the same key and the
+// attributes as the original one.
+func (c *ClaimCheck) WriteMessage(
+	ctx context.Context, topic string, partition int32, m *MQMessage,
+) (*MQMessage, error) {
+	ref := &claimCheckReference{
+		// The objects are not put into directories, since the local storage
+		// doesn't create the parent directories of the files.
+		Location: fmt.Sprintf("%s_%s_%d_%d_%s", c.changefeedID, topic, partition, m.Ts, uuid.New().String()),
+		Length:   len(m.Value),
+		Checksum: crc32.ChecksumIEEE(m.Value),
+	}
+	if err := c.storage.WriteFile(ctx, ref.Location, m.Value); err != nil {
+		return nil, cerror.WrapError(cerror.ErrExternalStorageAPI, err)
+	}
+	data, err := json.Marshal(ref)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	value := make([]byte, 0, len(claimCheckMagic)+len(data))
+	value = append(value, claimCheckMagic...)
+	value = append(value, data...)
+
+	log.Debug("oversized message is sent by claim check",
+		zap.String("changefeed", c.changefeedID),
+		zap.String("location", ref.Location),
+		zap.Int("length", m.Length()))
+
+	message := NewMQMessage(m.Protocol, m.Key, value, m.Ts, m.Type, m.Schema, m.Table)
+	message.rowsCount = m.rowsCount
+	return message, nil
+}
+
+// ResolveMessage returns the original value of a claim check message, the
+// value is returned as is if it's not a claim check message.
+func (c *ClaimCheck) ResolveMessage(ctx context.Context, value []byte) ([]byte, error) {
+	if !IsClaimCheckMessage(value) {
+		return value, nil
+	}
+	ref := &claimCheckReference{}
+	if err := json.Unmarshal(value[len(claimCheckMagic):], ref); err != nil {
+		return nil, cerror.WrapError(cerror.ErrClaimCheckCorrupted, err, string(value))
+	}
+	data, err := c.storage.ReadFile(ctx, ref.Location)
+	if err != nil {
+		return nil, cerror.WrapError(cerror.ErrExternalStorageAPI, err)
+	}
+	if len(data) != ref.Length || crc32.ChecksumIEEE(data) != ref.Checksum {
+		return nil, cerror.ErrClaimCheckCorrupted.GenWithStackByArgs(ref.Location)
+	}
+	return data, nil
+}
+
+// DecoderFactory creates a decoder for the key and the value of a message.
+type DecoderFactory func(key, value []byte) (EventBatchDecoder, error)
+
+// NewDecoderFactory returns the DecoderFactory which resolves the values of
+// the claim check messages before they are decoded by newDecoder.
+func (c *ClaimCheck) NewDecoderFactory(ctx context.Context, newDecoder DecoderFactory) DecoderFactory {
+	return func(key, value []byte) (EventBatchDecoder, error) {
+		value, err := c.ResolveMessage(ctx, value)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		return newDecoder(key, value)
+	}
+}
+
+// checkClaimCheckResolved returns an error if the value is a claim check
+// message, it must be resolved by the ClaimCheck before decoding.
+func checkClaimCheckResolved(value []byte) error {
+	if IsClaimCheckMessage(value) {
+		return cerror.ErrClaimCheckUnresolved.GenWithStackByArgs(ClaimCheckStorageURIKey)
+	}
+	return nil
+}
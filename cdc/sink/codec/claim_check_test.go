@@ -0,0 +1,172 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package codec
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pingcap/check"
+	"github.com/pingcap/errors"
+	"github.com/pingcap/tidb/parser/mysql"
+	"github.com/pingcap/tiflow/cdc/model"
+	"github.com/pingcap/tiflow/pkg/config"
+	cerror "github.com/pingcap/tiflow/pkg/errors"
+	"github.com/pingcap/tiflow/pkg/security"
+	"github.com/pingcap/tiflow/pkg/util/testleak"
+)
+
+type claimCheckSuite struct{}
+
+var _ = check.Suite(&claimCheckSuite{})
+
+func (s *claimCheckSuite) TestClaimCheck(c *check.C) {
+	defer testleak.AfterTest(c)()
+	ctx := context.Background()
+	dir := c.MkDir()
+
+	// the oversized row is allowed by the encoder if the claim check is enabled.
+	encoder := NewJSONEventBatchEncoder()
+	err := encoder.SetParams(map[string]string{"max-message-bytes": "256"})
+	c.Assert(err, check.IsNil)
+	row := &model.RowChangedEvent{
+		CommitTs: 100,
+		Table:    &model.TableName{Schema: "a", Table: "b"},
+		Columns:  []*model.Column{{Name: "col1", Type: 1, Value: strings.Repeat("a", 1024)}},
+	}
+	_, err = encoder.AppendRowChangedEvent(row)
+	c.Assert(cerror.ErrJSONCodecRowTooLarge.Equal(err), check.IsTrue)
+
+	err = encoder.SetParams(map[string]string{
+		"max-message-bytes":     "256",
+		ClaimCheckStorageURIKey: "file://" + dir,
+	})
+	c.Assert(err, check.IsNil)
+	_, err = encoder.AppendRowChangedEvent(row)
+	c.Assert(err, check.IsNil)
+	messages := encoder.Build()
+	c.Assert(messages, check.HasLen, 1)
+	c.Assert(messages[0].Length(), check.Greater, 256)
+
+	claimCheck, err := NewClaimCheck(ctx, "file://"+dir, "test-changefeed")
+	c.Assert(err, check.IsNil)
+	ref, err := claimCheck.WriteMessage(ctx, "test", 1, messages[0])
+	c.Assert(err, check.IsNil)
+	c.Assert(ref.Length(), check.Less, 256)
+	c.Assert(ref.Key, check.DeepEquals, messages[0].Key)
+	c.Assert(ref.Ts, check.Equals, uint64(100))
+	c.Assert(ref.GetRowsCount(), check.Equals, 1)
+	c.Assert(IsClaimCheckMessage(ref.Value), check.IsTrue)
+	c.Assert(IsClaimCheckMessage(messages[0].Value), check.IsFalse)
+
+	value, err := claimCheck.ResolveMessage(ctx, ref.Value)
+	c.Assert(err, check.IsNil)
+	c.Assert(value, check.DeepEquals, messages[0].Value)
+	decoder, err := NewJSONEventBatchDecoder(ref.Key, value)
+	c.Assert(err, check.IsNil)
+	_, hasNext, err := decoder.HasNext()
+	c.Assert(err, check.IsNil)
+	c.Assert(hasNext, check.IsTrue)
+	decoded, err := decoder.NextRowChangedEvent()
+	c.Assert(err, check.IsNil)
+	c.Assert(decoded.CommitTs, check.Equals, row.CommitTs)
+	c.Assert(decoded.Table, check.DeepEquals, row.Table)
+
+	// the other messages are returned as is.
+	value, err = claimCheck.ResolveMessage(ctx, []byte("value"))
+	c.Assert(err, check.IsNil)
+	c.Assert(value, check.DeepEquals, []byte("value"))
+
+	// the corrupted object is detected by the checksum.
+	files, err := os.ReadDir(dir)
+	c.Assert(err, check.IsNil)
+	c.Assert(files, check.HasLen, 1)
+	corrupted := append([]byte{}, messages[0].Value...)
+	corrupted[len(corrupted)-1]++
+	err = os.WriteFile(filepath.Join(dir, files[0].Name()), corrupted, 0o644)
+	c.Assert(err, check.IsNil)
+	_, err = claimCheck.ResolveMessage(ctx, ref.Value)
+	c.Assert(cerror.ErrClaimCheckCorrupted.Equal(err), check.IsTrue)
+}
+
+func (s *claimCheckSuite) TestClaimCheckProtocols(c *check.C) {
+	defer testleak.AfterTest(c)()
+	ctx := context.Background()
+	dir := c.MkDir()
+	claimCheck, err := NewClaimCheck(ctx, "file://"+dir, "test-changefeed")
+	c.Assert(err, check.IsNil)
+
+	opts := map[string]string{
+		"max-message-bytes":     "256",
+		ClaimCheckStorageURIKey: "file://" + dir,
+	}
+	row := &model.RowChangedEvent{
+		CommitTs: 100,
+		Table:    &model.TableName{Schema: "a", Table: "b"},
+		Columns: []*model.Column{
+			{Name: "id", Type: mysql.TypeLong, Flag: model.HandleKeyFlag | model.PrimaryKeyFlag, Value: int64(1)},
+			{Name: "name", Type: mysql.TypeVarchar, Value: []byte(strings.Repeat("a", 1024))},
+		},
+	}
+	testCases := []struct {
+		protocol   config.Protocol
+		newDecoder DecoderFactory
+	}{
+		{protocol: config.ProtocolOpen, newDecoder: NewJSONEventBatchDecoder},
+		{protocol: config.ProtocolCraft, newDecoder: func(key, value []byte) (EventBatchDecoder, error) {
+			return NewCraftEventBatchDecoder(value)
+		}},
+		{protocol: config.ProtocolCanal, newDecoder: func(key, value []byte) (EventBatchDecoder, error) {
+			return NewCanalEventBatchDecoder(value)
+		}},
+		{protocol: config.ProtocolCanalJSON, newDecoder: func(key, value []byte) (EventBatchDecoder, error) {
+			return NewCanalJSONEventBatchDecoder(value, false)
+		}},
+		{protocol: config.ProtocolMaxwell, newDecoder: NewMaxwellEventBatchDecoder},
+		{protocol: config.ProtocolDebezium, newDecoder: NewDebeziumEventBatchDecoder},
+	}
+	for _, tc := range testCases {
+		builder, err := NewEventBatchEncoderBuilder(tc.protocol, &security.Credential{}, opts)
+		c.Assert(err, check.IsNil)
+		encoder, err := builder.Build(ctx)
+		c.Assert(err, check.IsNil)
+		// the oversized row is never rejected by the encoders.
+		_, err = encoder.AppendRowChangedEvent(row)
+		c.Assert(err, check.IsNil, check.Commentf("%s", tc.protocol))
+		_, err = encoder.AppendResolvedEvent(row.CommitTs)
+		c.Assert(err, check.IsNil)
+		messages := encoder.Build()
+		c.Assert(messages, check.HasLen, 1, check.Commentf("%s", tc.protocol))
+		c.Assert(messages[0].Length(), check.Greater, 256)
+
+		ref, err := claimCheck.WriteMessage(ctx, "test", 1, messages[0])
+		c.Assert(err, check.IsNil)
+		// the decoders reject the references which are not resolved.
+		_, err = tc.newDecoder(ref.Key, ref.Value)
+		c.Assert(cerror.ErrClaimCheckUnresolved.Equal(errors.Cause(err)), check.IsTrue, check.Commentf("%s", tc.protocol))
+
+		decoder, err := claimCheck.NewDecoderFactory(ctx, tc.newDecoder)(ref.Key, ref.Value)
+		c.Assert(err, check.IsNil, check.Commentf("%s", tc.protocol))
+		tp, hasNext, err := decoder.HasNext()
+		c.Assert(err, check.IsNil)
+		c.Assert(hasNext, check.IsTrue)
+		c.Assert(tp, check.Equals, model.MqMessageTypeRow)
+		decoded, err := decoder.NextRowChangedEvent()
+		c.Assert(err, check.IsNil)
+		c.Assert(decoded.Table.Schema, check.Equals, row.Table.Schema)
+		c.Assert(decoded.Table.Table, check.Equals, row.Table.Table)
+	}
+}
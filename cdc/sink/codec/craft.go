@@ -265,6 +265,9 @@ func NewCraftEventBatchDecoder(bits []byte) (EventBatchDecoder, error) {
 
 // NewCraftEventBatchDecoderWithAllocator creates a new CraftEventBatchDecoder with given allocator.
 func NewCraftEventBatchDecoderWithAllocator(bits []byte, allocator *craft.SliceAllocator) (EventBatchDecoder, error) {
+	if err := checkClaimCheckResolved(bits); err != nil {
+		return nil, errors.Trace(err)
+	}
 	decoder, err := craft.NewMessageDecoder(bits, allocator)
 	if err != nil {
 		return nil, errors.Trace(err)
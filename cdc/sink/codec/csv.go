@@ -0,0 +1,179 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package codec
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/csv"
+	"strconv"
+
+	"github.com/pingcap/tiflow/cdc/model"
+	"github.com/pingcap/tiflow/pkg/config"
+	cerror "github.com/pingcap/tiflow/pkg/errors"
+)
+
+const (
+	csvOperationInsert = "I"
+	csvOperationUpdate = "U"
+	csvOperationDelete = "D"
+	// csvNullValue is the representation of NULL, which is the same as
+	// the default of `LOAD DATA`.
+	csvNullValue = `\N`
+)
+
+type csvEventBatchEncoderBuilder struct {
+	opts map[string]string
+}
+
+// Build a `CSVEventBatchEncoder`
+func (b *csvEventBatchEncoderBuilder) Build(_ context.Context) (EventBatchEncoder, error) {
+	encoder := NewCSVEventBatchEncoder()
+	if err := encoder.SetParams(b.opts); err != nil {
+		return nil, cerror.WrapError(cerror.ErrSinkInvalidConfig, err)
+	}
+	return encoder, nil
+}
+
+func newCSVEventBatchEncoderBuilder(opts map[string]string) EncoderBuilder {
+	return &csvEventBatchEncoderBuilder{opts: opts}
+}
+
+// CSVEventBatchEncoder encodes each row changed event into a CSV record like
+// `op,schema,table,commit-ts,col1,col2,...`, the op is one of `I`, `U` and `D`.
+// An update event is encoded with the new values, and a delete event is
+// encoded with the old values. DDL and checkpoint events are not supported
+// by CSV, they are ignored by the encoder.
+type CSVEventBatchEncoder struct {
+	unresolvedBuf []*MQMessage
+	resolvedBuf   []*MQMessage
+}
+
+// NewCSVEventBatchEncoder creates a new CSVEventBatchEncoder
+func NewCSVEventBatchEncoder() EventBatchEncoder {
+	return &CSVEventBatchEncoder{}
+}
+
+// EncodeCheckpointEvent implements the EventBatchEncoder interface
+func (c *CSVEventBatchEncoder) EncodeCheckpointEvent(ts uint64) (*MQMessage, error) {
+	return nil, nil
+}
+
+// AppendRowChangedEvent implements the EventBatchEncoder interface
+func (c *CSVEventBatchEncoder) AppendRowChangedEvent(e *model.RowChangedEvent) (EncoderResult, error) {
+	var op string
+	columns := e.Columns
+	switch {
+	case e.IsDelete():
+		op = csvOperationDelete
+		columns = e.PreColumns
+	case e.IsUpdate():
+		op = csvOperationUpdate
+	default:
+		op = csvOperationInsert
+	}
+
+	record := make([]string, 0, len(columns)+4)
+	record = append(record, op, e.Table.Schema, e.Table.Table, strconv.FormatUint(e.CommitTs, 10))
+	for _, col := range columns {
+		if col == nil {
+			continue
+		}
+		record = append(record, csvColumnValue(col))
+	}
+
+	buf := &bytes.Buffer{}
+	w := csv.NewWriter(buf)
+	if err := w.Write(record); err != nil {
+		return EncoderNoOperation, cerror.WrapError(cerror.ErrCSVEncodeFailed, err)
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return EncoderNoOperation, cerror.WrapError(cerror.ErrCSVEncodeFailed, err)
+	}
+	// the record separator is added by the users of the encoder.
+	value := bytes.TrimSuffix(buf.Bytes(), []byte("\n"))
+
+	m := NewMQMessage(config.ProtocolCSV, nil, value, e.CommitTs, model.MqMessageTypeRow, &e.Table.Schema, &e.Table.Table)
+	m.IncRowsCount()
+	c.unresolvedBuf = append(c.unresolvedBuf, m)
+	return EncoderNoOperation, nil
+}
+
+func csvColumnValue(col *model.Column) string {
+	switch v := col.Value.(type) {
+	case nil:
+		return csvNullValue
+	case []byte:
+		if col.Flag.IsBinary() {
+			return base64.StdEncoding.EncodeToString(v)
+		}
+		return string(v)
+	default:
+		return model.ColumnValueString(v)
+	}
+}
+
+// AppendResolvedEvent implements the EventBatchEncoder interface
+func (c *CSVEventBatchEncoder) AppendResolvedEvent(ts uint64) (EncoderResult, error) {
+	nextIdx := 0
+	for _, msg := range c.unresolvedBuf {
+		if msg.Ts > ts {
+			break
+		}
+		c.resolvedBuf = append(c.resolvedBuf, msg)
+		nextIdx++
+	}
+	c.unresolvedBuf = c.unresolvedBuf[nextIdx:]
+	if len(c.resolvedBuf) > 0 {
+		return EncoderNeedAsyncWrite, nil
+	}
+	return EncoderNoOperation, nil
+}
+
+// EncodeDDLEvent implements the EventBatchEncoder interface
+func (c *CSVEventBatchEncoder) EncodeDDLEvent(e *model.DDLEvent) (*MQMessage, error) {
+	return nil, nil
+}
+
+// Build implements the EventBatchEncoder interface
+func (c *CSVEventBatchEncoder) Build() []*MQMessage {
+	if len(c.resolvedBuf) == 0 {
+		return nil
+	}
+	ret := c.resolvedBuf
+	c.resolvedBuf = nil
+	return ret
+}
+
+// MixedBuild is not used here
+func (c *CSVEventBatchEncoder) MixedBuild(_ bool) []byte {
+	panic("MixedBuild not supported by CSVEventBatchEncoder")
+}
+
+// Size implements the EventBatchEncoder interface
+func (c *CSVEventBatchEncoder) Size() int {
+	return -1
+}
+
+// Reset implements the EventBatchEncoder interface
+func (c *CSVEventBatchEncoder) Reset() {
+	panic("Reset not supported by CSVEventBatchEncoder")
+}
+
+// SetParams implements the EventBatchEncoder interface
+func (c *CSVEventBatchEncoder) SetParams(params map[string]string) error {
+	return nil
+}
@@ -0,0 +1,78 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package codec
+
+import (
+	"github.com/pingcap/check"
+	"github.com/pingcap/tidb/parser/mysql"
+	"github.com/pingcap/tiflow/cdc/model"
+	"github.com/pingcap/tiflow/pkg/util/testleak"
+)
+
+type csvBatchSuite struct{}
+
+var _ = check.Suite(&csvBatchSuite{})
+
+func (s *csvBatchSuite) TestCSVEventBatchEncoder(c *check.C) {
+	defer testleak.AfterTest(c)()
+	encoder := NewCSVEventBatchEncoder()
+	table := &model.TableName{Schema: "test", Table: "t1"}
+
+	rows := []*model.RowChangedEvent{
+		{
+			CommitTs: 1,
+			Table:    table,
+			Columns: []*model.Column{
+				{Name: "id", Type: mysql.TypeLong, Value: 1},
+				{Name: "name", Type: mysql.TypeVarchar, Value: []byte("a,\"b\"")},
+				{Name: "data", Type: mysql.TypeBlob, Value: []byte{0x1, 0x2}, Flag: model.BinaryFlag},
+				{Name: "note", Type: mysql.TypeVarchar, Value: nil},
+			},
+		},
+		{
+			CommitTs:   2,
+			Table:      table,
+			PreColumns: []*model.Column{{Name: "id", Type: mysql.TypeLong, Value: 1}},
+			Columns:    []*model.Column{{Name: "id", Type: mysql.TypeLong, Value: 2}},
+		},
+		{
+			CommitTs:   3,
+			Table:      table,
+			PreColumns: []*model.Column{{Name: "id", Type: mysql.TypeLong, Value: 2}},
+		},
+	}
+	for _, row := range rows {
+		_, err := encoder.AppendRowChangedEvent(row)
+		c.Assert(err, check.IsNil)
+	}
+
+	result, err := encoder.AppendResolvedEvent(2)
+	c.Assert(err, check.IsNil)
+	c.Assert(result, check.Equals, EncoderNeedAsyncWrite)
+	msgs := encoder.Build()
+	c.Assert(msgs, check.HasLen, 2)
+	c.Assert(string(msgs[0].Value), check.Equals, `I,test,t1,1,1,"a,""b""",AQI=,\N`)
+	c.Assert(string(msgs[1].Value), check.Equals, "U,test,t1,2,2")
+	c.Assert(msgs[1].GetRowsCount(), check.Equals, 1)
+
+	_, err = encoder.AppendResolvedEvent(3)
+	c.Assert(err, check.IsNil)
+	msgs = encoder.Build()
+	c.Assert(msgs, check.HasLen, 1)
+	c.Assert(string(msgs[0].Value), check.Equals, "D,test,t1,3,2")
+
+	msg, err := encoder.EncodeDDLEvent(&model.DDLEvent{CommitTs: 4})
+	c.Assert(err, check.IsNil)
+	c.Assert(msg, check.IsNil)
+}
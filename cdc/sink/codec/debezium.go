@@ -0,0 +1,962 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package codec
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pingcap/errors"
+	timodel "github.com/pingcap/tidb/parser/model"
+	"github.com/pingcap/tidb/parser/mysql"
+	"github.com/pingcap/tidb/parser/types"
+	"github.com/pingcap/tiflow/cdc/model"
+	"github.com/pingcap/tiflow/pkg/config"
+	cerror "github.com/pingcap/tiflow/pkg/errors"
+	"github.com/pingcap/tiflow/pkg/util"
+	"github.com/pingcap/tiflow/pkg/version"
+	"github.com/tikv/client-go/v2/oracle"
+)
+
+// The Debezium protocol follows the message format of the Debezium MySQL
+// connector with the default JSON converter, so that each message is a
+// `{"schema": ..., "payload": ...}` envelope. The connector is configured as
+//   - decimal.handling.mode=string
+//   - time.precision.mode=adaptive_time_microseconds
+//   - bigint.unsigned.handling.mode=long
+//   - column.propagate.source.type=.*
+//   - tombstones.on.delete=true
+//
+// Enum and set columns are sent as the index and the bitmask, since the
+// elements of them are not carried by the row changed events.
+const (
+	debeziumConnector = "TiCDC"
+
+	debeziumOpCreate = "c"
+	debeziumOpUpdate = "u"
+	debeziumOpDelete = "d"
+
+	debeziumSourceColumnType = "__debezium.source.column.type"
+
+	debeziumBits           = "io.debezium.data.Bits"
+	debeziumJSON           = "io.debezium.data.Json"
+	debeziumDate           = "io.debezium.time.Date"
+	debeziumMicroTime      = "io.debezium.time.MicroTime"
+	debeziumMicroTimestamp = "io.debezium.time.MicroTimestamp"
+	debeziumZonedTimestamp = "io.debezium.time.ZonedTimestamp"
+	debeziumYear           = "io.debezium.time.Year"
+
+	debeziumZonedTimestampFormat = "2006-01-02T15:04:05.999999Z"
+	debeziumTimeFormat           = "2006-01-02 15:04:05.999999"
+	debeziumDateFormat           = "2006-01-02"
+)
+
+// debeziumSchema is the schema of the Kafka Connect JSON converter.
+type debeziumSchema struct {
+	Type       string            `json:"type"`
+	Optional   bool              `json:"optional"`
+	Name       string            `json:"name,omitempty"`
+	Version    int               `json:"version,omitempty"`
+	Parameters map[string]string `json:"parameters,omitempty"`
+	Field      string            `json:"field,omitempty"`
+	Fields     []*debeziumSchema `json:"fields,omitempty"`
+	Items      *debeziumSchema   `json:"items,omitempty"`
+}
+
+type debeziumMessage struct {
+	Schema  *debeziumSchema `json:"schema"`
+	Payload interface{}     `json:"payload"`
+}
+
+type debeziumSource struct {
+	Version   string `json:"version"`
+	Connector string `json:"connector"`
+	Name      string `json:"name"`
+	TsMs      int64  `json:"ts_ms"`
+	Snapshot  string `json:"snapshot"`
+	DB        string `json:"db"`
+	Table     string `json:"table,omitempty"`
+	// CommitTs is the TiDB commit ts of the change.
+	CommitTs uint64 `json:"commit_ts"`
+}
+
+type debeziumRowPayload struct {
+	Before      map[string]interface{} `json:"before"`
+	After       map[string]interface{} `json:"after"`
+	Source      *debeziumSource        `json:"source"`
+	Op          string                 `json:"op"`
+	TsMs        int64                  `json:"ts_ms"`
+	Transaction interface{}            `json:"transaction"`
+}
+
+type debeziumTableColumn struct {
+	Name     string `json:"name"`
+	TypeName string `json:"typeName"`
+	Position int    `json:"position"`
+}
+
+type debeziumTableChange struct {
+	Type  string `json:"type"`
+	ID    string `json:"id"`
+	Table struct {
+		Columns []debeziumTableColumn `json:"columns"`
+	} `json:"table"`
+}
+
+type debeziumSchemaChangePayload struct {
+	Source       *debeziumSource       `json:"source"`
+	TsMs         int64                 `json:"ts_ms"`
+	DatabaseName string                `json:"databaseName"`
+	DDL          string                `json:"ddl"`
+	TableChanges []debeziumTableChange `json:"tableChanges"`
+}
+
+// debeziumWatermarkPayload is a TiDB extension of the protocol, which is
+// only sent when `enable-tidb-extension` is true.
+type debeziumWatermarkPayload struct {
+	TsMs        int64  `json:"ts_ms"`
+	WatermarkTs uint64 `json:"watermark_ts"`
+}
+
+// DebeziumEventBatchEncoder encodes the events into the Debezium JSON format.
+type DebeziumEventBatchEncoder struct {
+	// name is the logical name of the source, it's the changefeed ID.
+	name          string
+	tz            *time.Location
+	unresolvedBuf []*MQMessage
+	resolvedBuf   []*MQMessage
+	// When it is true, the checkpoint events are sent as watermark messages.
+	enableTiDBExtension bool
+}
+
+// NewDebeziumEventBatchEncoder creates a new DebeziumEventBatchEncoder.
+func NewDebeziumEventBatchEncoder() EventBatchEncoder {
+	return &DebeziumEventBatchEncoder{tz: time.UTC}
+}
+
+type debeziumEventBatchEncoderBuilder struct {
+	opts map[string]string
+}
+
+// Build a `DebeziumEventBatchEncoder`
+func (b *debeziumEventBatchEncoderBuilder) Build(ctx context.Context) (EventBatchEncoder, error) {
+	encoder := &DebeziumEventBatchEncoder{
+		name: util.ChangefeedIDFromCtx(ctx),
+		tz:   util.TimezoneFromCtx(ctx),
+	}
+	if encoder.tz == nil {
+		encoder.tz = time.UTC
+	}
+	if err := encoder.SetParams(b.opts); err != nil {
+		return nil, cerror.WrapError(cerror.ErrKafkaInvalidConfig, err)
+	}
+	return encoder, nil
+}
+
+func newDebeziumEventBatchEncoderBuilder(opts map[string]string) EncoderBuilder {
+	return &debeziumEventBatchEncoderBuilder{opts: opts}
+}
+
+func (d *DebeziumEventBatchEncoder) newSource(schema, table string, commitTs uint64) *debeziumSource {
+	return &debeziumSource{
+		Version:   version.ReleaseVersion,
+		Connector: debeziumConnector,
+		Name:      d.name,
+		TsMs:      oracle.ExtractPhysical(commitTs),
+		Snapshot:  "false",
+		DB:        schema,
+		Table:     table,
+		CommitTs:  commitTs,
+	}
+}
+
+// EncodeCheckpointEvent implements the EventBatchEncoder interface
+func (d *DebeziumEventBatchEncoder) EncodeCheckpointEvent(ts uint64) (*MQMessage, error) {
+	if !d.enableTiDBExtension {
+		return nil, nil
+	}
+	value, err := json.Marshal(&debeziumMessage{
+		Schema: newDebeziumStructSchema("io.debezium.connector.common.Heartbeat", "",
+			&debeziumSchema{Type: "int64", Field: "ts_ms"},
+			&debeziumSchema{Type: "int64", Field: "watermark_ts"},
+		),
+		Payload: &debeziumWatermarkPayload{
+			TsMs:        oracle.ExtractPhysical(ts),
+			WatermarkTs: ts,
+		},
+	})
+	if err != nil {
+		return nil, cerror.WrapError(cerror.ErrDebeziumEncodeFailed, err)
+	}
+	return newResolvedMQMessage(config.ProtocolDebezium, nil, value, ts), nil
+}
+
+// AppendRowChangedEvent implements the EventBatchEncoder interface.
+// A delete event is followed by a tombstone message which has the same key
+// and a null value, so that the deleted row can be removed by log compaction.
+func (d *DebeziumEventBatchEncoder) AppendRowChangedEvent(e *model.RowChangedEvent) (EncoderResult, error) {
+	key, err := d.encodeKey(e)
+	if err != nil {
+		return EncoderNoOperation, errors.Trace(err)
+	}
+	value, err := d.encodeValue(e)
+	if err != nil {
+		return EncoderNoOperation, errors.Trace(err)
+	}
+
+	m := NewMQMessage(config.ProtocolDebezium, key, value, e.CommitTs, model.MqMessageTypeRow, &e.Table.Schema, &e.Table.Table)
+	m.IncRowsCount()
+	d.unresolvedBuf = append(d.unresolvedBuf, m)
+	if e.IsDelete() && key != nil {
+		tombstone := NewMQMessage(config.ProtocolDebezium, key, nil, e.CommitTs, model.MqMessageTypeRow, &e.Table.Schema, &e.Table.Table)
+		d.unresolvedBuf = append(d.unresolvedBuf, tombstone)
+	}
+	return EncoderNoOperation, nil
+}
+
+func (d *DebeziumEventBatchEncoder) recordName(table *model.TableName, suffix string) string {
+	return fmt.Sprintf("%s.%s.%s.%s", d.name, table.Schema, table.Table, suffix)
+}
+
+// encodeKey encodes the handle key columns of the row, the key is nil if the
+// table has no handle key.
+func (d *DebeziumEventBatchEncoder) encodeKey(e *model.RowChangedEvent) ([]byte, error) {
+	columns := e.Columns
+	if e.IsDelete() {
+		columns = e.PreColumns
+	}
+	schema := newDebeziumStructSchema(d.recordName(e.Table, "Key"), "")
+	payload := make(map[string]interface{})
+	for _, col := range columns {
+		if col == nil || !col.Flag.IsHandleKey() {
+			continue
+		}
+		value, err := d.columnValue(col)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		schema.Fields = append(schema.Fields, newDebeziumColumnSchema(col))
+		payload[col.Name] = value
+	}
+	if len(schema.Fields) == 0 {
+		return nil, nil
+	}
+	key, err := json.Marshal(&debeziumMessage{Schema: schema, Payload: payload})
+	if err != nil {
+		return nil, cerror.WrapError(cerror.ErrDebeziumEncodeFailed, err)
+	}
+	return key, nil
+}
+
+func (d *DebeziumEventBatchEncoder) encodeValue(e *model.RowChangedEvent) ([]byte, error) {
+	payload := &debeziumRowPayload{
+		Source: d.newSource(e.Table.Schema, e.Table.Table, e.CommitTs),
+		TsMs:   time.Now().UnixNano() / int64(time.Millisecond),
+	}
+	columns := e.Columns
+	switch {
+	case e.IsDelete():
+		payload.Op = debeziumOpDelete
+		columns = e.PreColumns
+	case e.IsUpdate():
+		payload.Op = debeziumOpUpdate
+	default:
+		payload.Op = debeziumOpCreate
+	}
+
+	var err error
+	if payload.Before, err = d.columnValues(e.PreColumns); err != nil {
+		return nil, errors.Trace(err)
+	}
+	if payload.After, err = d.columnValues(e.Columns); err != nil {
+		return nil, errors.Trace(err)
+	}
+
+	rowSchema := newDebeziumStructSchema(d.recordName(e.Table, "Value"), "")
+	for _, col := range columns {
+		if col != nil {
+			rowSchema.Fields = append(rowSchema.Fields, newDebeziumColumnSchema(col))
+		}
+	}
+	before, after := *rowSchema, *rowSchema
+	before.Optional, before.Field = true, "before"
+	after.Optional, after.Field = true, "after"
+	schema := newDebeziumStructSchema(d.recordName(e.Table, "Envelope"), "",
+		&before,
+		&after,
+		newDebeziumSourceSchema(),
+		&debeziumSchema{Type: "string", Field: "op"},
+		&debeziumSchema{Type: "int64", Optional: true, Field: "ts_ms"},
+		&debeziumSchema{Type: "struct", Optional: true, Name: "event.block", Version: 1, Field: "transaction",
+			Fields: []*debeziumSchema{
+				{Type: "string", Field: "id"},
+				{Type: "int64", Field: "total_order"},
+				{Type: "int64", Field: "data_collection_order"},
+			}},
+	)
+
+	value, err := json.Marshal(&debeziumMessage{Schema: schema, Payload: payload})
+	if err != nil {
+		return nil, cerror.WrapError(cerror.ErrDebeziumEncodeFailed, err)
+	}
+	return value, nil
+}
+
+func (d *DebeziumEventBatchEncoder) columnValues(columns []*model.Column) (map[string]interface{}, error) {
+	if len(columns) == 0 {
+		return nil, nil
+	}
+	values := make(map[string]interface{}, len(columns))
+	for _, col := range columns {
+		if col == nil {
+			continue
+		}
+		value, err := d.columnValue(col)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		values[col.Name] = value
+	}
+	return values, nil
+}
+
+func newDebeziumStructSchema(name, field string, fields ...*debeziumSchema) *debeziumSchema {
+	return &debeziumSchema{Type: "struct", Name: name, Field: field, Fields: fields}
+}
+
+func newDebeziumSourceSchema() *debeziumSchema {
+	return newDebeziumStructSchema("io.debezium.connector.tidb.Source", "source",
+		&debeziumSchema{Type: "string", Field: "version"},
+		&debeziumSchema{Type: "string", Field: "connector"},
+		&debeziumSchema{Type: "string", Field: "name"},
+		&debeziumSchema{Type: "int64", Field: "ts_ms"},
+		&debeziumSchema{Type: "string", Optional: true, Field: "snapshot"},
+		&debeziumSchema{Type: "string", Field: "db"},
+		&debeziumSchema{Type: "string", Optional: true, Field: "table"},
+		&debeziumSchema{Type: "int64", Field: "commit_ts"},
+	)
+}
+
+// newDebeziumColumnSchema returns the schema of the column, the MySQL type of
+// the column is kept in the parameters.
+func newDebeziumColumnSchema(col *model.Column) *debeziumSchema {
+	tp, name := debeziumColumnType(col)
+	sourceType := strings.ToUpper(types.TypeStr(col.Type))
+	if col.Flag.IsUnsigned() {
+		sourceType += " UNSIGNED"
+	}
+	return &debeziumSchema{
+		Type:       tp,
+		Optional:   col.Flag.IsNullable(),
+		Name:       name,
+		Parameters: map[string]string{debeziumSourceColumnType: sourceType},
+		Field:      col.Name,
+	}
+}
+
+// debeziumColumnType returns the schema type and the logical name of the column.
+func debeziumColumnType(col *model.Column) (string, string) {
+	switch col.Type {
+	case mysql.TypeBit:
+		return "bytes", debeziumBits
+	case mysql.TypeTiny:
+		return "int16", ""
+	case mysql.TypeShort:
+		if col.Flag.IsUnsigned() {
+			return "int32", ""
+		}
+		return "int16", ""
+	case mysql.TypeInt24:
+		return "int32", ""
+	case mysql.TypeLong:
+		if col.Flag.IsUnsigned() {
+			return "int64", ""
+		}
+		return "int32", ""
+	case mysql.TypeLonglong, mysql.TypeEnum, mysql.TypeSet:
+		return "int64", ""
+	case mysql.TypeYear:
+		return "int32", debeziumYear
+	case mysql.TypeFloat:
+		return "float", ""
+	case mysql.TypeDouble:
+		return "double", ""
+	case mysql.TypeNewDecimal:
+		return "string", ""
+	case mysql.TypeDate, mysql.TypeNewDate:
+		return "int32", debeziumDate
+	case mysql.TypeDatetime:
+		return "int64", debeziumMicroTimestamp
+	case mysql.TypeTimestamp:
+		return "string", debeziumZonedTimestamp
+	case mysql.TypeDuration:
+		return "int64", debeziumMicroTime
+	case mysql.TypeJSON:
+		return "string", debeziumJSON
+	default:
+		if col.Flag.IsBinary() {
+			return "bytes", ""
+		}
+		return "string", ""
+	}
+}
+
+func debeziumStringValue(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case []byte:
+		return string(v)
+	default:
+		return model.ColumnValueString(v)
+	}
+}
+
+// columnValue converts the value of the column into the Debezium representation.
+func (d *DebeziumEventBatchEncoder) columnValue(col *model.Column) (interface{}, error) {
+	if col.Value == nil {
+		return nil, nil
+	}
+	// zero dates can't be represented, they are sent as null if the column
+	// is nullable, or as the epoch otherwise.
+	invalidTime := func() interface{} {
+		if col.Flag.IsNullable() {
+			return nil
+		}
+		return 0
+	}
+
+	switch col.Type {
+	case mysql.TypeBit:
+		v, ok := col.Value.(uint64)
+		if !ok {
+			return nil, cerror.ErrDebeziumEncodeFailed.GenWithStack("unexpected bit value %v", col.Value)
+		}
+		buf := make([]byte, 8)
+		binary.LittleEndian.PutUint64(buf, v)
+		for len(buf) > 1 && buf[len(buf)-1] == 0 {
+			buf = buf[:len(buf)-1]
+		}
+		return buf, nil
+	case mysql.TypeDate, mysql.TypeNewDate:
+		t, err := time.ParseInLocation(debeziumDateFormat, debeziumStringValue(col.Value), time.UTC)
+		if err != nil {
+			return invalidTime(), nil
+		}
+		return t.Unix() / int64(24*time.Hour/time.Second), nil
+	case mysql.TypeDatetime:
+		t, err := time.ParseInLocation(debeziumTimeFormat, debeziumStringValue(col.Value), time.UTC)
+		if err != nil {
+			return invalidTime(), nil
+		}
+		return t.UnixNano() / int64(time.Microsecond), nil
+	case mysql.TypeTimestamp:
+		t, err := time.ParseInLocation(debeziumTimeFormat, debeziumStringValue(col.Value), d.tz)
+		if err != nil {
+			return invalidTime(), nil
+		}
+		return t.UTC().Format(debeziumZonedTimestampFormat), nil
+	case mysql.TypeDuration:
+		micros, err := parseDebeziumMicroTime(debeziumStringValue(col.Value))
+		if err != nil {
+			return nil, cerror.WrapError(cerror.ErrDebeziumEncodeFailed, err)
+		}
+		return micros, nil
+	case mysql.TypeNewDecimal, mysql.TypeJSON:
+		return debeziumStringValue(col.Value), nil
+	}
+
+	switch v := col.Value.(type) {
+	case []byte:
+		if col.Flag.IsBinary() {
+			return v, nil
+		}
+		return string(v), nil
+	case string:
+		if col.Flag.IsBinary() {
+			return []byte(v), nil
+		}
+		return v, nil
+	}
+	return col.Value, nil
+}
+
+// parseDebeziumMicroTime parses a MySQL time like `-838:59:59.000000` into microseconds.
+func parseDebeziumMicroTime(s string) (int64, error) {
+	negative := strings.HasPrefix(s, "-")
+	s = strings.TrimPrefix(s, "-")
+	var frac string
+	if i := strings.IndexByte(s, '.'); i >= 0 {
+		s, frac = s[:i], s[i+1:]
+	}
+	parts := strings.Split(s, ":")
+	if len(parts) != 3 {
+		return 0, errors.Errorf("invalid time %s", s)
+	}
+	var micros int64
+	for _, part := range parts {
+		v, err := strconv.ParseInt(part, 10, 64)
+		if err != nil {
+			return 0, errors.Trace(err)
+		}
+		micros = micros*60 + v
+	}
+	micros *= int64(time.Second / time.Microsecond)
+	if frac != "" {
+		frac = (frac + "000000")[:6]
+		v, err := strconv.ParseInt(frac, 10, 64)
+		if err != nil {
+			return 0, errors.Trace(err)
+		}
+		micros += v
+	}
+	if negative {
+		micros = -micros
+	}
+	return micros, nil
+}
+
+func formatDebeziumMicroTime(micros int64) string {
+	sign := ""
+	if micros < 0 {
+		sign, micros = "-", -micros
+	}
+	seconds := micros / int64(time.Second/time.Microsecond)
+	s := fmt.Sprintf("%s%02d:%02d:%02d", sign, seconds/3600, seconds/60%60, seconds%60)
+	if frac := micros % int64(time.Second/time.Microsecond); frac != 0 {
+		s += strings.TrimRight(fmt.Sprintf(".%06d", frac), "0")
+	}
+	return s
+}
+
+// AppendResolvedEvent implements the EventBatchEncoder interface
+func (d *DebeziumEventBatchEncoder) AppendResolvedEvent(ts uint64) (EncoderResult, error) {
+	nextIdx := 0
+	for _, msg := range d.unresolvedBuf {
+		if msg.Ts > ts {
+			break
+		}
+		d.resolvedBuf = append(d.resolvedBuf, msg)
+		nextIdx++
+	}
+	d.unresolvedBuf = d.unresolvedBuf[nextIdx:]
+	if len(d.resolvedBuf) > 0 {
+		return EncoderNeedAsyncWrite, nil
+	}
+	return EncoderNoOperation, nil
+}
+
+// EncodeDDLEvent encodes the DDL into a schema change message.
+func (d *DebeziumEventBatchEncoder) EncodeDDLEvent(e *model.DDLEvent) (*MQMessage, error) {
+	key, err := json.Marshal(&debeziumMessage{
+		Schema: newDebeziumStructSchema("io.debezium.connector.tidb.SchemaChangeKey", "",
+			&debeziumSchema{Type: "string", Field: "databaseName"},
+		),
+		Payload: map[string]string{"databaseName": e.TableInfo.Schema},
+	})
+	if err != nil {
+		return nil, cerror.WrapError(cerror.ErrDebeziumEncodeFailed, err)
+	}
+
+	payload := &debeziumSchemaChangePayload{
+		Source:       d.newSource(e.TableInfo.Schema, e.TableInfo.Table, e.CommitTs),
+		TsMs:         time.Now().UnixNano() / int64(time.Millisecond),
+		DatabaseName: e.TableInfo.Schema,
+		DDL:          e.Query,
+		TableChanges: []debeziumTableChange{},
+	}
+	if e.TableInfo.Table != "" {
+		change := debeziumTableChange{
+			Type: "ALTER",
+			ID:   fmt.Sprintf("%q.%q", e.TableInfo.Schema, e.TableInfo.Table),
+		}
+		switch e.Type {
+		case timodel.ActionCreateTable:
+			change.Type = "CREATE"
+		case timodel.ActionDropTable:
+			change.Type = "DROP"
+		}
+		for i, col := range e.TableInfo.ColumnInfo {
+			change.Table.Columns = append(change.Table.Columns, debeziumTableColumn{
+				Name:     col.Name,
+				TypeName: strings.ToUpper(types.TypeStr(col.Type)),
+				Position: i + 1,
+			})
+		}
+		payload.TableChanges = append(payload.TableChanges, change)
+	}
+
+	column := &debeziumSchema{Type: "struct", Name: "io.debezium.connector.schema.Column",
+		Fields: []*debeziumSchema{
+			{Type: "string", Field: "name"},
+			{Type: "string", Field: "typeName"},
+			{Type: "int32", Field: "position"},
+		}}
+	change := &debeziumSchema{Type: "struct", Name: "io.debezium.connector.schema.Change",
+		Fields: []*debeziumSchema{
+			{Type: "string", Field: "type"},
+			{Type: "string", Field: "id"},
+			newDebeziumStructSchema("io.debezium.connector.schema.Table", "table",
+				&debeziumSchema{Type: "array", Field: "columns", Items: column},
+			),
+		}}
+	schema := newDebeziumStructSchema("io.debezium.connector.tidb.SchemaChangeValue", "",
+		newDebeziumSourceSchema(),
+		&debeziumSchema{Type: "int64", Field: "ts_ms"},
+		&debeziumSchema{Type: "string", Field: "databaseName"},
+		&debeziumSchema{Type: "string", Field: "ddl"},
+		&debeziumSchema{Type: "array", Field: "tableChanges", Items: change},
+	)
+	value, err := json.Marshal(&debeziumMessage{Schema: schema, Payload: payload})
+	if err != nil {
+		return nil, cerror.WrapError(cerror.ErrDebeziumEncodeFailed, err)
+	}
+	return newDDLMQMessage(config.ProtocolDebezium, key, value, e), nil
+}
+
+// Build implements the EventBatchEncoder interface
+func (d *DebeziumEventBatchEncoder) Build() []*MQMessage {
+	if len(d.resolvedBuf) == 0 {
+		return nil
+	}
+	ret := d.resolvedBuf
+	d.resolvedBuf = nil
+	return ret
+}
+
+// MixedBuild is not used here
+func (d *DebeziumEventBatchEncoder) MixedBuild(_ bool) []byte {
+	panic("MixedBuild not supported by DebeziumEventBatchEncoder")
+}
+
+// Size implements the EventBatchEncoder interface
+func (d *DebeziumEventBatchEncoder) Size() int {
+	return -1
+}
+
+// Reset implements the EventBatchEncoder interface
+func (d *DebeziumEventBatchEncoder) Reset() {
+	panic("Reset not supported by DebeziumEventBatchEncoder")
+}
+
+// SetParams implements the EventBatchEncoder interface
+func (d *DebeziumEventBatchEncoder) SetParams(params map[string]string) error {
+	if s, ok := params["enable-tidb-extension"]; ok {
+		a, err := strconv.ParseBool(s)
+		if err != nil {
+			return cerror.WrapError(cerror.ErrSinkInvalidConfig, err)
+		}
+		d.enableTiDBExtension = a
+	}
+	return nil
+}
+
+type debeziumRawMessage struct {
+	Schema  *debeziumSchema `json:"schema"`
+	Payload json.RawMessage `json:"payload"`
+}
+
+// DebeziumEventBatchDecoder decodes a Debezium message into the original event.
+type DebeziumEventBatchDecoder struct {
+	msgType model.MqMessageType
+	key     *debeziumRawMessage
+	value   *debeziumRawMessage
+	payload map[string]json.RawMessage
+}
+
+// NewDebeziumEventBatchDecoder creates a new DebeziumEventBatchDecoder.
+// A tombstone message, whose value is null, has no event to decode.
+func NewDebeziumEventBatchDecoder(key []byte, value []byte) (EventBatchDecoder, error) {
+	if err := checkClaimCheckResolved(value); err != nil {
+		return nil, errors.Trace(err)
+	}
+	decoder := &DebeziumEventBatchDecoder{msgType: model.MqMessageTypeUnknown}
+	if len(value) == 0 {
+		return decoder, nil
+	}
+	decoder.value = &debeziumRawMessage{}
+	if err := unmarshalDebezium(value, decoder.value); err != nil {
+		return nil, errors.Trace(err)
+	}
+	if err := unmarshalDebezium(decoder.value.Payload, &decoder.payload); err != nil {
+		return nil, errors.Trace(err)
+	}
+	if len(key) != 0 {
+		decoder.key = &debeziumRawMessage{}
+		if err := unmarshalDebezium(key, decoder.key); err != nil {
+			return nil, errors.Trace(err)
+		}
+	}
+
+	switch {
+	case decoder.payload["op"] != nil:
+		decoder.msgType = model.MqMessageTypeRow
+	case decoder.payload["ddl"] != nil:
+		decoder.msgType = model.MqMessageTypeDDL
+	case decoder.payload["watermark_ts"] != nil:
+		decoder.msgType = model.MqMessageTypeResolved
+	default:
+		return nil, cerror.ErrDebeziumDecodeFailed.GenWithStack("unknown message %s", value)
+	}
+	return decoder, nil
+}
+
+func unmarshalDebezium(data []byte, v interface{}) error {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if err := decoder.Decode(v); err != nil {
+		return cerror.WrapError(cerror.ErrDebeziumDecodeFailed, err)
+	}
+	return nil
+}
+
+// HasNext implements the EventBatchDecoder interface
+func (b *DebeziumEventBatchDecoder) HasNext() (model.MqMessageType, bool, error) {
+	if b.msgType == model.MqMessageTypeUnknown {
+		return model.MqMessageTypeUnknown, false, nil
+	}
+	return b.msgType, true, nil
+}
+
+// NextResolvedEvent implements the EventBatchDecoder interface
+func (b *DebeziumEventBatchDecoder) NextResolvedEvent() (uint64, error) {
+	if b.msgType != model.MqMessageTypeResolved {
+		return 0, cerror.ErrDebeziumDecodeFailed.GenWithStack("not found resolved event message")
+	}
+	payload := &debeziumWatermarkPayload{}
+	if err := unmarshalDebezium(b.value.Payload, payload); err != nil {
+		return 0, errors.Trace(err)
+	}
+	b.msgType = model.MqMessageTypeUnknown
+	return payload.WatermarkTs, nil
+}
+
+// NextRowChangedEvent implements the EventBatchDecoder interface
+func (b *DebeziumEventBatchDecoder) NextRowChangedEvent() (*model.RowChangedEvent, error) {
+	if b.msgType != model.MqMessageTypeRow {
+		return nil, cerror.ErrDebeziumDecodeFailed.GenWithStack("not found row changed event message")
+	}
+	payload := &debeziumRowPayload{}
+	if err := unmarshalDebezium(b.value.Payload, payload); err != nil {
+		return nil, errors.Trace(err)
+	}
+	if payload.Source == nil {
+		return nil, cerror.ErrDebeziumDecodeFailed.GenWithStack("source not found")
+	}
+
+	var rowSchema *debeziumSchema
+	if b.value.Schema != nil {
+		for _, field := range b.value.Schema.Fields {
+			if field.Field == "before" || field.Field == "after" {
+				rowSchema = field
+				break
+			}
+		}
+	}
+	if rowSchema == nil {
+		return nil, cerror.ErrDebeziumDecodeFailed.GenWithStack("row schema not found")
+	}
+	keys := make(map[string]struct{})
+	if b.key != nil && b.key.Schema != nil {
+		for _, field := range b.key.Schema.Fields {
+			keys[field.Field] = struct{}{}
+		}
+	}
+
+	row := &model.RowChangedEvent{
+		CommitTs: payload.Source.CommitTs,
+		Table:    &model.TableName{Schema: payload.Source.DB, Table: payload.Source.Table},
+	}
+	var err error
+	if row.PreColumns, err = decodeDebeziumColumns(rowSchema, payload.Before, keys); err != nil {
+		return nil, errors.Trace(err)
+	}
+	if row.Columns, err = decodeDebeziumColumns(rowSchema, payload.After, keys); err != nil {
+		return nil, errors.Trace(err)
+	}
+	b.msgType = model.MqMessageTypeUnknown
+	return row, nil
+}
+
+func decodeDebeziumColumns(
+	schema *debeziumSchema, values map[string]interface{}, keys map[string]struct{},
+) ([]*model.Column, error) {
+	if values == nil {
+		return nil, nil
+	}
+	columns := make([]*model.Column, 0, len(schema.Fields))
+	for _, field := range schema.Fields {
+		value, ok := values[field.Field]
+		if !ok {
+			continue
+		}
+		col, err := decodeDebeziumColumn(field, value)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		if _, ok := keys[field.Field]; ok {
+			col.Flag.SetIsHandleKey()
+			col.Flag.SetIsPrimaryKey()
+		}
+		columns = append(columns, col)
+	}
+	return columns, nil
+}
+
+func decodeDebeziumColumn(field *debeziumSchema, value interface{}) (*model.Column, error) {
+	sourceType := strings.ToLower(field.Parameters[debeziumSourceColumnType])
+	col := &model.Column{Name: field.Field}
+	if strings.HasSuffix(sourceType, " unsigned") {
+		sourceType = strings.TrimSuffix(sourceType, " unsigned")
+		col.Flag.SetIsUnsigned()
+	}
+	col.Type = types.StrToType(sourceType)
+	if field.Optional {
+		col.Flag.SetIsNullable()
+	}
+	if field.Type == "bytes" && field.Name != debeziumBits {
+		col.Flag.SetIsBinary()
+	}
+	if value == nil {
+		return col, nil
+	}
+
+	var err error
+	switch field.Type {
+	case "int16", "int32", "int64":
+		number, ok := value.(json.Number)
+		if !ok {
+			return nil, cerror.ErrDebeziumDecodeFailed.GenWithStack("invalid number %v of %s", value, field.Field)
+		}
+		col.Value, err = decodeDebeziumInteger(field, col, number)
+	case "float", "double":
+		number, ok := value.(json.Number)
+		if !ok {
+			return nil, cerror.ErrDebeziumDecodeFailed.GenWithStack("invalid number %v of %s", value, field.Field)
+		}
+		col.Value, err = number.Float64()
+	case "bytes", "string":
+		s, ok := value.(string)
+		if !ok {
+			return nil, cerror.ErrDebeziumDecodeFailed.GenWithStack("invalid string %v of %s", value, field.Field)
+		}
+		col.Value, err = decodeDebeziumString(field, col, s)
+	default:
+		col.Value = value
+	}
+	if err != nil {
+		return nil, cerror.WrapError(cerror.ErrDebeziumDecodeFailed, err)
+	}
+	return col, nil
+}
+
+func decodeDebeziumInteger(field *debeziumSchema, col *model.Column, number json.Number) (interface{}, error) {
+	switch field.Name {
+	case debeziumDate:
+		days, err := number.Int64()
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		return time.Unix(days*int64(24*time.Hour/time.Second), 0).UTC().Format(debeziumDateFormat), nil
+	case debeziumMicroTimestamp:
+		micros, err := number.Int64()
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		return time.Unix(0, micros*int64(time.Microsecond)).UTC().Format(debeziumTimeFormat), nil
+	case debeziumMicroTime:
+		micros, err := number.Int64()
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		return formatDebeziumMicroTime(micros), nil
+	}
+	if col.Flag.IsUnsigned() || col.Type == mysql.TypeEnum || col.Type == mysql.TypeSet {
+		return strconv.ParseUint(number.String(), 10, 64)
+	}
+	return number.Int64()
+}
+
+func decodeDebeziumString(field *debeziumSchema, col *model.Column, s string) (interface{}, error) {
+	switch {
+	case field.Name == debeziumBits:
+		buf, err := base64.StdEncoding.DecodeString(s)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		var v uint64
+		for i := len(buf) - 1; i >= 0; i-- {
+			v = v<<8 | uint64(buf[i])
+		}
+		return v, nil
+	case field.Name == debeziumZonedTimestamp:
+		t, err := time.Parse(time.RFC3339Nano, s)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		return t.UTC().Format(debeziumTimeFormat), nil
+	case field.Type == "bytes":
+		return base64.StdEncoding.DecodeString(s)
+	case col.Type == mysql.TypeNewDecimal || field.Name == debeziumJSON:
+		return s, nil
+	default:
+		return []byte(s), nil
+	}
+}
+
+// NextDDLEvent implements the EventBatchDecoder interface
+func (b *DebeziumEventBatchDecoder) NextDDLEvent() (*model.DDLEvent, error) {
+	if b.msgType != model.MqMessageTypeDDL {
+		return nil, cerror.ErrDebeziumDecodeFailed.GenWithStack("not found ddl event message")
+	}
+	payload := &debeziumSchemaChangePayload{}
+	if err := unmarshalDebezium(b.value.Payload, payload); err != nil {
+		return nil, errors.Trace(err)
+	}
+	if payload.Source == nil {
+		return nil, cerror.ErrDebeziumDecodeFailed.GenWithStack("source not found")
+	}
+
+	ddl := &model.DDLEvent{
+		CommitTs: payload.Source.CommitTs,
+		TableInfo: &model.SimpleTableInfo{
+			Schema: payload.DatabaseName,
+			Table:  payload.Source.Table,
+		},
+		Query: payload.DDL,
+	}
+	for _, change := range payload.TableChanges {
+		switch change.Type {
+		case "CREATE":
+			ddl.Type = timodel.ActionCreateTable
+		case "DROP":
+			ddl.Type = timodel.ActionDropTable
+		}
+		for _, col := range change.Table.Columns {
+			ddl.TableInfo.ColumnInfo = append(ddl.TableInfo.ColumnInfo, &model.ColumnInfo{
+				Name: col.Name,
+				Type: types.StrToType(strings.ToLower(col.TypeName)),
+			})
+		}
+	}
+	b.msgType = model.MqMessageTypeUnknown
+	return ddl, nil
+}
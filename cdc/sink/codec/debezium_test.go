@@ -0,0 +1,205 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package codec
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/pingcap/check"
+	timodel "github.com/pingcap/tidb/parser/model"
+	"github.com/pingcap/tidb/parser/mysql"
+	"github.com/pingcap/tiflow/cdc/model"
+	"github.com/pingcap/tiflow/pkg/util/testleak"
+)
+
+type debeziumSuite struct{}
+
+var _ = check.Suite(&debeziumSuite{})
+
+func newDebeziumTestColumns(id int64, name string) []*model.Column {
+	return []*model.Column{
+		{Name: "id", Type: mysql.TypeLonglong, Value: id, Flag: model.HandleKeyFlag | model.PrimaryKeyFlag},
+		{Name: "name", Type: mysql.TypeVarchar, Value: []byte(name), Flag: model.NullableFlag},
+		{Name: "tiny", Type: mysql.TypeTiny, Value: uint64(255), Flag: model.UnsignedFlag | model.NullableFlag},
+		{Name: "price", Type: mysql.TypeNewDecimal, Value: "12.34", Flag: model.NullableFlag},
+		{Name: "ratio", Type: mysql.TypeDouble, Value: 0.5, Flag: model.NullableFlag},
+		{Name: "data", Type: mysql.TypeBlob, Value: []byte{0, 1, 2}, Flag: model.BinaryFlag | model.NullableFlag},
+		{Name: "birthday", Type: mysql.TypeDate, Value: "1969-12-30", Flag: model.NullableFlag},
+		{Name: "created", Type: mysql.TypeDatetime, Value: "2022-01-02 03:04:05.123456", Flag: model.NullableFlag},
+		{Name: "updated", Type: mysql.TypeTimestamp, Value: "2022-01-02 03:04:05", Flag: model.NullableFlag},
+		{Name: "duration", Type: mysql.TypeDuration, Value: "-01:02:03.5", Flag: model.NullableFlag},
+		{Name: "bits", Type: mysql.TypeBit, Value: uint64(0x1ff), Flag: model.NullableFlag},
+		{Name: "doc", Type: mysql.TypeJSON, Value: `{"a": 1}`, Flag: model.NullableFlag},
+		{Name: "color", Type: mysql.TypeEnum, Value: uint64(2), Flag: model.NullableFlag},
+		{Name: "note", Type: mysql.TypeVarchar, Value: nil, Flag: model.NullableFlag},
+	}
+}
+
+func decodeDebeziumMessage(c *check.C, msg *MQMessage) EventBatchDecoder {
+	decoder, err := NewDebeziumEventBatchDecoder(msg.Key, msg.Value)
+	c.Assert(err, check.IsNil)
+	return decoder
+}
+
+func (s *debeziumSuite) TestRowChangedEvent(c *check.C) {
+	defer testleak.AfterTest(c)()
+	encoder := NewDebeziumEventBatchEncoder()
+	table := &model.TableName{Schema: "test", Table: "t1"}
+	events := []*model.RowChangedEvent{
+		{CommitTs: 1, Table: table, Columns: newDebeziumTestColumns(1, "a")},
+		{CommitTs: 2, Table: table, PreColumns: newDebeziumTestColumns(1, "a"), Columns: newDebeziumTestColumns(1, "b")},
+		{CommitTs: 3, Table: table, PreColumns: newDebeziumTestColumns(1, "b")},
+	}
+	for _, e := range events {
+		_, err := encoder.AppendRowChangedEvent(e)
+		c.Assert(err, check.IsNil)
+	}
+	result, err := encoder.AppendResolvedEvent(3)
+	c.Assert(err, check.IsNil)
+	c.Assert(result, check.Equals, EncoderNeedAsyncWrite)
+	msgs := encoder.Build()
+	// the delete event is followed by a tombstone.
+	c.Assert(msgs, check.HasLen, 4)
+	c.Assert(msgs[3].Value, check.IsNil)
+	c.Assert(msgs[3].Key, check.DeepEquals, msgs[2].Key)
+	c.Assert(msgs[3].GetRowsCount(), check.Equals, 0)
+	tp, hasNext, err := decodeDebeziumMessage(c, msgs[3]).HasNext()
+	c.Assert(err, check.IsNil)
+	c.Assert(hasNext, check.IsFalse)
+	c.Assert(tp, check.Equals, model.MqMessageTypeUnknown)
+
+	var key map[string]interface{}
+	c.Assert(json.Unmarshal(msgs[0].Key, &key), check.IsNil)
+	c.Assert(key["payload"], check.DeepEquals, map[string]interface{}{"id": float64(1)})
+
+	var value struct {
+		Payload struct {
+			Op     string                 `json:"op"`
+			Before map[string]interface{} `json:"before"`
+			After  map[string]interface{} `json:"after"`
+		} `json:"payload"`
+	}
+	c.Assert(json.Unmarshal(msgs[0].Value, &value), check.IsNil)
+	c.Assert(value.Payload.Op, check.Equals, "c")
+	c.Assert(value.Payload.Before, check.IsNil)
+	c.Assert(value.Payload.After["birthday"], check.Equals, float64(-2))
+	c.Assert(value.Payload.After["created"], check.Equals, float64(1641092645123456))
+	c.Assert(value.Payload.After["updated"], check.Equals, "2022-01-02T03:04:05Z")
+	c.Assert(value.Payload.After["duration"], check.Equals, float64(-3723500000))
+	c.Assert(value.Payload.After["data"], check.Equals, "AAEC")
+	c.Assert(value.Payload.After["bits"], check.Equals, "/wE=")
+
+	for i, e := range events {
+		decoder := decodeDebeziumMessage(c, msgs[i])
+		tp, hasNext, err := decoder.HasNext()
+		c.Assert(err, check.IsNil)
+		c.Assert(hasNext, check.IsTrue)
+		c.Assert(tp, check.Equals, model.MqMessageTypeRow)
+		row, err := decoder.NextRowChangedEvent()
+		c.Assert(err, check.IsNil)
+		c.Assert(row.CommitTs, check.Equals, e.CommitTs)
+		c.Assert(row.Table, check.DeepEquals, e.Table)
+		c.Assert(row.Columns, check.DeepEquals, e.Columns)
+		c.Assert(row.PreColumns, check.DeepEquals, e.PreColumns)
+		_, hasNext, err = decoder.HasNext()
+		c.Assert(err, check.IsNil)
+		c.Assert(hasNext, check.IsFalse)
+	}
+}
+
+func (s *debeziumSuite) TestTimestampTimeZone(c *check.C) {
+	defer testleak.AfterTest(c)()
+	encoder := &DebeziumEventBatchEncoder{tz: time.FixedZone("UTC+8", 8*60*60)}
+	value, err := encoder.columnValue(&model.Column{
+		Name: "updated", Type: mysql.TypeTimestamp, Value: "2022-01-02 08:04:05.5",
+	})
+	c.Assert(err, check.IsNil)
+	c.Assert(value, check.Equals, "2022-01-02T00:04:05.5Z")
+
+	// the zero time is sent as null if the column is nullable.
+	value, err = encoder.columnValue(&model.Column{
+		Name: "updated", Type: mysql.TypeTimestamp, Value: "0000-00-00 00:00:00", Flag: model.NullableFlag,
+	})
+	c.Assert(err, check.IsNil)
+	c.Assert(value, check.IsNil)
+}
+
+func (s *debeziumSuite) TestDDLEvent(c *check.C) {
+	defer testleak.AfterTest(c)()
+	encoder := NewDebeziumEventBatchEncoder()
+	ddl := &model.DDLEvent{
+		CommitTs: 417318403368288260,
+		TableInfo: &model.SimpleTableInfo{
+			Schema: "test",
+			Table:  "t1",
+			ColumnInfo: []*model.ColumnInfo{
+				{Name: "id", Type: mysql.TypeLonglong},
+				{Name: "name", Type: mysql.TypeVarchar},
+			},
+		},
+		Query: "create table t1(id bigint primary key, name varchar(32))",
+		Type:  timodel.ActionCreateTable,
+	}
+	msg, err := encoder.EncodeDDLEvent(ddl)
+	c.Assert(err, check.IsNil)
+	c.Assert(msg.Type, check.Equals, model.MqMessageTypeDDL)
+
+	decoder := decodeDebeziumMessage(c, msg)
+	tp, hasNext, err := decoder.HasNext()
+	c.Assert(err, check.IsNil)
+	c.Assert(hasNext, check.IsTrue)
+	c.Assert(tp, check.Equals, model.MqMessageTypeDDL)
+	decoded, err := decoder.NextDDLEvent()
+	c.Assert(err, check.IsNil)
+	c.Assert(decoded, check.DeepEquals, ddl)
+
+	// a schema level DDL has no table changes.
+	msg, err = encoder.EncodeDDLEvent(&model.DDLEvent{
+		CommitTs:  417318403368288261,
+		TableInfo: &model.SimpleTableInfo{Schema: "test"},
+		Query:     "create database test",
+		Type:      timodel.ActionCreateSchema,
+	})
+	c.Assert(err, check.IsNil)
+	decoded, err = decodeDebeziumMessage(c, msg).NextDDLEvent()
+	c.Assert(err, check.IsNil)
+	c.Assert(decoded.Query, check.Equals, "create database test")
+	c.Assert(decoded.TableInfo.Schema, check.Equals, "test")
+	c.Assert(decoded.TableInfo.ColumnInfo, check.HasLen, 0)
+}
+
+func (s *debeziumSuite) TestCheckpointEvent(c *check.C) {
+	defer testleak.AfterTest(c)()
+	encoder := NewDebeziumEventBatchEncoder()
+	msg, err := encoder.EncodeCheckpointEvent(417318403368288260)
+	c.Assert(err, check.IsNil)
+	c.Assert(msg, check.IsNil)
+
+	c.Assert(encoder.SetParams(map[string]string{"enable-tidb-extension": "true"}), check.IsNil)
+	msg, err = encoder.EncodeCheckpointEvent(417318403368288260)
+	c.Assert(err, check.IsNil)
+	c.Assert(msg.Type, check.Equals, model.MqMessageTypeResolved)
+
+	decoder := decodeDebeziumMessage(c, msg)
+	tp, hasNext, err := decoder.HasNext()
+	c.Assert(err, check.IsNil)
+	c.Assert(hasNext, check.IsTrue)
+	c.Assert(tp, check.Equals, model.MqMessageTypeResolved)
+	ts, err := decoder.NextResolvedEvent()
+	c.Assert(err, check.IsNil)
+	c.Assert(ts, check.Equals, uint64(417318403368288260))
+
+	c.Assert(encoder.SetParams(map[string]string{"enable-tidb-extension": "abc"}), check.NotNil)
+}
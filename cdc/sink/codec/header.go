@@ -0,0 +1,113 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package codec
+
+import (
+	"encoding/binary"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/pingcap/tiflow/cdc/model"
+)
+
+// The keys of the headers describing the events in a message, so that the
+// messages can be routed and filtered without decoding the payload.
+const (
+	HeaderCommitTs        = "ticdc-commit-ts"
+	HeaderSchema          = "ticdc-schema"
+	HeaderTable           = "ticdc-table"
+	HeaderType            = "ticdc-type"
+	HeaderChangefeedID    = "ticdc-changefeed-id"
+	HeaderProtocol        = "ticdc-protocol"
+	HeaderProtocolVersion = "ticdc-protocol-version"
+)
+
+// HeadersVersion is the version of the headers, it's sent in the header
+// HeaderProtocolVersion.
+const HeadersVersion = 1
+
+const (
+	// maximumHeaderOverhead is the overhead of a header in a record.
+	// reference: https://github.com/Shopify/sarama/blob/66521126c71c522c15a36663ae9cddc2b024c799/async_producer.go#L237
+	maximumHeaderOverhead = 2 * binary.MaxVarintLen32
+	// maxIdentifierBytes is the max bytes of a schema or a table name, which
+	// contains 64 characters at most.
+	maxIdentifierBytes = 64 * 4
+	// maxProtocolNameBytes is the room reserved for the protocol name.
+	maxProtocolNameBytes = 32
+)
+
+// MQMessageHeader is a header of an MQ message.
+type MQMessageHeader struct {
+	Key   []byte
+	Value []byte
+}
+
+func messageTypeName(tp model.MqMessageType) string {
+	switch tp {
+	case model.MqMessageTypeRow:
+		return "row"
+	case model.MqMessageTypeDDL:
+		return "ddl"
+	case model.MqMessageTypeResolved:
+		return "resolved"
+	default:
+		return "unknown"
+	}
+}
+
+// NewMQMessageHeaders creates the headers of the message, the schema and the
+// table are omitted if they're unknown.
+func NewMQMessageHeaders(m *MQMessage, changefeedID string) []MQMessageHeader {
+	headers := make([]MQMessageHeader, 0, 7)
+	appendHeader := func(key, value string) {
+		headers = append(headers, MQMessageHeader{Key: []byte(key), Value: []byte(value)})
+	}
+	appendHeader(HeaderCommitTs, strconv.FormatUint(m.Ts, 10))
+	if m.Schema != nil {
+		appendHeader(HeaderSchema, *m.Schema)
+	}
+	if m.Table != nil {
+		appendHeader(HeaderTable, *m.Table)
+	}
+	appendHeader(HeaderType, messageTypeName(m.Type))
+	appendHeader(HeaderChangefeedID, changefeedID)
+	appendHeader(HeaderProtocol, m.Protocol.String())
+	appendHeader(HeaderProtocolVersion, strconv.Itoa(HeadersVersion))
+	return headers
+}
+
+// MaxHeadersLength returns the max length of the headers created by
+// NewMQMessageHeaders, it should be reserved in max-message-bytes when the
+// headers are sent.
+func MaxHeadersLength(changefeedID string) int {
+	identifier := strings.Repeat("x", maxIdentifierBytes)
+	m := &MQMessage{
+		Ts:     math.MaxUint64,
+		Schema: &identifier,
+		Table:  &identifier,
+		// "resolved" is the longest type name.
+		Type: model.MqMessageTypeResolved,
+	}
+	length := 0
+	for _, header := range NewMQMessageHeaders(m, changefeedID) {
+		if string(header.Key) == HeaderProtocol {
+			length += len(header.Key) + maxProtocolNameBytes + maximumHeaderOverhead
+			continue
+		}
+		length += len(header.Key) + len(header.Value) + maximumHeaderOverhead
+	}
+	return length
+}
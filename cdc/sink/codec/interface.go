@@ -64,6 +64,7 @@ type MQMessage struct {
 	Table     *string             // table
 	Type      model.MqMessageType // type
 	Protocol  config.Protocol     // protocol
+	Headers   []MQMessageHeader   // headers, the producer may attach more
 	rowsCount int                 // rows in one MQ Message
 }
 
@@ -72,11 +73,13 @@ type MQMessage struct {
 // for TiCDC, minimum supported kafka version is `0.11.0.2`, which will be treated as `version = 2` by sarama producer.
 const maximumRecordOverhead = 5*binary.MaxVarintLen32 + binary.MaxVarintLen64 + 1
 
-// Length returns the expected size of the Kafka message
-// We didn't append any `Headers` when send the message, so ignore the calculations related to it.
-// If `ProducerMessage` Headers fields used, this method should also adjust.
+// Length returns the expected size of the Kafka message, including the headers.
 func (m *MQMessage) Length() int {
-	return len(m.Key) + len(m.Value) + maximumRecordOverhead
+	length := len(m.Key) + len(m.Value) + maximumRecordOverhead
+	for _, header := range m.Headers {
+		length += len(header.Key) + len(header.Value) + maximumHeaderOverhead
+	}
+	return length
 }
 
 // PhysicalTime returns physical time part of Ts in time.Time
@@ -179,6 +182,10 @@ func NewEventBatchEncoderBuilder(p config.Protocol, credential *security.Credent
 		return newCanalFlatEventBatchEncoderBuilder(opts), nil
 	case config.ProtocolCraft:
 		return newCraftEventBatchEncoderBuilder(opts), nil
+	case config.ProtocolCSV:
+		return newCSVEventBatchEncoderBuilder(opts), nil
+	case config.ProtocolDebezium:
+		return newDebeziumEventBatchEncoderBuilder(opts), nil
 	default:
 		log.Warn("unknown codec protocol value of EventBatchEncoder, use open-protocol as the default", zap.Int("protocolValue", int(p)))
 		return newJSONEventBatchEncoderBuilder(opts), nil
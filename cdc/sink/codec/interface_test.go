@@ -14,6 +14,9 @@
 package codec
 
 import (
+	"math"
+	"strings"
+
 	"github.com/pingcap/check"
 	timodel "github.com/pingcap/tidb/parser/model"
 	"github.com/pingcap/tidb/parser/mysql"
@@ -119,3 +122,41 @@ func (s *codecInterfaceSuite) TestCreate(c *check.C) {
 	c.Assert(msg.Table, check.IsNil)
 	c.Assert(msg.Protocol, check.Equals, config.ProtocolCanal)
 }
+
+func (s *codecInterfaceSuite) TestHeaders(c *check.C) {
+	defer testleak.AfterTest(c)()
+	schema, table := "test", "t1"
+	msg := NewMQMessage(config.ProtocolCanalJSON, []byte("key1"), []byte("value1"), 5678, model.MqMessageTypeRow, &schema, &table)
+	length := msg.Length()
+	c.Assert(length, check.Equals, len("key1")+len("value1")+maximumRecordOverhead)
+
+	msg.Headers = NewMQMessageHeaders(msg, "test-changefeed")
+	headers := make(map[string]string)
+	for _, header := range msg.Headers {
+		headers[string(header.Key)] = string(header.Value)
+		length += len(header.Key) + len(header.Value) + maximumHeaderOverhead
+	}
+	c.Assert(headers, check.DeepEquals, map[string]string{
+		HeaderCommitTs:        "5678",
+		HeaderSchema:          "test",
+		HeaderTable:           "t1",
+		HeaderType:            "row",
+		HeaderChangefeedID:    "test-changefeed",
+		HeaderProtocol:        "canal-json",
+		HeaderProtocolVersion: "1",
+	})
+	// the headers are counted in the length.
+	c.Assert(msg.Length(), check.Equals, length)
+
+	// the schema and the table of a resolved message are unknown.
+	msg = newResolvedMQMessage(config.ProtocolOpen, nil, []byte("value1"), 1234)
+	msg.Headers = NewMQMessageHeaders(msg, "test-changefeed")
+	c.Assert(msg.Headers, check.HasLen, 5)
+	c.Assert(string(msg.Headers[1].Value), check.Equals, "resolved")
+
+	// the max length is enough for the longest names.
+	longName := strings.Repeat("表", 64)
+	msg = NewMQMessage(config.ProtocolOpen, nil, nil, math.MaxUint64, model.MqMessageTypeResolved, &longName, &longName)
+	msg.Headers = NewMQMessageHeaders(msg, "test-changefeed")
+	c.Assert(msg.Length()-maximumRecordOverhead, check.LessEqual, MaxHeadersLength("test-changefeed"))
+}
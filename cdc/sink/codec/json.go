@@ -383,6 +383,9 @@ type JSONEventBatchEncoder struct {
 	// configs
 	maxMessageBytes int
 	maxBatchSize    int
+	// claimCheck means the oversized messages are sent by the claim check,
+	// so a single row larger than max-message-bytes is allowed.
+	claimCheck bool
 }
 
 // GetMaxMessageBytes is only for unit testing.
@@ -463,10 +466,11 @@ func (d *JSONEventBatchEncoder) AppendRowChangedEvent(e *model.RowChangedEvent)
 		d.valueBuf.Write(valueLenByte[:])
 		d.valueBuf.Write(value)
 	} else {
-		// for single message that longer than max-message-size, do not send it.
+		// for single message that longer than max-message-size, do not send it,
+		// unless it's sent by the claim check.
 		// 16 is the length of `keyLenByte` and `valueLenByte`, 8 is the length of `versionHead`
 		length := len(key) + len(value) + maximumRecordOverhead + 16 + 8
-		if length > d.maxMessageBytes {
+		if length > d.maxMessageBytes && !d.claimCheck {
 			log.Warn("Single message too large",
 				zap.Int("max-message-size", d.maxMessageBytes), zap.Int("length", length), zap.Any("table", e.Table))
 			return EncoderNoOperation, cerror.ErrJSONCodecRowTooLarge.GenWithStackByArgs()
@@ -639,6 +643,8 @@ func (d *JSONEventBatchEncoder) SetParams(params map[string]string) error {
 		return cerror.ErrSinkInvalidConfig.Wrap(errors.Errorf("invalid max-batch-size %d", d.maxBatchSize))
 	}
 
+	_, d.claimCheck = params[ClaimCheckStorageURIKey]
+
 	return nil
 }
 
@@ -874,6 +880,9 @@ func (b *JSONEventBatchDecoder) decodeNextKey() error {
 
 // NewJSONEventBatchDecoder creates a new JSONEventBatchDecoder.
 func NewJSONEventBatchDecoder(key []byte, value []byte) (EventBatchDecoder, error) {
+	if err := checkClaimCheckResolved(value); err != nil {
+		return nil, errors.Trace(err)
+	}
 	version := binary.BigEndian.Uint64(key[:8])
 	key = key[8:]
 	if version != BatchVersion1 {
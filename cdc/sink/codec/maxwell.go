@@ -18,6 +18,8 @@ import (
 	"context"
 	"encoding/binary"
 	"encoding/json"
+	"sort"
+	"strings"
 
 	"github.com/pingcap/errors"
 	model2 "github.com/pingcap/tidb/parser/model"
@@ -25,6 +27,7 @@ import (
 	"github.com/pingcap/tiflow/cdc/model"
 	"github.com/pingcap/tiflow/pkg/config"
 	cerror "github.com/pingcap/tiflow/pkg/errors"
+	"github.com/tikv/client-go/v2/oracle"
 	"github.com/tikv/pd/pkg/tsoutil"
 )
 
@@ -155,7 +158,8 @@ func rowEventToMaxwellMessage(e *model.RowChangedEvent) (*mqMessageKey, *maxwell
 							value.Old[v.Name] = nil
 						}
 					} else if v.Flag.IsBinary() {
-						if value.Data[v.Name] != v.Value {
+						// the binary values are byte slices, which are not comparable.
+						if !maxwellBinaryEqual(value.Data[v.Name], v.Value) {
 							value.Old[v.Name] = v.Value
 						}
 					} else {
@@ -175,6 +179,18 @@ func rowEventToMaxwellMessage(e *model.RowChangedEvent) (*mqMessageKey, *maxwell
 	return key, value
 }
 
+func maxwellBinaryEqual(a, b interface{}) bool {
+	aBytes, aOK := a.([]byte)
+	bBytes, bOK := b.([]byte)
+	if aOK && bOK {
+		return bytes.Equal(aBytes, bBytes)
+	}
+	if aOK || bOK {
+		return false
+	}
+	return a == b
+}
+
 // AppendRowChangedEvent implements the EventBatchEncoder interface
 func (d *MaxwellEventBatchEncoder) AppendRowChangedEvent(e *model.RowChangedEvent) (EncoderResult, error) {
 	_, valueMsg := rowEventToMaxwellMessage(e)
@@ -389,3 +405,144 @@ func columnToMaxwellType(columnType byte) (string, error) {
 		return "", cerror.ErrMaxwellInvalidData.GenWithStack("unsupported column type - %v", columnType)
 	}
 }
+
+// MaxwellEventBatchDecoder decodes the maxwell messages.
+// The maxwell protocol has no column types, and the commit ts of the rows is in
+// seconds, so the decoded events are lossy.
+type MaxwellEventBatchDecoder struct {
+	rows []*maxwellMessage
+	ddl  *DdlMaxwellMessage
+}
+
+// NewMaxwellEventBatchDecoder creates a new MaxwellEventBatchDecoder.
+func NewMaxwellEventBatchDecoder(key []byte, value []byte) (EventBatchDecoder, error) {
+	if err := checkClaimCheckResolved(value); err != nil {
+		return nil, errors.Trace(err)
+	}
+	// the key of a row batch is the batch version only, see `Reset`.
+	if len(key) == 8 && binary.BigEndian.Uint64(key) == BatchVersion1 {
+		decoder := json.NewDecoder(bytes.NewReader(value))
+		decoder.UseNumber()
+		var rows []*maxwellMessage
+		for decoder.More() {
+			row := new(maxwellMessage)
+			if err := decoder.Decode(row); err != nil {
+				return nil, cerror.WrapError(cerror.ErrMaxwellDecodeFailed, err)
+			}
+			rows = append(rows, row)
+		}
+		return &MaxwellEventBatchDecoder{rows: rows}, nil
+	}
+
+	msgKey := new(mqMessageKey)
+	if err := msgKey.Decode(key); err != nil {
+		return nil, errors.Trace(err)
+	}
+	if msgKey.Type != model.MqMessageTypeDDL {
+		return nil, cerror.ErrMaxwellDecodeFailed.GenWithStack("unexpected message type %d", msgKey.Type)
+	}
+	ddl := new(DdlMaxwellMessage)
+	if err := json.Unmarshal(value, ddl); err != nil {
+		return nil, cerror.WrapError(cerror.ErrMaxwellDecodeFailed, err)
+	}
+	return &MaxwellEventBatchDecoder{ddl: ddl}, nil
+}
+
+// HasNext implements the EventBatchDecoder interface
+func (d *MaxwellEventBatchDecoder) HasNext() (model.MqMessageType, bool, error) {
+	if d.ddl != nil {
+		return model.MqMessageTypeDDL, true, nil
+	}
+	if len(d.rows) > 0 {
+		return model.MqMessageTypeRow, true, nil
+	}
+	return model.MqMessageTypeUnknown, false, nil
+}
+
+// NextResolvedEvent implements the EventBatchDecoder interface
+func (d *MaxwellEventBatchDecoder) NextResolvedEvent() (uint64, error) {
+	return 0, cerror.ErrMaxwellDecodeFailed.GenWithStack("maxwell protocol has no resolved event")
+}
+
+// NextRowChangedEvent implements the EventBatchDecoder interface
+func (d *MaxwellEventBatchDecoder) NextRowChangedEvent() (*model.RowChangedEvent, error) {
+	if len(d.rows) == 0 {
+		return nil, cerror.ErrMaxwellDecodeFailed.GenWithStack("not found row changed event message")
+	}
+	msg := d.rows[0]
+	d.rows = d.rows[1:]
+
+	row := &model.RowChangedEvent{
+		CommitTs: oracle.ComposeTS(msg.Ts*1000, 0),
+		Table: &model.TableName{
+			Schema: msg.Database,
+			Table:  msg.Table,
+		},
+	}
+	switch msg.Type {
+	case "insert":
+		row.Columns = maxwellColumns2SinkColumns(msg.Data)
+	case "update":
+		// only the changed columns are in the old values.
+		old := make(map[string]interface{}, len(msg.Data))
+		for name, value := range msg.Data {
+			old[name] = value
+		}
+		for name, value := range msg.Old {
+			old[name] = value
+		}
+		row.PreColumns = maxwellColumns2SinkColumns(old)
+		row.Columns = maxwellColumns2SinkColumns(msg.Data)
+	case "delete":
+		row.PreColumns = maxwellColumns2SinkColumns(msg.Old)
+	default:
+		return nil, cerror.ErrMaxwellDecodeFailed.GenWithStack("unknown row type %s", msg.Type)
+	}
+	return row, nil
+}
+
+// NextDDLEvent implements the EventBatchDecoder interface
+func (d *MaxwellEventBatchDecoder) NextDDLEvent() (*model.DDLEvent, error) {
+	if d.ddl == nil {
+		return nil, cerror.ErrMaxwellDecodeFailed.GenWithStack("not found ddl event message")
+	}
+	msg := d.ddl
+	d.ddl = nil
+	return &model.DDLEvent{
+		CommitTs: msg.Ts,
+		TableInfo: &model.SimpleTableInfo{
+			Schema: msg.Database,
+			Table:  msg.Table,
+		},
+		Query: msg.SQL,
+	}, nil
+}
+
+// maxwellColumns2SinkColumns converts the maxwell values to columns sorted by the
+// names, the numbers are converted to int64 or float64 and the others are kept.
+func maxwellColumns2SinkColumns(values map[string]interface{}) []*model.Column {
+	if len(values) == 0 {
+		return nil
+	}
+	cols := make([]*model.Column, 0, len(values))
+	for name, value := range values {
+		if number, ok := value.(json.Number); ok {
+			if i, err := number.Int64(); err == nil {
+				value = i
+			} else if f, err := number.Float64(); err == nil {
+				value = f
+			} else {
+				value = number.String()
+			}
+		}
+		cols = append(cols, &model.Column{
+			Name:  name,
+			Type:  mysql.TypeUnspecified,
+			Value: value,
+		})
+	}
+	sort.Slice(cols, func(i, j int) bool {
+		return strings.Compare(cols[i].Name, cols[j].Name) < 0
+	})
+	return cols
+}
@@ -15,8 +15,10 @@ package codec
 
 import (
 	"github.com/pingcap/check"
+	"github.com/pingcap/tidb/parser/mysql"
 	"github.com/pingcap/tiflow/cdc/model"
 	"github.com/pingcap/tiflow/pkg/util/testleak"
+	"github.com/tikv/client-go/v2/oracle"
 )
 
 type maxwellbatchSuite struct {
@@ -74,6 +76,85 @@ func (s *maxwellbatchSuite) TestmaxwellEventBatchCodec(c *check.C) {
 	s.testmaxwellBatchCodec(c, NewMaxwellEventBatchEncoder)
 }
 
+func (s *maxwellbatchSuite) TestMaxwellEventBatchDecoder(c *check.C) {
+	defer testleak.AfterTest(c)()
+	table := &model.TableName{Schema: "test", Table: "t1"}
+	commitTs := oracle.ComposeTS(1640000000123, 5)
+	newColumns := func(name string, data []byte) []*model.Column {
+		return []*model.Column{
+			{Name: "id", Type: mysql.TypeLong, Flag: model.HandleKeyFlag, Value: int64(1)},
+			{Name: "name", Type: mysql.TypeVarchar, Value: []byte(name)},
+			{Name: "data", Type: mysql.TypeBlob, Flag: model.BinaryFlag, Value: data},
+			{Name: "note", Type: mysql.TypeVarchar, Flag: model.NullableFlag, Value: nil},
+		}
+	}
+	// the columns are sorted by the names, and the binary values are base64 encoded.
+	newDecodedColumns := func(name string, data string) []*model.Column {
+		return []*model.Column{
+			{Name: "data", Type: mysql.TypeUnspecified, Value: data},
+			{Name: "id", Type: mysql.TypeUnspecified, Value: int64(1)},
+			{Name: "name", Type: mysql.TypeUnspecified, Value: name},
+			{Name: "note", Type: mysql.TypeUnspecified, Value: nil},
+		}
+	}
+	rows := []*model.RowChangedEvent{
+		{CommitTs: commitTs, Table: table, Columns: newColumns("a", []byte{1, 2})},
+		{CommitTs: commitTs, Table: table, PreColumns: newColumns("a", []byte{1, 2}), Columns: newColumns("b", []byte{1, 2, 3})},
+		{CommitTs: commitTs, Table: table, PreColumns: newColumns("b", []byte{1, 2, 3})},
+	}
+	expectedRows := []*model.RowChangedEvent{
+		{Columns: newDecodedColumns("a", "AQI=")},
+		{PreColumns: newDecodedColumns("a", "AQI="), Columns: newDecodedColumns("b", "AQID")},
+		{PreColumns: newDecodedColumns("b", "AQID")},
+	}
+
+	encoder := NewMaxwellEventBatchEncoder()
+	for _, row := range rows {
+		_, err := encoder.AppendRowChangedEvent(row)
+		c.Assert(err, check.IsNil)
+	}
+	msgs := encoder.Build()
+	c.Assert(msgs, check.HasLen, 1)
+
+	decoder, err := NewMaxwellEventBatchDecoder(msgs[0].Key, msgs[0].Value)
+	c.Assert(err, check.IsNil)
+	for _, expected := range expectedRows {
+		tp, hasNext, err := decoder.HasNext()
+		c.Assert(err, check.IsNil)
+		c.Assert(hasNext, check.IsTrue)
+		c.Assert(tp, check.Equals, model.MqMessageTypeRow)
+		row, err := decoder.NextRowChangedEvent()
+		c.Assert(err, check.IsNil)
+		// the ts is in seconds in maxwell.
+		c.Assert(row.CommitTs, check.Equals, oracle.ComposeTS(1640000000000, 0))
+		c.Assert(row.Table, check.DeepEquals, table)
+		c.Assert(row.PreColumns, check.DeepEquals, expected.PreColumns)
+		c.Assert(row.Columns, check.DeepEquals, expected.Columns)
+	}
+	_, hasNext, err := decoder.HasNext()
+	c.Assert(err, check.IsNil)
+	c.Assert(hasNext, check.IsFalse)
+
+	ddl := &model.DDLEvent{
+		CommitTs:  commitTs,
+		TableInfo: &model.SimpleTableInfo{Schema: "test", Table: "t1"},
+		Query:     "create table t1(id int primary key)",
+	}
+	msg, err := encoder.EncodeDDLEvent(ddl)
+	c.Assert(err, check.IsNil)
+	decoder, err = NewMaxwellEventBatchDecoder(msg.Key, msg.Value)
+	c.Assert(err, check.IsNil)
+	tp, hasNext, err := decoder.HasNext()
+	c.Assert(err, check.IsNil)
+	c.Assert(hasNext, check.IsTrue)
+	c.Assert(tp, check.Equals, model.MqMessageTypeDDL)
+	decodedDDL, err := decoder.NextDDLEvent()
+	c.Assert(err, check.IsNil)
+	c.Assert(decodedDDL, check.DeepEquals, ddl)
+	_, err = decoder.NextRowChangedEvent()
+	c.Assert(err, check.NotNil)
+}
+
 var _ = check.Suite(&maxwellcolumnSuite{})
 
 type maxwellcolumnSuite struct{}
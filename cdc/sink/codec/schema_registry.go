@@ -21,6 +21,7 @@ import (
 	"net/http"
 	"net/url"
 	"regexp"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -47,6 +48,8 @@ type AvroSchemaManager struct {
 
 	cacheRWLock sync.RWMutex
 	cache       map[string]*schemaCacheEntry
+	// idCache caches the schemas looked up by the registry IDs.
+	idCache map[int]*goavro.Codec
 }
 
 type schemaCacheEntry struct {
@@ -65,6 +68,10 @@ type registerResponse struct {
 	ID int `json:"id"`
 }
 
+type compatibilityResponse struct {
+	IsCompatible bool `json:"is_compatible"`
+}
+
 type lookupResponse struct {
 	Name       string `json:"name"`
 	RegistryID int    `json:"id"`
@@ -107,6 +114,7 @@ func NewAvroSchemaManager(
 	return &AvroSchemaManager{
 		registryURL:   registryURL,
 		cache:         make(map[string]*schemaCacheEntry, 1),
+		idCache:       make(map[int]*goavro.Codec),
 		subjectSuffix: subjectSuffix,
 		credential:    credential,
 	}, nil
@@ -117,6 +125,10 @@ var regexRemoveSpaces = regexp.MustCompile(`\s`)
 // Register the latest schema for a table to the Registry, by passing in a Codec
 // Returns the Schema's ID and err
 func (m *AvroSchemaManager) Register(ctx context.Context, tableName model.TableName, codec *goavro.Codec) (int, error) {
+	return m.registerSubject(ctx, m.tableNameToSchemaSubject(tableName), codec)
+}
+
+func (m *AvroSchemaManager) registerSubject(ctx context.Context, subject string, codec *goavro.Codec) (int, error) {
 	// The Schema Registry expects the JSON to be without newline characters
 	reqBody := registerRequest{
 		Schema: regexRemoveSpaces.ReplaceAllString(codec.Schema(), ""),
@@ -128,7 +140,15 @@ func (m *AvroSchemaManager) Register(ctx context.Context, tableName model.TableN
 		return 0, errors.Annotate(
 			cerror.WrapError(cerror.ErrAvroSchemaAPIError, err), "Could not marshal request to the Registry")
 	}
-	uri := m.registryURL + "/subjects/" + url.QueryEscape(m.tableNameToSchemaSubject(tableName)) + "/versions"
+
+	// Registering an incompatible schema either fails with a 409 error, which
+	// would be retried forever, or silently breaks the downstream consumers if
+	// the compatibility level of the subject is NONE. So we check it first.
+	if err := m.checkCompatibility(ctx, subject, payload); err != nil {
+		return 0, err
+	}
+
+	uri := m.registryURL + "/subjects/" + url.QueryEscape(subject) + "/versions"
 	log.Debug("Registering schema", zap.String("uri", uri), zap.ByteString("payload", payload))
 
 	req, err := http.NewRequestWithContext(ctx, "POST", uri, bytes.NewReader(payload))
@@ -176,6 +196,50 @@ func (m *AvroSchemaManager) Register(ctx context.Context, tableName model.TableN
 	return jsonResp.ID, nil
 }
 
+// checkCompatibility tests the schema in payload against the latest version of
+// the subject. A subject without any version is compatible with any schema.
+func (m *AvroSchemaManager) checkCompatibility(ctx context.Context, subject string, payload []byte) error {
+	uri := m.registryURL + "/compatibility/subjects/" + url.QueryEscape(subject) + "/versions/latest"
+	log.Debug("Checking schema compatibility", zap.String("uri", uri), zap.ByteString("payload", payload))
+
+	req, err := http.NewRequestWithContext(ctx, "POST", uri, bytes.NewReader(payload))
+	if err != nil {
+		return cerror.WrapError(cerror.ErrAvroSchemaAPIError, err)
+	}
+	req.Header.Add("Accept", "application/vnd.schemaregistry.v1+json")
+	resp, err := httpRetry(ctx, m.credential, req, true)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return errors.Annotate(
+			cerror.WrapError(cerror.ErrAvroSchemaAPIError, err), "Failed to read response from Registry")
+	}
+
+	if resp.StatusCode == 404 {
+		log.Debug("No schema found in Registry, skip compatibility check", zap.String("subject", subject))
+		return nil
+	}
+
+	var jsonResp compatibilityResponse
+	err = json.Unmarshal(body, &jsonResp)
+	if err != nil {
+		return errors.Annotate(
+			cerror.WrapError(cerror.ErrAvroSchemaAPIError, err), "Failed to parse result from Registry")
+	}
+
+	if !jsonResp.IsCompatible {
+		log.Error("Schema is incompatible with the latest version in the Registry",
+			zap.String("uri", uri),
+			zap.ByteString("requestBody", payload))
+		return cerror.ErrAvroIncompatibleSchema.GenWithStackByArgs(subject)
+	}
+	return nil
+}
+
 // Lookup the latest schema and the Registry designated ID for that schema.
 // TiSchemaId is only used to trigger fetching from the Registry server.
 // Calling this method with a tiSchemaID other than that used last time will invariably trigger a RESTful request to the Registry.
@@ -265,6 +329,67 @@ func (m *AvroSchemaManager) Lookup(ctx context.Context, tableName model.TableNam
 	return cacheEntry.codec, cacheEntry.registryID, nil
 }
 
+// LookupByID looks up the schema with the Registry designated ID, which is
+// written in the envelope of every Avro message. The schemas are immutable once
+// registered, so they are cached forever.
+func (m *AvroSchemaManager) LookupByID(ctx context.Context, registryID int) (*goavro.Codec, error) {
+	m.cacheRWLock.RLock()
+	codec, exists := m.idCache[registryID]
+	m.cacheRWLock.RUnlock()
+	if exists {
+		return codec, nil
+	}
+
+	uri := m.registryURL + "/schemas/ids/" + strconv.Itoa(registryID)
+	log.Debug("Querying for schema by ID", zap.String("uri", uri))
+
+	req, err := http.NewRequestWithContext(ctx, "GET", uri, nil)
+	if err != nil {
+		return nil, errors.Annotate(
+			cerror.WrapError(cerror.ErrAvroSchemaAPIError, err), "Error constructing request for Registry lookup")
+	}
+	req.Header.Add("Accept", "application/vnd.schemaregistry.v1+json, application/vnd.schemaregistry+json, application/json")
+
+	resp, err := httpRetry(ctx, m.credential, req, true)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, errors.Annotate(
+			cerror.WrapError(cerror.ErrAvroSchemaAPIError, err), "Failed to read response from Registry")
+	}
+
+	if resp.StatusCode == 404 {
+		log.Warn("Specified schema ID not found in Registry", zap.Int("registryID", registryID))
+		return nil, cerror.ErrAvroSchemaAPIError.GenWithStack("Schema %d not found in Registry", registryID)
+	}
+
+	var jsonResp lookupResponse
+	err = json.Unmarshal(body, &jsonResp)
+	if err != nil {
+		return nil, errors.Annotate(
+			cerror.WrapError(cerror.ErrAvroSchemaAPIError, err), "Failed to parse result from Registry")
+	}
+
+	codec, err = goavro.NewCodec(jsonResp.Schema)
+	if err != nil {
+		return nil, errors.Annotate(
+			cerror.WrapError(cerror.ErrAvroSchemaAPIError, err), "Creating Avro codec failed")
+	}
+
+	m.cacheRWLock.Lock()
+	m.idCache[registryID] = codec
+	m.cacheRWLock.Unlock()
+
+	log.Info("Avro schema lookup by ID successful",
+		zap.Int("registryID", registryID),
+		zap.String("schema", codec.Schema()))
+	return codec, nil
+}
+
 // SchemaGenerator represents a function that returns an Avro schema in JSON.
 // Used for lazy evaluation
 type SchemaGenerator func() (string, error)
@@ -272,7 +397,10 @@ type SchemaGenerator func() (string, error)
 // GetCachedOrRegister checks if the suitable Avro schema has been cached.
 // If not, a new schema is generated, registered and cached.
 func (m *AvroSchemaManager) GetCachedOrRegister(ctx context.Context, tableName model.TableName, tiSchemaID uint64, schemaGen SchemaGenerator) (*goavro.Codec, int, error) {
-	key := m.tableNameToSchemaSubject(tableName)
+	return m.getCachedOrRegisterSubject(ctx, m.tableNameToSchemaSubject(tableName), tiSchemaID, schemaGen)
+}
+
+func (m *AvroSchemaManager) getCachedOrRegisterSubject(ctx context.Context, key string, tiSchemaID uint64, schemaGen SchemaGenerator) (*goavro.Codec, int, error) {
 	m.cacheRWLock.RLock()
 	if entry, exists := m.cache[key]; exists && entry.tiSchemaID == tiSchemaID {
 		log.Debug("Avro schema GetCachedOrRegister cache hit",
@@ -299,8 +427,11 @@ func (m *AvroSchemaManager) GetCachedOrRegister(ctx context.Context, tableName m
 			cerror.WrapError(cerror.ErrAvroSchemaAPIError, err), "GetCachedOrRegister: Could not make goavro codec")
 	}
 
-	id, err := m.Register(ctx, tableName, codec)
+	id, err := m.registerSubject(ctx, key, codec)
 	if err != nil {
+		if cerror.ErrAvroIncompatibleSchema.Equal(err) {
+			return nil, 0, errors.Trace(err)
+		}
 		return nil, 0, errors.Annotate(
 			cerror.WrapError(cerror.ErrAvroSchemaAPIError, err), "GetCachedOrRegister: Could not register schema")
 	}
@@ -311,7 +442,7 @@ func (m *AvroSchemaManager) GetCachedOrRegister(ctx context.Context, tableName m
 	cacheEntry.tiSchemaID = tiSchemaID
 
 	m.cacheRWLock.Lock()
-	m.cache[m.tableNameToSchemaSubject(tableName)] = cacheEntry
+	m.cache[key] = cacheEntry
 	m.cacheRWLock.Unlock()
 
 	log.Info("Avro schema GetCachedOrRegister successful with cache miss",
@@ -406,3 +537,9 @@ func (m *AvroSchemaManager) tableNameToSchemaSubject(tableName model.TableName)
 	// We should guarantee unique names for subjects
 	return tableName.Schema + "_" + tableName.Table + m.subjectSuffix
 }
+
+// nameToSchemaSubject returns the subject of a schema that does not belong to
+// any table. The name must not contain "_" to avoid conflicts with the tables.
+func (m *AvroSchemaManager) nameToSchemaSubject(name string) string {
+	return name + m.subjectSuffix
+}
@@ -19,6 +19,7 @@ import (
 	"encoding/json"
 	"io"
 	"net/http"
+	"strings"
 	"sync"
 	"time"
 
@@ -26,6 +27,7 @@ import (
 	"github.com/linkedin/goavro/v2"
 	"github.com/pingcap/check"
 	"github.com/pingcap/tiflow/cdc/model"
+	cerror "github.com/pingcap/tiflow/pkg/errors"
 	"github.com/pingcap/tiflow/pkg/security"
 	"github.com/pingcap/tiflow/pkg/util/testleak"
 )
@@ -37,6 +39,7 @@ var _ = check.Suite(&AvroSchemaRegistrySuite{})
 type mockRegistry struct {
 	mu       sync.Mutex
 	subjects map[string]*mockRegistrySchema
+	schemas  map[int]string
 	newID    int
 }
 
@@ -51,6 +54,7 @@ func startHTTPInterceptForTestingRegistry(c *check.C) {
 
 	registry := mockRegistry{
 		subjects: make(map[string]*mockRegistrySchema),
+		schemas:  make(map[int]string),
 		newID:    1,
 	}
 
@@ -95,11 +99,29 @@ func startHTTPInterceptForTestingRegistry(c *check.C) {
 					respData.ID = registry.newID
 				}
 			}
+			registry.schemas[respData.ID] = reqData.Schema
 			registry.newID++
 			registry.mu.Unlock()
 			return httpmock.NewJsonResponse(200, &respData)
 		})
 
+	httpmock.RegisterResponder("GET", `=~^http://127.0.0.1:8081/schemas/ids/(\d+)`,
+		func(req *http.Request) (*http.Response, error) {
+			id, err := httpmock.GetSubmatchAsInt(req, 1)
+			if err != nil {
+				return httpmock.NewStringResponse(500, "Internal Server Error"), err
+			}
+
+			registry.mu.Lock()
+			schema, exists := registry.schemas[int(id)]
+			registry.mu.Unlock()
+			if !exists {
+				return httpmock.NewStringResponse(404, ""), nil
+			}
+
+			return httpmock.NewJsonResponse(200, &lookupResponse{Schema: schema})
+		})
+
 	httpmock.RegisterResponder("GET", `=~^http://127.0.0.1:8081/subjects/(.+)/versions/latest`,
 		func(req *http.Request) (*http.Response, error) {
 			subject, err := httpmock.GetSubmatch(req, 1)
@@ -122,6 +144,53 @@ func startHTTPInterceptForTestingRegistry(c *check.C) {
 			return httpmock.NewJsonResponse(200, &respData)
 		})
 
+	// The mock registry follows the BACKWARD compatibility level, so a new field
+	// without a default value is incompatible with the latest version.
+	httpmock.RegisterResponder("POST", `=~^http://127.0.0.1:8081/compatibility/subjects/(.+)/versions/latest`,
+		func(req *http.Request) (*http.Response, error) {
+			subject, err := httpmock.GetSubmatch(req, 1)
+			if err != nil {
+				return nil, err
+			}
+			reqBody, err := io.ReadAll(req.Body)
+			if err != nil {
+				return nil, err
+			}
+			var reqData registerRequest
+			err = json.Unmarshal(reqBody, &reqData)
+			if err != nil {
+				return nil, err
+			}
+
+			registry.mu.Lock()
+			item, exists := registry.subjects[subject]
+			registry.mu.Unlock()
+			if !exists {
+				return httpmock.NewStringResponse(404, ""), nil
+			}
+
+			var oldSchema, newSchema avroSchemaTop
+			if err := json.Unmarshal([]byte(item.content), &oldSchema); err != nil {
+				return nil, err
+			}
+			if err := json.Unmarshal([]byte(reqData.Schema), &newSchema); err != nil {
+				return nil, err
+			}
+			oldFields := make(map[interface{}]struct{}, len(oldSchema.Fields))
+			for _, field := range oldSchema.Fields {
+				oldFields[field["name"]] = struct{}{}
+			}
+			respData := compatibilityResponse{IsCompatible: true}
+			for _, field := range newSchema.Fields {
+				_, inOld := oldFields[field["name"]]
+				_, hasDefault := field["default"]
+				if !inOld && !hasDefault {
+					respData.IsCompatible = false
+				}
+			}
+			return httpmock.NewJsonResponse(200, &respData)
+		})
+
 	httpmock.RegisterResponder("DELETE", `=~^http://127.0.0.1:8081/subjects/(.+)`,
 		func(req *http.Request) (*http.Response, error) {
 			subject, err := httpmock.GetSubmatch(req, 1)
@@ -383,6 +452,62 @@ func (s *AvroSchemaRegistrySuite) TestGetCachedOrRegister(c *check.C) {
 	wg.Wait()
 }
 
+func (s *AvroSchemaRegistrySuite) TestSchemaRegistryIncompatible(c *check.C) {
+	defer testleak.AfterTest(c)()
+	table := model.TableName{
+		Schema: "testdb",
+		Table:  "test2",
+	}
+
+	manager, err := NewAvroSchemaManager(getTestingContext(), &security.Credential{}, "http://127.0.0.1:8081", "-value")
+	c.Assert(err, check.IsNil)
+	err = manager.ClearRegistry(getTestingContext(), table)
+	c.Assert(err, check.IsNil)
+
+	schemaGen := func() (string, error) {
+		return `{
+       "type": "record",
+       "name": "test",
+       "fields":
+         [
+           {
+             "type": "string",
+             "name": "field1"
+           }
+          ]
+     }`, nil
+	}
+	_, _, err = manager.GetCachedOrRegister(getTestingContext(), table, 1, schemaGen)
+	c.Assert(err, check.IsNil)
+
+	// a new field without default value can not be read from the old data.
+	schemaGen = func() (string, error) {
+		return `{
+       "type": "record",
+       "name": "test",
+       "fields":
+         [
+           {
+             "type": "string",
+             "name": "field1"
+           },
+           {
+             "type": "string",
+             "name": "field2"
+           }
+          ]
+     }`, nil
+	}
+	_, _, err = manager.GetCachedOrRegister(getTestingContext(), table, 2, schemaGen)
+	c.Assert(cerror.ErrAvroIncompatibleSchema.Equal(err), check.IsTrue)
+	c.Assert(cerror.ChangefeedFastFailError(err), check.IsTrue)
+
+	// the incompatible schema is neither registered nor cached.
+	codec, _, err := manager.Lookup(getTestingContext(), table, 2)
+	c.Assert(err, check.IsNil)
+	c.Assert(strings.Contains(codec.Schema(), "field2"), check.IsFalse)
+}
+
 func (s *AvroSchemaRegistrySuite) TestHTTPRetry(c *check.C) {
 	defer testleak.AfterTest(c)()
 	payload := []byte("test")
@@ -0,0 +1,200 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package columnselector
+
+import (
+	"strings"
+
+	filter "github.com/pingcap/tidb-tools/pkg/table-filter"
+	"github.com/pingcap/tiflow/cdc/model"
+	"github.com/pingcap/tiflow/pkg/config"
+	cerror "github.com/pingcap/tiflow/pkg/errors"
+)
+
+// columnSchemaPlaceholder is the schema part used to reuse the table filter
+// syntax for column patterns, a column pattern `c` is parsed as `*.c`.
+const columnSchemaPlaceholder = "column"
+
+type selector struct {
+	tableFilter  filter.Filter
+	columnFilter filter.Filter
+}
+
+// ColumnSelector keeps the selected columns of the row changed events
+// and drops all the others, according to the column selectors in the sink config.
+type ColumnSelector struct {
+	selectors []*selector
+}
+
+// New creates a ColumnSelector, the tables which are not matched by any
+// selector keep all their columns.
+func New(cfg *config.ReplicaConfig) (*ColumnSelector, error) {
+	selectors := make([]*selector, 0, len(cfg.Sink.ColumnSelectors))
+	for _, rule := range cfg.Sink.ColumnSelectors {
+		tableFilter, err := filter.Parse(rule.Matcher)
+		if err != nil {
+			return nil, cerror.WrapError(cerror.ErrColumnSelectorInvalid, err)
+		}
+		if !cfg.CaseSensitive {
+			tableFilter = filter.CaseInsensitive(tableFilter)
+		}
+		columnFilter, err := parseColumnFilter(rule.Columns)
+		if err != nil {
+			return nil, cerror.WrapError(cerror.ErrColumnSelectorInvalid, err)
+		}
+		selectors = append(selectors, &selector{
+			tableFilter:  tableFilter,
+			columnFilter: columnFilter,
+		})
+	}
+	return &ColumnSelector{selectors: selectors}, nil
+}
+
+// parseColumnFilter parses the column patterns with the table filter syntax,
+// so that wildcards and `!` exclusions behave the same as the table matchers.
+// Column names are case-insensitive.
+func parseColumnFilter(columns []string) (filter.Filter, error) {
+	rules := make([]string, 0, len(columns))
+	for _, column := range columns {
+		column = strings.TrimSpace(column)
+		if strings.HasPrefix(column, "!") {
+			rules = append(rules, "!*."+strings.TrimPrefix(column, "!"))
+			continue
+		}
+		rules = append(rules, "*."+column)
+	}
+	f, err := filter.Parse(rules)
+	if err != nil {
+		return nil, err
+	}
+	return filter.CaseInsensitive(f), nil
+}
+
+func (s *selector) matchColumn(column string) bool {
+	return s.columnFilter.MatchTable(columnSchemaPlaceholder, column)
+}
+
+func (c *ColumnSelector) match(schema, table string) *selector {
+	for _, s := range c.selectors {
+		if s.tableFilter.MatchTable(schema, table) {
+			return s
+		}
+	}
+	return nil
+}
+
+// Apply returns the row changed event which only contains the selected columns.
+// The handle key columns must be selected, they are verified when the changefeed
+// is created, and an error is returned for the tables created later which
+// violate the selectors.
+// The input event is never modified, because it may be shared with others.
+func (c *ColumnSelector) Apply(row *model.RowChangedEvent) (*model.RowChangedEvent, error) {
+	s := c.match(row.Table.Schema, row.Table.Table)
+	if s == nil {
+		return row, nil
+	}
+
+	columnNum := len(row.Columns)
+	if columnNum == 0 {
+		columnNum = len(row.PreColumns)
+	}
+	// offsets maps the offset of a column in the input event to the
+	// offset in the output event, -1 means the column is dropped.
+	offsets := make([]int, columnNum)
+	selected := 0
+	for i := 0; i < columnNum; i++ {
+		var col *model.Column
+		if i < len(row.Columns) && row.Columns[i] != nil {
+			col = row.Columns[i]
+		} else if i < len(row.PreColumns) {
+			col = row.PreColumns[i]
+		}
+		if col != nil && !s.matchColumn(col.Name) {
+			if col.Flag.IsHandleKey() {
+				return nil, cerror.ErrColumnSelectorFailed.GenWithStackByArgs(
+					row.Table.String(), col.Name)
+			}
+			offsets[i] = -1
+			continue
+		}
+		offsets[i] = selected
+		selected++
+	}
+	if selected == columnNum {
+		return row, nil
+	}
+
+	newRow := *row
+	newRow.Columns = selectColumns(row.Columns, offsets, selected)
+	newRow.PreColumns = selectColumns(row.PreColumns, offsets, selected)
+	newRow.IndexColumns = make([][]int, 0, len(row.IndexColumns))
+	for _, index := range row.IndexColumns {
+		newIndex := make([]int, 0, len(index))
+		for _, offset := range index {
+			if offset >= len(offsets) || offsets[offset] < 0 {
+				newIndex = nil
+				break
+			}
+			newIndex = append(newIndex, offsets[offset])
+		}
+		// the index is dropped if any of its columns is dropped.
+		if newIndex != nil {
+			newRow.IndexColumns = append(newRow.IndexColumns, newIndex)
+		}
+	}
+	return &newRow, nil
+}
+
+func selectColumns(columns []*model.Column, offsets []int, selected int) []*model.Column {
+	if len(columns) == 0 {
+		return columns
+	}
+	result := make([]*model.Column, selected)
+	for i, col := range columns {
+		if i >= len(offsets) || offsets[i] < 0 {
+			continue
+		}
+		result[offsets[i]] = col
+	}
+	return result
+}
+
+// VerifyTable checks that the handle key columns and the dispatch columns of the
+// table are selected, they are required to identify the rows and to dispatch the events.
+func (c *ColumnSelector) VerifyTable(tableInfo *model.TableInfo, dispatchColumns []string) error {
+	s := c.match(tableInfo.TableName.Schema, tableInfo.TableName.Table)
+	if s == nil {
+		return nil
+	}
+	for _, colInfo := range tableInfo.Columns {
+		if !model.IsColCDCVisible(colInfo) {
+			continue
+		}
+		flag := tableInfo.ColumnsFlag[colInfo.ID]
+		if !flag.IsHandleKey() {
+			continue
+		}
+		if !s.matchColumn(colInfo.Name.O) {
+			return cerror.ErrColumnSelectorFailed.GenWithStackByArgs(
+				tableInfo.TableName.String(), colInfo.Name.O)
+		}
+	}
+	for _, column := range dispatchColumns {
+		if !s.matchColumn(column) {
+			return cerror.ErrColumnSelectorFailed.GenWithStackByArgs(
+				tableInfo.TableName.String(), column)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,256 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package columnselector
+
+import (
+	"testing"
+
+	timodel "github.com/pingcap/tidb/parser/model"
+	"github.com/pingcap/tidb/parser/mysql"
+	parser_types "github.com/pingcap/tidb/parser/types"
+	"github.com/pingcap/tiflow/cdc/model"
+	"github.com/pingcap/tiflow/pkg/config"
+	cerror "github.com/pingcap/tiflow/pkg/errors"
+	"github.com/stretchr/testify/require"
+)
+
+func newReplicaConfig(selectors ...*config.ColumnSelector) *config.ReplicaConfig {
+	cfg := config.GetDefaultReplicaConfig()
+	cfg.Sink.ColumnSelectors = selectors
+	return cfg
+}
+
+func newRow(schema, table string, columns ...string) *model.RowChangedEvent {
+	row := &model.RowChangedEvent{
+		Table: &model.TableName{Schema: schema, Table: table},
+	}
+	for i, name := range columns {
+		col := &model.Column{Name: name, Value: i}
+		if i == 0 {
+			col.Flag.SetIsHandleKey()
+			col.Flag.SetIsPrimaryKey()
+		}
+		row.Columns = append(row.Columns, col)
+	}
+	return row
+}
+
+func columnNames(columns []*model.Column) []string {
+	names := make([]string, 0, len(columns))
+	for _, col := range columns {
+		names = append(names, col.Name)
+	}
+	return names
+}
+
+func TestNewColumnSelector(t *testing.T) {
+	t.Parallel()
+
+	_, err := New(newReplicaConfig(&config.ColumnSelector{
+		Matcher: []string{"test.*"},
+		Columns: []string{"id", "name"},
+	}))
+	require.Nil(t, err)
+
+	_, err = New(newReplicaConfig(&config.ColumnSelector{
+		Matcher: []string{"test"},
+		Columns: []string{"id"},
+	}))
+	require.Regexp(t, ".*ErrColumnSelectorInvalid.*", err)
+
+	_, err = New(newReplicaConfig(&config.ColumnSelector{
+		Matcher: []string{"test.*"},
+		Columns: []string{"[id"},
+	}))
+	require.Regexp(t, ".*ErrColumnSelectorInvalid.*", err)
+}
+
+func TestColumnSelectorApply(t *testing.T) {
+	t.Parallel()
+
+	selector, err := New(newReplicaConfig(
+		&config.ColumnSelector{
+			Matcher: []string{"test.t1"},
+			Columns: []string{"id", "name", "Age"},
+		},
+		&config.ColumnSelector{
+			Matcher: []string{"test.t2"},
+			Columns: []string{"*", "!email"},
+		},
+		&config.ColumnSelector{
+			Matcher: []string{"test.t3"},
+			Columns: []string{"id", "src*", "!src1"},
+		},
+		&config.ColumnSelector{
+			Matcher: []string{"test.t4"},
+			Columns: []string{"id", "sdb?c"},
+		},
+	))
+	require.Nil(t, err)
+
+	testCases := []struct {
+		row      *model.RowChangedEvent
+		expected []string
+	}{
+		{
+			row:      newRow("test", "t1", "id", "name", "email", "age"),
+			expected: []string{"id", "name", "age"},
+		},
+		{
+			row:      newRow("test", "t2", "id", "name", "EMAIL", "age"),
+			expected: []string{"id", "name", "age"},
+		},
+		{
+			row:      newRow("test", "t3", "id", "src", "src1", "src2", "dst"),
+			expected: []string{"id", "src", "src2"},
+		},
+		{
+			row:      newRow("test", "t4", "id", "sdb1c", "sdbc", "sdb-c", "sdb12c"),
+			expected: []string{"id", "sdb1c", "sdb-c"},
+		},
+		{
+			row:      newRow("test", "t5", "id", "name", "email"),
+			expected: []string{"id", "name", "email"},
+		},
+	}
+	for _, tc := range testCases {
+		row, err := selector.Apply(tc.row)
+		require.Nil(t, err)
+		require.Equal(t, tc.expected, columnNames(row.Columns), tc.row.Table.String())
+	}
+}
+
+func TestColumnSelectorApplyKeepsInput(t *testing.T) {
+	t.Parallel()
+
+	selector, err := New(newReplicaConfig(&config.ColumnSelector{
+		Matcher: []string{"test.*"},
+		Columns: []string{"id", "uk", "age"},
+	}))
+	require.Nil(t, err)
+
+	row := newRow("test", "t1", "id", "uk", "email", "age")
+	row.PreColumns = newRow("test", "t1", "id", "uk", "email", "age").Columns
+	row.IndexColumns = [][]int{{0}, {1}, {2, 3}}
+	selected, err := selector.Apply(row)
+	require.Nil(t, err)
+
+	require.Equal(t, []string{"id", "uk", "age"}, columnNames(selected.Columns))
+	require.Equal(t, []string{"id", "uk", "age"}, columnNames(selected.PreColumns))
+	require.Equal(t, [][]int{{0}, {1}}, selected.IndexColumns)
+	require.Equal(t, 3, selected.Columns[2].Value)
+
+	// the input event is not modified.
+	require.Equal(t, []string{"id", "uk", "email", "age"}, columnNames(row.Columns))
+	require.Equal(t, []string{"id", "uk", "email", "age"}, columnNames(row.PreColumns))
+	require.Equal(t, [][]int{{0}, {1}, {2, 3}}, row.IndexColumns)
+
+	// delete events only have the pre columns.
+	row = newRow("test", "t1", "id", "uk", "email")
+	row.PreColumns, row.Columns = row.Columns, nil
+	selected, err = selector.Apply(row)
+	require.Nil(t, err)
+	require.Nil(t, selected.Columns)
+	require.Equal(t, []string{"id", "uk"}, columnNames(selected.PreColumns))
+}
+
+func TestColumnSelectorApplyDropsHandleKey(t *testing.T) {
+	t.Parallel()
+
+	selector, err := New(newReplicaConfig(&config.ColumnSelector{
+		Matcher: []string{"test.*"},
+		Columns: []string{"name"},
+	}))
+	require.Nil(t, err)
+
+	// the handle key is never added back, the event is rejected instead.
+	_, err = selector.Apply(newRow("test", "t1", "id", "name"))
+	require.True(t, cerror.ErrColumnSelectorFailed.Equal(err))
+	require.Regexp(t, "test.t1.*drops the required column id", err)
+
+	// the first column is the handle key.
+	row, err := selector.Apply(newRow("test", "t1", "name", "id"))
+	require.Nil(t, err)
+	require.Equal(t, []string{"name"}, columnNames(row.Columns))
+}
+
+func TestColumnSelectorVerifyTable(t *testing.T) {
+	t.Parallel()
+
+	tableInfo := model.WrapTableInfo(1, "test", 0, &timodel.TableInfo{
+		Name: timodel.CIStr{O: "t1"},
+		Columns: []*timodel.ColumnInfo{
+			{
+				ID:        1,
+				Name:      timodel.CIStr{O: "id"},
+				FieldType: parser_types.FieldType{Flag: mysql.PriKeyFlag | mysql.NotNullFlag},
+				State:     timodel.StatePublic,
+			},
+			{
+				ID:    2,
+				Name:  timodel.CIStr{O: "name"},
+				State: timodel.StatePublic,
+			},
+			{
+				ID:    3,
+				Name:  timodel.CIStr{O: "email"},
+				State: timodel.StatePublic,
+			},
+		},
+		PKIsHandle: true,
+	})
+
+	testCases := []struct {
+		selector        *config.ColumnSelector
+		dispatchColumns []string
+		hasError        bool
+	}{
+		{
+			selector: &config.ColumnSelector{Matcher: []string{"test.*"}, Columns: []string{"id", "name"}},
+		},
+		{
+			selector:        &config.ColumnSelector{Matcher: []string{"test.*"}, Columns: []string{"id", "name"}},
+			dispatchColumns: []string{"Name"},
+		},
+		{
+			selector:        &config.ColumnSelector{Matcher: []string{"test.*"}, Columns: []string{"id", "name"}},
+			dispatchColumns: []string{"email"},
+			hasError:        true,
+		},
+		{
+			selector: &config.ColumnSelector{Matcher: []string{"test.*"}, Columns: []string{"*", "!email"}},
+		},
+		{
+			selector: &config.ColumnSelector{Matcher: []string{"other.*"}, Columns: []string{"name"}},
+		},
+		{
+			selector: &config.ColumnSelector{Matcher: []string{"test.t1"}, Columns: []string{"name"}},
+			hasError: true,
+		},
+		{
+			selector: &config.ColumnSelector{Matcher: []string{"test.*"}, Columns: []string{"*", "!ID"}},
+			hasError: true,
+		},
+	}
+	for _, tc := range testCases {
+		selector, err := New(newReplicaConfig(tc.selector))
+		require.Nil(t, err)
+		err = selector.VerifyTable(tableInfo, tc.dispatchColumns)
+		if tc.hasError {
+			require.True(t, cerror.ErrColumnSelectorFailed.Equal(err), "%v", tc.selector)
+		} else {
+			require.Nil(t, err, "%v", tc.selector)
+		}
+	}
+}
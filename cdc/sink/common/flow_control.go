@@ -138,9 +138,110 @@ func (c *TableMemoryQuota) GetConsumption() uint64 {
 	return c.Consumed
 }
 
+// ChangefeedMemoryQuota is designed to curb the total memory consumption of
+// the tables of a changefeed in a processor, it's shared by the
+// TableFlowControllers of the tables.
+type ChangefeedMemoryQuota struct {
+	quota uint64
+
+	mu       sync.Mutex
+	consumed uint64
+	cond     *sync.Cond
+}
+
+// NewChangefeedMemoryQuota creates a new ChangefeedMemoryQuota
+// quota: max advised memory consumption in bytes, 0 means no limit, only the
+// consumption is tracked.
+func NewChangefeedMemoryQuota(quota uint64) *ChangefeedMemoryQuota {
+	ret := &ChangefeedMemoryQuota{quota: quota}
+	ret.cond = sync.NewCond(&ret.mu)
+	return ret
+}
+
+// consumeWithBlocking blocks until the quota is available or the table is
+// aborted. The table must have consumed nBytes from its own quota.
+// A table holding no other memory is never blocked, because the memory of the
+// other tables is released after the global resolved ts advances, which
+// needs the table to proceed.
+func (c *ChangefeedMemoryQuota) consumeWithBlocking(
+	nBytes uint64, table *TableMemoryQuota, blockCallBack func() error,
+) error {
+	mustBlock := func() bool {
+		return c.quota != 0 && c.consumed+nBytes >= c.quota && table.GetConsumption() > nBytes
+	}
+
+	c.mu.Lock()
+	if mustBlock() {
+		c.mu.Unlock()
+		err := blockCallBack()
+		if err != nil {
+			return errors.Trace(err)
+		}
+		c.mu.Lock()
+	}
+	defer c.mu.Unlock()
+
+	for {
+		if atomic.LoadUint32(&table.IsAborted) == 1 {
+			return cerrors.ErrFlowControllerAborted.GenWithStackByArgs()
+		}
+		if !mustBlock() {
+			break
+		}
+		c.cond.Wait()
+	}
+
+	c.consumed += nBytes
+	return nil
+}
+
+func (c *ChangefeedMemoryQuota) forceConsume(nBytes uint64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.consumed += nBytes
+}
+
+func (c *ChangefeedMemoryQuota) release(nBytes uint64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.consumed < nBytes {
+		log.Panic("ChangefeedMemoryQuota: releasing more than consumed, report a bug",
+			zap.Uint64("consumed", c.consumed),
+			zap.Uint64("released", nBytes))
+	}
+	c.consumed -= nBytes
+	// The tables waiting for the quota are all woken up, as the tables
+	// releasing their memory may unblock any of them.
+	c.cond.Broadcast()
+}
+
+// wakeUp wakes up the waiting tables to check whether they are aborted.
+func (c *ChangefeedMemoryQuota) wakeUp() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.cond.Broadcast()
+}
+
+// GetConsumption returns the current memory consumption
+func (c *ChangefeedMemoryQuota) GetConsumption() uint64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.consumed
+}
+
+// GetQuota returns the memory quota
+func (c *ChangefeedMemoryQuota) GetQuota() uint64 {
+	return c.quota
+}
+
 // TableFlowController provides a convenient interface to control the memory consumption of a per table event stream
 type TableFlowController struct {
 	memoryQuota *TableMemoryQuota
+	// changefeedQuota is shared by the tables of the changefeed, nil means
+	// there is no quota for the changefeed.
+	changefeedQuota *ChangefeedMemoryQuota
 
 	mu    sync.Mutex
 	queue deque.Deque
@@ -153,11 +254,13 @@ type commitTsSizeEntry struct {
 	Size     uint64
 }
 
-// NewTableFlowController creates a new TableFlowController
-func NewTableFlowController(quota uint64) *TableFlowController {
+// NewTableFlowController creates a new TableFlowController, changefeedQuota
+// is optional.
+func NewTableFlowController(quota uint64, changefeedQuota *ChangefeedMemoryQuota) *TableFlowController {
 	return &TableFlowController{
-		memoryQuota: NewTableMemoryQuota(quota),
-		queue:       deque.NewDeque(),
+		memoryQuota:     NewTableMemoryQuota(quota),
+		changefeedQuota: changefeedQuota,
+		queue:           deque.NewDeque(),
 	}
 }
 
@@ -178,6 +281,13 @@ func (c *TableFlowController) Consume(commitTs uint64, size uint64, blockCallBac
 		if err != nil {
 			return errors.Trace(err)
 		}
+		if c.changefeedQuota != nil {
+			err = c.changefeedQuota.consumeWithBlocking(size, c.memoryQuota, blockCallBack)
+			if err != nil {
+				c.memoryQuota.Release(size)
+				return errors.Trace(err)
+			}
+		}
 	} else {
 		// Here commitTs == lastCommitTs, which means that we are not crossing
 		// a transaction boundary. In this situation, we use `ForceConsume` because
@@ -188,6 +298,9 @@ func (c *TableFlowController) Consume(commitTs uint64, size uint64, blockCallBac
 		if err != nil {
 			return errors.Trace(err)
 		}
+		if c.changefeedQuota != nil {
+			c.changefeedQuota.forceConsume(size)
+		}
 	}
 
 	c.mu.Lock()
@@ -216,11 +329,17 @@ func (c *TableFlowController) Release(resolvedTs uint64) {
 	c.mu.Unlock()
 
 	c.memoryQuota.Release(nBytesToRelease)
+	if c.changefeedQuota != nil && nBytesToRelease > 0 {
+		c.changefeedQuota.release(nBytesToRelease)
+	}
 }
 
 // Abort interrupts any ongoing Consume call
 func (c *TableFlowController) Abort() {
 	c.memoryQuota.Abort()
+	if c.changefeedQuota != nil {
+		c.changefeedQuota.wakeUp()
+	}
 }
 
 // GetConsumption returns the current memory consumption
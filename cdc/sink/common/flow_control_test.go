@@ -178,7 +178,7 @@ func (s *flowControlSuite) TestFlowControlBasic(c *check.C) {
 	defer cancel()
 	errg, ctx := errgroup.WithContext(ctx)
 	mockedRowsCh := make(chan *commitTsSizeEntry, 1024)
-	flowController := NewTableFlowController(2048)
+	flowController := NewTableFlowController(2048, nil)
 
 	errg.Go(func() error {
 		lastCommitTs := uint64(1)
@@ -288,7 +288,7 @@ func (s *flowControlSuite) TestFlowControlAbort(c *check.C) {
 	defer testleak.AfterTest(c)()
 
 	callBacker := &mockCallBacker{}
-	controller := NewTableFlowController(1024)
+	controller := NewTableFlowController(1024, nil)
 	var wg sync.WaitGroup
 	wg.Add(1)
 	go func() {
@@ -318,7 +318,7 @@ func (s *flowControlSuite) TestFlowControlCallBack(c *check.C) {
 	defer cancel()
 	errg, ctx := errgroup.WithContext(ctx)
 	mockedRowsCh := make(chan *commitTsSizeEntry, 1024)
-	flowController := NewTableFlowController(512)
+	flowController := NewTableFlowController(512, nil)
 
 	errg.Go(func() error {
 		lastCommitTs := uint64(1)
@@ -421,7 +421,7 @@ func (s *flowControlSuite) TestFlowControlCallBackNotBlockingRelease(c *check.C)
 	defer testleak.AfterTest(c)()
 
 	var wg sync.WaitGroup
-	controller := NewTableFlowController(512)
+	controller := NewTableFlowController(512, nil)
 	wg.Add(1)
 
 	ctx, cancel := context.WithCancel(context.TODO())
@@ -463,7 +463,7 @@ func (s *flowControlSuite) TestFlowControlCallBackError(c *check.C) {
 	defer testleak.AfterTest(c)()
 
 	var wg sync.WaitGroup
-	controller := NewTableFlowController(512)
+	controller := NewTableFlowController(512, nil)
 	wg.Add(1)
 
 	ctx, cancel := context.WithCancel(context.TODO())
@@ -492,7 +492,7 @@ func (s *flowControlSuite) TestFlowControlCallBackError(c *check.C) {
 func (s *flowControlSuite) TestFlowControlConsumeLargerThanQuota(c *check.C) {
 	defer testleak.AfterTest(c)()
 
-	controller := NewTableFlowController(1024)
+	controller := NewTableFlowController(1024, nil)
 	err := controller.Consume(1, 2048, func() error {
 		c.Fatalf("unreachable")
 		return nil
@@ -505,7 +505,7 @@ func BenchmarkTableFlowController(B *testing.B) {
 	defer cancel()
 	errg, ctx := errgroup.WithContext(ctx)
 	mockedRowsCh := make(chan *commitTsSizeEntry, 102400)
-	flowController := NewTableFlowController(20 * 1024 * 1024) // 20M
+	flowController := NewTableFlowController(20*1024*1024, nil) // 20M
 
 	errg.Go(func() error {
 		lastCommitTs := uint64(1)
@@ -598,3 +598,83 @@ func BenchmarkTableFlowController(B *testing.B) {
 		return nil
 	})
 }
+
+func (s *flowControlSuite) TestChangefeedMemoryQuota(c *check.C) {
+	defer testleak.AfterTest(c)()
+
+	changefeedQuota := NewChangefeedMemoryQuota(1024)
+	table1 := NewTableFlowController(4096, changefeedQuota)
+	table2 := NewTableFlowController(4096, changefeedQuota)
+
+	c.Assert(table1.Consume(1, 600, dummyCallBack), check.IsNil)
+	// The table holding no memory isn't blocked even if the quota is exceeded.
+	c.Assert(table2.Consume(1, 600, dummyCallBack), check.IsNil)
+	c.Assert(changefeedQuota.GetConsumption(), check.Equals, uint64(1200))
+
+	// The table holding memory is blocked until the quota is available.
+	callBacker := &mockCallBacker{}
+	done := make(chan error, 1)
+	go func() {
+		done <- table1.Consume(2, 100, callBacker.cb)
+	}()
+	select {
+	case <-done:
+		c.Fatal("the table is not blocked by the changefeed quota")
+	case <-time.After(100 * time.Millisecond):
+	}
+	table2.Release(1)
+	c.Assert(<-done, check.IsNil)
+	c.Assert(callBacker.timesCalled, check.Equals, 1)
+	c.Assert(changefeedQuota.GetConsumption(), check.Equals, uint64(700))
+
+	// The blocked table is woken up by Abort.
+	c.Assert(table2.Consume(2, 500, dummyCallBack), check.IsNil)
+	go func() {
+		done <- table1.Consume(3, 100, dummyCallBack)
+	}()
+	time.Sleep(100 * time.Millisecond)
+	table1.Abort()
+	c.Assert(<-done, check.ErrorMatches, ".*ErrFlowControllerAborted.*")
+	c.Assert(table1.GetConsumption(), check.Equals, uint64(700))
+	c.Assert(changefeedQuota.GetConsumption(), check.Equals, uint64(1200))
+
+	table1.Release(3)
+	table2.Release(2)
+	c.Assert(changefeedQuota.GetConsumption(), check.Equals, uint64(0))
+}
+
+func (s *flowControlSuite) TestChangefeedMemoryQuotaUnlimited(c *check.C) {
+	defer testleak.AfterTest(c)()
+
+	// The changefeeds without a memory quota are only limited by the per-table
+	// quota, the consumption is still tracked.
+	changefeedQuota := NewChangefeedMemoryQuota(0)
+	table1 := NewTableFlowController(1024, changefeedQuota)
+	table2 := NewTableFlowController(1024, changefeedQuota)
+
+	c.Assert(table1.Consume(1, 600, dummyCallBack), check.IsNil)
+	c.Assert(table1.Consume(2, 400, dummyCallBack), check.IsNil)
+	c.Assert(table2.Consume(1, 600, dummyCallBack), check.IsNil)
+	c.Assert(table2.Consume(2, 400, dummyCallBack), check.IsNil)
+	c.Assert(changefeedQuota.GetConsumption(), check.Equals, uint64(2000))
+
+	// The table is still blocked by its own quota.
+	callBacker := &mockCallBacker{}
+	done := make(chan error, 1)
+	go func() {
+		done <- table1.Consume(3, 100, callBacker.cb)
+	}()
+	select {
+	case <-done:
+		c.Fatal("the table is not blocked by the per-table quota")
+	case <-time.After(100 * time.Millisecond):
+	}
+	table1.Release(1)
+	c.Assert(<-done, check.IsNil)
+	c.Assert(callBacker.timesCalled, check.Equals, 1)
+	c.Assert(changefeedQuota.GetConsumption(), check.Equals, uint64(1500))
+
+	table1.Release(3)
+	table2.Release(2)
+	c.Assert(changefeedQuota.GetConsumption(), check.Equals, uint64(0))
+}
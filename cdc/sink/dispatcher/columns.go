@@ -0,0 +1,91 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dispatcher
+
+import (
+	"strings"
+
+	"github.com/pingcap/tiflow/cdc/model"
+	cerror "github.com/pingcap/tiflow/pkg/errors"
+	"github.com/pingcap/tiflow/pkg/hash"
+)
+
+// columnsDispatcher dispatches rows by the values of the specified columns,
+// so that the rows with the same values are always sent to the same partition.
+type columnsDispatcher struct {
+	hasher  *hash.PositionInertia
+	columns []string
+}
+
+func newColumnsDispatcher(columns []string) *columnsDispatcher {
+	return &columnsDispatcher{
+		hasher:  hash.NewPositionInertia(),
+		columns: columns,
+	}
+}
+
+// Dispatch dispatches the row by the new values, or by the old values for delete events.
+func (r *columnsDispatcher) Dispatch(row *model.RowChangedEvent, partitionNum int32) int32 {
+	dispatchCols := row.Columns
+	if len(row.Columns) == 0 {
+		dispatchCols = row.PreColumns
+	}
+	return r.dispatchColumns(row.Table, dispatchCols, partitionNum)
+}
+
+// dispatchPreColumns dispatches the row by the old values.
+func (r *columnsDispatcher) dispatchPreColumns(row *model.RowChangedEvent, partitionNum int32) int32 {
+	return r.dispatchColumns(row.Table, row.PreColumns, partitionNum)
+}
+
+func (r *columnsDispatcher) dispatchColumns(
+	table *model.TableName, cols []*model.Column, partitionNum int32,
+) int32 {
+	r.hasher.Reset()
+	r.hasher.Write([]byte(table.Schema), []byte(table.Table))
+	for _, name := range r.columns {
+		for _, col := range cols {
+			if col == nil || !strings.EqualFold(col.Name, name) {
+				continue
+			}
+			r.hasher.Write([]byte(col.Name), []byte(model.ColumnValueString(col.Value)))
+			break
+		}
+	}
+	return int32(r.hasher.Sum32() % uint32(partitionNum))
+}
+
+// verifyTable checks that the dispatch columns exist in the table and are not null.
+func (r *columnsDispatcher) verifyTable(tableInfo *model.TableInfo) error {
+	for _, name := range r.columns {
+		found := false
+		for _, colInfo := range tableInfo.Columns {
+			if !model.IsColCDCVisible(colInfo) || !strings.EqualFold(colInfo.Name.O, name) {
+				continue
+			}
+			flag := tableInfo.ColumnsFlag[colInfo.ID]
+			if flag.IsNullable() {
+				return cerror.ErrDispatchColumnInvalid.GenWithStackByArgs(
+					name, tableInfo.TableName.String(), "the column can be null")
+			}
+			found = true
+			break
+		}
+		if !found {
+			return cerror.ErrDispatchColumnInvalid.GenWithStackByArgs(
+				name, tableInfo.TableName.String(), "the column is not found")
+		}
+	}
+	return nil
+}
@@ -0,0 +1,98 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dispatcher
+
+import (
+	"testing"
+
+	timodel "github.com/pingcap/tidb/parser/model"
+	"github.com/pingcap/tidb/parser/mysql"
+	parser_types "github.com/pingcap/tidb/parser/types"
+	"github.com/pingcap/tiflow/cdc/model"
+	cerror "github.com/pingcap/tiflow/pkg/errors"
+	"github.com/stretchr/testify/require"
+)
+
+func newTenantRow(id, tenantID int) *model.RowChangedEvent {
+	return &model.RowChangedEvent{
+		Table: &model.TableName{Schema: "test", Table: "t1"},
+		Columns: []*model.Column{
+			{Name: "id", Value: id, Flag: model.HandleKeyFlag},
+			{Name: "tenant_id", Value: tenantID},
+		},
+	}
+}
+
+func TestColumnsDispatcher(t *testing.T) {
+	t.Parallel()
+
+	d := newColumnsDispatcher([]string{"Tenant_ID"})
+	// rows of the same tenant are dispatched to the same partition.
+	p := d.Dispatch(newTenantRow(1, 1), 16)
+	for i := 2; i < 100; i++ {
+		require.Equal(t, p, d.Dispatch(newTenantRow(i, 1), 16))
+	}
+
+	// rows of different tenants are spread over the partitions.
+	partitions := make(map[int32]struct{})
+	for i := 0; i < 100; i++ {
+		partitions[d.Dispatch(newTenantRow(1, i), 16)] = struct{}{}
+	}
+	require.Greater(t, len(partitions), 1)
+
+	// delete events are dispatched by the old values.
+	row := newTenantRow(1, 1)
+	row.PreColumns, row.Columns = row.Columns, nil
+	require.Equal(t, p, d.Dispatch(row, 16))
+	require.Equal(t, p, d.dispatchPreColumns(row, 16))
+}
+
+func TestColumnsDispatcherVerifyTable(t *testing.T) {
+	t.Parallel()
+
+	tableInfo := model.WrapTableInfo(1, "test", 0, &timodel.TableInfo{
+		Name: timodel.CIStr{O: "t1"},
+		Columns: []*timodel.ColumnInfo{
+			{
+				ID:        1,
+				Name:      timodel.CIStr{O: "id"},
+				FieldType: parser_types.FieldType{Flag: mysql.PriKeyFlag | mysql.NotNullFlag},
+				State:     timodel.StatePublic,
+			},
+			{
+				ID:        2,
+				Name:      timodel.CIStr{O: "tenant_id"},
+				FieldType: parser_types.FieldType{Flag: mysql.NotNullFlag},
+				State:     timodel.StatePublic,
+			},
+			{
+				ID:    3,
+				Name:  timodel.CIStr{O: "name"},
+				State: timodel.StatePublic,
+			},
+		},
+		PKIsHandle: true,
+	})
+
+	require.Nil(t, newColumnsDispatcher([]string{"tenant_id"}).verifyTable(tableInfo))
+	require.Nil(t, newColumnsDispatcher([]string{"TENANT_ID", "id"}).verifyTable(tableInfo))
+
+	err := newColumnsDispatcher([]string{"name"}).verifyTable(tableInfo)
+	require.True(t, cerror.ErrDispatchColumnInvalid.Equal(err))
+	require.Regexp(t, "can be null", err)
+
+	err = newColumnsDispatcher([]string{"tenant_id", "org_id"}).verifyTable(tableInfo)
+	require.True(t, cerror.ErrDispatchColumnInvalid.Equal(err))
+	require.Regexp(t, "not found", err)
+}
@@ -18,27 +18,25 @@ import (
 )
 
 type defaultDispatcher struct {
-	partitionNum   int32
 	tbd            *tableDispatcher
 	ivd            *indexValueDispatcher
 	enableOldValue bool
 }
 
-func newDefaultDispatcher(partitionNum int32, enableOldValue bool) *defaultDispatcher {
+func newDefaultDispatcher(enableOldValue bool) *defaultDispatcher {
 	return &defaultDispatcher{
-		partitionNum:   partitionNum,
-		tbd:            newTableDispatcher(partitionNum),
-		ivd:            newIndexValueDispatcher(partitionNum),
+		tbd:            newTableDispatcher(),
+		ivd:            newIndexValueDispatcher(),
 		enableOldValue: enableOldValue,
 	}
 }
 
-func (d *defaultDispatcher) Dispatch(row *model.RowChangedEvent) int32 {
+func (d *defaultDispatcher) Dispatch(row *model.RowChangedEvent, partitionNum int32) int32 {
 	if d.enableOldValue {
-		return d.tbd.Dispatch(row)
+		return d.tbd.Dispatch(row, partitionNum)
 	}
 	if len(row.IndexColumns) != 1 {
-		return d.tbd.Dispatch(row)
+		return d.tbd.Dispatch(row, partitionNum)
 	}
-	return d.ivd.Dispatch(row)
+	return d.ivd.Dispatch(row, partitionNum)
 }
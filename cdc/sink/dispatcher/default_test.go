@@ -193,9 +193,9 @@ func TestDefaultDispatcher(t *testing.T) {
 			IndexColumns: [][]int{{0}, {1}},
 		}, exceptPartition: 3},
 	}
-	p := newDefaultDispatcher(16, false)
+	p := newDefaultDispatcher(false)
 	for _, tc := range testCases {
-		require.Equal(t, tc.exceptPartition, p.Dispatch(tc.row))
+		require.Equal(t, tc.exceptPartition, p.Dispatch(tc.row, 16))
 	}
 }
 
@@ -221,6 +221,6 @@ func TestDefaultDispatcherWithOldValue(t *testing.T) {
 		IndexColumns: [][]int{{0}, {1}},
 	}
 
-	p := newDefaultDispatcher(16, true)
-	require.Equal(t, int32(3), p.Dispatch(row))
+	p := newDefaultDispatcher(true)
+	require.Equal(t, int32(3), p.Dispatch(row, 16))
 }
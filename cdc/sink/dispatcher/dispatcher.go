@@ -27,7 +27,8 @@ import (
 // Dispatcher is an abstraction for dispatching rows into different partitions
 type Dispatcher interface {
 	// Dispatch returns an index of partitions according to RowChangedEvent
-	Dispatch(row *model.RowChangedEvent) int32
+	// and the partition number of the target topic.
+	Dispatch(row *model.RowChangedEvent, partitionNum int32) int32
 }
 
 type dispatchRule int
@@ -38,6 +39,7 @@ const (
 	dispatchRuleTS
 	dispatchRuleTable
 	dispatchRuleIndexValue
+	dispatchRuleColumns
 )
 
 func (r *dispatchRule) fromString(rule string) {
@@ -52,42 +54,35 @@ func (r *dispatchRule) fromString(rule string) {
 		*r = dispatchRuleTable
 	case "index-value":
 		*r = dispatchRuleIndexValue
+	case "columns":
+		*r = dispatchRuleColumns
 	default:
 		*r = dispatchRuleDefault
 		log.Warn("can't support dispatch rule, using default rule", zap.String("rule", rule))
 	}
 }
 
-type dispatcherSwitcher struct {
-	rules []struct {
-		Dispatcher
+// EventRouter is a router that routes events to the topic and partition
+// matched by the dispatch rules.
+type EventRouter struct {
+	defaultTopic string
+	rules        []struct {
+		partitionDispatcher Dispatcher
+		topicDispatcher     TopicDispatcher
 		filter.Filter
 	}
 }
 
-func (s *dispatcherSwitcher) Dispatch(row *model.RowChangedEvent) int32 {
-	return s.matchDispatcher(row).Dispatch(row)
-}
-
-func (s *dispatcherSwitcher) matchDispatcher(row *model.RowChangedEvent) Dispatcher {
-	for _, rule := range s.rules {
-		if !rule.MatchTable(row.Table.Schema, row.Table.Table) {
-			continue
-		}
-		return rule.Dispatcher
-	}
-	log.Panic("the dispatch rule must cover all tables")
-	return nil
-}
-
-// NewDispatcher creates a new dispatcher
-func NewDispatcher(cfg *config.ReplicaConfig, partitionNum int32) (Dispatcher, error) {
+// NewEventRouter creates a new EventRouter, events of the tables which are not
+// matched by any topic rule are dispatched to the defaultTopic.
+func NewEventRouter(cfg *config.ReplicaConfig, defaultTopic string) (*EventRouter, error) {
 	ruleConfigs := append(cfg.Sink.DispatchRules, &config.DispatchRule{
 		Matcher:    []string{"*.*"},
 		Dispatcher: "default",
 	})
 	rules := make([]struct {
-		Dispatcher
+		partitionDispatcher Dispatcher
+		topicDispatcher     TopicDispatcher
 		filter.Filter
 	}, 0, len(ruleConfigs))
 
@@ -99,30 +94,167 @@ func NewDispatcher(cfg *config.ReplicaConfig, partitionNum int32) (Dispatcher, e
 		if !cfg.CaseSensitive {
 			f = filter.CaseInsensitive(f)
 		}
-		var d Dispatcher
-		var rule dispatchRule
-		rule.fromString(ruleConfig.Dispatcher)
-		switch rule {
-		case dispatchRuleRowID, dispatchRuleIndexValue:
-			if cfg.EnableOldValue {
-				log.Warn("This index-value distribution mode " +
-					"does not guarantee row-level orderliness when " +
-					"switching on the old value, so please use caution!")
-			}
-			d = newIndexValueDispatcher(partitionNum)
-		case dispatchRuleTS:
-			d = newTsDispatcher(partitionNum)
-		case dispatchRuleTable:
-			d = newTableDispatcher(partitionNum)
-		case dispatchRuleDefault:
-			d = newDefaultDispatcher(partitionNum, cfg.EnableOldValue)
+		td, err := getTopicDispatcher(ruleConfig.TopicRule, defaultTopic)
+		if err != nil {
+			return nil, err
+		}
+		pd, err := getPartitionDispatcher(ruleConfig, cfg.EnableOldValue)
+		if err != nil {
+			return nil, err
 		}
 		rules = append(rules, struct {
-			Dispatcher
+			partitionDispatcher Dispatcher
+			topicDispatcher     TopicDispatcher
 			filter.Filter
-		}{Dispatcher: d, Filter: f})
+		}{
+			partitionDispatcher: pd,
+			topicDispatcher:     td,
+			Filter:              f,
+		})
 	}
-	return &dispatcherSwitcher{
-		rules: rules,
+	return &EventRouter{
+		defaultTopic: defaultTopic,
+		rules:        rules,
 	}, nil
 }
+
+// GetTopicForRowChange returns the target topic for the row changed event.
+func (s *EventRouter) GetTopicForRowChange(row *model.RowChangedEvent) string {
+	topicDispatcher, _ := s.matchDispatcher(row.Table.Schema, row.Table.Table)
+	return topicDispatcher.Substitute(row.Table.Schema, row.Table.Table)
+}
+
+// GetTopicForDDL returns the target topic for the DDL event, the DDLs which
+// are not bound to a table (such as `CREATE DATABASE`) go to the default topic.
+func (s *EventRouter) GetTopicForDDL(ddl *model.DDLEvent) string {
+	var schema, table string
+	if ddl.PreTableInfo != nil {
+		schema, table = ddl.PreTableInfo.Schema, ddl.PreTableInfo.Table
+	} else {
+		schema, table = ddl.TableInfo.Schema, ddl.TableInfo.Table
+	}
+	if table == "" {
+		return s.defaultTopic
+	}
+	topicDispatcher, _ := s.matchDispatcher(schema, table)
+	return topicDispatcher.Substitute(schema, table)
+}
+
+// GetPartitionForRowChange returns the target partition for the row changed event.
+func (s *EventRouter) GetPartitionForRowChange(row *model.RowChangedEvent, partitionNum int32) int32 {
+	_, partitionDispatcher := s.matchDispatcher(row.Table.Schema, row.Table.Table)
+	return partitionDispatcher.Dispatch(row, partitionNum)
+}
+
+// SplitUpdateEvent splits the update event into a delete event of the old values
+// and an insert event of the new values, if they are dispatched into different
+// partitions by the `columns` dispatcher. So that the events with the same column
+// values are always sent to one partition in order. Otherwise the event is returned as is.
+func (s *EventRouter) SplitUpdateEvent(row *model.RowChangedEvent, partitionNum int32) []*model.RowChangedEvent {
+	if !row.IsUpdate() {
+		return []*model.RowChangedEvent{row}
+	}
+	_, partitionDispatcher := s.matchDispatcher(row.Table.Schema, row.Table.Table)
+	d, ok := partitionDispatcher.(*columnsDispatcher)
+	if !ok || d.dispatchPreColumns(row, partitionNum) == d.Dispatch(row, partitionNum) {
+		return []*model.RowChangedEvent{row}
+	}
+
+	deleteEvent := *row
+	deleteEvent.Columns = nil
+	insertEvent := *row
+	insertEvent.PreColumns = nil
+	return []*model.RowChangedEvent{&deleteEvent, &insertEvent}
+}
+
+// VerifyTable checks that the columns used by the `columns` dispatcher
+// exist in the table and are not null.
+func (s *EventRouter) VerifyTable(tableInfo *model.TableInfo) error {
+	_, partitionDispatcher := s.matchDispatcher(tableInfo.TableName.Schema, tableInfo.TableName.Table)
+	if d, ok := partitionDispatcher.(*columnsDispatcher); ok {
+		return d.verifyTable(tableInfo)
+	}
+	return nil
+}
+
+// GetDispatchColumns returns the columns used to dispatch the events of the table,
+// it's empty if the table isn't dispatched by the `columns` dispatcher.
+func (s *EventRouter) GetDispatchColumns(schema, table string) []string {
+	_, partitionDispatcher := s.matchDispatcher(schema, table)
+	if d, ok := partitionDispatcher.(*columnsDispatcher); ok {
+		return d.columns
+	}
+	return nil
+}
+
+// GetActiveTopics returns the deduplicated topics of the given tables,
+// the default topic is always included.
+func (s *EventRouter) GetActiveTopics(activeTables []model.TableName) []string {
+	topics := []string{s.defaultTopic}
+	seen := map[string]struct{}{s.defaultTopic: {}}
+	for _, table := range activeTables {
+		topicDispatcher, _ := s.matchDispatcher(table.Schema, table.Table)
+		topic := topicDispatcher.Substitute(table.Schema, table.Table)
+		if _, ok := seen[topic]; ok {
+			continue
+		}
+		seen[topic] = struct{}{}
+		topics = append(topics, topic)
+	}
+	return topics
+}
+
+// GetDefaultTopic returns the default topic.
+func (s *EventRouter) GetDefaultTopic() string {
+	return s.defaultTopic
+}
+
+func (s *EventRouter) matchDispatcher(schema, table string) (TopicDispatcher, Dispatcher) {
+	for _, rule := range s.rules {
+		if !rule.MatchTable(schema, table) {
+			continue
+		}
+		return rule.topicDispatcher, rule.partitionDispatcher
+	}
+	log.Panic("the dispatch rule must cover all tables")
+	return nil, nil
+}
+
+func getPartitionDispatcher(ruleConfig *config.DispatchRule, enableOldValue bool) (Dispatcher, error) {
+	var rule dispatchRule
+	rule.fromString(ruleConfig.Dispatcher)
+	switch rule {
+	case dispatchRuleRowID, dispatchRuleIndexValue:
+		if enableOldValue {
+			log.Warn("This index-value distribution mode " +
+				"does not guarantee row-level orderliness when " +
+				"switching on the old value, so please use caution!")
+		}
+		return newIndexValueDispatcher(), nil
+	case dispatchRuleTS:
+		return newTsDispatcher(), nil
+	case dispatchRuleTable:
+		return newTableDispatcher(), nil
+	case dispatchRuleColumns:
+		if len(ruleConfig.Columns) == 0 {
+			return nil, cerror.ErrSinkInvalidConfig.GenWithStack(
+				"columns dispatcher requires the columns, matcher: %v", ruleConfig.Matcher)
+		}
+		return newColumnsDispatcher(ruleConfig.Columns), nil
+	default:
+		return newDefaultDispatcher(enableOldValue), nil
+	}
+}
+
+// getTopicDispatcher returns the topic dispatcher for the topic rule,
+// an empty rule means the events are sent to the default topic.
+func getTopicDispatcher(topicRule string, defaultTopic string) (TopicDispatcher, error) {
+	if topicRule == "" {
+		return newStaticTopicDispatcher(defaultTopic), nil
+	}
+	expression := TopicExpression(topicRule)
+	if err := expression.Validate(); err != nil {
+		return nil, err
+	}
+	return newDynamicTopicDispatcher(expression), nil
+}
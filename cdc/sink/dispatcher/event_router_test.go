@@ -0,0 +1,175 @@
+// Copyright 2020 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dispatcher
+
+import (
+	"testing"
+
+	"github.com/pingcap/tiflow/cdc/model"
+	"github.com/pingcap/tiflow/pkg/config"
+	cerror "github.com/pingcap/tiflow/pkg/errors"
+	"github.com/stretchr/testify/require"
+)
+
+func partitionDispatcherOf(r *EventRouter, schema, table string) Dispatcher {
+	_, d := r.matchDispatcher(schema, table)
+	return d
+}
+
+func TestEventRouter(t *testing.T) {
+	t.Parallel()
+
+	d, err := NewEventRouter(config.GetDefaultReplicaConfig(), "test")
+	require.Nil(t, err)
+	require.IsType(t, &defaultDispatcher{}, partitionDispatcherOf(d, "test", "test"))
+
+	d, err = NewEventRouter(&config.ReplicaConfig{
+		Sink: &config.SinkConfig{
+			DispatchRules: []*config.DispatchRule{
+				{Matcher: []string{"test_default1.*"}, Dispatcher: "default"},
+				{Matcher: []string{"test_default2.*"}, Dispatcher: "unknown-dispatcher"},
+				{Matcher: []string{"test_table.*"}, Dispatcher: "table"},
+				{Matcher: []string{"test_index_value.*"}, Dispatcher: "index-value"},
+				{Matcher: []string{"test.*"}, Dispatcher: "rowid"},
+				{Matcher: []string{"*.*", "!*.test"}, Dispatcher: "ts"},
+			},
+		},
+	}, "test")
+	require.Nil(t, err)
+	require.IsType(t, &indexValueDispatcher{}, partitionDispatcherOf(d, "test", "table1"))
+	require.IsType(t, &tsDispatcher{}, partitionDispatcherOf(d, "sbs", "table2"))
+	require.IsType(t, &defaultDispatcher{}, partitionDispatcherOf(d, "sbs", "test"))
+	require.IsType(t, &defaultDispatcher{}, partitionDispatcherOf(d, "test_default1", "test"))
+	require.IsType(t, &defaultDispatcher{}, partitionDispatcherOf(d, "test_default2", "test"))
+	require.IsType(t, &tableDispatcher{}, partitionDispatcherOf(d, "test_table", "test"))
+	require.IsType(t, &indexValueDispatcher{}, partitionDispatcherOf(d, "test_index_value", "test"))
+}
+
+func TestEventRouterTopic(t *testing.T) {
+	t.Parallel()
+
+	r, err := NewEventRouter(&config.ReplicaConfig{
+		Sink: &config.SinkConfig{
+			DispatchRules: []*config.DispatchRule{
+				{Matcher: []string{"test_default.*"}, Dispatcher: "default"},
+				{Matcher: []string{"test_table.*"}, Dispatcher: "table", TopicRule: "hello_{schema}"},
+				{Matcher: []string{"test.*"}, Dispatcher: "ts", TopicRule: "{schema}_{table}"},
+			},
+		},
+	}, "default_topic")
+	require.Nil(t, err)
+	require.Equal(t, "default_topic", r.GetDefaultTopic())
+
+	row := &model.RowChangedEvent{
+		Table:    &model.TableName{Schema: "test", Table: "t1"},
+		CommitTs: 5,
+	}
+	require.Equal(t, "test_t1", r.GetTopicForRowChange(row))
+	require.Equal(t, int32(1), r.GetPartitionForRowChange(row, 4))
+	require.Equal(t, int32(5), r.GetPartitionForRowChange(row, 16))
+
+	row.Table = &model.TableName{Schema: "test_table", Table: "t1"}
+	require.Equal(t, "hello_test_table", r.GetTopicForRowChange(row))
+	row.Table = &model.TableName{Schema: "test_default", Table: "t1"}
+	require.Equal(t, "default_topic", r.GetTopicForRowChange(row))
+	row.Table = &model.TableName{Schema: "other", Table: "t1"}
+	require.Equal(t, "default_topic", r.GetTopicForRowChange(row))
+
+	// DDL events which are not bound to a table go to the default topic,
+	// and rename table DDL goes to the topic of the old table.
+	require.Equal(t, "default_topic", r.GetTopicForDDL(&model.DDLEvent{
+		TableInfo: &model.SimpleTableInfo{Schema: "test"},
+	}))
+	require.Equal(t, "test_t1", r.GetTopicForDDL(&model.DDLEvent{
+		TableInfo: &model.SimpleTableInfo{Schema: "test", Table: "t1"},
+	}))
+	require.Equal(t, "test_t1", r.GetTopicForDDL(&model.DDLEvent{
+		PreTableInfo: &model.SimpleTableInfo{Schema: "test", Table: "t1"},
+		TableInfo:    &model.SimpleTableInfo{Schema: "test", Table: "t2"},
+	}))
+
+	require.Equal(t, []string{"default_topic", "test_t1", "hello_test_table"},
+		r.GetActiveTopics([]model.TableName{
+			{Schema: "test", Table: "t1"},
+			{Schema: "test_table", Table: "t1"},
+			{Schema: "test_table", Table: "t2"},
+			{Schema: "other", Table: "t1"},
+		}))
+
+	_, err = NewEventRouter(&config.ReplicaConfig{
+		Sink: &config.SinkConfig{
+			DispatchRules: []*config.DispatchRule{
+				{Matcher: []string{"test.*"}, TopicRule: "{table}_{schema}"},
+			},
+		},
+	}, "default_topic")
+	require.Regexp(t, "invalid topic expression", err)
+}
+
+func TestEventRouterColumns(t *testing.T) {
+	t.Parallel()
+
+	r, err := NewEventRouter(&config.ReplicaConfig{
+		Sink: &config.SinkConfig{
+			DispatchRules: []*config.DispatchRule{
+				{Matcher: []string{"test.*"}, Dispatcher: "columns", Columns: []string{"tenant_id"}},
+			},
+		},
+	}, "default_topic")
+	require.Nil(t, err)
+	require.IsType(t, &columnsDispatcher{}, partitionDispatcherOf(r, "test", "t1"))
+	require.Equal(t, []string{"tenant_id"}, r.GetDispatchColumns("test", "t1"))
+	require.Nil(t, r.GetDispatchColumns("other", "t1"))
+
+	// find two tenants which are dispatched into different partitions.
+	oldTenant, newTenant := 0, 1
+	for r.GetPartitionForRowChange(newTenantRow(1, oldTenant), 16) ==
+		r.GetPartitionForRowChange(newTenantRow(1, newTenant), 16) {
+		newTenant++
+	}
+
+	// an update event which moves the row to another partition is split.
+	row := newTenantRow(1, newTenant)
+	row.PreColumns = newTenantRow(1, oldTenant).Columns
+	events := r.SplitUpdateEvent(row, 16)
+	require.Len(t, events, 2)
+	require.True(t, events[0].IsDelete())
+	require.True(t, events[1].IsInsert())
+	require.Equal(t, r.GetPartitionForRowChange(newTenantRow(1, oldTenant), 16),
+		r.GetPartitionForRowChange(events[0], 16))
+	require.Equal(t, r.GetPartitionForRowChange(newTenantRow(1, newTenant), 16),
+		r.GetPartitionForRowChange(events[1], 16))
+	// the input event is not modified.
+	require.True(t, row.IsUpdate())
+
+	// an update event in the same partition is not split.
+	row = newTenantRow(2, oldTenant)
+	row.PreColumns = newTenantRow(1, oldTenant).Columns
+	require.Equal(t, []*model.RowChangedEvent{row}, r.SplitUpdateEvent(row, 16))
+
+	// the events which are not dispatched by the columns are not split.
+	row = newTenantRow(1, newTenant)
+	row.PreColumns = newTenantRow(1, oldTenant).Columns
+	row.Table = &model.TableName{Schema: "other", Table: "t1"}
+	require.Equal(t, []*model.RowChangedEvent{row}, r.SplitUpdateEvent(row, 16))
+
+	_, err = NewEventRouter(&config.ReplicaConfig{
+		Sink: &config.SinkConfig{
+			DispatchRules: []*config.DispatchRule{
+				{Matcher: []string{"test.*"}, Dispatcher: "columns"},
+			},
+		},
+	}, "default_topic")
+	require.True(t, cerror.ErrSinkInvalidConfig.Equal(err))
+}
@@ -19,18 +19,16 @@ import (
 )
 
 type indexValueDispatcher struct {
-	partitionNum int32
-	hasher       *hash.PositionInertia
+	hasher *hash.PositionInertia
 }
 
-func newIndexValueDispatcher(partitionNum int32) *indexValueDispatcher {
+func newIndexValueDispatcher() *indexValueDispatcher {
 	return &indexValueDispatcher{
-		partitionNum: partitionNum,
-		hasher:       hash.NewPositionInertia(),
+		hasher: hash.NewPositionInertia(),
 	}
 }
 
-func (r *indexValueDispatcher) Dispatch(row *model.RowChangedEvent) int32 {
+func (r *indexValueDispatcher) Dispatch(row *model.RowChangedEvent, partitionNum int32) int32 {
 	r.hasher.Reset()
 	r.hasher.Write([]byte(row.Table.Schema), []byte(row.Table.Table))
 	// FIXME(leoppro): if the row events includes both pre-cols and cols
@@ -49,5 +47,5 @@ func (r *indexValueDispatcher) Dispatch(row *model.RowChangedEvent) int32 {
 			r.hasher.Write([]byte(col.Name), []byte(model.ColumnValueString(col.Value)))
 		}
 	}
-	return int32(r.hasher.Sum32() % uint32(r.partitionNum))
+	return int32(r.hasher.Sum32() % uint32(partitionNum))
 }
@@ -147,8 +147,8 @@ func TestIndexValueDispatcher(t *testing.T) {
 			},
 		}, exceptPartition: 2},
 	}
-	p := newIndexValueDispatcher(16)
+	p := newIndexValueDispatcher()
 	for _, tc := range testCases {
-		require.Equal(t, tc.exceptPartition, p.Dispatch(tc.row))
+		require.Equal(t, tc.exceptPartition, p.Dispatch(tc.row, 16))
 	}
 }
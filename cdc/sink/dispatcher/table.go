@@ -19,20 +19,18 @@ import (
 )
 
 type tableDispatcher struct {
-	partitionNum int32
-	hasher       *hash.PositionInertia
+	hasher *hash.PositionInertia
 }
 
-func newTableDispatcher(partitionNum int32) *tableDispatcher {
+func newTableDispatcher() *tableDispatcher {
 	return &tableDispatcher{
-		partitionNum: partitionNum,
-		hasher:       hash.NewPositionInertia(),
+		hasher: hash.NewPositionInertia(),
 	}
 }
 
-func (t *tableDispatcher) Dispatch(row *model.RowChangedEvent) int32 {
+func (t *tableDispatcher) Dispatch(row *model.RowChangedEvent, partitionNum int32) int32 {
 	t.hasher.Reset()
 	// distribute partition by table
 	t.hasher.Write([]byte(row.Table.Schema), []byte(row.Table.Table))
-	return int32(t.hasher.Sum32() % uint32(t.partitionNum))
+	return int32(t.hasher.Sum32() % uint32(partitionNum))
 }
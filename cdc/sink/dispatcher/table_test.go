@@ -77,8 +77,8 @@ func TestTableDispatcher(t *testing.T) {
 			CommitTs: 3,
 		}, exceptPartition: 3},
 	}
-	p := newTableDispatcher(16)
+	p := newTableDispatcher()
 	for _, tc := range testCases {
-		require.Equal(t, tc.exceptPartition, p.Dispatch(tc.row))
+		require.Equal(t, tc.exceptPartition, p.Dispatch(tc.row, 16))
 	}
 }
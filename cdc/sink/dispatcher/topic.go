@@ -0,0 +1,52 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dispatcher
+
+// TopicDispatcher is an abstraction for dispatching events into different topics.
+type TopicDispatcher interface {
+	// Substitute returns the topic name for the given schema and table.
+	Substitute(schema, table string) string
+}
+
+// staticTopicDispatcher dispatches all events to the same topic,
+// it's used for the tables without a topic rule.
+type staticTopicDispatcher struct {
+	defaultTopic string
+}
+
+func newStaticTopicDispatcher(defaultTopic string) *staticTopicDispatcher {
+	return &staticTopicDispatcher{
+		defaultTopic: defaultTopic,
+	}
+}
+
+func (s *staticTopicDispatcher) Substitute(schema, table string) string {
+	return s.defaultTopic
+}
+
+// dynamicTopicDispatcher dispatches events into topics calculated
+// by the topic expression.
+type dynamicTopicDispatcher struct {
+	expression TopicExpression
+}
+
+func newDynamicTopicDispatcher(expression TopicExpression) *dynamicTopicDispatcher {
+	return &dynamicTopicDispatcher{
+		expression: expression,
+	}
+}
+
+func (d *dynamicTopicDispatcher) Substitute(schema, table string) string {
+	return d.expression.Substitute(schema, table)
+}
@@ -0,0 +1,69 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dispatcher
+
+import (
+	"regexp"
+	"strings"
+
+	cerror "github.com/pingcap/tiflow/pkg/errors"
+)
+
+// kafkaTopicNameMaxLength is the max length of a Kafka topic name,
+// see https://github.com/apache/kafka/blob/trunk/clients/src/main/java/org/apache/kafka/common/internals/Topic.java
+const kafkaTopicNameMaxLength = 249
+
+var (
+	// topicExpressionRE is used to match a valid topic expression, the
+	// case-insensitive keywords `{schema}` and `{table}` are both optional,
+	// and all the other characters must be valid for a Kafka topic name.
+	topicExpressionRE = regexp.MustCompile(
+		`(?i)^[A-Za-z0-9\._\-]*(\{schema\})?[A-Za-z0-9\._\-]*(\{table\})?[A-Za-z0-9\._\-]*$`,
+	)
+	schemaPlaceholderRE = regexp.MustCompile(`(?i)\{schema\}`)
+	tablePlaceholderRE  = regexp.MustCompile(`(?i)\{table\}`)
+	// invalidTopicCharRE matches the characters which are not allowed in a Kafka topic name.
+	invalidTopicCharRE = regexp.MustCompile(`[^a-zA-Z0-9\._\-]`)
+)
+
+// TopicExpression represents a topic expression such as `prefix_{schema}_{table}`,
+// `{schema}` and `{table}` are replaced by the schema and table name of the event.
+// An expression without any keyword dispatches all the events to a fixed topic.
+type TopicExpression string
+
+// Validate checks whether the expression is valid. Kafka rejects `.` and `..`
+// as topic names, so they're not valid expressions either.
+func (e TopicExpression) Validate() error {
+	if e != "" && e != "." && e != ".." && topicExpressionRE.MatchString(string(e)) {
+		return nil
+	}
+	return cerror.ErrKafkaTopicExprInvalid.GenWithStackByArgs(string(e))
+}
+
+// Substitute converts the expression to a topic name by the schema and table name.
+// Characters that can't be used in a Kafka topic name are replaced by `_`, and
+// the result is truncated to the max length of a Kafka topic name. The names
+// `.` and `..` are rejected by Kafka, their dots are replaced by `_` too.
+func (e TopicExpression) Substitute(schema, table string) string {
+	topicExpr := schemaPlaceholderRE.ReplaceAllLiteralString(string(e), schema)
+	topicExpr = tablePlaceholderRE.ReplaceAllLiteralString(topicExpr, table)
+	topicName := invalidTopicCharRE.ReplaceAllString(topicExpr, "_")
+	if topicName == "." || topicName == ".." {
+		return strings.Repeat("_", len(topicName))
+	}
+	if len(topicName) > kafkaTopicNameMaxLength {
+		return topicName[:kafkaTopicNameMaxLength]
+	}
+	return topicName
+}
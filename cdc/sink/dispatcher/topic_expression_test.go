@@ -0,0 +1,81 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dispatcher
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTopicExpressionValidate(t *testing.T) {
+	t.Parallel()
+
+	validCases := []string{
+		"{schema}",
+		"{table}",
+		"{schema}_{table}",
+		"{Schema}_{TABLE}",
+		"prefix.{schema}-{table}_suffix",
+		"hello_{schema}_world",
+		"static_topic",
+	}
+	for _, expr := range validCases {
+		require.Nil(t, TopicExpression(expr).Validate(), expr)
+	}
+
+	invalidCases := []string{
+		"",
+		"{table}_{schema}",
+		"{schema}_{table}_{table}",
+		"hello#{schema}",
+		"{schema",
+		".",
+		"..",
+	}
+	for _, expr := range invalidCases {
+		require.Regexp(t, "invalid topic expression", TopicExpression(expr).Validate(), expr)
+	}
+}
+
+func TestTopicExpressionSubstitute(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		expression string
+		schema     string
+		table      string
+		expected   string
+	}{
+		{expression: "{schema}_{table}", schema: "test", table: "t1", expected: "test_t1"},
+		{expression: "hello_{schema}", schema: "test", table: "t1", expected: "hello_test"},
+		{expression: "flink{SCHEMA}{Table}", schema: "test", table: "t1", expected: "flinktestt1"},
+		{expression: "test-cdc", schema: "test", table: "t1", expected: "test-cdc"},
+		{expression: "{schema}.{table}", schema: "测试", table: "t 1", expected: "__.t_1"},
+		{expression: "{schema}_{table}", schema: "a$b", table: "c/d", expected: "a_b_c_d"},
+		// `.` and `..` are not valid Kafka topic names.
+		{expression: "{schema}", schema: ".", table: "t1", expected: "_"},
+		{expression: "{table}", schema: "test", table: "..", expected: "__"},
+		{expression: "{schema}{table}", schema: ".", table: ".", expected: "__"},
+		{expression: "{schema}.{table}", schema: "", table: "", expected: "_"},
+		{expression: "{table}", schema: "test", table: "...", expected: "..."},
+	}
+	for _, tc := range testCases {
+		require.Equal(t, tc.expected, TopicExpression(tc.expression).Substitute(tc.schema, tc.table))
+	}
+
+	longName := strings.Repeat("a", kafkaTopicNameMaxLength)
+	require.Len(t, TopicExpression("{schema}_{table}").Substitute(longName, "t1"), kafkaTopicNameMaxLength)
+}
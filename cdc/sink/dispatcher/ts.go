@@ -15,16 +15,12 @@ package dispatcher
 
 import "github.com/pingcap/tiflow/cdc/model"
 
-type tsDispatcher struct {
-	partitionNum int32
-}
+type tsDispatcher struct{}
 
-func newTsDispatcher(partitionNum int32) *tsDispatcher {
-	return &tsDispatcher{
-		partitionNum: partitionNum,
-	}
+func newTsDispatcher() *tsDispatcher {
+	return &tsDispatcher{}
 }
 
-func (t *tsDispatcher) Dispatch(row *model.RowChangedEvent) int32 {
-	return int32(row.CommitTs % uint64(t.partitionNum))
+func (t *tsDispatcher) Dispatch(row *model.RowChangedEvent, partitionNum int32) int32 {
+	return int32(row.CommitTs % uint64(partitionNum))
 }
@@ -70,8 +70,8 @@ func TestTsDispatcher(t *testing.T) {
 			CommitTs: 3,
 		}, exceptPartition: 3},
 	}
-	p := &tsDispatcher{partitionNum: 16}
+	p := newTsDispatcher()
 	for _, tc := range testCases {
-		require.Equal(t, tc.exceptPartition, p.Dispatch(tc.row))
+		require.Equal(t, tc.exceptPartition, p.Dispatch(tc.row, 16))
 	}
 }
@@ -86,7 +86,7 @@ func (c *checkSink) FlushRowChangedEvents(ctx context.Context, tableID model.Tab
 	return c.lastResolvedTs[tableID], nil
 }
 
-func (c *checkSink) EmitCheckpointTs(ctx context.Context, ts uint64) error {
+func (c *checkSink) EmitCheckpointTs(ctx context.Context, ts uint64, tables []model.TableName) error {
 	panic("unreachable")
 }
 
@@ -360,7 +360,7 @@ func (e *errorSink) FlushRowChangedEvents(ctx context.Context, tableID model.Tab
 	return 0, errors.New("error in flush row changed events")
 }
 
-func (e *errorSink) EmitCheckpointTs(ctx context.Context, ts uint64) error {
+func (e *errorSink) EmitCheckpointTs(ctx context.Context, ts uint64, tables []model.TableName) error {
 	panic("unreachable")
 }
 
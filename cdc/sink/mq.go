@@ -16,6 +16,7 @@ package sink
 import (
 	"context"
 	"net/url"
+	"strconv"
 	"strings"
 	"sync"
 	"sync/atomic"
@@ -25,10 +26,12 @@ import (
 	"github.com/pingcap/log"
 	"github.com/pingcap/tiflow/cdc/model"
 	"github.com/pingcap/tiflow/cdc/sink/codec"
+	"github.com/pingcap/tiflow/cdc/sink/columnselector"
 	"github.com/pingcap/tiflow/cdc/sink/dispatcher"
 	"github.com/pingcap/tiflow/cdc/sink/producer"
 	"github.com/pingcap/tiflow/cdc/sink/producer/kafka"
 	"github.com/pingcap/tiflow/cdc/sink/producer/pulsar"
+	"github.com/pingcap/tiflow/cdc/sink/router"
 	"github.com/pingcap/tiflow/pkg/config"
 	cerror "github.com/pingcap/tiflow/pkg/errors"
 	"github.com/pingcap/tiflow/pkg/filter"
@@ -49,6 +52,14 @@ type resolvedTsEvent struct {
 	resolvedTs model.Ts
 }
 
+// mqWorker encodes and sends the events of one partition of a topic.
+type mqWorker struct {
+	topic      string
+	partition  int32
+	input      chan mqEvent
+	resolvedTs uint64
+}
+
 const (
 	// Depend on this size, every `partitionInputCh` will take
 	// approximately 16.3 KiB memory.
@@ -62,14 +73,26 @@ const (
 
 type mqSink struct {
 	mqProducer     producer.Producer
-	dispatcher     dispatcher.Dispatcher
+	eventRouter    *dispatcher.EventRouter
+	columnSelector *columnselector.ColumnSelector
+	router         *router.Router
 	encoderBuilder codec.EncoderBuilder
 	filter         *filter.Filter
 	protocol       config.Protocol
 
-	partitionNum         int32
-	partitionInput       []chan mqEvent
-	partitionResolvedTs  []uint64
+	// claimCheck sends the messages larger than maxMessageBytes, it's nil if
+	// the claim check is disabled.
+	claimCheck      *codec.ClaimCheck
+	maxMessageBytes int
+
+	// workers holds the partition workers of each topic, the workers of a
+	// topic are started when the topic is used for the first time.
+	workersMu sync.RWMutex
+	workers   map[string][]*mqWorker
+	// workerGroup and workerCtx are used to run the background goroutines.
+	workerGroup *errgroup.Group
+	workerCtx   context.Context
+
 	tableCheckpointTsMap sync.Map
 	resolvedBuffer       chan resolvedTsEvent
 	resolvedNotifier     *notify.Notifier
@@ -83,14 +106,19 @@ type mqSink struct {
 
 func newMqSink(
 	ctx context.Context, credential *security.Credential, mqProducer producer.Producer,
-	filter *filter.Filter, replicaConfig *config.ReplicaConfig, opts map[string]string,
-	errCh chan error,
+	filter *filter.Filter, defaultTopic string, replicaConfig *config.ReplicaConfig,
+	opts map[string]string, errCh chan error,
 ) (*mqSink, error) {
 	var protocol config.Protocol
 	err := protocol.FromString(replicaConfig.Sink.Protocol)
 	if err != nil {
 		return nil, cerror.WrapError(cerror.ErrKafkaInvalidConfig, err)
 	}
+	// CSV can't represent the DDL and checkpoint events,
+	// it's only supported by the storage sink.
+	if protocol == config.ProtocolCSV {
+		return nil, cerror.ErrMQSinkUnknownProtocol.GenWithStackByArgs(protocol.String())
+	}
 	encoderBuilder, err := codec.NewEventBatchEncoderBuilder(protocol, credential, opts)
 	if err != nil {
 		return nil, cerror.WrapError(cerror.ErrKafkaInvalidConfig, err)
@@ -100,15 +128,19 @@ func newMqSink(
 		return nil, cerror.WrapError(cerror.ErrKafkaInvalidConfig, err)
 	}
 
-	partitionNum := mqProducer.GetPartitionNum()
-	d, err := dispatcher.NewDispatcher(replicaConfig, partitionNum)
+	eventRouter, err := dispatcher.NewEventRouter(replicaConfig, defaultTopic)
 	if err != nil {
 		return nil, errors.Trace(err)
 	}
 
-	partitionInput := make([]chan mqEvent, partitionNum)
-	for i := 0; i < int(partitionNum); i++ {
-		partitionInput[i] = make(chan mqEvent, defaultPartitionInputChSize)
+	columnSelector, err := columnselector.New(replicaConfig)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+
+	sinkRouter, err := router.New(replicaConfig)
+	if err != nil {
+		return nil, errors.Trace(err)
 	}
 
 	notifier := new(notify.Notifier)
@@ -120,16 +152,37 @@ func newMqSink(
 	changefeedID := util.ChangefeedIDFromCtx(ctx)
 	role := util.RoleFromCtx(ctx)
 
+	var claimCheck *codec.ClaimCheck
+	var maxMessageBytes int
+	if storageURI, ok := opts[codec.ClaimCheckStorageURIKey]; ok {
+		maxMessageBytes, err = strconv.Atoi(opts["max-message-bytes"])
+		if err != nil {
+			resolvedReceiver.Stop()
+			return nil, cerror.WrapError(cerror.ErrKafkaInvalidConfig, err)
+		}
+		claimCheck, err = codec.NewClaimCheck(ctx, storageURI, changefeedID)
+		if err != nil {
+			resolvedReceiver.Stop()
+			return nil, errors.Trace(err)
+		}
+	}
+
+	workerGroup, workerCtx := errgroup.WithContext(ctx)
 	s := &mqSink{
 		mqProducer:     mqProducer,
-		dispatcher:     d,
+		eventRouter:    eventRouter,
+		columnSelector: columnSelector,
+		router:         sinkRouter,
 		encoderBuilder: encoderBuilder,
 		filter:         filter,
 		protocol:       protocol,
 
-		partitionNum:        partitionNum,
-		partitionInput:      partitionInput,
-		partitionResolvedTs: make([]uint64, partitionNum),
+		claimCheck:      claimCheck,
+		maxMessageBytes: maxMessageBytes,
+
+		workers:     make(map[string][]*mqWorker),
+		workerGroup: workerGroup,
+		workerCtx:   workerCtx,
 
 		resolvedBuffer:   make(chan resolvedTsEvent, defaultResolvedTsEventBufferSize),
 		resolvedNotifier: notifier,
@@ -141,8 +194,15 @@ func newMqSink(
 		id:   changefeedID,
 	}
 
+	// Start the workers of the default topic in advance, so that the resolved
+	// events are always sent to all partitions of the default topic.
+	if _, err := s.getOrCreateWorkers(defaultTopic); err != nil {
+		resolvedReceiver.Stop()
+		return nil, errors.Trace(err)
+	}
+
 	go func() {
-		if err := s.run(ctx); err != nil && errors.Cause(err) != context.Canceled {
+		if err := s.run(); err != nil && errors.Cause(err) != context.Canceled {
 			select {
 			case <-ctx.Done():
 				return
@@ -177,14 +237,27 @@ func (k *mqSink) EmitRowChangedEvents(ctx context.Context, rows ...*model.RowCha
 				zap.Any("role", k.role))
 			continue
 		}
-		partition := k.dispatcher.Dispatch(row)
-		select {
-		case <-ctx.Done():
-			return ctx.Err()
-		case k.partitionInput[partition] <- struct {
-			row        *model.RowChangedEvent
-			resolvedTs uint64
-		}{row: row}:
+		topic := k.eventRouter.GetTopicForRowChange(row)
+		workers, err := k.getOrCreateWorkers(topic)
+		if err != nil {
+			return errors.Trace(err)
+		}
+		partitionNum := int32(len(workers))
+		for _, event := range k.eventRouter.SplitUpdateEvent(row, partitionNum) {
+			partition := k.eventRouter.GetPartitionForRowChange(event, partitionNum)
+			// The columns are selected and the tables are routed after dispatching,
+			// the dispatchers may use the columns that are not selected, such as
+			// the unique keys, and they always match the upstream table names.
+			selected, err := k.columnSelector.Apply(event)
+			if err != nil {
+				return errors.Trace(err)
+			}
+			event = k.router.ApplyRow(selected)
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case workers[partition].input <- mqEvent{row: event}:
+			}
 		}
 		rowsCount++
 	}
@@ -238,13 +311,64 @@ func (k *mqSink) bgFlushTs(ctx context.Context) error {
 	}
 }
 
+// getOrCreateWorkers returns the partition workers of the topic,
+// the workers are created and started if the topic is used for the first time.
+func (k *mqSink) getOrCreateWorkers(topic string) ([]*mqWorker, error) {
+	k.workersMu.RLock()
+	workers, ok := k.workers[topic]
+	k.workersMu.RUnlock()
+	if ok {
+		return workers, nil
+	}
+
+	partitionNum, err := k.mqProducer.GetPartitionNum(topic)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+
+	k.workersMu.Lock()
+	defer k.workersMu.Unlock()
+	if workers, ok := k.workers[topic]; ok {
+		return workers, nil
+	}
+	workers = make([]*mqWorker, partitionNum)
+	for i := range workers {
+		worker := &mqWorker{
+			topic:     topic,
+			partition: int32(i),
+			input:     make(chan mqEvent, defaultPartitionInputChSize),
+		}
+		workers[i] = worker
+		k.workerGroup.Go(func() error {
+			return k.runWorker(k.workerCtx, worker)
+		})
+	}
+	k.workers[topic] = workers
+	log.Info("MQ sink workers started for topic",
+		zap.String("topic", topic), zap.Int32("partitionNum", partitionNum),
+		zap.String("changefeed", k.id), zap.Any("role", k.role))
+	return workers, nil
+}
+
+// allWorkers returns the partition workers of all topics.
+func (k *mqSink) allWorkers() []*mqWorker {
+	k.workersMu.RLock()
+	defer k.workersMu.RUnlock()
+	var all []*mqWorker
+	for _, workers := range k.workers {
+		all = append(all, workers...)
+	}
+	return all
+}
+
 func (k *mqSink) flushTsToWorker(ctx context.Context, resolvedTs model.Ts) error {
 	// flush resolvedTs to all partition workers
-	for i := 0; i < int(k.partitionNum); i++ {
+	workers := k.allWorkers()
+	for _, worker := range workers {
 		select {
 		case <-ctx.Done():
 			return errors.Trace(ctx.Err())
-		case k.partitionInput[i] <- mqEvent{resolvedTs: resolvedTs}:
+		case worker.input <- mqEvent{resolvedTs: resolvedTs}:
 		}
 	}
 
@@ -255,8 +379,8 @@ flushLoop:
 		case <-ctx.Done():
 			return errors.Trace(ctx.Err())
 		case <-k.resolvedReceiver.C:
-			for i := 0; i < int(k.partitionNum); i++ {
-				if resolvedTs > atomic.LoadUint64(&k.partitionResolvedTs[i]) {
+			for _, worker := range workers {
+				if resolvedTs > atomic.LoadUint64(&worker.resolvedTs) {
 					continue flushLoop
 				}
 			}
@@ -265,7 +389,11 @@ flushLoop:
 	}
 }
 
-func (k *mqSink) EmitCheckpointTs(ctx context.Context, ts uint64) error {
+// EmitCheckpointTs broadcasts the checkpoint event to all partitions of
+// the topics which the tables are dispatched to, and the default topic.
+// The topics are selected by the upstream table names, the same as the rows
+// and the DDLs, the routes only change the table names in the messages.
+func (k *mqSink) EmitCheckpointTs(ctx context.Context, ts uint64, tables []model.TableName) error {
 	encoder, err := k.encoderBuilder.Build(ctx)
 	if err != nil {
 		return errors.Trace(err)
@@ -277,12 +405,21 @@ func (k *mqSink) EmitCheckpointTs(ctx context.Context, ts uint64) error {
 	if msg == nil {
 		return nil
 	}
-	err = k.writeToProducer(ctx, msg, codec.EncoderNeedSyncWrite, -1)
-	return errors.Trace(err)
+	for _, topic := range k.eventRouter.GetActiveTopics(tables) {
+		err = k.writeToProducer(ctx, msg, codec.EncoderNeedSyncWrite, topic, -1)
+		if err != nil {
+			return errors.Trace(err)
+		}
+	}
+	return nil
 }
 
 func (k *mqSink) EmitDDLEvent(ctx context.Context, ddl *model.DDLEvent) error {
-	if k.filter.ShouldIgnoreDDLEvent(ddl.StartTs, ddl.Type, ddl.TableInfo.Schema, ddl.TableInfo.Table) {
+	ignore, err := k.filter.ShouldIgnoreDDLByEventType(ddl)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	if ignore || k.filter.ShouldIgnoreDDLEvent(ddl.StartTs, ddl.Type, ddl.TableInfo.Schema, ddl.TableInfo.Table) {
 		log.Info(
 			"DDL event ignored",
 			zap.String("query", ddl.Query),
@@ -293,11 +430,15 @@ func (k *mqSink) EmitDDLEvent(ctx context.Context, ddl *model.DDLEvent) error {
 		)
 		return cerror.ErrDDLEventIgnored.GenWithStackByArgs()
 	}
+	routedDDL, err := k.router.ApplyDDL(ddl)
+	if err != nil {
+		return errors.Trace(err)
+	}
 	encoder, err := k.encoderBuilder.Build(ctx)
 	if err != nil {
 		return errors.Trace(err)
 	}
-	msg, err := encoder.EncodeDDLEvent(ddl)
+	msg, err := encoder.EncodeDDLEvent(routedDDL)
 	if err != nil {
 		return errors.Trace(err)
 	}
@@ -315,11 +456,13 @@ func (k *mqSink) EmitDDLEvent(ctx context.Context, ddl *model.DDLEvent) error {
 		partition = 0
 	}
 
+	topic := k.eventRouter.GetTopicForDDL(ddl)
 	k.statistics.AddDDLCount()
 	log.Debug("emit ddl event", zap.String("query", ddl.Query),
-		zap.Uint64("commitTs", ddl.CommitTs), zap.Int32("partition", partition),
+		zap.Uint64("commitTs", ddl.CommitTs),
+		zap.String("topic", topic), zap.Int32("partition", partition),
 		zap.String("changefeed", k.id), zap.Any("role", k.role))
-	err = k.writeToProducer(ctx, msg, codec.EncoderNeedSyncWrite, partition)
+	err = k.writeToProducer(ctx, msg, codec.EncoderNeedSyncWrite, topic, partition)
 	return errors.Trace(err)
 }
 
@@ -334,25 +477,18 @@ func (k *mqSink) Barrier(cxt context.Context, tableID model.TableID) error {
 	return nil
 }
 
-func (k *mqSink) run(ctx context.Context) error {
+func (k *mqSink) run() error {
 	defer k.resolvedReceiver.Stop()
-	wg, ctx := errgroup.WithContext(ctx)
-	wg.Go(func() error {
-		return k.bgFlushTs(ctx)
+	k.workerGroup.Go(func() error {
+		return k.bgFlushTs(k.workerCtx)
 	})
-	for i := int32(0); i < k.partitionNum; i++ {
-		partition := i
-		wg.Go(func() error {
-			return k.runWorker(ctx, partition)
-		})
-	}
-	return wg.Wait()
+	return k.workerGroup.Wait()
 }
 
 const batchSizeLimit = 4 * 1024 * 1024 // 4MB
 
-func (k *mqSink) runWorker(ctx context.Context, partition int32) error {
-	input := k.partitionInput[partition]
+func (k *mqSink) runWorker(ctx context.Context, worker *mqWorker) error {
+	input := worker.input
 	encoder, err := k.encoderBuilder.Build(ctx)
 	if err != nil {
 		return errors.Trace(err)
@@ -369,7 +505,7 @@ func (k *mqSink) runWorker(ctx context.Context, partition int32) error {
 			}
 
 			for _, msg := range messages {
-				err := k.writeToProducer(ctx, msg, codec.EncoderNeedAsyncWrite, partition)
+				err := k.writeToProducer(ctx, msg, codec.EncoderNeedAsyncWrite, worker.topic, worker.partition)
 				if err != nil {
 					return 0, err
 				}
@@ -411,7 +547,7 @@ func (k *mqSink) runWorker(ctx context.Context, partition int32) error {
 					return errors.Trace(err)
 				}
 
-				atomic.StoreUint64(&k.partitionResolvedTs[partition], e.resolvedTs)
+				atomic.StoreUint64(&worker.resolvedTs, e.resolvedTs)
 				k.resolvedNotifier.Notify()
 			}
 			continue
@@ -433,27 +569,44 @@ func (k *mqSink) runWorker(ctx context.Context, partition int32) error {
 	}
 }
 
-func (k *mqSink) writeToProducer(ctx context.Context, message *codec.MQMessage, op codec.EncoderResult, partition int32) error {
+func (k *mqSink) writeToProducer(
+	ctx context.Context, message *codec.MQMessage, op codec.EncoderResult,
+	topic string, partition int32,
+) error {
+	if k.claimCheck != nil && op != codec.EncoderNoOperation &&
+		message.Length() > k.maxMessageBytes {
+		var err error
+		message, err = k.claimCheck.WriteMessage(ctx, topic, partition, message)
+		if err != nil {
+			return errors.Trace(err)
+		}
+	}
+
 	switch op {
 	case codec.EncoderNeedAsyncWrite:
 		if partition >= 0 {
-			return k.mqProducer.AsyncSendMessage(ctx, message, partition)
+			return k.mqProducer.AsyncSendMessage(ctx, topic, partition, message)
 		}
 		return cerror.ErrAsyncBroadcastNotSupport.GenWithStackByArgs()
 	case codec.EncoderNeedSyncWrite:
 		if partition >= 0 {
-			err := k.mqProducer.AsyncSendMessage(ctx, message, partition)
+			err := k.mqProducer.AsyncSendMessage(ctx, topic, partition, message)
 			if err != nil {
 				return err
 			}
 			return k.mqProducer.Flush(ctx)
 		}
-		return k.mqProducer.SyncBroadcastMessage(ctx, message)
+		partitionNum, err := k.mqProducer.GetPartitionNum(topic)
+		if err != nil {
+			return errors.Trace(err)
+		}
+		return k.mqProducer.SyncBroadcastMessage(ctx, topic, partitionNum, message)
 	}
 
 	log.Warn("writeToProducer called with no-op",
 		zap.ByteString("key", message.Key),
 		zap.ByteString("value", message.Value),
+		zap.String("topic", topic),
 		zap.Int32("partition", partition),
 		zap.String("changefeed", k.id),
 		zap.Any("role", k.role))
@@ -484,7 +637,7 @@ func newKafkaSaramaSink(ctx context.Context, sinkURI *url.URL,
 	if err != nil {
 		return nil, errors.Trace(err)
 	}
-	sink, err := newMqSink(ctx, producerConfig.Credential, sProducer, filter, replicaConfig, opts, errCh)
+	sink, err := newMqSink(ctx, producerConfig.Credential, sProducer, filter, topic, replicaConfig, opts, errCh)
 	if err != nil {
 		return nil, errors.Trace(err)
 	}
@@ -515,10 +668,19 @@ func newPulsarSink(ctx context.Context, sinkURI *url.URL, filter *filter.Filter,
 	if err != nil {
 		return nil, err
 	}
+	// The pulsar producer is bound to the topic in the sink URI,
+	// so dispatching events to other topics is not supported.
+	for _, rule := range replicaConfig.Sink.DispatchRules {
+		if rule.TopicRule != "" {
+			return nil, cerror.ErrSinkURIInvalid.GenWithStack(
+				"topic dispatch rule is not supported by pulsar sink, matcher: %v", rule.Matcher)
+		}
+	}
 	// For now, it's a placeholder. Avro format have to make connection to Schema Registry,
 	// and it may need credential.
 	credential := &security.Credential{}
-	sink, err := newMqSink(ctx, credential, producer, filter, replicaConfig, opts, errCh)
+	topic := strings.Trim(sinkURI.Path, "/")
+	sink, err := newMqSink(ctx, credential, producer, filter, topic, replicaConfig, opts, errCh)
 	if err != nil {
 		return nil, errors.Trace(err)
 	}
@@ -17,11 +17,13 @@ import (
 	"context"
 	"fmt"
 	"net/url"
+	"sync"
 
 	"github.com/Shopify/sarama"
 	"github.com/pingcap/check"
 	"github.com/pingcap/errors"
 	"github.com/pingcap/failpoint"
+	"github.com/pingcap/tidb/parser/mysql"
 	"github.com/pingcap/tiflow/cdc/model"
 	"github.com/pingcap/tiflow/cdc/sink/codec"
 	kafkap "github.com/pingcap/tiflow/cdc/sink/producer/kafka"
@@ -29,6 +31,7 @@ import (
 	cerror "github.com/pingcap/tiflow/pkg/errors"
 	"github.com/pingcap/tiflow/pkg/filter"
 	"github.com/pingcap/tiflow/pkg/kafka"
+	"github.com/pingcap/tiflow/pkg/security"
 	"github.com/pingcap/tiflow/pkg/util/testleak"
 )
 
@@ -104,7 +107,7 @@ func (s mqSinkSuite) TestKafkaSink(c *check.C) {
 
 	// mock kafka broker processes 1 checkpoint ts event
 	leader.Returns(prodSuccess)
-	err = sink.EmitCheckpointTs(ctx, uint64(120))
+	err = sink.EmitCheckpointTs(ctx, uint64(120), nil)
 	c.Assert(err, check.IsNil)
 
 	// mock kafka broker processes 1 ddl event
@@ -130,7 +133,7 @@ func (s mqSinkSuite) TestKafkaSink(c *check.C) {
 	if err != nil {
 		c.Assert(errors.Cause(err), check.Equals, context.Canceled)
 	}
-	err = sink.EmitCheckpointTs(ctx, uint64(140))
+	err = sink.EmitCheckpointTs(ctx, uint64(140), nil)
 	if err != nil {
 		c.Assert(errors.Cause(err), check.Equals, context.Canceled)
 	}
@@ -237,6 +240,29 @@ func (s mqSinkSuite) TestPulsarSinkEncoderConfig(c *check.C) {
 	c.Assert(encoder.(*codec.JSONEventBatchEncoder).GetMaxMessageBytes(), check.Equals, 4194304)
 }
 
+func (s mqSinkSuite) TestPulsarSinkTopicRule(c *check.C) {
+	defer testleak.AfterTest(c)()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	err := failpoint.Enable("github.com/pingcap/tiflow/cdc/sink/producer/pulsar/MockPulsar", "return(true)")
+	c.Assert(err, check.IsNil)
+	defer func() {
+		_ = failpoint.Disable("github.com/pingcap/tiflow/cdc/sink/producer/pulsar/MockPulsar")
+	}()
+
+	sinkURI, err := url.Parse("pulsar://127.0.0.1:1234/kafka-test")
+	c.Assert(err, check.IsNil)
+	replicaConfig := config.GetDefaultReplicaConfig()
+	replicaConfig.Sink.DispatchRules = []*config.DispatchRule{
+		{Matcher: []string{"test.*"}, Dispatcher: "table", TopicRule: "{schema}_{table}"},
+	}
+	fr, err := filter.NewFilter(replicaConfig)
+	c.Assert(err, check.IsNil)
+	_, err = newPulsarSink(ctx, sinkURI, fr, replicaConfig, map[string]string{}, make(chan error, 1))
+	c.Assert(err, check.ErrorMatches, ".*topic dispatch rule is not supported by pulsar sink.*")
+}
+
 func (s mqSinkSuite) TestFlushRowChangedEvents(c *check.C) {
 	defer testleak.AfterTest(c)()
 	ctx, cancel := context.WithCancel(context.Background())
@@ -344,3 +370,86 @@ func (s mqSinkSuite) TestFlushRowChangedEvents(c *check.C) {
 		c.Assert(errors.Cause(err), check.Equals, context.Canceled)
 	}
 }
+
+// topicRecordingProducer records the topics used by the rows and the
+// broadcast messages, it has only one partition for each topic.
+type topicRecordingProducer struct {
+	mu              sync.Mutex
+	rowTopics       map[string]struct{}
+	broadcastTopics map[string]struct{}
+}
+
+func (p *topicRecordingProducer) AsyncSendMessage(
+	ctx context.Context, topic string, partition int32, message *codec.MQMessage,
+) error {
+	return nil
+}
+
+func (p *topicRecordingProducer) SyncBroadcastMessage(
+	ctx context.Context, topic string, partitionsNum int32, message *codec.MQMessage,
+) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.broadcastTopics[topic] = struct{}{}
+	return nil
+}
+
+func (p *topicRecordingProducer) Flush(ctx context.Context) error {
+	return nil
+}
+
+// GetPartitionNum is called when the workers of a topic are created for the
+// first time, which happens when a row is dispatched to the topic.
+func (p *topicRecordingProducer) GetPartitionNum(topic string) (int32, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.rowTopics[topic] = struct{}{}
+	return 1, nil
+}
+
+func (p *topicRecordingProducer) Close() error {
+	return nil
+}
+
+func (s mqSinkSuite) TestEmitCheckpointTsWithRoutes(c *check.C) {
+	defer testleak.AfterTest(c)()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	replicaConfig := config.GetDefaultReplicaConfig()
+	replicaConfig.Sink.Protocol = "open-protocol"
+	replicaConfig.Sink.DispatchRules = []*config.DispatchRule{
+		{Matcher: []string{"test.*"}, Dispatcher: "table", TopicRule: "{schema}_{table}"},
+	}
+	replicaConfig.Sink.Routes = []*config.RouteRule{
+		{Matcher: []string{"test.*"}, TargetSchema: "archive", TargetTable: "{schema}_{table}"},
+	}
+	fr, err := filter.NewFilter(replicaConfig)
+	c.Assert(err, check.IsNil)
+	p := &topicRecordingProducer{
+		rowTopics:       make(map[string]struct{}),
+		broadcastTopics: make(map[string]struct{}),
+	}
+	sink, err := newMqSink(ctx, &security.Credential{}, p, fr, "default-topic",
+		replicaConfig, map[string]string{"max-message-bytes": "1048576"}, make(chan error, 1))
+	c.Assert(err, check.IsNil)
+
+	row := &model.RowChangedEvent{
+		Table:    &model.TableName{Schema: "test", Table: "t1", TableID: 1},
+		StartTs:  100,
+		CommitTs: 120,
+		Columns:  []*model.Column{{Name: "col1", Type: mysql.TypeLong, Value: 1}},
+	}
+	err = sink.EmitRowChangedEvents(ctx, row)
+	c.Assert(err, check.IsNil)
+	c.Assert(p.rowTopics, check.DeepEquals, map[string]struct{}{"default-topic": {}, "test_t1": {}})
+
+	// the checkpoint is sent to the topics of the rows, not the ones of the
+	// routed table names.
+	err = sink.EmitCheckpointTs(ctx, 120, []model.TableName{*row.Table})
+	c.Assert(err, check.IsNil)
+	c.Assert(p.broadcastTopics, check.DeepEquals, p.rowTopics)
+
+	err = sink.Close(ctx)
+	c.Assert(err, check.IsNil)
+}
@@ -31,6 +31,7 @@ import (
 	"github.com/pingcap/tidb/parser/mysql"
 	"github.com/pingcap/tiflow/cdc/model"
 	"github.com/pingcap/tiflow/cdc/sink/common"
+	"github.com/pingcap/tiflow/cdc/sink/router"
 	dmutils "github.com/pingcap/tiflow/dm/pkg/utils"
 	"github.com/pingcap/tiflow/pkg/config"
 	"github.com/pingcap/tiflow/pkg/cyclic"
@@ -56,8 +57,14 @@ type mysqlSink struct {
 	params *sinkParams
 
 	filter *tifilter.Filter
+	router *router.Router
 	cyclic *cyclic.Cyclic
 
+	// conflictLog records the rows skipped by the log-and-skip conflict policy.
+	conflictLog *conflictLog
+	// asyncDDL executes the slow DDLs asynchronously if it's not nil.
+	asyncDDL *asyncDDLExecutor
+
 	txnCache           *common.UnresolvedTxnCache
 	workers            []*mysqlSinkWorker
 	tableCheckpointTs  sync.Map
@@ -96,6 +103,11 @@ func newMySQLSink(
 	}
 
 	params.enableOldValue = replicaConfig.EnableOldValue
+	if !params.enableOldValue &&
+		(params.onConflict == conflictPolicyIgnore || params.onConflict == conflictPolicyLogAndSkip) {
+		return nil, cerror.WrapError(cerror.ErrMySQLInvalidConfig,
+			errors.Errorf("on-conflict=%s requires the old value to be enabled", params.onConflict))
+	}
 
 	// dsn format of the driver:
 	// [username[:password]@][protocol[(address)]]/dbname[?param1=value1&...&paramN=valueN]
@@ -165,6 +177,16 @@ func newMySQLSink(
 		return nil, err
 	}
 
+	sinkRouter, err := router.New(replicaConfig)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+
+	conflictLog, err := newConflictLog(ctx, db, params)
+	if err != nil {
+		return nil, err
+	}
+
 	log.Info("Start mysql sink")
 
 	db.SetMaxIdleConns(params.workerCount)
@@ -183,7 +205,9 @@ func newMySQLSink(
 		db:                              db,
 		params:                          params,
 		filter:                          filter,
+		router:                          sinkRouter,
 		cyclic:                          sinkCyclic,
+		conflictLog:                     conflictLog,
 		txnCache:                        common.NewUnresolvedTxnCache(),
 		statistics:                      NewStatistics(ctx, "mysql", opts),
 		metricConflictDetectDurationHis: metricConflictDetectDurationHis,
@@ -193,6 +217,15 @@ func newMySQLSink(
 		cancel:                          cancel,
 	}
 
+	if params.asyncDDLEnabled {
+		if isTiDB(ctx, db) {
+			sink.asyncDDL = newAsyncDDLExecutor(ctx, db, sink.execDDLWithMaxRetries)
+		} else {
+			log.Warn("async DDL only works with TiDB, execute the DDLs synchronously",
+				zap.String("changefeed", params.changefeedID))
+		}
+	}
+
 	sink.execWaitNotifier = new(notify.Notifier)
 	sink.resolvedNotifier = new(notify.Notifier)
 
@@ -217,7 +250,17 @@ func (s *mysqlSink) TryEmitRowChangedEvents(ctx context.Context, rows ...*model.
 }
 
 func (s *mysqlSink) EmitRowChangedEvents(ctx context.Context, rows ...*model.RowChangedEvent) error {
-	count := s.txnCache.Append(s.filter, rows...)
+	// The rows are filtered by the upstream table names, and then routed to
+	// the target tables before the SQLs are generated.
+	routedRows := make([]*model.RowChangedEvent, 0, len(rows))
+	for _, row := range rows {
+		if s.filter != nil && s.filter.ShouldIgnoreDMLEvent(row.StartTs, row.Table.Schema, row.Table.Table) {
+			log.Info("Row changed event ignored", zap.Uint64("start-ts", row.StartTs))
+			continue
+		}
+		routedRows = append(routedRows, s.router.ApplyRow(row))
+	}
+	count := s.txnCache.Append(nil, routedRows...)
 	s.statistics.AddRowsCount(count)
 	return nil
 }
@@ -281,13 +324,29 @@ func (s *mysqlSink) flushRowChangedEvents(ctx context.Context, receiver *notify.
 	}
 }
 
-func (s *mysqlSink) EmitCheckpointTs(ctx context.Context, ts uint64) error {
-	// do nothing
+func (s *mysqlSink) EmitCheckpointTs(ctx context.Context, ts uint64, tables []model.TableName) error {
+	// report the failures of the async DDLs, as the EmitCheckpointTs is
+	// called periodically.
+	if s.asyncDDL != nil {
+		return s.asyncDDL.error()
+	}
 	return nil
 }
 
+// RunningDDLs implements AsyncDDLSink.
+func (s *mysqlSink) RunningDDLs(ctx context.Context) ([]*model.RunningDDL, error) {
+	if s.asyncDDL == nil {
+		return nil, nil
+	}
+	return s.asyncDDL.runningDDLs(ctx)
+}
+
 func (s *mysqlSink) EmitDDLEvent(ctx context.Context, ddl *model.DDLEvent) error {
-	if s.filter.ShouldIgnoreDDLEvent(ddl.StartTs, ddl.Type, ddl.TableInfo.Schema, ddl.TableInfo.Table) {
+	ignore, err := s.filter.ShouldIgnoreDDLByEventType(ddl)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	if ignore || s.filter.ShouldIgnoreDDLEvent(ddl.StartTs, ddl.Type, ddl.TableInfo.Schema, ddl.TableInfo.Table) {
 		log.Info(
 			"DDL event ignored",
 			zap.String("query", ddl.Query),
@@ -296,8 +355,21 @@ func (s *mysqlSink) EmitDDLEvent(ctx context.Context, ddl *model.DDLEvent) error
 		)
 		return cerror.ErrDDLEventIgnored.GenWithStackByArgs()
 	}
+	ddl, err = s.router.ApplyDDL(ddl)
+	if err != nil {
+		return errors.Trace(err)
+	}
 	s.statistics.AddDDLCount()
-	err := s.execDDLWithMaxRetries(ctx, ddl)
+	if s.asyncDDL != nil {
+		if err := s.asyncDDL.wait(ctx, ddl); err != nil {
+			return errors.Trace(err)
+		}
+		if isAsyncDDL(ddl) {
+			s.asyncDDL.start(ddl)
+			return nil
+		}
+	}
+	err = s.execDDLWithMaxRetries(ctx, ddl)
 	return errors.Trace(err)
 }
 
@@ -467,6 +539,10 @@ func (s *mysqlSink) Close(ctx context.Context) error {
 	s.resolvedNotifier.Close()
 	err := s.db.Close()
 	s.cancel()
+	s.asyncDDL.close()
+	if closeErr := s.conflictLog.close(); closeErr != nil {
+		log.Warn("failed to close the conflict log", zap.Error(closeErr))
+	}
 	return cerror.WrapError(cerror.ErrMySQLConnectionError, err)
 }
 
@@ -556,6 +632,9 @@ func (s *mysqlSink) execDMLWithMaxRetries(ctx context.Context, dmls *preparedDML
 		})
 
 		err := s.statistics.RecordBatchExecution(func() (int, error) {
+			if s.params.onConflict == conflictPolicyLogAndSkip {
+				return s.execDMLsSkippingConflicts(ctx, dmls)
+			}
 			tx, err := s.db.BeginTx(ctx, nil)
 			if err != nil {
 				return 0, logDMLTxnErr(cerror.WrapError(cerror.ErrMySQLTxnError, err))
@@ -599,8 +678,11 @@ func (s *mysqlSink) execDMLWithMaxRetries(ctx context.Context, dmls *preparedDML
 }
 
 type preparedDMLs struct {
-	sqls     []string
-	values   [][]interface{}
+	sqls   []string
+	values [][]interface{}
+	// rows are the rows of the sqls, they're only used to record the
+	// conflicting rows by the log-and-skip conflict policy.
+	rows     []*model.RowChangedEvent
 	markSQL  string
 	rowCount int
 }
@@ -613,6 +695,24 @@ func (s *mysqlSink) prepareDMLs(rows []*model.RowChangedEvent, replicaID uint64,
 	rowCount := 0
 	// translateToInsert control the update and insert behavior
 	translateToInsert := s.params.enableOldValue && !s.params.safeMode
+	// dmlRows are the rows of the sqls, which are only needed to record the
+	// conflicting rows, the batched sqls are never used in that case.
+	var dmlRows []*model.RowChangedEvent
+	recordRows := s.params.onConflict == conflictPolicyLogAndSkip
+	appendSQL := func(query string, args []interface{}, row *model.RowChangedEvent) {
+		sqls = append(sqls, query)
+		values = append(values, args)
+		if recordRows {
+			dmlRows = append(dmlRows, row)
+		}
+		rowCount++
+	}
+	ignoreConflicts := s.params.onConflict == conflictPolicyIgnore
+	// batcher merges the rows with handle keys into multi-row statements.
+	var batcher *dmlBatcher
+	if s.params.batchDMLEnabled {
+		batcher = newDMLBatcher(translateToInsert, ignoreConflicts, s.params.batchDMLSize)
+	}
 
 	// flush cached batch replace or insert, to keep the sequence of DMLs
 	flushCacheDMLs := func() {
@@ -623,21 +723,40 @@ func (s *mysqlSink) prepareDMLs(rows []*model.RowChangedEvent, replicaID uint64,
 			replaces = make(map[string][][]interface{})
 		}
 	}
+	// flush the multi-row statements of the batcher, to keep the sequence of DMLs
+	flushBatchDMLs := func() {
+		if batcher != nil {
+			batchSqls, batchValues := batcher.flush()
+			sqls = append(sqls, batchSqls...)
+			values = append(values, batchValues...)
+		}
+	}
 
 	for _, row := range rows {
 		var query string
 		var args []interface{}
 		quoteTable := quotes.QuoteSchema(row.Table.Schema, row.Table.Table)
 
+		// The rows without handle keys are executed one by one, as they can't
+		// be compacted and merged.
+		if batcher != nil && hasHandleKey(row) {
+			flushCacheDMLs()
+			batcher.append(row)
+			rowCount++
+			continue
+		}
+		flushBatchDMLs()
+
 		// If the old value is enabled, is not in safe mode and is an update event, then translate to UPDATE.
 		// NOTICE: Only update events with the old value feature enabled will have both columns and preColumns.
 		if translateToInsert && len(row.PreColumns) != 0 && len(row.Columns) != 0 {
 			flushCacheDMLs()
 			query, args = prepareUpdate(quoteTable, row.PreColumns, row.Columns, s.forceReplicate)
 			if query != "" {
-				sqls = append(sqls, query)
-				values = append(values, args)
-				rowCount++
+				if ignoreConflicts {
+					query = "UPDATE IGNORE" + strings.TrimPrefix(query, "UPDATE")
+				}
+				appendSQL(query, args, row)
 			}
 			continue
 		}
@@ -652,9 +771,7 @@ func (s *mysqlSink) prepareDMLs(rows []*model.RowChangedEvent, replicaID uint64,
 			flushCacheDMLs()
 			query, args = prepareDelete(quoteTable, row.PreColumns, s.forceReplicate)
 			if query != "" {
-				sqls = append(sqls, query)
-				values = append(values, args)
-				rowCount++
+				appendSQL(query, args, row)
 			}
 		}
 
@@ -670,6 +787,9 @@ func (s *mysqlSink) prepareDMLs(rows []*model.RowChangedEvent, replicaID uint64,
 			if s.params.batchReplaceEnabled {
 				query, args = prepareReplace(quoteTable, row.Columns, false /* appendPlaceHolder */, translateToInsert)
 				if query != "" {
+					if ignoreConflicts {
+						query = "INSERT IGNORE" + strings.TrimPrefix(query, "INSERT")
+					}
 					if _, ok := replaces[query]; !ok {
 						replaces[query] = make([][]interface{}, 0)
 					}
@@ -679,18 +799,21 @@ func (s *mysqlSink) prepareDMLs(rows []*model.RowChangedEvent, replicaID uint64,
 			} else {
 				query, args = prepareReplace(quoteTable, row.Columns, true /* appendPlaceHolder */, translateToInsert)
 				if query != "" {
-					sqls = append(sqls, query)
-					values = append(values, args)
-					rowCount++
+					if ignoreConflicts {
+						query = "INSERT IGNORE" + strings.TrimPrefix(query, "INSERT")
+					}
+					appendSQL(query, args, row)
 				}
 			}
 		}
 	}
 	flushCacheDMLs()
+	flushBatchDMLs()
 
 	dmls := &preparedDMLs{
 		sqls:   sqls,
 		values: values,
+		rows:   dmlRows,
 	}
 	if s.cyclic != nil && len(rows) > 0 {
 		// Write mark table with the current replica ID.
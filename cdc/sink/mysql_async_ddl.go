@@ -0,0 +1,262 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sink
+
+import (
+	"context"
+	"database/sql"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pingcap/errors"
+	"github.com/pingcap/log"
+	timodel "github.com/pingcap/tidb/parser/model"
+	"github.com/pingcap/tiflow/cdc/model"
+	cerror "github.com/pingcap/tiflow/pkg/errors"
+	"go.uber.org/zap"
+)
+
+// asyncDDLTypes are the DDL types executed asynchronously, they're slow on
+// large tables, and the DMLs of the tables needn't be held while they're
+// running in the downstream.
+var asyncDDLTypes = map[timodel.ActionType]struct{}{
+	timodel.ActionAddIndex: {},
+}
+
+func isAsyncDDL(ddl *model.DDLEvent) bool {
+	if _, ok := asyncDDLTypes[ddl.Type]; !ok {
+		return false
+	}
+	return ddl.TableInfo != nil && ddl.TableInfo.Table != ""
+}
+
+// isTiDB returns whether the downstream is TiDB.
+func isTiDB(ctx context.Context, db *sql.DB) bool {
+	var version string
+	err := db.QueryRowContext(ctx, "SELECT tidb_version()").Scan(&version)
+	return err == nil
+}
+
+type runningDDL struct {
+	ddl  *model.DDLEvent
+	info *model.RunningDDL
+	done chan struct{}
+}
+
+// asyncDDLExecutor executes the async DDLs in the background, so the
+// changefeed isn't blocked by them. The DDLs touching the tables of a running
+// DDL wait for it to finish.
+// The owner holds the checkpoint of the changefeed before the running DDLs,
+// so the failed ones are executed again after the changefeed is restarted.
+// It only works with TiDB, whose DDL jobs keep running after the connections
+// are closed, and the DDLs executed again are ignored once the jobs finish.
+type asyncDDLExecutor struct {
+	ctx  context.Context
+	db   *sql.DB
+	exec func(ctx context.Context, ddl *model.DDLEvent) error
+
+	mu      sync.Mutex
+	running map[model.TableName]*runningDDL
+	// err is the first error of the async DDLs, it fails the changefeed.
+	err error
+	wg  sync.WaitGroup
+}
+
+func newAsyncDDLExecutor(
+	ctx context.Context, db *sql.DB, exec func(ctx context.Context, ddl *model.DDLEvent) error,
+) *asyncDDLExecutor {
+	return &asyncDDLExecutor{
+		ctx:     ctx,
+		db:      db,
+		exec:    exec,
+		running: make(map[model.TableName]*runningDDL),
+	}
+}
+
+// touchesTable returns whether the DDL touches the table, the schema DDLs
+// touch all tables in the schema.
+func touchesTable(ddl *model.DDLEvent, table model.TableName) bool {
+	for _, info := range []*model.SimpleTableInfo{ddl.TableInfo, ddl.PreTableInfo} {
+		if info == nil || info.Schema != table.Schema {
+			continue
+		}
+		if info.Table == "" || info.Table == table.Table {
+			return true
+		}
+	}
+	return false
+}
+
+// wait waits for the running DDLs touched by the DDL.
+func (e *asyncDDLExecutor) wait(ctx context.Context, ddl *model.DDLEvent) error {
+	for {
+		var r *runningDDL
+		e.mu.Lock()
+		for table, running := range e.running {
+			if touchesTable(ddl, table) {
+				r = running
+				break
+			}
+		}
+		e.mu.Unlock()
+		if r == nil {
+			return e.error()
+		}
+		log.Info("wait for the running async DDL",
+			zap.String("query", ddl.Query), zap.String("running", r.ddl.Query))
+		select {
+		case <-ctx.Done():
+			return errors.Trace(ctx.Err())
+		case <-r.done:
+		}
+	}
+}
+
+// start executes the DDL in the background, the running DDLs of the table
+// must be waited before.
+func (e *asyncDDLExecutor) start(ddl *model.DDLEvent) {
+	table := model.TableName{Schema: ddl.TableInfo.Schema, Table: ddl.TableInfo.Table}
+	r := &runningDDL{
+		ddl: ddl,
+		info: &model.RunningDDL{
+			Query:     ddl.Query,
+			CommitTs:  ddl.CommitTs,
+			StartTime: time.Now().Unix(),
+		},
+		done: make(chan struct{}),
+	}
+	e.mu.Lock()
+	e.running[table] = r
+	e.mu.Unlock()
+	log.Info("start the async DDL", zap.String("query", ddl.Query), zap.Uint64("commitTs", ddl.CommitTs))
+
+	e.wg.Add(1)
+	go func() {
+		defer e.wg.Done()
+		start := time.Now()
+		err := e.exec(e.ctx, ddl)
+		e.mu.Lock()
+		delete(e.running, table)
+		if err != nil && e.err == nil {
+			e.err = err
+		}
+		e.mu.Unlock()
+		close(r.done)
+		if err != nil {
+			log.Error("execute the async DDL failed", zap.String("query", ddl.Query), zap.Error(err))
+			return
+		}
+		log.Info("execute the async DDL succeeded",
+			zap.String("query", ddl.Query), zap.Duration("duration", time.Since(start)))
+	}()
+}
+
+func (e *asyncDDLExecutor) error() error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.err
+}
+
+// runningDDLs returns the running DDLs in the order of commitTs, the
+// progress of them is queried from the downstream.
+func (e *asyncDDLExecutor) runningDDLs(ctx context.Context) ([]*model.RunningDDL, error) {
+	e.mu.Lock()
+	if e.err != nil {
+		e.mu.Unlock()
+		return nil, e.err
+	}
+	ddls := make([]*model.RunningDDL, 0, len(e.running))
+	tables := make(map[*model.RunningDDL]model.TableName, len(e.running))
+	for table, r := range e.running {
+		info := *r.info
+		ddls = append(ddls, &info)
+		tables[&info] = table
+	}
+	e.mu.Unlock()
+	if len(ddls) == 0 {
+		return nil, nil
+	}
+	sort.Slice(ddls, func(i, j int) bool { return ddls[i].CommitTs < ddls[j].CommitTs })
+
+	// the progress is only for display, so the failures are ignored.
+	jobs, err := queryDDLJobs(ctx, e.db)
+	if err != nil {
+		log.Warn("query the progress of the async DDLs failed", zap.Error(err))
+		return ddls, nil
+	}
+	for _, ddl := range ddls {
+		if job, ok := jobs[tables[ddl]]; ok {
+			ddl.JobID, ddl.State, ddl.RowCount = job.JobID, job.State, job.RowCount
+		}
+	}
+	return ddls, nil
+}
+
+// close waits for the async DDLs to exit, the ctx of the executor should be
+// canceled before.
+func (e *asyncDDLExecutor) close() {
+	if e == nil {
+		return
+	}
+	e.wg.Wait()
+}
+
+// queryDDLJobs returns the latest unfinished DDL jobs of the tables by
+// `ADMIN SHOW DDL JOBS`, the columns are found by names as they vary
+// between the versions of TiDB.
+func queryDDLJobs(ctx context.Context, db *sql.DB) (map[model.TableName]*model.RunningDDL, error) {
+	rows, err := db.QueryContext(ctx, "ADMIN SHOW DDL JOBS")
+	if err != nil {
+		return nil, cerror.WrapError(cerror.ErrMySQLQueryError, err)
+	}
+	defer rows.Close()
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, cerror.WrapError(cerror.ErrMySQLQueryError, err)
+	}
+	values := make([]sql.RawBytes, len(columns))
+	dest := make([]interface{}, len(columns))
+	for i := range values {
+		dest[i] = &values[i]
+	}
+
+	jobs := make(map[model.TableName]*model.RunningDDL)
+	for rows.Next() {
+		if err := rows.Scan(dest...); err != nil {
+			return nil, cerror.WrapError(cerror.ErrMySQLQueryError, err)
+		}
+		row := make(map[string]string, len(columns))
+		for i, column := range columns {
+			row[strings.ToUpper(column)] = string(values[i])
+		}
+		switch row["STATE"] {
+		case "synced", "cancelled", "rollback done":
+			continue
+		}
+		table := model.TableName{Schema: row["DB_NAME"], Table: row["TABLE_NAME"]}
+		jobID, _ := strconv.ParseInt(row["JOB_ID"], 10, 64)
+		if job, ok := jobs[table]; ok && job.JobID > jobID {
+			continue
+		}
+		rowCount, _ := strconv.ParseInt(row["ROW_COUNT"], 10, 64)
+		jobs[table] = &model.RunningDDL{JobID: jobID, State: row["STATE"], RowCount: rowCount}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, cerror.WrapError(cerror.ErrMySQLQueryError, err)
+	}
+	return jobs, nil
+}
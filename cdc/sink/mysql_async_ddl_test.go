@@ -0,0 +1,111 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sink
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	timodel "github.com/pingcap/tidb/parser/model"
+	"github.com/pingcap/tiflow/cdc/model"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTouchesTable(t *testing.T) {
+	t.Parallel()
+
+	table := model.TableName{Schema: "test", Table: "t1"}
+	for _, tc := range []struct {
+		ddl     *model.DDLEvent
+		touches bool
+	}{
+		{&model.DDLEvent{TableInfo: &model.SimpleTableInfo{Schema: "test", Table: "t1"}}, true},
+		{&model.DDLEvent{TableInfo: &model.SimpleTableInfo{Schema: "test", Table: "t2"}}, false},
+		{&model.DDLEvent{TableInfo: &model.SimpleTableInfo{Schema: "test"}}, true},
+		{&model.DDLEvent{TableInfo: &model.SimpleTableInfo{Schema: "test1"}}, false},
+		{&model.DDLEvent{
+			TableInfo:    &model.SimpleTableInfo{Schema: "test", Table: "t3"},
+			PreTableInfo: &model.SimpleTableInfo{Schema: "test", Table: "t1"},
+		}, true},
+	} {
+		require.Equal(t, tc.touches, touchesTable(tc.ddl, table))
+	}
+}
+
+func TestAsyncDDLExecutor(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	require.Nil(t, err)
+	defer db.Close()
+
+	finish := make(chan error)
+	e := newAsyncDDLExecutor(ctx, db, func(ctx context.Context, ddl *model.DDLEvent) error {
+		return <-finish
+	})
+	defer e.close()
+
+	addIndex := &model.DDLEvent{
+		CommitTs:  10,
+		Query:     "ALTER TABLE test.t1 ADD INDEX idx(a)",
+		Type:      timodel.ActionAddIndex,
+		TableInfo: &model.SimpleTableInfo{Schema: "test", Table: "t1"},
+	}
+	require.True(t, isAsyncDDL(addIndex))
+	e.start(addIndex)
+
+	mock.ExpectQuery("ADMIN SHOW DDL JOBS").WillReturnRows(
+		sqlmock.NewRows([]string{"JOB_ID", "DB_NAME", "TABLE_NAME", "JOB_TYPE", "STATE", "ROW_COUNT"}).
+			AddRow(12, "test", "t1", "add index", "running", 1000).
+			AddRow(11, "test", "t1", "add index", "cancelled", 0).
+			AddRow(10, "test", "t2", "create table", "synced", 0))
+	ddls, err := e.runningDDLs(ctx)
+	require.Nil(t, err)
+	require.Len(t, ddls, 1)
+	require.Equal(t, addIndex.Query, ddls[0].Query)
+	require.Equal(t, uint64(10), ddls[0].CommitTs)
+	require.Equal(t, int64(12), ddls[0].JobID)
+	require.Equal(t, "running", ddls[0].State)
+	require.Equal(t, int64(1000), ddls[0].RowCount)
+	require.Nil(t, mock.ExpectationsWereMet())
+
+	// the DDLs of the other tables aren't blocked.
+	createTable := &model.DDLEvent{
+		CommitTs:  11,
+		Type:      timodel.ActionCreateTable,
+		TableInfo: &model.SimpleTableInfo{Schema: "test", Table: "t2"},
+	}
+	require.False(t, isAsyncDDL(createTable))
+	require.Nil(t, e.wait(ctx, createTable))
+
+	// the DDLs of the same table wait for the running DDL.
+	dropTable := &model.DDLEvent{
+		CommitTs:  12,
+		Type:      timodel.ActionDropTable,
+		TableInfo: &model.SimpleTableInfo{Schema: "test", Table: "t1"},
+	}
+	waitCtx, waitCancel := context.WithTimeout(ctx, 100*time.Millisecond)
+	defer waitCancel()
+	require.ErrorIs(t, e.wait(waitCtx, dropTable), context.DeadlineExceeded)
+
+	execErr := errors.New("add index failed")
+	finish <- execErr
+	require.ErrorIs(t, e.wait(ctx, dropTable), execErr)
+	_, err = e.runningDDLs(ctx)
+	require.ErrorIs(t, err, execErr)
+}
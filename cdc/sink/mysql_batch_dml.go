@@ -0,0 +1,296 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sink
+
+import (
+	"strings"
+
+	timodel "github.com/pingcap/tidb/parser/model"
+	"github.com/pingcap/tidb/parser/mysql"
+	parser_types "github.com/pingcap/tidb/parser/types"
+	"github.com/pingcap/tiflow/cdc/model"
+	"github.com/pingcap/tiflow/pkg/sqlmodel"
+)
+
+// batchedChange is a row change buffered in the dmlBatcher.
+type batchedChange struct {
+	*sqlmodel.RowChange
+	tableInfo *timodel.TableInfo
+	// replace is set if the insert may conflict with an existing row,
+	// e.g. DELETE + INSERT of the same row is compacted to a REPLACE.
+	replace bool
+}
+
+type batchTableKey struct {
+	table   model.TableName
+	version uint64
+}
+
+// dmlBatcher compacts the changes of the same rows and merges the changes
+// of the same table into multi-row statements by pkg/sqlmodel. It only
+// accepts the rows with handle keys, which identify the rows.
+// All the statements of one batcher are executed in one downstream
+// transaction, so the rows of different upstream transactions can be
+// compacted without breaking the atomicity.
+type dmlBatcher struct {
+	// translateToInsert is the same as the one in prepareDMLs, the updates
+	// are split into DELETE + REPLACE if it's false.
+	translateToInsert bool
+	ignoreConflicts   bool
+	batchSize         int
+
+	tableInfos map[batchTableKey]*timodel.TableInfo
+	// changes are in the order of the rows, the compacted ones are nil.
+	changes []*batchedChange
+	// keys are the positions of the changes by the table and identity key.
+	keys map[string]map[string]int
+	// lastPos are the positions of the last changes by the table.
+	lastPos map[string]int
+}
+
+func newDMLBatcher(translateToInsert, ignoreConflicts bool, batchSize int) *dmlBatcher {
+	return &dmlBatcher{
+		translateToInsert: translateToInsert,
+		ignoreConflicts:   ignoreConflicts,
+		batchSize:         batchSize,
+		tableInfos:        make(map[batchTableKey]*timodel.TableInfo),
+		keys:              make(map[string]map[string]int),
+		lastPos:           make(map[string]int),
+	}
+}
+
+// hasHandleKey returns whether the row can be identified by handle keys.
+func hasHandleKey(row *model.RowChangedEvent) bool {
+	cols := row.Columns
+	if len(cols) == 0 {
+		cols = row.PreColumns
+	}
+	for _, col := range cols {
+		if col != nil && col.Flag.IsHandleKey() {
+			return true
+		}
+	}
+	return false
+}
+
+// newBatchTableInfo builds the table info which sqlmodel needs from the
+// columns of the row, the handle key columns form a NOT NULL unique index.
+func newBatchTableInfo(cols []*model.Column) *timodel.TableInfo {
+	tableInfo := &timodel.TableInfo{}
+	handleIndex := &timodel.IndexInfo{Name: timodel.NewCIStr("handle"), Unique: true, State: timodel.StatePublic}
+	for _, col := range cols {
+		if col == nil || col.Flag.IsGeneratedColumn() {
+			continue
+		}
+		colInfo := &timodel.ColumnInfo{
+			ID:        int64(len(tableInfo.Columns) + 1),
+			Name:      timodel.NewCIStr(col.Name),
+			Offset:    len(tableInfo.Columns),
+			FieldType: *parser_types.NewFieldType(col.Type),
+			State:     timodel.StatePublic,
+		}
+		if col.Flag.IsHandleKey() {
+			colInfo.Flag |= mysql.NotNullFlag
+			handleIndex.Columns = append(handleIndex.Columns, &timodel.IndexColumn{
+				Name:   colInfo.Name,
+				Offset: colInfo.Offset,
+				Length: parser_types.UnspecifiedLength,
+			})
+		}
+		tableInfo.Columns = append(tableInfo.Columns, colInfo)
+	}
+	tableInfo.Indices = []*timodel.IndexInfo{handleIndex}
+	return tableInfo
+}
+
+// batchColumnValues returns the values of the columns in newBatchTableInfo.
+func batchColumnValues(cols []*model.Column) []interface{} {
+	values := make([]interface{}, 0, len(cols))
+	for _, col := range cols {
+		if col == nil || col.Flag.IsGeneratedColumn() {
+			continue
+		}
+		values = append(values, col.Value)
+	}
+	return values
+}
+
+func (b *dmlBatcher) tableInfo(row *model.RowChangedEvent) *timodel.TableInfo {
+	key := batchTableKey{table: *row.Table, version: row.TableInfoVersion}
+	if tableInfo, ok := b.tableInfos[key]; ok {
+		return tableInfo
+	}
+	cols := row.Columns
+	if len(cols) == 0 {
+		cols = row.PreColumns
+	}
+	tableInfo := newBatchTableInfo(cols)
+	b.tableInfos[key] = tableInfo
+	return tableInfo
+}
+
+// append adds the row to the batcher, the row must have handle keys.
+func (b *dmlBatcher) append(row *model.RowChangedEvent) {
+	tableInfo := b.tableInfo(row)
+	newChange := func(preValues, postValues []interface{}) *batchedChange {
+		return &batchedChange{
+			RowChange: sqlmodel.NewRowChange(row.Table, nil, preValues, postValues, tableInfo, nil, nil),
+			tableInfo: tableInfo,
+		}
+	}
+
+	if b.translateToInsert && row.IsUpdate() {
+		change := newChange(batchColumnValues(row.PreColumns), batchColumnValues(row.Columns))
+		// the updates of the identity are split, so the rows are still
+		// identified by the identity keys in the batcher.
+		if change.IsIdentityUpdated() {
+			del, ins := change.SplitUpdate()
+			b.compact(&batchedChange{RowChange: del, tableInfo: tableInfo})
+			b.compact(&batchedChange{RowChange: ins, tableInfo: tableInfo})
+			return
+		}
+		b.compact(change)
+		return
+	}
+	// the same as prepareDMLs, the updates are translated to DELETE + REPLACE
+	// if the old value is disabled or in safe mode.
+	if len(row.PreColumns) != 0 {
+		b.compact(newChange(batchColumnValues(row.PreColumns), nil))
+	}
+	if len(row.Columns) != 0 {
+		change := newChange(nil, batchColumnValues(row.Columns))
+		change.replace = !b.translateToInsert
+		b.compact(change)
+	}
+}
+
+// compact merges the change with the previous change of the same row, the
+// merged change takes the place of the latter one. The changes are only
+// merged if there is no change of the same table between them, otherwise
+// moving the previous change may break the unique keys other than the
+// identity, e.g. DELETE (1, 'a'), INSERT (2, 'a'), INSERT (1, 'b') would
+// insert (2, 'a') before (1, 'a') is deleted.
+func (b *dmlBatcher) compact(change *batchedChange) {
+	tableID := change.TargetTableID()
+	keys, ok := b.keys[tableID]
+	if !ok {
+		keys = make(map[string]int)
+		b.keys[tableID] = keys
+	}
+	key := change.IdentityKey()
+	if prevPos, ok := keys[key]; ok && prevPos == b.lastPos[tableID] {
+		prev := b.changes[prevPos]
+		switch {
+		case change.Type() == sqlmodel.RowChangeInsert && prev.Type() == sqlmodel.RowChangeDelete:
+			// DELETE + INSERT => REPLACE
+			change.replace = true
+		case change.Type() == sqlmodel.RowChangeUpdate && prev.Type() == sqlmodel.RowChangeInsert:
+			// INSERT + UPDATE => INSERT, DELETE + INSERT + UPDATE => REPLACE
+			change.replace = prev.replace
+			change.Reduce(prev.RowChange)
+		default:
+			change.Reduce(prev.RowChange)
+		}
+		b.changes[prevPos] = nil
+	}
+	keys[key] = len(b.changes)
+	b.lastPos[tableID] = len(b.changes)
+	b.changes = append(b.changes, change)
+}
+
+func (b *dmlBatcher) dmlType(change *batchedChange) sqlmodel.DMLType {
+	switch change.Type() {
+	case sqlmodel.RowChangeInsert:
+		if change.replace {
+			return sqlmodel.DMLReplace
+		}
+		return sqlmodel.DMLInsert
+	case sqlmodel.RowChangeUpdate:
+		return sqlmodel.DMLUpdate
+	default:
+		return sqlmodel.DMLDelete
+	}
+}
+
+// genSQL generates one statement for the changes with the same type, table
+// and columns.
+func (b *dmlBatcher) genSQL(tp sqlmodel.DMLType, changes []*sqlmodel.RowChange) (string, []interface{}) {
+	var query string
+	var args []interface{}
+	switch {
+	case len(changes) == 1:
+		query, args = changes[0].GenSQL(tp)
+	case tp == sqlmodel.DMLUpdate:
+		query, args = sqlmodel.GenUpdateSQL(changes...)
+	case tp == sqlmodel.DMLDelete:
+		query, args = sqlmodel.GenDeleteSQL(changes...)
+	default:
+		query, args = sqlmodel.GenInsertSQL(tp, changes...)
+	}
+	if b.ignoreConflicts {
+		switch tp {
+		case sqlmodel.DMLInsert:
+			query = "INSERT IGNORE" + strings.TrimPrefix(query, "INSERT")
+		case sqlmodel.DMLUpdate:
+			query = "UPDATE IGNORE" + strings.TrimPrefix(query, "UPDATE")
+		}
+	}
+	return query, args
+}
+
+// flush generates the statements of the buffered changes in order, the
+// consecutive changes of the same type and table are merged into one
+// statement with at most batchSize rows.
+func (b *dmlBatcher) flush() ([]string, [][]interface{}) {
+	if len(b.changes) == 0 {
+		return nil, nil
+	}
+	var (
+		sqls      []string
+		values    [][]interface{}
+		group     []*sqlmodel.RowChange
+		groupType sqlmodel.DMLType
+		groupInfo *timodel.TableInfo
+	)
+	flushGroup := func() {
+		for len(group) > 0 {
+			n := len(group)
+			if n > b.batchSize {
+				n = b.batchSize
+			}
+			query, args := b.genSQL(groupType, group[:n])
+			sqls = append(sqls, query)
+			values = append(values, args)
+			group = group[n:]
+		}
+	}
+	for _, change := range b.changes {
+		if change == nil {
+			continue
+		}
+		tp := b.dmlType(change)
+		if len(group) > 0 && (tp != groupType || change.tableInfo != groupInfo ||
+			!sqlmodel.SameTypeTargetAndColumns(group[len(group)-1], change.RowChange)) {
+			flushGroup()
+		}
+		groupType, groupInfo = tp, change.tableInfo
+		group = append(group, change.RowChange)
+	}
+	flushGroup()
+
+	b.changes = b.changes[:0]
+	b.keys = make(map[string]map[string]int)
+	b.lastPos = make(map[string]int)
+	return sqls, values
+}
@@ -0,0 +1,177 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sink
+
+import (
+	"context"
+	"testing"
+
+	"github.com/pingcap/tidb/parser/mysql"
+	"github.com/pingcap/tiflow/cdc/model"
+	"github.com/stretchr/testify/require"
+)
+
+func newBatchDMLRow(table string, pre, post []interface{}) *model.RowChangedEvent {
+	newColumns := func(values []interface{}) []*model.Column {
+		if values == nil {
+			return nil
+		}
+		return []*model.Column{
+			{Name: "id", Type: mysql.TypeLong, Flag: model.HandleKeyFlag | model.PrimaryKeyFlag, Value: values[0]},
+			{Name: "name", Type: mysql.TypeVarchar, Value: values[1]},
+			{Name: "gen", Type: mysql.TypeLong, Flag: model.GeneratedColumnFlag, Value: values[0]},
+		}
+	}
+	return &model.RowChangedEvent{
+		Table:      &model.TableName{Schema: "test", Table: table},
+		PreColumns: newColumns(pre),
+		Columns:    newColumns(post),
+	}
+}
+
+func TestPrepareDMLsBatchDML(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ms := newMySQLSink4Test(ctx, t)
+	ms.params.batchDMLEnabled = true
+	ms.params.batchDMLSize = 2
+	ms.params.safeMode = false
+	ms.params.enableOldValue = true
+
+	rows := []*model.RowChangedEvent{
+		newBatchDMLRow("t1", nil, []interface{}{1, "a"}),
+		newBatchDMLRow("t1", nil, []interface{}{2, "b"}),
+		newBatchDMLRow("t1", nil, []interface{}{3, "c"}),
+		// INSERT + UPDATE => INSERT
+		newBatchDMLRow("t1", []interface{}{3, "c"}, []interface{}{3, "cc"}),
+		newBatchDMLRow("t2", []interface{}{1, "a"}, []interface{}{1, "aa"}),
+		newBatchDMLRow("t2", []interface{}{2, "b"}, []interface{}{2, "bb"}),
+		// the update of the identity is split into DELETE + INSERT
+		newBatchDMLRow("t2", []interface{}{3, "c"}, []interface{}{4, "c"}),
+		newBatchDMLRow("t2", []interface{}{5, "e"}, nil),
+		// DELETE + INSERT => REPLACE
+		newBatchDMLRow("t2", nil, []interface{}{5, "ee"}),
+		// the rows without handle keys are not batched
+		{
+			Table:   &model.TableName{Schema: "test", Table: "t3"},
+			Columns: []*model.Column{{Name: "a", Type: mysql.TypeLong, Value: 1}},
+		},
+		newBatchDMLRow("t1", []interface{}{1, "a"}, nil),
+	}
+	dmls := ms.prepareDMLs(rows, 0, 0)
+	require.Equal(t, &preparedDMLs{
+		sqls: []string{
+			"INSERT INTO `test`.`t1` (`id`,`name`) VALUES (?,?),(?,?)",
+			"INSERT INTO `test`.`t1` (`id`,`name`) VALUES (?,?)",
+			"UPDATE `test`.`t2` SET `id`=CASE WHEN `id`=? THEN ? WHEN `id`=? THEN ? END, " +
+				"`name`=CASE WHEN `id`=? THEN ? WHEN `id`=? THEN ? END WHERE `id` IN (?,?)",
+			"DELETE FROM `test`.`t2` WHERE `id` = ? LIMIT 1",
+			"INSERT INTO `test`.`t2` (`id`,`name`) VALUES (?,?)",
+			"REPLACE INTO `test`.`t2` (`id`,`name`) VALUES (?,?)",
+			"INSERT INTO `test`.`t3`(`a`) VALUES (?);",
+			"DELETE FROM `test`.`t1` WHERE `id` = ? LIMIT 1",
+		},
+		values: [][]interface{}{
+			{1, "a", 2, "b"},
+			{3, "cc"},
+			{1, 1, 2, 2, 1, "aa", 2, "bb", 1, 2},
+			{3},
+			{4, "c"},
+			{5, "ee"},
+			{1},
+			{1},
+		},
+		rowCount: 11,
+	}, dmls)
+}
+
+func TestPrepareDMLsBatchDMLSafeMode(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ms := newMySQLSink4Test(ctx, t)
+	ms.params.batchDMLEnabled = true
+	ms.params.batchDMLSize = defaultBatchDMLSize
+	ms.params.safeMode = true
+	ms.params.enableOldValue = true
+
+	rows := []*model.RowChangedEvent{
+		newBatchDMLRow("t1", nil, []interface{}{1, "a"}),
+		newBatchDMLRow("t1", []interface{}{2, "b"}, []interface{}{2, "bb"}),
+		newBatchDMLRow("t1", []interface{}{3, "c"}, []interface{}{4, "c"}),
+	}
+	dmls := ms.prepareDMLs(rows, 0, 0)
+	// the updates are translated to DELETE + REPLACE in the safe mode, and
+	// DELETE + REPLACE of the same row is compacted to REPLACE.
+	require.Equal(t, &preparedDMLs{
+		sqls: []string{
+			"REPLACE INTO `test`.`t1` (`id`,`name`) VALUES (?,?),(?,?)",
+			"DELETE FROM `test`.`t1` WHERE `id` = ? LIMIT 1",
+			"REPLACE INTO `test`.`t1` (`id`,`name`) VALUES (?,?)",
+		},
+		values: [][]interface{}{
+			{1, "a", 2, "bb"},
+			{3},
+			{4, "c"},
+		},
+		rowCount: 3,
+	}, dmls)
+}
+
+func TestPrepareDMLsBatchDMLUniqueKey(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ms := newMySQLSink4Test(ctx, t)
+	ms.params.batchDMLEnabled = true
+	ms.params.batchDMLSize = defaultBatchDMLSize
+	ms.params.safeMode = false
+	ms.params.enableOldValue = true
+
+	newRow := func(pre, post []interface{}) *model.RowChangedEvent {
+		newColumns := func(values []interface{}) []*model.Column {
+			if values == nil {
+				return nil
+			}
+			return []*model.Column{
+				{Name: "id", Type: mysql.TypeLong, Flag: model.HandleKeyFlag | model.PrimaryKeyFlag, Value: values[0]},
+				{Name: "u", Type: mysql.TypeVarchar, Flag: model.UniqueKeyFlag, Value: values[1]},
+			}
+		}
+		return &model.RowChangedEvent{
+			Table:      &model.TableName{Schema: "test", Table: "t"},
+			PreColumns: newColumns(pre),
+			Columns:    newColumns(post),
+		}
+	}
+	rows := []*model.RowChangedEvent{
+		newRow([]interface{}{1, "a"}, nil),
+		newRow(nil, []interface{}{2, "a"}),
+		// the row 1 is not compacted to a REPLACE after the row 2, which
+		// would conflict with the old row 1 on the unique key.
+		newRow(nil, []interface{}{1, "b"}),
+		// the consecutive changes of the same row are still compacted.
+		newRow([]interface{}{1, "b"}, []interface{}{1, "c"}),
+	}
+	dmls := ms.prepareDMLs(rows, 0, 0)
+	require.Equal(t, &preparedDMLs{
+		sqls: []string{
+			"DELETE FROM `test`.`t` WHERE `id` = ? LIMIT 1",
+			"INSERT INTO `test`.`t` (`id`,`u`) VALUES (?,?),(?,?)",
+		},
+		values: [][]interface{}{
+			{1},
+			{2, "a", 1, "c"},
+		},
+		rowCount: 4,
+	}, dmls)
+}
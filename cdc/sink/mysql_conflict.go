@@ -0,0 +1,324 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sink
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/pingcap/errors"
+	"github.com/pingcap/log"
+	"github.com/pingcap/tidb/parser/mysql"
+	"github.com/pingcap/tiflow/cdc/model"
+	cerror "github.com/pingcap/tiflow/pkg/errors"
+	"github.com/pingcap/tiflow/pkg/quotes"
+	"go.uber.org/zap"
+)
+
+// conflictPolicy decides how the MySQL sink handles the rows conflicting
+// with the data in the downstream, such as the local writes.
+type conflictPolicy string
+
+const (
+	// conflictPolicyError fails the changefeed on conflicts, the inserts are
+	// still replaced in the safe mode.
+	conflictPolicyError conflictPolicy = "error"
+	// conflictPolicyOverwrite overwrites the conflicting rows, it's the same
+	// as the safe mode.
+	conflictPolicyOverwrite conflictPolicy = "overwrite"
+	// conflictPolicyIgnore keeps the conflicting rows in the downstream by
+	// `INSERT IGNORE` and `UPDATE IGNORE`.
+	conflictPolicyIgnore conflictPolicy = "ignore"
+	// conflictPolicyLogAndSkip executes the rows one by one, the rows with
+	// duplicate keys or updating and deleting missing rows are skipped and
+	// recorded in the conflict log.
+	conflictPolicyLogAndSkip conflictPolicy = "log-and-skip"
+)
+
+const (
+	conflictReasonDuplicateKey = "duplicate-key"
+	conflictReasonMissingRow   = "missing-row"
+)
+
+// parseConflictParams parses the conflict policy and the conflict log, the
+// policies except error override the safe mode.
+func parseConflictParams(query url.Values, params *sinkParams) error {
+	s := query.Get("on-conflict")
+	if s != "" {
+		switch policy := conflictPolicy(s); policy {
+		case conflictPolicyError, conflictPolicyOverwrite, conflictPolicyIgnore, conflictPolicyLogAndSkip:
+			params.onConflict = policy
+		default:
+			return cerror.WrapError(cerror.ErrMySQLInvalidConfig,
+				fmt.Errorf("invalid on-conflict %s, should be error, overwrite, ignore or log-and-skip", s))
+		}
+	}
+	switch params.onConflict {
+	case conflictPolicyOverwrite:
+		params.safeMode = true
+	case conflictPolicyIgnore:
+		params.safeMode = false
+	case conflictPolicyLogAndSkip:
+		// The statements are executed one by one to find the conflicting rows.
+		params.safeMode = false
+		params.batchReplaceEnabled = false
+		params.batchDMLEnabled = false
+	}
+
+	params.conflictLogTable = query.Get("conflict-log-table")
+	params.conflictLogFile = query.Get("conflict-log-file")
+	if params.conflictLogTable == "" && params.conflictLogFile == "" {
+		return nil
+	}
+	if params.onConflict != conflictPolicyLogAndSkip {
+		return cerror.WrapError(cerror.ErrMySQLInvalidConfig,
+			errors.New("conflict-log-table and conflict-log-file only work with on-conflict=log-and-skip"))
+	}
+	if params.conflictLogTable != "" && params.conflictLogFile != "" {
+		return cerror.WrapError(cerror.ErrMySQLInvalidConfig,
+			errors.New("conflict-log-table and conflict-log-file can't be both set"))
+	}
+	if params.conflictLogTable != "" && len(strings.Split(params.conflictLogTable, ".")) != 2 {
+		return cerror.WrapError(cerror.ErrMySQLInvalidConfig,
+			fmt.Errorf("invalid conflict-log-table %s, should be schema.table", params.conflictLogTable))
+	}
+	return nil
+}
+
+// conflictRecord records a row skipped by the log-and-skip policy.
+type conflictRecord struct {
+	StartTs    uint64                 `json:"start-ts"`
+	CommitTs   uint64                 `json:"commit-ts"`
+	Schema     string                 `json:"schema"`
+	Table      string                 `json:"table"`
+	Reason     string                 `json:"reason"`
+	Message    string                 `json:"message,omitempty"`
+	PreColumns map[string]interface{} `json:"pre-columns,omitempty"`
+	Columns    map[string]interface{} `json:"columns,omitempty"`
+}
+
+func newConflictRecord(row *model.RowChangedEvent, reason string, err error) *conflictRecord {
+	columnValues := func(cols []*model.Column) map[string]interface{} {
+		if len(cols) == 0 {
+			return nil
+		}
+		values := make(map[string]interface{}, len(cols))
+		for _, col := range cols {
+			if col == nil {
+				continue
+			}
+			if b, ok := col.Value.([]byte); ok {
+				values[col.Name] = string(b)
+				continue
+			}
+			values[col.Name] = col.Value
+		}
+		return values
+	}
+	record := &conflictRecord{
+		StartTs:    row.StartTs,
+		CommitTs:   row.CommitTs,
+		Schema:     row.Table.Schema,
+		Table:      row.Table.Table,
+		Reason:     reason,
+		PreColumns: columnValues(row.PreColumns),
+		Columns:    columnValues(row.Columns),
+	}
+	if err != nil {
+		record.Message = err.Error()
+	}
+	return record
+}
+
+// conflictLog records the skipped rows in a downstream table or a local file,
+// the rows are only logged if neither of them is configured.
+type conflictLog struct {
+	changefeedID string
+	// table is the quoted name of the conflict table, the records are written
+	// in the same transaction as the other rows.
+	table string
+	// file is appended after the transaction is committed, so the records
+	// may be duplicated if the transaction is retried.
+	fileMu sync.Mutex
+	file   *os.File
+}
+
+func newConflictLog(ctx context.Context, db *sql.DB, params *sinkParams) (*conflictLog, error) {
+	l := &conflictLog{changefeedID: params.changefeedID}
+	if params.conflictLogTable != "" {
+		names := strings.Split(params.conflictLogTable, ".")
+		l.table = quotes.QuoteSchema(names[0], names[1])
+		for _, query := range []string{
+			"CREATE DATABASE IF NOT EXISTS " + quotes.QuoteName(names[0]),
+			"CREATE TABLE IF NOT EXISTS " + l.table + ` (
+				id BIGINT NOT NULL AUTO_INCREMENT PRIMARY KEY,
+				changefeed VARCHAR(255) NOT NULL,
+				start_ts BIGINT UNSIGNED NOT NULL,
+				commit_ts BIGINT UNSIGNED NOT NULL,
+				schema_name VARCHAR(128) NOT NULL,
+				table_name VARCHAR(128) NOT NULL,
+				reason VARCHAR(32) NOT NULL,
+				message TEXT,
+				row_data LONGTEXT,
+				created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+			)`,
+		} {
+			if _, err := db.ExecContext(ctx, query); err != nil {
+				return nil, cerror.WrapError(cerror.ErrMySQLQueryError, err)
+			}
+		}
+	}
+	if params.conflictLogFile != "" {
+		file, err := os.OpenFile(params.conflictLogFile, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+		if err != nil {
+			return nil, cerror.WrapError(cerror.ErrMySQLInvalidConfig, err)
+		}
+		l.file = file
+	}
+	return l, nil
+}
+
+// execInTxn writes the records to the conflict table in the transaction.
+func (l *conflictLog) execInTxn(ctx context.Context, tx *sql.Tx, records []*conflictRecord) error {
+	if l.table == "" {
+		return nil
+	}
+	query := "INSERT INTO " + l.table +
+		" (changefeed, start_ts, commit_ts, schema_name, table_name, reason, message, row_data)" +
+		" VALUES (?,?,?,?,?,?,?,?);"
+	for _, record := range records {
+		rowData, err := json.Marshal(struct {
+			PreColumns map[string]interface{} `json:"pre-columns,omitempty"`
+			Columns    map[string]interface{} `json:"columns,omitempty"`
+		}{record.PreColumns, record.Columns})
+		if err != nil {
+			return cerror.WrapError(cerror.ErrMarshalFailed, err)
+		}
+		if _, err := tx.ExecContext(ctx, query, l.changefeedID, record.StartTs, record.CommitTs,
+			record.Schema, record.Table, record.Reason, record.Message, string(rowData)); err != nil {
+			return cerror.WrapError(cerror.ErrMySQLTxnError, err)
+		}
+	}
+	return nil
+}
+
+// commit logs the records and appends them to the conflict file, it's called
+// after the transaction is committed, so the failures of writing the file are
+// only logged, otherwise the committed transaction would be retried.
+func (l *conflictLog) commit(records []*conflictRecord) {
+	for _, record := range records {
+		log.Warn("row conflicts with the downstream, skip it",
+			zap.String("changefeed", l.changefeedID),
+			zap.Uint64("commitTs", record.CommitTs),
+			zap.String("schema", record.Schema),
+			zap.String("table", record.Table),
+			zap.String("reason", record.Reason),
+			zap.String("message", record.Message))
+	}
+	if l.file == nil {
+		return
+	}
+	l.fileMu.Lock()
+	defer l.fileMu.Unlock()
+	for _, record := range records {
+		data, err := json.Marshal(struct {
+			Changefeed string `json:"changefeed"`
+			*conflictRecord
+		}{l.changefeedID, record})
+		if err == nil {
+			_, err = l.file.Write(append(data, '\n'))
+		}
+		if err != nil {
+			log.Error("failed to write the conflict log file",
+				zap.String("changefeed", l.changefeedID),
+				zap.String("file", l.file.Name()), zap.Error(err))
+			return
+		}
+	}
+}
+
+func (l *conflictLog) close() error {
+	if l == nil || l.file == nil {
+		return nil
+	}
+	return l.file.Close()
+}
+
+func isDuplicateKeyError(err error) bool {
+	errCode, ok := getSQLErrCode(err)
+	return ok && errCode == mysql.ErrDupEntry
+}
+
+// execDMLsSkippingConflicts executes the statements of the rows one by one,
+// the conflicting rows are skipped and recorded in the conflict log.
+func (s *mysqlSink) execDMLsSkippingConflicts(ctx context.Context, dmls *preparedDMLs) (int, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, logDMLTxnErr(cerror.WrapError(cerror.ErrMySQLTxnError, err))
+	}
+	rollback := func(err error) (int, error) {
+		if rbErr := tx.Rollback(); rbErr != nil {
+			log.Warn("failed to rollback txn", zap.Error(rbErr))
+		}
+		return 0, logDMLTxnErr(err)
+	}
+
+	var records []*conflictRecord
+	for i, query := range dmls.sqls {
+		args := dmls.values[i]
+		row := dmls.rows[i]
+		log.Debug("exec row", zap.String("sql", query), zap.Any("args", args))
+		// The failed statement is rolled back by the downstream, and the
+		// transaction continues.
+		res, err := tx.ExecContext(ctx, query, args...)
+		if err != nil {
+			if row != nil && isDuplicateKeyError(err) {
+				records = append(records, newConflictRecord(row, conflictReasonDuplicateKey, err))
+				continue
+			}
+			return rollback(cerror.WrapError(cerror.ErrMySQLTxnError, err))
+		}
+		if row == nil || row.IsInsert() {
+			continue
+		}
+		affected, err := res.RowsAffected()
+		if err != nil {
+			return rollback(cerror.WrapError(cerror.ErrMySQLTxnError, err))
+		}
+		if affected == 0 {
+			records = append(records, newConflictRecord(row, conflictReasonMissingRow, nil))
+		}
+	}
+
+	if err := s.conflictLog.execInTxn(ctx, tx, records); err != nil {
+		return rollback(err)
+	}
+	if len(dmls.markSQL) != 0 {
+		log.Debug("exec row", zap.String("sql", dmls.markSQL))
+		if _, err := tx.ExecContext(ctx, dmls.markSQL); err != nil {
+			return rollback(cerror.WrapError(cerror.ErrMySQLTxnError, err))
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, logDMLTxnErr(cerror.WrapError(cerror.ErrMySQLTxnError, err))
+	}
+	s.conflictLog.commit(records)
+	return dmls.rowCount - len(records), nil
+}
@@ -0,0 +1,160 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sink
+
+import (
+	"context"
+	"encoding/json"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	dmysql "github.com/go-sql-driver/mysql"
+	"github.com/pingcap/tidb/parser/mysql"
+	"github.com/pingcap/tiflow/cdc/model"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseConflictParams(t *testing.T) {
+	t.Parallel()
+
+	for _, tc := range []struct {
+		query      string
+		onConflict conflictPolicy
+		safeMode   bool
+		batch      bool
+	}{
+		{"", conflictPolicyError, defaultSafeMode, defaultBatchReplaceEnabled},
+		{"safe-mode=false", conflictPolicyError, false, defaultBatchReplaceEnabled},
+		{"on-conflict=overwrite&safe-mode=false", conflictPolicyOverwrite, true, defaultBatchReplaceEnabled},
+		{"on-conflict=ignore", conflictPolicyIgnore, false, defaultBatchReplaceEnabled},
+		{"on-conflict=log-and-skip&conflict-log-table=tidb_cdc.conflicts", conflictPolicyLogAndSkip, false, false},
+	} {
+		uri, err := url.Parse("mysql://127.0.0.1:3306/?" + tc.query)
+		require.Nil(t, err)
+		params, err := parseSinkURIToParams(context.TODO(), uri, map[string]string{})
+		require.Nil(t, err)
+		require.Equal(t, tc.onConflict, params.onConflict, tc.query)
+		require.Equal(t, tc.safeMode, params.safeMode, tc.query)
+		require.Equal(t, tc.batch, params.batchReplaceEnabled, tc.query)
+	}
+
+	for _, query := range []string{
+		"on-conflict=replace",
+		"conflict-log-file=/tmp/conflicts.log",
+		"on-conflict=log-and-skip&conflict-log-table=conflicts",
+		"on-conflict=log-and-skip&conflict-log-table=tidb_cdc.conflicts&conflict-log-file=/tmp/conflicts.log",
+	} {
+		uri, err := url.Parse("mysql://127.0.0.1:3306/?" + query)
+		require.Nil(t, err)
+		_, err = parseSinkURIToParams(context.TODO(), uri, map[string]string{})
+		require.Error(t, err, query)
+	}
+}
+
+func TestPrepareDMLsIgnoreConflicts(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ms := newMySQLSink4Test(ctx, t)
+	ms.params.onConflict = conflictPolicyIgnore
+	ms.params.safeMode = false
+	ms.params.enableOldValue = true
+
+	rows := []*model.RowChangedEvent{{
+		Table:   &model.TableName{Schema: "s1", Table: "t1"},
+		Columns: []*model.Column{{Name: "a", Type: mysql.TypeLong, Flag: model.HandleKeyFlag, Value: 1}},
+	}, {
+		Table:      &model.TableName{Schema: "s1", Table: "t1"},
+		PreColumns: []*model.Column{{Name: "a", Type: mysql.TypeLong, Flag: model.HandleKeyFlag, Value: 1}},
+		Columns:    []*model.Column{{Name: "a", Type: mysql.TypeLong, Flag: model.HandleKeyFlag, Value: 2}},
+	}}
+	dmls := ms.prepareDMLs(rows, 0, 0)
+	require.Equal(t, &preparedDMLs{
+		sqls: []string{
+			"INSERT IGNORE INTO `s1`.`t1`(`a`) VALUES (?);",
+			"UPDATE IGNORE `s1`.`t1` SET `a`=? WHERE `a`=? LIMIT 1;",
+		},
+		values:   [][]interface{}{{1}, {2, 1}},
+		rowCount: 2,
+	}, dmls)
+}
+
+func TestExecDMLsSkippingConflicts(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	require.Nil(t, err)
+	defer db.Close()
+	logFile := filepath.Join(t.TempDir(), "conflicts.log")
+	file, err := os.OpenFile(logFile, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	require.Nil(t, err)
+
+	ms := newMySQLSink4Test(ctx, t)
+	ms.db = db
+	ms.params.onConflict = conflictPolicyLogAndSkip
+	ms.params.safeMode = false
+	ms.params.enableOldValue = true
+	ms.conflictLog = &conflictLog{
+		changefeedID: "test-changefeed",
+		table:        "`tidb_cdc`.`conflicts`",
+		file:         file,
+	}
+	defer ms.conflictLog.close()
+
+	newColumns := func(v int) []*model.Column {
+		return []*model.Column{{Name: "a", Type: mysql.TypeLong, Flag: model.HandleKeyFlag, Value: v}}
+	}
+	rows := []*model.RowChangedEvent{
+		{CommitTs: 10, Table: &model.TableName{Schema: "s1", Table: "t1"}, Columns: newColumns(1)},
+		{CommitTs: 10, Table: &model.TableName{Schema: "s1", Table: "t1"}, PreColumns: newColumns(2), Columns: newColumns(3)},
+		{CommitTs: 10, Table: &model.TableName{Schema: "s1", Table: "t1"}, PreColumns: newColumns(4)},
+	}
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT INTO `s1`.`t1`(`a`) VALUES (?);").WithArgs(1).
+		WillReturnError(&dmysql.MySQLError{Number: uint16(mysql.ErrDupEntry), Message: "Duplicate entry '1'"})
+	mock.ExpectExec("UPDATE `s1`.`t1` SET `a`=? WHERE `a`=? LIMIT 1;").WithArgs(3, 2).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("DELETE FROM `s1`.`t1` WHERE `a` = ? LIMIT 1;").WithArgs(4).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	insertConflict := "INSERT INTO `tidb_cdc`.`conflicts`" +
+		" (changefeed, start_ts, commit_ts, schema_name, table_name, reason, message, row_data)" +
+		" VALUES (?,?,?,?,?,?,?,?);"
+	mock.ExpectExec(insertConflict).
+		WithArgs("test-changefeed", 0, 10, "s1", "t1", conflictReasonDuplicateKey, sqlmock.AnyArg(), `{"columns":{"a":1}}`).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec(insertConflict).
+		WithArgs("test-changefeed", 0, 10, "s1", "t1", conflictReasonMissingRow, "", `{"pre-columns":{"a":2},"columns":{"a":3}}`).
+		WillReturnResult(sqlmock.NewResult(2, 1))
+	mock.ExpectCommit()
+
+	err = ms.execDMLs(ctx, rows, 0, 0)
+	require.Nil(t, err)
+	require.Nil(t, mock.ExpectationsWereMet())
+
+	data, err := os.ReadFile(logFile)
+	require.Nil(t, err)
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	require.Len(t, lines, 2)
+	for i, reason := range []string{conflictReasonDuplicateKey, conflictReasonMissingRow} {
+		record := make(map[string]interface{})
+		require.Nil(t, json.Unmarshal([]byte(lines[i]), &record))
+		require.Equal(t, "test-changefeed", record["changefeed"])
+		require.Equal(t, reason, record["reason"])
+		require.EqualValues(t, 10, record["commit-ts"])
+	}
+}
@@ -46,10 +46,13 @@ const (
 	defaultFlushInterval       = time.Millisecond * 50
 	defaultBatchReplaceEnabled = true
 	defaultBatchReplaceSize    = 20
+	defaultBatchDMLEnabled     = false
+	defaultBatchDMLSize        = 20
 	defaultReadTimeout         = "2m"
 	defaultWriteTimeout        = "2m"
 	defaultDialTimeout         = "2m"
 	defaultSafeMode            = true
+	defaultOnConflict          = conflictPolicyError
 	defaultTxnIsolationRC      = "READ-COMMITTED"
 )
 
@@ -59,10 +62,13 @@ var defaultParams = &sinkParams{
 	tidbTxnMode:         defaultTiDBTxnMode,
 	batchReplaceEnabled: defaultBatchReplaceEnabled,
 	batchReplaceSize:    defaultBatchReplaceSize,
+	batchDMLEnabled:     defaultBatchDMLEnabled,
+	batchDMLSize:        defaultBatchDMLSize,
 	readTimeout:         defaultReadTimeout,
 	writeTimeout:        defaultWriteTimeout,
 	dialTimeout:         defaultDialTimeout,
 	safeMode:            defaultSafeMode,
+	onConflict:          defaultOnConflict,
 }
 
 var validSchemes = map[string]bool{
@@ -80,6 +86,9 @@ type sinkParams struct {
 	captureAddr         string
 	batchReplaceEnabled bool
 	batchReplaceSize    int
+	batchDMLEnabled     bool
+	batchDMLSize        int
+	asyncDDLEnabled     bool
 	readTimeout         string
 	writeTimeout        string
 	dialTimeout         string
@@ -87,6 +96,9 @@ type sinkParams struct {
 	safeMode            bool
 	timezone            string
 	tls                 string
+	onConflict          conflictPolicy
+	conflictLogTable    string
+	conflictLogFile     string
 }
 
 func (s *sinkParams) Clone() *sinkParams {
@@ -187,6 +199,35 @@ func parseSinkURIToParams(ctx context.Context, sinkURI *url.URL, opts map[string
 		params.batchReplaceSize = size
 	}
 
+	s = sinkURI.Query().Get("batch-dml-enable")
+	if s != "" {
+		enable, err := strconv.ParseBool(s)
+		if err != nil {
+			return nil, cerror.WrapError(cerror.ErrMySQLInvalidConfig, err)
+		}
+		params.batchDMLEnabled = enable
+	}
+	if params.batchDMLEnabled && sinkURI.Query().Get("batch-dml-size") != "" {
+		size, err := strconv.Atoi(sinkURI.Query().Get("batch-dml-size"))
+		if err != nil {
+			return nil, cerror.WrapError(cerror.ErrMySQLInvalidConfig, err)
+		}
+		if size <= 0 {
+			return nil, cerror.WrapError(cerror.ErrMySQLInvalidConfig,
+				fmt.Errorf("invalid batch-dml-size %d, which must be greater than 0", size))
+		}
+		params.batchDMLSize = size
+	}
+
+	s = sinkURI.Query().Get("async-ddl-enable")
+	if s != "" {
+		enable, err := strconv.ParseBool(s)
+		if err != nil {
+			return nil, cerror.WrapError(cerror.ErrMySQLInvalidConfig, err)
+		}
+		params.asyncDDLEnabled = enable
+	}
+
 	// TODO: force safe mode in startup phase
 	s = sinkURI.Query().Get("safe-mode")
 	if s != "" {
@@ -197,6 +238,10 @@ func parseSinkURIToParams(ctx context.Context, sinkURI *url.URL, opts map[string
 		params.safeMode = safeModeEnabled
 	}
 
+	if err := parseConflictParams(sinkURI.Query(), params); err != nil {
+		return nil, err
+	}
+
 	if _, ok := sinkURI.Query()["time-zone"]; ok {
 		s = sinkURI.Query().Get("time-zone")
 		if s == "" {
@@ -287,6 +332,20 @@ func generateDSNByParams(
 		dsnCfg.Params["tidb_txn_mode"] = txnMode
 	}
 
+	if params.onConflict == conflictPolicyLogAndSkip {
+		// The conflicting statements are detected one by one, so the unique
+		// keys are checked when the statements are executed, and the matched
+		// rows are counted even if they are not changed by the updates.
+		dsnCfg.ClientFoundRows = true
+		checkInPlace, err := checkTiDBVariable(ctx, testDB, "tidb_constraint_check_in_place", "1")
+		if err != nil {
+			return "", err
+		}
+		if checkInPlace != "" {
+			dsnCfg.Params["tidb_constraint_check_in_place"] = checkInPlace
+		}
+	}
+
 	dsnClone := dsnCfg.Clone()
 	dsnClone.Passwd = "******"
 	log.Info("sink uri is configured", zap.String("dsn", dsnClone.FormatDSN()))
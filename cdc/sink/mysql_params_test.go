@@ -38,10 +38,12 @@ func TestSinkParamsClone(t *testing.T) {
 		tidbTxnMode:         defaultTiDBTxnMode,
 		batchReplaceEnabled: defaultBatchReplaceEnabled,
 		batchReplaceSize:    defaultBatchReplaceSize,
+		batchDMLSize:        defaultBatchDMLSize,
 		readTimeout:         defaultReadTimeout,
 		writeTimeout:        defaultWriteTimeout,
 		dialTimeout:         defaultDialTimeout,
 		safeMode:            defaultSafeMode,
+		onConflict:          defaultOnConflict,
 	}, param1)
 	require.Equal(t, &sinkParams{
 		changefeedID:        "123",
@@ -50,10 +52,12 @@ func TestSinkParamsClone(t *testing.T) {
 		tidbTxnMode:         defaultTiDBTxnMode,
 		batchReplaceEnabled: false,
 		batchReplaceSize:    defaultBatchReplaceSize,
+		batchDMLSize:        defaultBatchDMLSize,
 		readTimeout:         defaultReadTimeout,
 		writeTimeout:        defaultWriteTimeout,
 		dialTimeout:         defaultDialTimeout,
 		safeMode:            defaultSafeMode,
+		onConflict:          defaultOnConflict,
 	}, param2)
 }
 
@@ -130,6 +134,9 @@ func TestParseSinkURIToParams(t *testing.T) {
 	expected.maxTxnRow = 20
 	expected.batchReplaceEnabled = true
 	expected.batchReplaceSize = 50
+	expected.batchDMLEnabled = true
+	expected.batchDMLSize = 40
+	expected.asyncDDLEnabled = true
 	expected.safeMode = true
 	expected.timezone = `"UTC"`
 	expected.changefeedID = "cf-id"
@@ -137,6 +144,7 @@ func TestParseSinkURIToParams(t *testing.T) {
 	expected.tidbTxnMode = "pessimistic"
 	uriStr := "mysql://127.0.0.1:3306/?worker-count=64&max-txn-row=20" +
 		"&batch-replace-enable=true&batch-replace-size=50&safe-mode=true" +
+		"&batch-dml-enable=true&batch-dml-size=40&async-ddl-enable=true" +
 		"&tidb-txn-mode=pessimistic"
 	opts := map[string]string{
 		OptChangefeedID: expected.changefeedID,
@@ -224,6 +232,10 @@ func TestParseSinkURIBadQueryString(t *testing.T) {
 		"mysql://127.0.0.1:3306/?ssl-ca=only-ca-exists",
 		"mysql://127.0.0.1:3306/?batch-replace-enable=not-bool",
 		"mysql://127.0.0.1:3306/?batch-replace-enable=true&batch-replace-size=not-number",
+		"mysql://127.0.0.1:3306/?batch-dml-enable=not-bool",
+		"mysql://127.0.0.1:3306/?batch-dml-enable=true&batch-dml-size=not-number",
+		"mysql://127.0.0.1:3306/?batch-dml-enable=true&batch-dml-size=0",
+		"mysql://127.0.0.1:3306/?async-ddl-enable=not-bool",
 		"mysql://127.0.0.1:3306/?safe-mode=not-bool",
 		"mysql://127.0.0.1:3306/?time-zone=badtz",
 		"mysql://127.0.0.1:3306/?write-timeout=badduration",
@@ -33,6 +33,7 @@ import (
 	"github.com/pingcap/tidb/parser/mysql"
 	"github.com/pingcap/tiflow/cdc/model"
 	"github.com/pingcap/tiflow/cdc/sink/common"
+	"github.com/pingcap/tiflow/cdc/sink/router"
 	"github.com/pingcap/tiflow/pkg/config"
 	"github.com/pingcap/tiflow/pkg/cyclic/mark"
 	cerror "github.com/pingcap/tiflow/pkg/errors"
@@ -44,11 +45,14 @@ import (
 func newMySQLSink4Test(ctx context.Context, t *testing.T) *mysqlSink {
 	f, err := filter.NewFilter(config.GetDefaultReplicaConfig())
 	require.Nil(t, err)
+	r, err := router.New(config.GetDefaultReplicaConfig())
+	require.Nil(t, err)
 	params := defaultParams.Clone()
 	params.batchReplaceEnabled = false
 	return &mysqlSink{
 		txnCache:   common.NewUnresolvedTxnCache(),
 		filter:     f,
+		router:     r,
 		statistics: NewStatistics(ctx, "test", make(map[string]string)),
 		params:     params,
 	}
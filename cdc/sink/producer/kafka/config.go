@@ -46,6 +46,9 @@ type Config struct {
 	SaslScram       *security.SaslScram
 	// control whether to create topic
 	AutoCreate bool
+	// EnableHeaders controls whether to attach the headers describing the
+	// events, such as the commit ts and the table, to the messages.
+	EnableHeaders bool
 
 	// Timeout for sarama `config.Net` configurations, default to `10s`
 	DialTimeout  time.Duration
@@ -188,6 +191,20 @@ func CompleteConfigsAndOpts(sinkURI *url.URL, producerConfig *Config, replicaCon
 		producerConfig.AutoCreate = autoCreate
 	}
 
+	s = params.Get("enable-headers")
+	if s != "" {
+		enableHeaders, err := strconv.ParseBool(s)
+		if err != nil {
+			return err
+		}
+		producerConfig.EnableHeaders = enableHeaders
+	}
+
+	s = params.Get("claim-check-storage-uri")
+	if s != "" {
+		opts["claim-check-storage-uri"] = s
+	}
+
 	s = params.Get(config.ProtocolKey)
 	if s != "" {
 		replicaConfig.Sink.Protocol = s
@@ -199,8 +216,11 @@ func CompleteConfigsAndOpts(sinkURI *url.URL, producerConfig *Config, replicaCon
 		if err != nil {
 			return err
 		}
-		if replicaConfig.Sink.Protocol != "canal-json" {
-			return cerror.WrapError(cerror.ErrKafkaInvalidConfig, errors.New("enable-tidb-extension only support canal-json protocol"))
+		switch replicaConfig.Sink.Protocol {
+		case config.ProtocolCanalJSON.String(), config.ProtocolAvro.String(), config.ProtocolDebezium.String():
+		default:
+			return cerror.WrapError(cerror.ErrKafkaInvalidConfig,
+				errors.New("enable-tidb-extension only support canal-json, avro and debezium protocol"))
 		}
 		opts["enable-tidb-extension"] = s
 	}
@@ -257,6 +277,11 @@ func newSaramaConfig(ctx context.Context, c *Config) (*sarama.Config, error) {
 		return nil, errors.Trace(err)
 	}
 	config.Version = version
+	// Kafka supports the record headers since 0.11.0.
+	if c.EnableHeaders && !version.IsAtLeast(sarama.V0_11_0_0) {
+		return nil, cerror.ErrKafkaInvalidConfig.GenWithStack(
+			"enable-headers requires kafka-version 0.11.0.0 or later, but it's %s", c.Version)
+	}
 
 	// Producer fetch metadata from brokers frequently, if metadata cannot be
 	// refreshed easily, this would indicate the network condition between the
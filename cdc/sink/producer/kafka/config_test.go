@@ -17,11 +17,14 @@ import (
 	"context"
 	"fmt"
 	"net/url"
+	"strconv"
 	"time"
 
 	"github.com/Shopify/sarama"
 	"github.com/pingcap/check"
 	"github.com/pingcap/errors"
+	"github.com/pingcap/tiflow/cdc/model"
+	"github.com/pingcap/tiflow/cdc/sink/codec"
 	"github.com/pingcap/tiflow/pkg/config"
 	cerror "github.com/pingcap/tiflow/pkg/errors"
 	"github.com/pingcap/tiflow/pkg/security"
@@ -190,7 +193,7 @@ func (s *kafkaSuite) TestCompleteConfigByOpts(c *check.C) {
 	c.Assert(err, check.IsNil)
 	cfg = NewConfig()
 	err = CompleteConfigsAndOpts(sinkURI, cfg, config.GetDefaultReplicaConfig(), opts)
-	c.Assert(errors.Cause(err), check.ErrorMatches, ".*enable-tidb-extension only support canal-json protocol.*")
+	c.Assert(errors.Cause(err), check.ErrorMatches, ".*enable-tidb-extension only support canal-json, avro and debezium protocol.*")
 
 	// Test enable-tidb-extension.
 	uri = "kafka://127.0.0.1:9092/abc?enable-tidb-extension=true&protocol=canal-json"
@@ -206,6 +209,85 @@ func (s *kafkaSuite) TestCompleteConfigByOpts(c *check.C) {
 	for k, v := range opts {
 		c.Assert(v, check.Equals, expectedOpts[k])
 	}
+
+	// Test enable-tidb-extension on avro protocol.
+	uri = "kafka://127.0.0.1:9092/abc?enable-tidb-extension=true&protocol=avro"
+	sinkURI, err = url.Parse(uri)
+	c.Assert(err, check.IsNil)
+	cfg = NewConfig()
+	opts = make(map[string]string)
+	err = CompleteConfigsAndOpts(sinkURI, cfg, config.GetDefaultReplicaConfig(), opts)
+	c.Assert(err, check.IsNil)
+	c.Assert(opts["enable-tidb-extension"], check.Equals, "true")
+}
+
+func (s *kafkaSuite) TestDebeziumTiDBExtension(c *check.C) {
+	defer testleak.AfterTest(c)()
+	ctx := context.Background()
+	for _, enable := range []bool{false, true} {
+		sinkURI, err := url.Parse(fmt.Sprintf(
+			"kafka://127.0.0.1:9092/abc?protocol=debezium&enable-tidb-extension=%t", enable))
+		c.Assert(err, check.IsNil)
+		replicaConfig := config.GetDefaultReplicaConfig()
+		opts := make(map[string]string)
+		err = CompleteConfigsAndOpts(sinkURI, NewConfig(), replicaConfig, opts)
+		c.Assert(err, check.IsNil)
+		c.Assert(opts["enable-tidb-extension"], check.Equals, strconv.FormatBool(enable))
+
+		var protocol config.Protocol
+		c.Assert(protocol.FromString(replicaConfig.Sink.Protocol), check.IsNil)
+		builder, err := codec.NewEventBatchEncoderBuilder(protocol, &security.Credential{}, opts)
+		c.Assert(err, check.IsNil)
+		encoder, err := builder.Build(ctx)
+		c.Assert(err, check.IsNil)
+		msg, err := encoder.EncodeCheckpointEvent(417318403368288260)
+		c.Assert(err, check.IsNil)
+		// the watermark messages are only sent with the TiDB extension.
+		if !enable {
+			c.Assert(msg, check.IsNil)
+			continue
+		}
+		c.Assert(msg, check.NotNil)
+
+		decoder, err := codec.NewDebeziumEventBatchDecoder(msg.Key, msg.Value)
+		c.Assert(err, check.IsNil)
+		tp, hasNext, err := decoder.HasNext()
+		c.Assert(err, check.IsNil)
+		c.Assert(hasNext, check.IsTrue)
+		c.Assert(tp, check.Equals, model.MqMessageTypeResolved)
+		ts, err := decoder.NextResolvedEvent()
+		c.Assert(err, check.IsNil)
+		c.Assert(ts, check.Equals, uint64(417318403368288260))
+	}
+}
+
+func (s *kafkaSuite) TestEnableHeaders(c *check.C) {
+	defer testleak.AfterTest(c)()
+	sinkURI, err := url.Parse("kafka://127.0.0.1:9092/abc?kafka-version=0.10.2.0&enable-headers=true")
+	c.Assert(err, check.IsNil)
+	cfg := NewConfig()
+	err = CompleteConfigsAndOpts(sinkURI, cfg, config.GetDefaultReplicaConfig(), make(map[string]string))
+	c.Assert(err, check.IsNil)
+	c.Assert(cfg.EnableHeaders, check.IsTrue)
+	// the headers are not supported before 0.11.0.
+	_, err = newSaramaConfigImpl(context.Background(), cfg)
+	c.Assert(cerror.ErrKafkaInvalidConfig.Equal(err), check.IsTrue)
+	cfg.Version = "0.11.0.2"
+	_, err = newSaramaConfigImpl(context.Background(), cfg)
+	c.Assert(err, check.IsNil)
+
+	sinkURI, err = url.Parse("kafka://127.0.0.1:9092/abc?enable-headers=a")
+	c.Assert(err, check.IsNil)
+	err = CompleteConfigsAndOpts(sinkURI, NewConfig(), config.GetDefaultReplicaConfig(), make(map[string]string))
+	c.Assert(errors.Cause(err), check.ErrorMatches, ".*invalid syntax.*")
+
+	opts := map[string]string{"max-message-bytes": "4096"}
+	c.Assert(reserveHeadersRoom(opts, "test-changefeed"), check.IsNil)
+	c.Assert(opts["max-message-bytes"], check.Equals,
+		strconv.Itoa(4096-codec.MaxHeadersLength("test-changefeed")))
+	opts["max-message-bytes"] = "100"
+	err = reserveHeadersRoom(opts, "test-changefeed")
+	c.Assert(cerror.ErrKafkaInvalidConfig.Equal(err), check.IsTrue)
 }
 
 func (s *kafkaSuite) TestSetPartitionNum(c *check.C) {
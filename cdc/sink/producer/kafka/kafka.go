@@ -58,15 +58,21 @@ type kafkaSaramaProducer struct {
 	asyncProducer sarama.AsyncProducer
 	syncProducer  sarama.SyncProducer
 
+	// admin is used to create the topics which are dispatched by the topic rules.
+	admin  kafka.ClusterAdminClient
+	config *Config
+
 	// producersReleased records whether asyncProducer and syncProducer have been closed properly
 	producersReleased bool
-	topic             string
-	partitionNum      int32
 
-	partitionOffset []struct {
-		flushed uint64
-		sent    uint64
-	}
+	// topicLock is used to prevent creating the same topic concurrently.
+	topicLock sync.Mutex
+	// topicPartitionNums caches the partition number of each topic, map[string]int32.
+	topicPartitionNums sync.Map
+	// partitionOffsets records the sent and flushed offsets of each
+	// topic partition, map[topicPartitionKey]*partitionOffset.
+	partitionOffsets sync.Map
+
 	flushedNotifier *notify.Notifier
 	flushedReceiver *notify.Receiver
 
@@ -84,7 +90,56 @@ type kafkaSaramaProducer struct {
 
 type kafkaProducerClosingFlag = int32
 
-func (k *kafkaSaramaProducer) AsyncSendMessage(ctx context.Context, message *codec.MQMessage, partition int32) error {
+type topicPartitionKey struct {
+	topic     string
+	partition int32
+}
+
+type partitionOffset struct {
+	flushed uint64
+	sent    uint64
+}
+
+func (k *kafkaSaramaProducer) getPartitionOffset(topic string, partition int32) *partitionOffset {
+	key := topicPartitionKey{topic: topic, partition: partition}
+	if offset, ok := k.partitionOffsets.Load(key); ok {
+		return offset.(*partitionOffset)
+	}
+	offset, _ := k.partitionOffsets.LoadOrStore(key, &partitionOffset{})
+	return offset.(*partitionOffset)
+}
+
+// newProducerMessage creates the sarama message of the MQMessage, the headers
+// describing the message are attached after the headers of the MQMessage if
+// they're enabled. The MQMessage is never modified, because it may be sent
+// again on retries.
+func (k *kafkaSaramaProducer) newProducerMessage(
+	topic string, partition int32, message *codec.MQMessage,
+) *sarama.ProducerMessage {
+	msg := &sarama.ProducerMessage{
+		Topic:     topic,
+		Key:       sarama.ByteEncoder(message.Key),
+		Value:     sarama.ByteEncoder(message.Value),
+		Partition: partition,
+	}
+	headers := message.Headers
+	if k.config.EnableHeaders {
+		describing := codec.NewMQMessageHeaders(message, k.id)
+		headers = make([]codec.MQMessageHeader, 0, len(message.Headers)+len(describing))
+		headers = append(append(headers, message.Headers...), describing...)
+	}
+	if len(headers) != 0 {
+		msg.Headers = make([]sarama.RecordHeader, 0, len(headers))
+		for _, header := range headers {
+			msg.Headers = append(msg.Headers, sarama.RecordHeader{Key: header.Key, Value: header.Value})
+		}
+	}
+	return msg
+}
+
+func (k *kafkaSaramaProducer) AsyncSendMessage(
+	ctx context.Context, topic string, partition int32, message *codec.MQMessage,
+) error {
 	k.clientLock.RLock()
 	defer k.clientLock.RUnlock()
 
@@ -94,13 +149,8 @@ func (k *kafkaSaramaProducer) AsyncSendMessage(ctx context.Context, message *cod
 		return nil
 	}
 
-	msg := &sarama.ProducerMessage{
-		Topic:     k.topic,
-		Key:       sarama.ByteEncoder(message.Key),
-		Value:     sarama.ByteEncoder(message.Value),
-		Partition: partition,
-	}
-	msg.Metadata = atomic.AddUint64(&k.partitionOffset[partition].sent, 1)
+	msg := k.newProducerMessage(topic, partition, message)
+	msg.Metadata = atomic.AddUint64(&k.getPartitionOffset(topic, partition).sent, 1)
 
 	failpoint.Inject("KafkaSinkAsyncSendError", func() {
 		// simulate sending message to input channel successfully but flushing
@@ -126,17 +176,14 @@ func (k *kafkaSaramaProducer) AsyncSendMessage(ctx context.Context, message *cod
 	return nil
 }
 
-func (k *kafkaSaramaProducer) SyncBroadcastMessage(ctx context.Context, message *codec.MQMessage) error {
+func (k *kafkaSaramaProducer) SyncBroadcastMessage(
+	ctx context.Context, topic string, partitionsNum int32, message *codec.MQMessage,
+) error {
 	k.clientLock.RLock()
 	defer k.clientLock.RUnlock()
-	msgs := make([]*sarama.ProducerMessage, k.partitionNum)
-	for i := 0; i < int(k.partitionNum); i++ {
-		msgs[i] = &sarama.ProducerMessage{
-			Topic:     k.topic,
-			Key:       sarama.ByteEncoder(message.Key),
-			Value:     sarama.ByteEncoder(message.Value),
-			Partition: int32(i),
-		}
+	msgs := make([]*sarama.ProducerMessage, partitionsNum)
+	for i := 0; i < int(partitionsNum); i++ {
+		msgs[i] = k.newProducerMessage(topic, int32(i), message)
 	}
 	select {
 	case <-ctx.Done():
@@ -150,14 +197,16 @@ func (k *kafkaSaramaProducer) SyncBroadcastMessage(ctx context.Context, message
 }
 
 func (k *kafkaSaramaProducer) Flush(ctx context.Context) error {
-	targetOffsets := make([]uint64, k.partitionNum)
-	for i := 0; i < len(k.partitionOffset); i++ {
-		targetOffsets[i] = atomic.LoadUint64(&k.partitionOffset[i].sent)
-	}
+	targetOffsets := make(map[*partitionOffset]uint64)
+	k.partitionOffsets.Range(func(_, value interface{}) bool {
+		offset := value.(*partitionOffset)
+		targetOffsets[offset] = atomic.LoadUint64(&offset.sent)
+		return true
+	})
 
 	noEventsToFLush := true
-	for i, target := range targetOffsets {
-		if target > atomic.LoadUint64(&k.partitionOffset[i].flushed) {
+	for offset, target := range targetOffsets {
+		if target > atomic.LoadUint64(&offset.flushed) {
 			noEventsToFLush = false
 			break
 		}
@@ -169,8 +218,8 @@ func (k *kafkaSaramaProducer) Flush(ctx context.Context) error {
 
 	// checkAllPartitionFlushed checks whether data in each partition is flushed
 	checkAllPartitionFlushed := func() bool {
-		for i, target := range targetOffsets {
-			if target > atomic.LoadUint64(&k.partitionOffset[i].flushed) {
+		for offset, target := range targetOffsets {
+			if target > atomic.LoadUint64(&offset.flushed) {
 				return false
 			}
 		}
@@ -196,8 +245,70 @@ flushLoop:
 	}
 }
 
-func (k *kafkaSaramaProducer) GetPartitionNum() int32 {
-	return k.partitionNum
+// GetPartitionNum returns the partition number of the topic, the topic is
+// created if it does not exist and `auto-create-topic` is enabled.
+func (k *kafkaSaramaProducer) GetPartitionNum(topic string) (int32, error) {
+	if partitionNum, ok := k.topicPartitionNums.Load(topic); ok {
+		return partitionNum.(int32), nil
+	}
+
+	k.topicLock.Lock()
+	defer k.topicLock.Unlock()
+	if partitionNum, ok := k.topicPartitionNums.Load(topic); ok {
+		return partitionNum.(int32), nil
+	}
+	partitionNum, err := k.createTopicIfNotExists(topic)
+	if err != nil {
+		return 0, errors.Trace(err)
+	}
+	k.topicPartitionNums.Store(topic, partitionNum)
+	return partitionNum, nil
+}
+
+// createTopicIfNotExists returns the partition number of the topic,
+// and creates it with the configured partition number if not found.
+// It must be called under `topicLock`.
+func (k *kafkaSaramaProducer) createTopicIfNotExists(topic string) (int32, error) {
+	topics, err := k.admin.ListTopics()
+	if err != nil {
+		return 0, cerror.WrapError(cerror.ErrKafkaNewSaramaProducer, err)
+	}
+	if info, exists := topics[topic]; exists {
+		log.Info("topic already exists, use its partition number",
+			zap.String("topic", topic), zap.Int32("partitions", info.NumPartitions),
+			zap.String("changefeed", k.id), zap.Any("role", k.role))
+		return info.NumPartitions, nil
+	}
+
+	if !k.config.AutoCreate {
+		return 0, cerror.ErrKafkaTopicNotFound.GenWithStackByArgs(topic)
+	}
+
+	err = k.admin.CreateTopic(topic, &sarama.TopicDetail{
+		NumPartitions:     k.config.PartitionNum,
+		ReplicationFactor: k.config.ReplicationFactor,
+	}, false)
+	if err != nil {
+		if !strings.Contains(err.Error(), "already exists") {
+			return 0, cerror.WrapError(cerror.ErrKafkaNewSaramaProducer, err)
+		}
+		// The topic is created by others concurrently, fetch its partition number.
+		topics, err = k.admin.ListTopics()
+		if err != nil {
+			return 0, cerror.WrapError(cerror.ErrKafkaNewSaramaProducer, err)
+		}
+		if info, exists := topics[topic]; exists {
+			return info.NumPartitions, nil
+		}
+		return 0, cerror.ErrKafkaTopicNotFound.GenWithStackByArgs(topic)
+	}
+
+	log.Info("TiCDC create the topic",
+		zap.String("topic", topic),
+		zap.Int32("partition-num", k.config.PartitionNum),
+		zap.Int16("replication-factor", k.config.ReplicationFactor),
+		zap.String("changefeed", k.id), zap.Any("role", k.role))
+	return k.config.PartitionNum, nil
 }
 
 // stop closes the closeCh to signal other routines to exit
@@ -262,6 +373,13 @@ func (k *kafkaSaramaProducer) Close() error {
 			zap.String("changefeed", k.id), zap.Any("role", k.role))
 	}
 
+	start = time.Now()
+	if err := k.admin.Close(); err != nil {
+		log.Warn("close kafka cluster admin with error", zap.Error(err),
+			zap.Duration("duration", time.Since(start)),
+			zap.String("changefeed", k.id), zap.Any("role", k.role))
+	}
+
 	k.metricsMonitor.Cleanup()
 	return nil
 }
@@ -293,7 +411,7 @@ func (k *kafkaSaramaProducer) run(ctx context.Context) error {
 				continue
 			}
 			flushedOffset := msg.Metadata.(uint64)
-			atomic.StoreUint64(&k.partitionOffset[msg.Partition].flushed, flushedOffset)
+			atomic.StoreUint64(&k.getPartitionOffset(msg.Topic, msg.Partition).flushed, flushedOffset)
 			k.flushedNotifier.Notify()
 		case err := <-k.asyncProducer.Errors():
 			// We should not wrap a nil pointer if the pointer is of a subtype of `error`
@@ -312,7 +430,8 @@ var (
 	NewAdminClientImpl  kafka.ClusterAdminClientCreator = kafka.NewSaramaAdminClient
 )
 
-// NewKafkaSaramaProducer creates a kafka sarama producer
+// NewKafkaSaramaProducer creates a kafka sarama producer, the topic is the
+// default topic which is validated and created before the producer starts.
 func NewKafkaSaramaProducer(ctx context.Context, topic string, config *Config,
 	opts map[string]string, errCh chan error) (*kafkaSaramaProducer, error) {
 	changefeedID := util.ChangefeedIDFromCtx(ctx)
@@ -329,47 +448,57 @@ func NewKafkaSaramaProducer(ctx context.Context, topic string, config *Config,
 	if err != nil {
 		return nil, cerror.WrapError(cerror.ErrKafkaNewSaramaProducer, err)
 	}
-	defer func() {
+	// The admin is kept open to create the dispatched topics,
+	// it's closed along with the producer.
+	closeAdmin := func() {
 		if err := admin.Close(); err != nil {
 			log.Warn("close kafka cluster admin failed", zap.Error(err),
 				zap.String("changefeed", changefeedID), zap.Any("role", role))
 		}
-	}()
+	}
 
 	if err := validateAndCreateTopic(admin, topic, config, cfg, opts); err != nil {
+		closeAdmin()
 		return nil, cerror.WrapError(cerror.ErrKafkaNewSaramaProducer, err)
 	}
 
+	if config.EnableHeaders {
+		if err := reserveHeadersRoom(opts, changefeedID); err != nil {
+			closeAdmin()
+			return nil, errors.Trace(err)
+		}
+	}
+
 	client, err := sarama.NewClient(config.BrokerEndpoints, cfg)
 	if err != nil {
+		closeAdmin()
 		return nil, cerror.WrapError(cerror.ErrKafkaNewSaramaProducer, err)
 	}
 
 	asyncProducer, err := sarama.NewAsyncProducerFromClient(client)
 	if err != nil {
+		closeAdmin()
 		return nil, cerror.WrapError(cerror.ErrKafkaNewSaramaProducer, err)
 	}
 
 	syncProducer, err := sarama.NewSyncProducerFromClient(client)
 	if err != nil {
+		closeAdmin()
 		return nil, cerror.WrapError(cerror.ErrKafkaNewSaramaProducer, err)
 	}
 
 	notifier := new(notify.Notifier)
 	flushedReceiver, err := notifier.NewReceiver(50 * time.Millisecond)
 	if err != nil {
+		closeAdmin()
 		return nil, err
 	}
 	k := &kafkaSaramaProducer{
-		client:        client,
-		asyncProducer: asyncProducer,
-		syncProducer:  syncProducer,
-		topic:         topic,
-		partitionNum:  config.PartitionNum,
-		partitionOffset: make([]struct {
-			flushed uint64
-			sent    uint64
-		}, config.PartitionNum),
+		client:          client,
+		asyncProducer:   asyncProducer,
+		syncProducer:    syncProducer,
+		admin:           admin,
+		config:          config,
 		flushedNotifier: notifier,
 		flushedReceiver: flushedReceiver,
 		closeCh:         make(chan struct{}),
@@ -382,6 +511,7 @@ func NewKafkaSaramaProducer(ctx context.Context, topic string, config *Config,
 		metricsMonitor: NewSaramaMetricsMonitor(cfg.MetricRegistry,
 			util.CaptureAddrFromCtx(ctx), changefeedID),
 	}
+	k.topicPartitionNums.Store(topic, config.PartitionNum)
 	go func() {
 		if err := k.run(ctx); err != nil && errors.Cause(err) != context.Canceled {
 			select {
@@ -515,6 +645,23 @@ func validateAndCreateTopic(admin kafka.ClusterAdminClient, topic string, config
 	return nil
 }
 
+// reserveHeadersRoom reserves the room of the headers in the max-message-bytes
+// of the encoders, since the headers are counted in the size of a message by
+// the broker.
+func reserveHeadersRoom(opts map[string]string, changefeedID string) error {
+	maxMessageBytes, err := strconv.Atoi(opts["max-message-bytes"])
+	if err != nil {
+		return cerror.WrapError(cerror.ErrKafkaInvalidConfig, err)
+	}
+	headersLength := codec.MaxHeadersLength(changefeedID)
+	if maxMessageBytes <= headersLength {
+		return cerror.ErrKafkaInvalidConfig.GenWithStack(
+			"max-message-bytes %d is too small to hold the headers of %d bytes", maxMessageBytes, headersLength)
+	}
+	opts["max-message-bytes"] = strconv.Itoa(maxMessageBytes - headersLength)
+	return nil
+}
+
 func validateMinInsyncReplicas(admin kafka.ClusterAdminClient,
 	topics map[string]sarama.TopicDetail, topic string, replicationFactor int) error {
 	minInsyncReplicasConfigGetter := func() (string, bool, error) {
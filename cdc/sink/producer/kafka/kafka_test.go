@@ -24,7 +24,10 @@ import (
 	"github.com/Shopify/sarama"
 	"github.com/pingcap/check"
 	"github.com/pingcap/errors"
+	"github.com/pingcap/tiflow/cdc/model"
 	"github.com/pingcap/tiflow/cdc/sink/codec"
+	"github.com/pingcap/tiflow/pkg/config"
+	cerror "github.com/pingcap/tiflow/pkg/errors"
 	"github.com/pingcap/tiflow/pkg/kafka"
 	"github.com/pingcap/tiflow/pkg/util"
 	"github.com/pingcap/tiflow/pkg/util/testleak"
@@ -112,18 +115,20 @@ func (s *kafkaSuite) TestNewSaramaProducer(c *check.C) {
 	ctx = util.PutRoleInCtx(ctx, util.RoleTester)
 	producer, err := NewKafkaSaramaProducer(ctx, topic, config, opts, errCh)
 	c.Assert(err, check.IsNil)
-	c.Assert(producer.GetPartitionNum(), check.Equals, int32(2))
+	partitionNum, err := producer.GetPartitionNum(topic)
+	c.Assert(err, check.IsNil)
+	c.Assert(partitionNum, check.Equals, int32(2))
 	c.Assert(opts, check.HasKey, "max-message-bytes")
 	for i := 0; i < 100; i++ {
-		err = producer.AsyncSendMessage(ctx, &codec.MQMessage{
+		err = producer.AsyncSendMessage(ctx, topic, int32(0), &codec.MQMessage{
 			Key:   []byte("test-key-1"),
 			Value: []byte("test-value"),
-		}, int32(0))
+		})
 		c.Assert(err, check.IsNil)
-		err = producer.AsyncSendMessage(ctx, &codec.MQMessage{
+		err = producer.AsyncSendMessage(ctx, topic, int32(1), &codec.MQMessage{
 			Key:   []byte("test-key-1"),
 			Value: []byte("test-value"),
-		}, int32(1))
+		})
 		c.Assert(err, check.IsNil)
 	}
 
@@ -145,14 +150,12 @@ func (s *kafkaSuite) TestNewSaramaProducer(c *check.C) {
 
 	err = producer.Flush(ctx)
 	c.Assert(err, check.IsNil)
-	expected := []struct {
-		flushed uint64
-		sent    uint64
-	}{
-		{100, 100},
-		{100, 100},
+	for i := int32(0); i < 2; i++ {
+		c.Assert(producer.getPartitionOffset(topic, i), check.DeepEquals, &partitionOffset{
+			flushed: 100,
+			sent:    100,
+		})
 	}
-	c.Assert(producer.partitionOffset, check.DeepEquals, expected)
 	select {
 	case err := <-errCh:
 		c.Fatalf("unexpected err: %s", err)
@@ -162,7 +165,7 @@ func (s *kafkaSuite) TestNewSaramaProducer(c *check.C) {
 	err = producer.Flush(ctx)
 	c.Assert(err, check.IsNil)
 
-	err = producer.SyncBroadcastMessage(ctx, &codec.MQMessage{
+	err = producer.SyncBroadcastMessage(ctx, topic, partitionNum, &codec.MQMessage{
 		Key:   []byte("test-broadcast"),
 		Value: nil,
 	})
@@ -177,14 +180,14 @@ func (s *kafkaSuite) TestNewSaramaProducer(c *check.C) {
 	wg.Wait()
 
 	// check send messages when context is canceled or producer closed
-	err = producer.AsyncSendMessage(ctx, &codec.MQMessage{
+	err = producer.AsyncSendMessage(ctx, topic, int32(0), &codec.MQMessage{
 		Key:   []byte("cancel"),
 		Value: nil,
-	}, int32(0))
+	})
 	if err != nil {
 		c.Assert(err, check.Equals, context.Canceled)
 	}
-	err = producer.SyncBroadcastMessage(ctx, &codec.MQMessage{
+	err = producer.SyncBroadcastMessage(ctx, topic, partitionNum, &codec.MQMessage{
 		Key:   []byte("cancel"),
 		Value: nil,
 	})
@@ -404,10 +407,10 @@ func (s *kafkaSuite) TestProducerSendMessageFailed(c *check.C) {
 	go func() {
 		defer wg.Done()
 		for i := 0; i < 20; i++ {
-			err = producer.AsyncSendMessage(ctx, &codec.MQMessage{
+			err = producer.AsyncSendMessage(ctx, topic, int32(0), &codec.MQMessage{
 				Key:   []byte("test-key-1"),
 				Value: []byte("test-value"),
-			}, int32(0))
+			})
 			c.Assert(err, check.IsNil)
 		}
 	}()
@@ -474,3 +477,86 @@ func (s *kafkaSuite) TestProducerDoubleClose(c *check.C) {
 	err = producer.Close()
 	c.Assert(err, check.IsNil)
 }
+
+func (s *kafkaSuite) TestProducerGetPartitionNum(c *check.C) {
+	defer testleak.AfterTest(c)()
+	topic := kafka.DefaultMockTopicName
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*10)
+	defer cancel()
+
+	leader := sarama.NewMockBroker(c, 2)
+	defer leader.Close()
+	metadataResponse := new(sarama.MetadataResponse)
+	metadataResponse.AddBroker(leader.Addr(), leader.BrokerID())
+	metadataResponse.AddTopicPartition(topic, 0, leader.BrokerID(), nil, nil, nil, sarama.ErrNoError)
+	metadataResponse.AddTopicPartition(topic, 1, leader.BrokerID(), nil, nil, nil, sarama.ErrNoError)
+	// Response for `sarama.NewClient`
+	leader.Returns(metadataResponse)
+
+	config := NewConfig()
+	config.Version = "0.9.0.0"
+	config.PartitionNum = int32(2)
+	config.AutoCreate = false
+	config.BrokerEndpoints = strings.Split(leader.Addr(), ",")
+
+	NewAdminClientImpl = kafka.NewMockAdminClient
+	defer func() {
+		NewAdminClientImpl = kafka.NewSaramaAdminClient
+	}()
+
+	errCh := make(chan error, 1)
+	opts := make(map[string]string)
+	ctx = util.PutRoleInCtx(ctx, util.RoleTester)
+	producer, err := NewKafkaSaramaProducer(ctx, topic, config, opts, errCh)
+	c.Assert(err, check.IsNil)
+	defer func() {
+		err := producer.Close()
+		c.Assert(err, check.IsNil)
+	}()
+
+	// the partition number of the default topic is decided by the config.
+	partitionNum, err := producer.GetPartitionNum(topic)
+	c.Assert(err, check.IsNil)
+	c.Assert(partitionNum, check.Equals, int32(2))
+
+	// the topic can't be created if `auto-create-topic` is false.
+	_, err = producer.GetPartitionNum("dispatched-topic")
+	c.Assert(cerror.ErrKafkaTopicNotFound.Equal(err), check.IsTrue)
+
+	config.AutoCreate = true
+	config.PartitionNum = int32(4)
+	partitionNum, err = producer.GetPartitionNum("dispatched-topic")
+	c.Assert(err, check.IsNil)
+	c.Assert(partitionNum, check.Equals, int32(4))
+	topics, err := producer.admin.ListTopics()
+	c.Assert(err, check.IsNil)
+	c.Assert(topics, check.HasKey, "dispatched-topic")
+
+	// the partition number is cached.
+	config.PartitionNum = int32(8)
+	partitionNum, err = producer.GetPartitionNum("dispatched-topic")
+	c.Assert(err, check.IsNil)
+	c.Assert(partitionNum, check.Equals, int32(4))
+}
+
+func (s *kafkaSuite) TestNewProducerMessageHeaders(c *check.C) {
+	defer testleak.AfterTest(c)()
+	schema, table := "test", "t1"
+	message := codec.NewMQMessage(config.ProtocolOpen, []byte("key"), []byte("value"), 1234,
+		model.MqMessageTypeRow, &schema, &table)
+	message.Headers = []codec.MQMessageHeader{{Key: []byte("custom"), Value: []byte("value")}}
+
+	producer := &kafkaSaramaProducer{config: NewConfig(), id: "test-changefeed"}
+	msg := producer.newProducerMessage("topic", 1, message)
+	c.Assert(msg.Headers, check.HasLen, 1)
+
+	producer.config.EnableHeaders = true
+	// the message may be sent more than once, the headers are not accumulated.
+	for i := 0; i < 2; i++ {
+		msg = producer.newProducerMessage("topic", 1, message)
+		c.Assert(msg.Headers, check.HasLen, 8)
+		c.Assert(string(msg.Headers[0].Key), check.Equals, "custom")
+		c.Assert(string(msg.Headers[1].Key), check.Equals, codec.HeaderCommitTs)
+		c.Assert(message.Headers, check.HasLen, 1)
+	}
+}
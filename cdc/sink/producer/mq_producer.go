@@ -21,15 +21,20 @@ import (
 
 // Producer is an interface of mq producer
 type Producer interface {
-	// AsyncSendMessage sends a message asynchronously.
-	AsyncSendMessage(ctx context.Context, message *codec.MQMessage, partition int32) error
-	// SyncBroadcastMessage broadcasts a message synchronously.
-	SyncBroadcastMessage(ctx context.Context, message *codec.MQMessage) error
+	// AsyncSendMessage sends a message to the partition of the topic asynchronously.
+	AsyncSendMessage(
+		ctx context.Context, topic string, partition int32, message *codec.MQMessage,
+	) error
+	// SyncBroadcastMessage broadcasts a message to all partitions of the topic synchronously.
+	SyncBroadcastMessage(
+		ctx context.Context, topic string, partitionsNum int32, message *codec.MQMessage,
+	) error
 	// Flush all the messages buffered in the client and wait until all messages have been successfully
 	// persisted.
 	Flush(ctx context.Context) error
-	// GetPartitionNum gets partition number of topic.
-	GetPartitionNum() int32
+	// GetPartitionNum gets partition number of the topic,
+	// the topic may be created if it does not exist.
+	GetPartitionNum(topic string) (int32, error)
 	// Close closes the producer and client(s).
 	Close() error
 }
@@ -85,8 +85,11 @@ func createProperties(message *codec.MQMessage, partition int32) map[string]stri
 	return properties
 }
 
-// SendMessage send key-value msg to target partition.
-func (p *Producer) AsyncSendMessage(ctx context.Context, message *codec.MQMessage, partition int32) error {
+// AsyncSendMessage send key-value msg to target partition.
+// The producer is bound to the topic in the sink URI, so the topic is ignored.
+func (p *Producer) AsyncSendMessage(
+	ctx context.Context, _ string, partition int32, message *codec.MQMessage,
+) error {
 	p.producer.SendAsync(ctx, &pulsar.ProducerMessage{
 		Payload:    message.Value,
 		Key:        string(message.Key),
@@ -107,8 +110,10 @@ func (p *Producer) errors(_ pulsar.MessageID, _ *pulsar.ProducerMessage, err err
 }
 
 // SyncBroadcastMessage send key-value msg to all partition.
-func (p *Producer) SyncBroadcastMessage(ctx context.Context, message *codec.MQMessage) error {
-	for partition := 0; partition < p.partitionNum; partition++ {
+func (p *Producer) SyncBroadcastMessage(
+	ctx context.Context, _ string, partitionsNum int32, message *codec.MQMessage,
+) error {
+	for partition := 0; partition < int(partitionsNum); partition++ {
 		_, err := p.producer.Send(ctx, &pulsar.ProducerMessage{
 			Payload:    message.Value,
 			Key:        string(message.Key),
@@ -128,8 +133,8 @@ func (p *Producer) Flush(_ context.Context) error {
 }
 
 // GetPartitionNum got current topic's partition size.
-func (p *Producer) GetPartitionNum() int32 {
-	return int32(p.partitionNum)
+func (p *Producer) GetPartitionNum(_ string) (int32, error) {
+	return int32(p.partitionNum), nil
 }
 
 // Close closes the producer and client.
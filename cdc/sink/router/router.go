@@ -0,0 +1,180 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package router
+
+import (
+	"regexp"
+	"sync"
+
+	"github.com/pingcap/errors"
+	filterV1 "github.com/pingcap/tidb-tools/pkg/filter"
+	filter "github.com/pingcap/tidb-tools/pkg/table-filter"
+	"github.com/pingcap/tidb/parser"
+	"github.com/pingcap/tiflow/cdc/model"
+	"github.com/pingcap/tiflow/pkg/config"
+	"github.com/pingcap/tiflow/pkg/ddlrewrite"
+	cerror "github.com/pingcap/tiflow/pkg/errors"
+)
+
+var (
+	schemaPlaceholderRE = regexp.MustCompile(`(?i)\{schema\}`)
+	tablePlaceholderRE  = regexp.MustCompile(`(?i)\{table\}`)
+)
+
+type rule struct {
+	matcher      filter.Filter
+	targetSchema string
+	targetTable  string
+}
+
+// Router routes the events to the target schemas and tables in the downstream,
+// according to the route rules in the sink config.
+type Router struct {
+	rules []*rule
+
+	// parser is used to rewrite the DDLs, it's not thread safe.
+	parserMu sync.Mutex
+	parser   *parser.Parser
+}
+
+// New creates a Router, the tables which are not matched by any rule keep
+// their upstream names.
+func New(cfg *config.ReplicaConfig) (*Router, error) {
+	rules := make([]*rule, 0, len(cfg.Sink.Routes))
+	for _, r := range cfg.Sink.Routes {
+		if r.TargetSchema == "" && r.TargetTable == "" {
+			return nil, cerror.ErrRouteRuleInvalid.GenWithStackByArgs()
+		}
+		matcher, err := filter.Parse(r.Matcher)
+		if err != nil {
+			return nil, cerror.WrapError(cerror.ErrRouteRuleInvalid, err)
+		}
+		if !cfg.CaseSensitive {
+			matcher = filter.CaseInsensitive(matcher)
+		}
+		rules = append(rules, &rule{
+			matcher:      matcher,
+			targetSchema: r.TargetSchema,
+			targetTable:  r.TargetTable,
+		})
+	}
+	return &Router{rules: rules, parser: parser.New()}, nil
+}
+
+func substitute(expr, schema, table string) string {
+	expr = schemaPlaceholderRE.ReplaceAllLiteralString(expr, schema)
+	return tablePlaceholderRE.ReplaceAllLiteralString(expr, table)
+}
+
+// Route returns the target schema and table of the upstream table. An empty
+// table means the schema itself, it's routed by the first rule which may
+// match the tables in the schema, except the rules routing single tables
+// to the target tables.
+func (r *Router) Route(schema, table string) (string, string) {
+	for _, rule := range r.rules {
+		if table == "" {
+			if rule.targetTable != "" || !rule.matcher.MatchSchema(schema) {
+				continue
+			}
+		} else if !rule.matcher.MatchTable(schema, table) {
+			continue
+		}
+		targetSchema, targetTable := schema, table
+		if rule.targetSchema != "" {
+			targetSchema = substitute(rule.targetSchema, schema, table)
+		}
+		if rule.targetTable != "" {
+			targetTable = substitute(rule.targetTable, schema, table)
+		}
+		return targetSchema, targetTable
+	}
+	return schema, table
+}
+
+// ApplyRow returns the row changed event with the target table name.
+// The input event is never modified, because it may be shared with others.
+func (r *Router) ApplyRow(row *model.RowChangedEvent) *model.RowChangedEvent {
+	if len(r.rules) == 0 {
+		return row
+	}
+	schema, table := r.Route(row.Table.Schema, row.Table.Table)
+	if schema == row.Table.Schema && table == row.Table.Table {
+		return row
+	}
+	newRow := *row
+	newTable := *row.Table
+	newTable.Schema, newTable.Table = schema, table
+	newRow.Table = &newTable
+	return &newRow
+}
+
+// ApplyDDL returns the DDL event with the target table names, all the tables
+// in the query are rewritten, including the ones in the other schemas, such
+// as the old and the new table of `RENAME TABLE`.
+// The input event is never modified, because it may be shared with others.
+func (r *Router) ApplyDDL(ddl *model.DDLEvent) (*model.DDLEvent, error) {
+	if len(r.rules) == 0 || ddl.TableInfo == nil {
+		return ddl, nil
+	}
+	query, err := r.routeQuery(ddl.TableInfo.Schema, ddl.Query)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	newDDL := *ddl
+	newDDL.Query = query
+	newDDL.TableInfo = r.routeTableInfo(ddl.TableInfo)
+	newDDL.PreTableInfo = r.routeTableInfo(ddl.PreTableInfo)
+	return &newDDL, nil
+}
+
+func (r *Router) routeTableInfo(info *model.SimpleTableInfo) *model.SimpleTableInfo {
+	if info == nil {
+		return nil
+	}
+	newInfo := *info
+	newInfo.Schema, newInfo.Table = r.Route(info.Schema, info.Table)
+	return &newInfo
+}
+
+// routeQuery rewrites the table names in the DDL query, the tables without
+// schema in the query belong to the current schema of the DDL.
+func (r *Router) routeQuery(currentSchema, query string) (string, error) {
+	r.parserMu.Lock()
+	defer r.parserMu.Unlock()
+	stmt, err := r.parser.ParseOneStmt(query, "", "")
+	if err != nil {
+		return "", cerror.WrapError(cerror.ErrRouteDDLFailed, err, query)
+	}
+	tables, err := ddlrewrite.FetchTables(currentSchema, stmt)
+	if err != nil {
+		return "", cerror.WrapError(cerror.ErrRouteDDLFailed, err, query)
+	}
+	routed := false
+	targets := make([]*filterV1.Table, 0, len(tables))
+	for _, table := range tables {
+		schema, name := r.Route(table.Schema, table.Name)
+		if schema != table.Schema || name != table.Name {
+			routed = true
+		}
+		targets = append(targets, &filterV1.Table{Schema: schema, Name: name})
+	}
+	if !routed {
+		return query, nil
+	}
+	newQuery, err := ddlrewrite.RenameTables(stmt, targets)
+	if err != nil {
+		return "", cerror.WrapError(cerror.ErrRouteDDLFailed, err, query)
+	}
+	return newQuery, nil
+}
@@ -0,0 +1,184 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package router
+
+import (
+	"testing"
+
+	timodel "github.com/pingcap/tidb/parser/model"
+	"github.com/pingcap/tiflow/cdc/model"
+	"github.com/pingcap/tiflow/pkg/config"
+	"github.com/stretchr/testify/require"
+)
+
+func newReplicaConfig(rules ...*config.RouteRule) *config.ReplicaConfig {
+	cfg := config.GetDefaultReplicaConfig()
+	cfg.Sink.Routes = rules
+	return cfg
+}
+
+func newTestRouter(t *testing.T) *Router {
+	r, err := New(newReplicaConfig(
+		&config.RouteRule{Matcher: []string{"test.log"}, TargetSchema: "archive", TargetTable: "{schema}_{table}"},
+		&config.RouteRule{Matcher: []string{"test.*", "other.*"}, TargetSchema: "c1_{schema}"},
+	))
+	require.Nil(t, err)
+	return r
+}
+
+func TestNewRouter(t *testing.T) {
+	t.Parallel()
+
+	_, err := New(newReplicaConfig(&config.RouteRule{Matcher: []string{"test.*"}}))
+	require.Regexp(t, ".*ErrRouteRuleInvalid.*", err)
+
+	_, err = New(newReplicaConfig(&config.RouteRule{Matcher: []string{"[test.*"}, TargetSchema: "c1"}))
+	require.Regexp(t, ".*ErrRouteRuleInvalid.*", err)
+
+	r, err := New(newReplicaConfig())
+	require.Nil(t, err)
+	row := &model.RowChangedEvent{Table: &model.TableName{Schema: "test", Table: "t"}}
+	require.Same(t, row, r.ApplyRow(row))
+}
+
+func TestRoute(t *testing.T) {
+	t.Parallel()
+
+	r := newTestRouter(t)
+	for _, tc := range []struct {
+		schema, table             string
+		targetSchema, targetTable string
+	}{
+		{"test", "t", "c1_test", "t"},
+		// the matchers are case-sensitive by default.
+		{"TEST", "T", "TEST", "T"},
+		{"test", "log", "archive", "test_log"},
+		{"other", "t", "c1_other", "t"},
+		{"unmatched", "t", "unmatched", "t"},
+		{"test", "", "c1_test", ""},
+		{"other", "", "c1_other", ""},
+		{"unmatched", "", "unmatched", ""},
+	} {
+		schema, table := r.Route(tc.schema, tc.table)
+		require.Equal(t, tc.targetSchema, schema)
+		require.Equal(t, tc.targetTable, table)
+	}
+
+	cfg := newReplicaConfig(&config.RouteRule{Matcher: []string{"test.*"}, TargetSchema: "c1_{schema}"})
+	cfg.CaseSensitive = false
+	r, err := New(cfg)
+	require.Nil(t, err)
+	schema, table := r.Route("TEST", "T")
+	require.Equal(t, "c1_TEST", schema)
+	require.Equal(t, "T", table)
+}
+
+func TestApplyRow(t *testing.T) {
+	t.Parallel()
+
+	r := newTestRouter(t)
+	row := &model.RowChangedEvent{
+		Table:   &model.TableName{Schema: "test", Table: "t", TableID: 1},
+		Columns: []*model.Column{{Name: "id", Value: 1}},
+	}
+	routed := r.ApplyRow(row)
+	require.Equal(t, &model.TableName{Schema: "c1_test", Table: "t", TableID: 1}, routed.Table)
+	require.Equal(t, row.Columns, routed.Columns)
+	// the input event is not modified.
+	require.Equal(t, "test", row.Table.Schema)
+
+	row = &model.RowChangedEvent{Table: &model.TableName{Schema: "unmatched", Table: "t"}}
+	require.Same(t, row, r.ApplyRow(row))
+}
+
+func TestApplyDDL(t *testing.T) {
+	t.Parallel()
+
+	r := newTestRouter(t)
+	for _, tc := range []struct {
+		ddl      *model.DDLEvent
+		query    string
+		schema   string
+		table    string
+		preTable *model.SimpleTableInfo
+	}{
+		{
+			ddl: &model.DDLEvent{
+				TableInfo: &model.SimpleTableInfo{Schema: "test"},
+				Type:      timodel.ActionCreateSchema,
+				Query:     "CREATE DATABASE test",
+			},
+			query:  "CREATE DATABASE `c1_test`",
+			schema: "c1_test",
+		},
+		{
+			ddl: &model.DDLEvent{
+				TableInfo: &model.SimpleTableInfo{Schema: "test", Table: "t"},
+				Type:      timodel.ActionAddColumn,
+				Query:     "ALTER TABLE t ADD COLUMN a INT",
+			},
+			query:  "ALTER TABLE `c1_test`.`t` ADD COLUMN `a` INT",
+			schema: "c1_test",
+			table:  "t",
+		},
+		{
+			ddl: &model.DDLEvent{
+				TableInfo:    &model.SimpleTableInfo{Schema: "other", Table: "t2"},
+				PreTableInfo: &model.SimpleTableInfo{Schema: "test", Table: "log"},
+				Type:         timodel.ActionRenameTable,
+				Query:        "RENAME TABLE test.log TO other.t2",
+			},
+			query:    "RENAME TABLE `archive`.`test_log` TO `c1_other`.`t2`",
+			schema:   "c1_other",
+			table:    "t2",
+			preTable: &model.SimpleTableInfo{Schema: "archive", Table: "test_log"},
+		},
+		{
+			ddl: &model.DDLEvent{
+				TableInfo: &model.SimpleTableInfo{Schema: "unmatched", Table: "t"},
+				Type:      timodel.ActionCreateTable,
+				Query:     "CREATE TABLE t LIKE test.t",
+			},
+			query:  "CREATE TABLE `unmatched`.`t` LIKE `c1_test`.`t`",
+			schema: "unmatched",
+			table:  "t",
+		},
+		{
+			ddl: &model.DDLEvent{
+				TableInfo: &model.SimpleTableInfo{Schema: "unmatched", Table: "t"},
+				Type:      timodel.ActionTruncateTable,
+				Query:     "TRUNCATE TABLE t",
+			},
+			query:  "TRUNCATE TABLE t",
+			schema: "unmatched",
+			table:  "t",
+		},
+	} {
+		query := tc.ddl.Query
+		routed, err := r.ApplyDDL(tc.ddl)
+		require.Nil(t, err)
+		require.Equal(t, tc.query, routed.Query)
+		require.Equal(t, tc.schema, routed.TableInfo.Schema)
+		require.Equal(t, tc.table, routed.TableInfo.Table)
+		require.Equal(t, tc.preTable, routed.PreTableInfo)
+		// the input event is not modified.
+		require.Equal(t, query, tc.ddl.Query)
+	}
+
+	_, err := r.ApplyDDL(&model.DDLEvent{
+		TableInfo: &model.SimpleTableInfo{Schema: "test", Table: "t"},
+		Query:     "ALTER TABLE",
+	})
+	require.Regexp(t, ".*ErrRouteDDLFailed.*", err)
+}
@@ -205,7 +205,7 @@ func (s *simpleMySQLSink) FlushRowChangedEvents(ctx context.Context, _ model.Tab
 
 // EmitCheckpointTs sends CheckpointTs to Sink
 // TiCDC guarantees that all Events **in the cluster** which of commitTs less than or equal `checkpointTs` are sent to downstream successfully.
-func (s *simpleMySQLSink) EmitCheckpointTs(ctx context.Context, ts uint64) error {
+func (s *simpleMySQLSink) EmitCheckpointTs(ctx context.Context, ts uint64, tables []model.TableName) error {
 	// do nothing
 	return nil
 }
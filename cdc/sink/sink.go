@@ -18,10 +18,14 @@ import (
 	"net/url"
 	"strings"
 
+	"github.com/pingcap/errors"
 	"github.com/pingcap/tiflow/cdc/model"
+	"github.com/pingcap/tiflow/cdc/sink/columnselector"
+	"github.com/pingcap/tiflow/cdc/sink/dispatcher"
 	"github.com/pingcap/tiflow/pkg/config"
 	cerror "github.com/pingcap/tiflow/pkg/errors"
 	"github.com/pingcap/tiflow/pkg/filter"
+	"github.com/pingcap/tiflow/pkg/masking"
 	"github.com/pingcap/tiflow/pkg/util"
 )
 
@@ -62,10 +66,12 @@ type Sink interface {
 	// EmitCheckpointTs sends CheckpointTs to Sink.
 	// TiCDC guarantees that all Events **in the cluster** which of commitTs
 	// less than or equal `checkpointTs` are sent to downstream successfully.
+	// The tables are the tables being replicated at the checkpoint,
+	// sinks like MQ use them to decide which topics the checkpoint goes to.
 	//
 	// EmitCheckpointTs is thread-safety.
 	// FIXME: some sink implementation is not thread-safety, but they should be.
-	EmitCheckpointTs(ctx context.Context, ts uint64) error
+	EmitCheckpointTs(ctx context.Context, ts uint64, tables []model.TableName) error
 
 	// Close closes the Sink.
 	//
@@ -81,6 +87,15 @@ type Sink interface {
 	Barrier(ctx context.Context, tableID model.TableID) error
 }
 
+// AsyncDDLSink is implemented by the sinks executing some DDLs
+// asynchronously, EmitDDLEvent returns before these DDLs are finished, and
+// the checkpoint must not pass them until they're finished.
+type AsyncDDLSink interface {
+	// RunningDDLs returns the DDLs still running in the downstream, or the
+	// error of the failed DDLs.
+	RunningDDLs(ctx context.Context) ([]*model.RunningDDL, error)
+}
+
 var sinkIniterMap = make(map[string]sinkInitFunc)
 
 type sinkInitFunc func(context.Context, model.ChangeFeedID, *url.URL, *filter.Filter, *config.ReplicaConfig, map[string]string, chan error) (Sink, error)
@@ -114,6 +129,14 @@ func init() {
 		return newPulsarSink(ctx, sinkURI, filter, config, opts, errCh)
 	}
 	sinkIniterMap["pulsar+ssl"] = sinkIniterMap["pulsar"]
+
+	// register storage sink
+	sinkIniterMap["file"] = func(ctx context.Context, changefeedID model.ChangeFeedID, sinkURI *url.URL,
+		filter *filter.Filter, config *config.ReplicaConfig, opts map[string]string, errCh chan error) (Sink, error) {
+		return newStorageSink(ctx, sinkURI, filter, config, opts)
+	}
+	sinkIniterMap["s3"] = sinkIniterMap["file"]
+	sinkIniterMap["gcs"] = sinkIniterMap["file"]
 }
 
 // New creates a new sink with the sink-uri
@@ -155,3 +178,51 @@ func Validate(ctx context.Context, sinkURI string, cfg *config.ReplicaConfig, op
 	}
 	return nil
 }
+
+// VerifyTables returns the ineligible and eligible tables, and checks that the
+// dispatchers, the column selectors, the masking rules and the filter
+// expressions are valid for the eligible tables.
+func VerifyTables(cfg *config.ReplicaConfig, tables map[model.TableID]*model.TableInfo) (ineligibleTables, eligibleTables []model.TableName, err error) {
+	filter, err := filter.NewFilter(cfg)
+	if err != nil {
+		return nil, nil, errors.Trace(err)
+	}
+	columnSelector, err := columnselector.New(cfg)
+	if err != nil {
+		return nil, nil, errors.Trace(err)
+	}
+	masker, err := masking.New(cfg)
+	if err != nil {
+		return nil, nil, errors.Trace(err)
+	}
+	// The default topic is not used, only the partition dispatchers are verified.
+	eventRouter, err := dispatcher.NewEventRouter(cfg, "")
+	if err != nil {
+		return nil, nil, errors.Trace(err)
+	}
+
+	for _, tableInfo := range tables {
+		if filter.ShouldIgnoreTable(tableInfo.TableName.Schema, tableInfo.TableName.Table) {
+			continue
+		}
+		if !tableInfo.IsEligible(false /* forceReplicate */) {
+			ineligibleTables = append(ineligibleTables, tableInfo.TableName)
+			continue
+		}
+		if err := eventRouter.VerifyTable(tableInfo); err != nil {
+			return nil, nil, err
+		}
+		dispatchColumns := eventRouter.GetDispatchColumns(tableInfo.TableName.Schema, tableInfo.TableName.Table)
+		if err := columnSelector.VerifyTable(tableInfo, dispatchColumns); err != nil {
+			return nil, nil, err
+		}
+		if err := masker.VerifyTable(tableInfo); err != nil {
+			return nil, nil, err
+		}
+		if err := filter.VerifyTableExprs(tableInfo); err != nil {
+			return nil, nil, err
+		}
+		eligibleTables = append(eligibleTables, tableInfo.TableName)
+	}
+	return
+}
@@ -17,6 +17,11 @@ import (
 	"context"
 	"testing"
 
+	"github.com/pingcap/tidb/ddl"
+	"github.com/pingcap/tidb/parser"
+	"github.com/pingcap/tidb/parser/ast"
+	timock "github.com/pingcap/tidb/util/mock"
+	"github.com/pingcap/tiflow/cdc/model"
 	"github.com/pingcap/tiflow/pkg/config"
 	"github.com/pingcap/tiflow/pkg/util/testleak"
 	"github.com/stretchr/testify/require"
@@ -42,3 +47,34 @@ func TestValidateSink(t *testing.T) {
 	err = Validate(ctx, sinkURI, replicateConfig, opts)
 	require.Nil(t, err)
 }
+
+func TestVerifyTablesExprFilter(t *testing.T) {
+	defer testleak.AfterTestT(t)()
+
+	mockTableInfo := func(sql string) *model.TableInfo {
+		node, err := parser.New().ParseOneStmt(sql, "", "")
+		require.Nil(t, err)
+		ti, err := ddl.MockTableInfo(timock.NewContext(), node.(*ast.CreateTableStmt), 1)
+		require.Nil(t, err)
+		return model.WrapTableInfo(1, "test", 1, ti)
+	}
+	tables := map[model.TableID]*model.TableInfo{
+		1: mockTableInfo("create table t (id int primary key, level int)"),
+		2: mockTableInfo("create table t1 (id int primary key)"),
+	}
+
+	cfg := config.GetDefaultReplicaConfig()
+	cfg.Filter.EventFilters = []*config.EventFilterRule{{
+		Matcher:               []string{"test.t"},
+		IgnoreInsertValueExpr: "level > 3",
+	}}
+	ineligibleTables, eligibleTables, err := VerifyTables(cfg, tables)
+	require.Nil(t, err)
+	require.Len(t, ineligibleTables, 0)
+	require.Len(t, eligibleTables, 2)
+
+	// the expression uses a column which doesn't exist in the table.
+	cfg.Filter.EventFilters[0].IgnoreInsertValueExpr = "status = 'deleted'"
+	_, _, err = VerifyTables(cfg, tables)
+	require.Regexp(t, ".*ErrExprColumnUnknown.*", err)
+}
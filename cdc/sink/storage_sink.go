@@ -0,0 +1,560 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sink
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pingcap/errors"
+	"github.com/pingcap/log"
+	"github.com/pingcap/tidb/br/pkg/storage"
+	"github.com/pingcap/tidb/parser/types"
+	"github.com/pingcap/tiflow/cdc/model"
+	"github.com/pingcap/tiflow/cdc/sink/codec"
+	"github.com/pingcap/tiflow/pkg/config"
+	cerror "github.com/pingcap/tiflow/pkg/errors"
+	"github.com/pingcap/tiflow/pkg/filter"
+	"github.com/pingcap/tiflow/pkg/security"
+	"github.com/pingcap/tiflow/pkg/util"
+	"go.uber.org/zap"
+)
+
+// The storage sink writes the changes into an external storage with the layout:
+//
+//	metadata                                         the checkpoint of the changefeed
+//	{schema}/meta/ddl_{commit-ts}.json               the schema level DDLs
+//	{schema}/{table}/meta/ddl_{commit-ts}.json       the table level DDLs
+//	{schema}/{table}/{version}/meta/schema.json      the columns of the table version
+//	{schema}/{table}/{version}/meta/CDC.index        the name of the last data file
+//	{schema}/{table}/{version}/CDC000001.csv         the data files
+//
+// The version is the table info version of the rows, which is the commit ts
+// of the DDL that creates the version. Each data file is written at once, and
+// all the changes whose commit ts is not greater than the checkpoint in the
+// metadata file are in the data files.
+const (
+	storageMetadataFile   = "metadata"
+	storageMetaDir        = "meta"
+	storageSchemaFile     = "schema.json"
+	storageIndexFile      = "CDC.index"
+	storageDataFilePrefix = "CDC"
+
+	storageFlushIntervalKey = "flush-interval"
+	storageFileSizeKey      = "file-size"
+
+	defaultStorageFlushInterval = 5 * time.Second
+	defaultStorageFileSize      = 64 * 1024 * 1024
+)
+
+type storageColumn struct {
+	Name         string `json:"name"`
+	Type         string `json:"type"`
+	IsPrimaryKey bool   `json:"is-primary-key,omitempty"`
+	IsNullable   bool   `json:"is-nullable,omitempty"`
+}
+
+type storageTableSchema struct {
+	Schema  string          `json:"schema"`
+	Table   string          `json:"table"`
+	Version uint64          `json:"version"`
+	Columns []storageColumn `json:"columns"`
+}
+
+type storageDDL struct {
+	Schema   string          `json:"schema"`
+	Table    string          `json:"table,omitempty"`
+	CommitTs uint64          `json:"commit-ts"`
+	Type     string          `json:"type"`
+	Query    string          `json:"query"`
+	Columns  []storageColumn `json:"columns,omitempty"`
+}
+
+type storageMetadata struct {
+	CheckpointTs uint64 `json:"checkpoint-ts"`
+}
+
+type storageEncodedRow struct {
+	commitTs uint64
+	version  uint64
+	data     []byte
+}
+
+// storageTableWriter buffers the encoded rows of a table until they are
+// written into the data files.
+type storageTableWriter struct {
+	mu sync.Mutex
+
+	table        model.TableName
+	encoder      codec.EventBatchEncoder
+	rows         []storageEncodedRow
+	size         int
+	checkpointTs uint64
+	lastFlush    time.Time
+	// schemas holds the columns of each table version seen by the writer.
+	schemas map[uint64][]storageColumn
+	// fileIndexes holds the index of the last data file of each table version.
+	fileIndexes map[uint64]uint64
+}
+
+type storageSink struct {
+	storage storage.ExternalStorage
+	// localDir is the root directory of a local storage, it's empty for the
+	// remote storages. The directories of a local storage are created by the sink.
+	localDir string
+	filter   *filter.Filter
+
+	encoderBuilder codec.EncoderBuilder
+	fileExt        string
+	flushInterval  time.Duration
+	fileSize       int
+
+	writersMu sync.Mutex
+	writers   map[model.TableID]*storageTableWriter
+
+	lastCheckpointTs uint64
+
+	statistics *Statistics
+	id         model.ChangeFeedID
+	role       util.Role
+}
+
+func newStorageSink(
+	ctx context.Context, sinkURI *url.URL, filter *filter.Filter,
+	replicaConfig *config.ReplicaConfig, opts map[string]string,
+) (*storageSink, error) {
+	params := sinkURI.Query()
+	protocolStr := params.Get(config.ProtocolKey)
+	if protocolStr == "" {
+		protocolStr = replicaConfig.Sink.Protocol
+	}
+	if protocolStr == "" {
+		protocolStr = config.ProtocolCSV.String()
+	}
+	var protocol config.Protocol
+	if err := protocol.FromString(protocolStr); err != nil {
+		return nil, cerror.WrapError(cerror.ErrSinkURIInvalid, err)
+	}
+	var fileExt string
+	switch protocol {
+	case config.ProtocolDefault:
+		protocol = config.ProtocolCSV
+		fileExt = ".csv"
+	case config.ProtocolCSV:
+		fileExt = ".csv"
+	case config.ProtocolCanalJSON:
+		fileExt = ".json"
+	default:
+		return nil, cerror.ErrSinkURIInvalid.GenWithStack(
+			"protocol %s is not supported by storage sink, only csv and canal-json are supported", protocolStr)
+	}
+
+	flushInterval := defaultStorageFlushInterval
+	if s := params.Get(storageFlushIntervalKey); s != "" {
+		d, err := time.ParseDuration(s)
+		if err != nil || d <= 0 {
+			return nil, cerror.ErrSinkURIInvalid.GenWithStack("invalid %s %s", storageFlushIntervalKey, s)
+		}
+		flushInterval = d
+	}
+	fileSize := defaultStorageFileSize
+	if s := params.Get(storageFileSizeKey); s != "" {
+		size, err := strconv.Atoi(s)
+		if err != nil || size <= 0 {
+			return nil, cerror.ErrSinkURIInvalid.GenWithStack("invalid %s %s", storageFileSizeKey, s)
+		}
+		fileSize = size
+	}
+	if s := params.Get("enable-tidb-extension"); s != "" {
+		opts["enable-tidb-extension"] = s
+	}
+
+	encoderBuilder, err := codec.NewEventBatchEncoderBuilder(protocol, &security.Credential{}, opts)
+	if err != nil {
+		return nil, cerror.WrapError(cerror.ErrSinkInvalidConfig, err)
+	}
+
+	// The parameters of the sink are removed, the others are the
+	// parameters of the storage, such as the endpoint of s3.
+	for _, key := range []string{config.ProtocolKey, storageFlushIntervalKey, storageFileSizeKey, "enable-tidb-extension"} {
+		params.Del(key)
+	}
+	storageURI := *sinkURI
+	storageURI.RawQuery = params.Encode()
+	backend, err := storage.ParseBackend(storageURI.String(), nil)
+	if err != nil {
+		return nil, cerror.WrapError(cerror.ErrStorageInitialize, err)
+	}
+	var localDir string
+	if local := backend.GetLocal(); local != nil {
+		localDir = local.GetPath()
+		if err := os.MkdirAll(localDir, 0o755); err != nil {
+			return nil, cerror.WrapError(cerror.ErrStorageInitialize, err)
+		}
+	}
+	extStorage, err := storage.New(ctx, backend, &storage.ExternalStorageOptions{
+		SendCredentials: false,
+	})
+	if err != nil {
+		return nil, cerror.WrapError(cerror.ErrStorageInitialize, err)
+	}
+
+	s := &storageSink{
+		storage:        extStorage,
+		localDir:       localDir,
+		filter:         filter,
+		encoderBuilder: encoderBuilder,
+		fileExt:        fileExt,
+		flushInterval:  flushInterval,
+		fileSize:       fileSize,
+		writers:        make(map[model.TableID]*storageTableWriter),
+		statistics:     NewStatistics(ctx, "storage", opts),
+		id:             util.ChangefeedIDFromCtx(ctx),
+		role:           util.RoleFromCtx(ctx),
+	}
+	log.Info("storage sink created",
+		zap.String("uri", extStorage.URI()), zap.String("protocol", protocol.String()),
+		zap.Duration("flushInterval", flushInterval), zap.Int("fileSize", fileSize),
+		zap.String("changefeed", s.id), zap.Any("role", s.role))
+	return s, nil
+}
+
+func (s *storageSink) getWriter(ctx context.Context, tableID model.TableID, create bool) (*storageTableWriter, error) {
+	s.writersMu.Lock()
+	defer s.writersMu.Unlock()
+	w, ok := s.writers[tableID]
+	if ok || !create {
+		return w, nil
+	}
+	encoder, err := s.encoderBuilder.Build(ctx)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	w = &storageTableWriter{
+		encoder:     encoder,
+		lastFlush:   time.Now(),
+		schemas:     make(map[uint64][]storageColumn),
+		fileIndexes: make(map[uint64]uint64),
+	}
+	s.writers[tableID] = w
+	return w, nil
+}
+
+func (s *storageSink) TryEmitRowChangedEvents(ctx context.Context, rows ...*model.RowChangedEvent) (bool, error) {
+	err := s.EmitRowChangedEvents(ctx, rows...)
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func (s *storageSink) EmitRowChangedEvents(ctx context.Context, rows ...*model.RowChangedEvent) error {
+	rowsCount := 0
+	for _, row := range rows {
+		if s.filter.ShouldIgnoreDMLEvent(row.StartTs, row.Table.Schema, row.Table.Table) {
+			log.Info("Row changed event ignored",
+				zap.Uint64("start-ts", row.StartTs),
+				zap.String("changefeed", s.id),
+				zap.Any("role", s.role))
+			continue
+		}
+		w, err := s.getWriter(ctx, row.Table.TableID, true)
+		if err != nil {
+			return errors.Trace(err)
+		}
+		if err := w.appendRow(row); err != nil {
+			return errors.Trace(err)
+		}
+		rowsCount++
+	}
+	s.statistics.AddRowsCount(rowsCount)
+	return nil
+}
+
+func (w *storageTableWriter) appendRow(row *model.RowChangedEvent) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.table = *row.Table
+	if _, ok := w.schemas[row.TableInfoVersion]; !ok {
+		columns := row.Columns
+		if len(columns) == 0 {
+			columns = row.PreColumns
+		}
+		w.schemas[row.TableInfoVersion] = newStorageColumns(columns)
+	}
+
+	if _, err := w.encoder.AppendRowChangedEvent(row); err != nil {
+		return errors.Trace(err)
+	}
+	if _, err := w.encoder.AppendResolvedEvent(row.CommitTs); err != nil {
+		return errors.Trace(err)
+	}
+	for _, msg := range w.encoder.Build() {
+		w.rows = append(w.rows, storageEncodedRow{
+			commitTs: msg.Ts,
+			version:  row.TableInfoVersion,
+			data:     msg.Value,
+		})
+		w.size += len(msg.Value) + 1
+	}
+	return nil
+}
+
+func newStorageColumns(columns []*model.Column) []storageColumn {
+	result := make([]storageColumn, 0, len(columns))
+	for _, col := range columns {
+		if col == nil {
+			continue
+		}
+		result = append(result, storageColumn{
+			Name:         col.Name,
+			Type:         types.TypeStr(col.Type),
+			IsPrimaryKey: col.Flag.IsPrimaryKey(),
+			IsNullable:   col.Flag.IsNullable(),
+		})
+	}
+	return result
+}
+
+// FlushRowChangedEvents writes the buffered rows of the table into the data files,
+// if the buffered rows are large enough or the flush interval is reached.
+// Otherwise the rows are kept, and the checkpoint of the last write is returned.
+func (s *storageSink) FlushRowChangedEvents(ctx context.Context, tableID model.TableID, resolvedTs uint64) (uint64, error) {
+	w, err := s.getWriter(ctx, tableID, false)
+	if err != nil {
+		return 0, errors.Trace(err)
+	}
+	if w == nil {
+		return resolvedTs, nil
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if len(w.rows) != 0 {
+		if w.size < s.fileSize && time.Since(w.lastFlush) < s.flushInterval {
+			return w.checkpointTs, nil
+		}
+		err := s.statistics.RecordBatchExecution(func() (int, error) {
+			return s.writeRows(ctx, w, resolvedTs)
+		})
+		if err != nil {
+			return w.checkpointTs, errors.Trace(err)
+		}
+		w.lastFlush = time.Now()
+	}
+	if resolvedTs > w.checkpointTs {
+		w.checkpointTs = resolvedTs
+	}
+	s.statistics.PrintStatus(ctx)
+	return w.checkpointTs, nil
+}
+
+// writeRows writes the rows whose commit ts is not greater than the resolved ts,
+// each data file only contains the rows of one table version and is no larger
+// than the file size, unless a single row is larger than it.
+func (s *storageSink) writeRows(ctx context.Context, w *storageTableWriter, resolvedTs uint64) (int, error) {
+	written := 0
+	buf := &bytes.Buffer{}
+	for len(w.rows) > 0 && w.rows[0].commitTs <= resolvedTs {
+		version := w.rows[0].version
+		buf.Reset()
+		n := 0
+		for n < len(w.rows) && w.rows[n].commitTs <= resolvedTs && w.rows[n].version == version {
+			if n > 0 && buf.Len()+len(w.rows[n].data)+1 > s.fileSize {
+				break
+			}
+			buf.Write(w.rows[n].data)
+			buf.WriteByte('\n')
+			n++
+		}
+		if err := s.writeDataFile(ctx, w, version, buf.Bytes()); err != nil {
+			return written, errors.Trace(err)
+		}
+		w.rows = w.rows[n:]
+		w.size -= buf.Len()
+		written += n
+	}
+	if len(w.rows) == 0 {
+		// release the underlying array of the written rows.
+		w.rows = nil
+		w.size = 0
+	}
+	return written, nil
+}
+
+func (s *storageSink) writeDataFile(ctx context.Context, w *storageTableWriter, version uint64, data []byte) error {
+	dir := path.Join(w.table.Schema, w.table.Table, strconv.FormatUint(version, 10))
+	index, ok := w.fileIndexes[version]
+	if !ok {
+		// This is the first data file of the version written by this writer,
+		// the files written before restarting must not be overwritten.
+		var err error
+		index, err = s.lastFileIndex(ctx, dir)
+		if err != nil {
+			return errors.Trace(err)
+		}
+		schema, err := json.Marshal(&storageTableSchema{
+			Schema:  w.table.Schema,
+			Table:   w.table.Table,
+			Version: version,
+			Columns: w.schemas[version],
+		})
+		if err != nil {
+			return cerror.WrapError(cerror.ErrMarshalFailed, err)
+		}
+		if err := s.writeFile(ctx, path.Join(dir, storageMetaDir, storageSchemaFile), schema); err != nil {
+			return errors.Trace(err)
+		}
+	}
+
+	index++
+	name := fmt.Sprintf("%s%06d%s", storageDataFilePrefix, index, s.fileExt)
+	if err := s.writeFile(ctx, path.Join(dir, name), data); err != nil {
+		return errors.Trace(err)
+	}
+	w.fileIndexes[version] = index
+	// The index file is written after the data file,
+	// so the data files it points to are always complete.
+	return s.writeFile(ctx, path.Join(dir, storageMetaDir, storageIndexFile), []byte(name))
+}
+
+// lastFileIndex returns the largest index of the data files in the directory.
+func (s *storageSink) lastFileIndex(ctx context.Context, dir string) (uint64, error) {
+	if err := s.ensureDir(dir); err != nil {
+		return 0, errors.Trace(err)
+	}
+	var maxIndex uint64
+	err := s.storage.WalkDir(ctx, &storage.WalkOption{SubDir: dir}, func(filePath string, _ int64) error {
+		name := path.Base(filePath)
+		if !strings.HasPrefix(name, storageDataFilePrefix) || !strings.HasSuffix(name, s.fileExt) {
+			return nil
+		}
+		index, err := strconv.ParseUint(
+			strings.TrimSuffix(strings.TrimPrefix(name, storageDataFilePrefix), s.fileExt), 10, 64)
+		if err != nil {
+			return nil
+		}
+		if index > maxIndex {
+			maxIndex = index
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, cerror.WrapError(cerror.ErrExternalStorageAPI, err)
+	}
+	return maxIndex, nil
+}
+
+// ensureDir creates the directory if the storage is a local storage.
+func (s *storageSink) ensureDir(dir string) error {
+	if s.localDir == "" {
+		return nil
+	}
+	err := os.MkdirAll(filepath.Join(s.localDir, filepath.FromSlash(dir)), 0o755)
+	return cerror.WrapError(cerror.ErrExternalStorageAPI, err)
+}
+
+func (s *storageSink) writeFile(ctx context.Context, name string, data []byte) error {
+	if err := s.ensureDir(path.Dir(name)); err != nil {
+		return errors.Trace(err)
+	}
+	return cerror.WrapError(cerror.ErrExternalStorageAPI, s.storage.WriteFile(ctx, name, data))
+}
+
+// EmitCheckpointTs writes the checkpoint into the metadata file, all the
+// changes whose commit ts is not greater than it are written into the data files.
+func (s *storageSink) EmitCheckpointTs(ctx context.Context, ts uint64, tables []model.TableName) error {
+	if ts <= s.lastCheckpointTs {
+		return nil
+	}
+	data, err := json.Marshal(&storageMetadata{CheckpointTs: ts})
+	if err != nil {
+		return cerror.WrapError(cerror.ErrMarshalFailed, err)
+	}
+	if err := s.writeFile(ctx, storageMetadataFile, data); err != nil {
+		return errors.Trace(err)
+	}
+	s.lastCheckpointTs = ts
+	return nil
+}
+
+// EmitDDLEvent writes the DDL into a separate file.
+func (s *storageSink) EmitDDLEvent(ctx context.Context, ddl *model.DDLEvent) error {
+	ignore, err := s.filter.ShouldIgnoreDDLByEventType(ddl)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	if ignore || s.filter.ShouldIgnoreDDLEvent(ddl.StartTs, ddl.Type, ddl.TableInfo.Schema, ddl.TableInfo.Table) {
+		log.Info(
+			"DDL event ignored",
+			zap.String("query", ddl.Query),
+			zap.Uint64("startTs", ddl.StartTs),
+			zap.Uint64("commitTs", ddl.CommitTs),
+			zap.String("changefeed", s.id),
+			zap.Any("role", s.role),
+		)
+		return cerror.ErrDDLEventIgnored.GenWithStackByArgs()
+	}
+	ddlFile := &storageDDL{
+		Schema:   ddl.TableInfo.Schema,
+		Table:    ddl.TableInfo.Table,
+		CommitTs: ddl.CommitTs,
+		Type:     ddl.Type.String(),
+		Query:    ddl.Query,
+	}
+	for _, col := range ddl.TableInfo.ColumnInfo {
+		ddlFile.Columns = append(ddlFile.Columns, storageColumn{
+			Name: col.Name,
+			Type: types.TypeStr(col.Type),
+		})
+	}
+	data, err := json.Marshal(ddlFile)
+	if err != nil {
+		return cerror.WrapError(cerror.ErrMarshalFailed, err)
+	}
+
+	dir := path.Join(ddl.TableInfo.Schema, ddl.TableInfo.Table, storageMetaDir)
+	name := path.Join(dir, fmt.Sprintf("ddl_%d.json", ddl.CommitTs))
+	err = s.statistics.RecordDDLExecution(func() error {
+		return s.writeFile(ctx, name, data)
+	})
+	if err != nil {
+		return errors.Trace(err)
+	}
+	s.statistics.AddDDLCount()
+	log.Info("storage sink writes the ddl file",
+		zap.String("file", name), zap.String("query", ddl.Query),
+		zap.String("changefeed", s.id), zap.Any("role", s.role))
+	return nil
+}
+
+func (s *storageSink) Close(ctx context.Context) error {
+	return nil
+}
+
+func (s *storageSink) Barrier(ctx context.Context, tableID model.TableID) error {
+	// Barrier does nothing because FlushRowChangedEvents in storage sink has flushed
+	// all the events whose commit ts are not greater than the returned checkpoint.
+	return nil
+}
@@ -0,0 +1,254 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sink
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	timodel "github.com/pingcap/tidb/parser/model"
+	"github.com/pingcap/tidb/parser/mysql"
+	"github.com/pingcap/tiflow/cdc/model"
+	"github.com/pingcap/tiflow/pkg/config"
+	cerror "github.com/pingcap/tiflow/pkg/errors"
+	"github.com/pingcap/tiflow/pkg/filter"
+	"github.com/pingcap/tiflow/pkg/util/testleak"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestStorageSink(ctx context.Context, t *testing.T, dir string, query string) *storageSink {
+	return newTestStorageSinkWithConfig(ctx, t, dir, query, config.GetDefaultReplicaConfig())
+}
+
+func newTestStorageSinkWithConfig(
+	ctx context.Context, t *testing.T, dir string, query string, replicaConfig *config.ReplicaConfig,
+) *storageSink {
+	sinkURI, err := url.Parse(fmt.Sprintf("file://%s?%s", dir, query))
+	require.Nil(t, err)
+	f, err := filter.NewFilter(replicaConfig)
+	require.Nil(t, err)
+	s, err := newStorageSink(ctx, sinkURI, f, replicaConfig, make(map[string]string))
+	require.Nil(t, err)
+	return s
+}
+
+func newStorageTestRow(id int, commitTs, version uint64) *model.RowChangedEvent {
+	return &model.RowChangedEvent{
+		CommitTs:         commitTs,
+		TableInfoVersion: version,
+		Table:            &model.TableName{Schema: "test", Table: "t1", TableID: 1},
+		Columns: []*model.Column{
+			{Name: "id", Type: mysql.TypeLong, Value: id, Flag: model.HandleKeyFlag | model.PrimaryKeyFlag},
+			{Name: "name", Type: mysql.TypeVarchar, Value: nil, Flag: model.NullableFlag},
+		},
+	}
+}
+
+func readStorageFile(t *testing.T, elems ...string) string {
+	data, err := os.ReadFile(filepath.Join(elems...))
+	require.Nil(t, err)
+	return string(data)
+}
+
+func TestNewStorageSink(t *testing.T) {
+	defer testleak.AfterTestT(t)()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	dir := t.TempDir()
+
+	for _, query := range []string{
+		"protocol=avro",
+		"flush-interval=abc",
+		"flush-interval=-1s",
+		"file-size=0",
+	} {
+		sinkURI, err := url.Parse(fmt.Sprintf("file://%s?%s", dir, query))
+		require.Nil(t, err)
+		_, err = newStorageSink(ctx, sinkURI, nil, config.GetDefaultReplicaConfig(), make(map[string]string))
+		require.True(t, cerror.ErrSinkURIInvalid.Equal(err), query)
+	}
+
+	s := newTestStorageSink(ctx, t, dir, "protocol=canal-json&flush-interval=1m&file-size=1024")
+	require.Equal(t, ".json", s.fileExt)
+	require.Equal(t, 1024, s.fileSize)
+	require.Equal(t, "1m0s", s.flushInterval.String())
+}
+
+func TestStorageSinkWriteRows(t *testing.T) {
+	defer testleak.AfterTestT(t)()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	dir := t.TempDir()
+	// each row is about 20 bytes, so a file contains 2 rows at most.
+	s := newTestStorageSink(ctx, t, dir, "file-size=50")
+
+	require.Nil(t, s.EmitRowChangedEvents(ctx,
+		newStorageTestRow(1, 100, 10),
+		newStorageTestRow(2, 101, 10),
+		newStorageTestRow(3, 102, 10),
+		newStorageTestRow(4, 103, 11),
+		newStorageTestRow(5, 200, 11),
+	))
+	checkpointTs, err := s.FlushRowChangedEvents(ctx, 1, 150)
+	require.Nil(t, err)
+	require.Equal(t, uint64(150), checkpointTs)
+
+	versionDir := filepath.Join(dir, "test", "t1", "10")
+	require.Equal(t, "I,test,t1,100,1,\\N\nI,test,t1,101,2,\\N\n", readStorageFile(t, versionDir, "CDC000001.csv"))
+	require.Equal(t, "I,test,t1,102,3,\\N\n", readStorageFile(t, versionDir, "CDC000002.csv"))
+	require.Equal(t, "CDC000002.csv", readStorageFile(t, versionDir, "meta", "CDC.index"))
+	require.Equal(t, "I,test,t1,103,4,\\N\n", readStorageFile(t, dir, "test", "t1", "11", "CDC000001.csv"))
+
+	var schema storageTableSchema
+	require.Nil(t, json.Unmarshal([]byte(readStorageFile(t, versionDir, "meta", "schema.json")), &schema))
+	require.Equal(t, uint64(10), schema.Version)
+	require.Equal(t, []storageColumn{
+		{Name: "id", Type: "int", IsPrimaryKey: true},
+		{Name: "name", Type: "varchar", IsNullable: true},
+	}, schema.Columns)
+
+	// the row whose commit ts is greater than the resolved ts is kept.
+	require.Len(t, s.writers[1].rows, 1)
+
+	// the rows are buffered until the flush interval is reached.
+	require.Nil(t, s.EmitRowChangedEvents(ctx, newStorageTestRow(6, 201, 11)))
+	checkpointTs, err = s.FlushRowChangedEvents(ctx, 1, 300)
+	require.Nil(t, err)
+	require.Equal(t, uint64(150), checkpointTs)
+	s.flushInterval = 0
+	checkpointTs, err = s.FlushRowChangedEvents(ctx, 1, 300)
+	require.Nil(t, err)
+	require.Equal(t, uint64(300), checkpointTs)
+	require.Equal(t, "I,test,t1,200,5,\\N\nI,test,t1,201,6,\\N\n",
+		readStorageFile(t, dir, "test", "t1", "11", "CDC000002.csv"))
+
+	// a new sink continues the file index of the existing files.
+	s = newTestStorageSink(ctx, t, dir, "")
+	require.Nil(t, s.EmitRowChangedEvents(ctx, newStorageTestRow(7, 400, 10)))
+	s.flushInterval = 0
+	_, err = s.FlushRowChangedEvents(ctx, 1, 400)
+	require.Nil(t, err)
+	require.Equal(t, "CDC000003.csv", readStorageFile(t, versionDir, "meta", "CDC.index"))
+
+	// the checkpoint of a table without any rows is the resolved ts.
+	checkpointTs, err = s.FlushRowChangedEvents(ctx, 2, 500)
+	require.Nil(t, err)
+	require.Equal(t, uint64(500), checkpointTs)
+}
+
+func TestStorageSinkDDLAndCheckpoint(t *testing.T) {
+	defer testleak.AfterTestT(t)()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	dir := t.TempDir()
+	s := newTestStorageSink(ctx, t, dir, "")
+
+	require.Nil(t, s.EmitDDLEvent(ctx, &model.DDLEvent{
+		CommitTs:  100,
+		TableInfo: &model.SimpleTableInfo{Schema: "test"},
+		Query:     "create database test",
+		Type:      timodel.ActionCreateSchema,
+	}))
+	require.Nil(t, s.EmitDDLEvent(ctx, &model.DDLEvent{
+		CommitTs: 110,
+		TableInfo: &model.SimpleTableInfo{
+			Schema:     "test",
+			Table:      "t1",
+			ColumnInfo: []*model.ColumnInfo{{Name: "id", Type: mysql.TypeLong}},
+		},
+		Query: "create table t1(id int primary key)",
+		Type:  timodel.ActionCreateTable,
+	}))
+	require.True(t, strings.Contains(
+		readStorageFile(t, dir, "test", "meta", "ddl_100.json"), "create database test"))
+
+	var ddl storageDDL
+	require.Nil(t, json.Unmarshal([]byte(readStorageFile(t, dir, "test", "t1", "meta", "ddl_110.json")), &ddl))
+	require.Equal(t, storageDDL{
+		Schema:   "test",
+		Table:    "t1",
+		CommitTs: 110,
+		Type:     "create table",
+		Query:    "create table t1(id int primary key)",
+		Columns:  []storageColumn{{Name: "id", Type: "int"}},
+	}, ddl)
+
+	require.Nil(t, s.EmitCheckpointTs(ctx, 120, nil))
+	require.Equal(t, `{"checkpoint-ts":120}`, readStorageFile(t, dir, "metadata"))
+	// the smaller checkpoint is ignored.
+	require.Nil(t, s.EmitCheckpointTs(ctx, 110, nil))
+	require.Equal(t, `{"checkpoint-ts":120}`, readStorageFile(t, dir, "metadata"))
+}
+
+func TestStorageSinkFilter(t *testing.T) {
+	defer testleak.AfterTestT(t)()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	dir := t.TempDir()
+	replicaConfig := config.GetDefaultReplicaConfig()
+	replicaConfig.Filter.Rules = []string{"test.*", "!test.t2"}
+	replicaConfig.Filter.IgnoreTxnStartTs = []uint64{99}
+	replicaConfig.Filter.EventFilters = []*config.EventFilterRule{{
+		Matcher:   []string{"test.*"},
+		IgnoreSQL: []string{"^DROP TABLE"},
+	}}
+	s := newTestStorageSinkWithConfig(ctx, t, dir, "", replicaConfig)
+
+	ignoredTableRow := newStorageTestRow(2, 101, 10)
+	ignoredTableRow.Table = &model.TableName{Schema: "test", Table: "t2", TableID: 2}
+	ignoredTxnRow := newStorageTestRow(3, 102, 10)
+	ignoredTxnRow.StartTs = 99
+	require.Nil(t, s.EmitRowChangedEvents(ctx, newStorageTestRow(1, 100, 10), ignoredTableRow, ignoredTxnRow))
+	require.Len(t, s.writers, 1)
+	require.Len(t, s.writers[1].rows, 1)
+
+	for _, ddl := range []*model.DDLEvent{
+		{
+			CommitTs:  110,
+			TableInfo: &model.SimpleTableInfo{Schema: "test", Table: "t2"},
+			Query:     "create table t2(id int primary key)",
+			Type:      timodel.ActionCreateTable,
+		},
+		{
+			StartTs:   99,
+			CommitTs:  111,
+			TableInfo: &model.SimpleTableInfo{Schema: "test", Table: "t1"},
+			Query:     "alter table t1 add column c int",
+			Type:      timodel.ActionAddColumn,
+		},
+		{
+			CommitTs:  112,
+			TableInfo: &model.SimpleTableInfo{Schema: "test", Table: "t1"},
+			Query:     "DROP TABLE t1",
+			Type:      timodel.ActionDropTable,
+		},
+	} {
+		err := s.EmitDDLEvent(ctx, ddl)
+		require.True(t, cerror.ErrDDLEventIgnored.Equal(err), ddl.Query)
+	}
+	_, err := os.Stat(filepath.Join(dir, "test", "t2"))
+	require.True(t, os.IsNotExist(err))
+	_, err = os.Stat(filepath.Join(dir, "test", "t1", "meta"))
+	require.True(t, os.IsNotExist(err))
+}
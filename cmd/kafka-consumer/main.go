@@ -17,14 +17,12 @@ import (
 	"context"
 	"flag"
 	"fmt"
-	"math"
 	"net/url"
 	"os"
 	"os/signal"
 	"strconv"
 	"strings"
 	"sync"
-	"sync/atomic"
 	"syscall"
 	"time"
 
@@ -32,13 +30,9 @@ import (
 	"github.com/google/uuid"
 	"github.com/pingcap/errors"
 	"github.com/pingcap/log"
-	"github.com/pingcap/tiflow/cdc/model"
-	"github.com/pingcap/tiflow/cdc/sink"
-	"github.com/pingcap/tiflow/cdc/sink/codec"
 	"github.com/pingcap/tiflow/pkg/config"
-	cdcfilter "github.com/pingcap/tiflow/pkg/filter"
+	"github.com/pingcap/tiflow/pkg/kafka/consumer"
 	"github.com/pingcap/tiflow/pkg/logutil"
-	"github.com/pingcap/tiflow/pkg/quotes"
 	"github.com/pingcap/tiflow/pkg/security"
 	"github.com/pingcap/tiflow/pkg/util"
 	"go.uber.org/zap"
@@ -51,8 +45,14 @@ var (
 	kafkaPartitionNum    int32
 	kafkaGroupID         = fmt.Sprintf("ticdc_kafka_consumer_%s", uuid.New().String())
 	kafkaVersion         = "2.4.0"
-	kafkaMaxMessageBytes = math.MaxInt64
-	kafkaMaxBatchSize    = math.MaxInt64
+	kafkaMaxMessageBytes = 0
+	kafkaMaxBatchSize    = 0
+
+	protocol            = config.ProtocolOpen
+	enableTiDBExtension bool
+	schemaRegistry      string
+	// claimCheckStorageURI is the external storage of the claim check messages.
+	claimCheckStorageURI string
 
 	downstreamURIStr string
 
@@ -144,6 +144,25 @@ func init() {
 		log.Info("Setting max-batch-size", zap.Int("max-batch-size", c))
 		kafkaMaxBatchSize = c
 	}
+
+	s = upstreamURI.Query().Get("protocol")
+	if s != "" {
+		if err := protocol.FromString(s); err != nil {
+			log.Panic("invalid protocol of upstream-uri", zap.Error(err))
+		}
+	}
+
+	s = upstreamURI.Query().Get("enable-tidb-extension")
+	if s != "" {
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			log.Panic("invalid enable-tidb-extension of upstream-uri")
+		}
+		enableTiDBExtension = b
+	}
+
+	schemaRegistry = upstreamURI.Query().Get("schema-registry")
+	claimCheckStorageURI = upstreamURI.Query().Get("claim-check-storage-uri")
 }
 
 func getPartitionNum(address []string, topic string, cfg *sarama.Config) (int32, error) {
@@ -206,11 +225,7 @@ func newSaramaConfig() (*sarama.Config, error) {
 
 	if len(ca) != 0 {
 		config.Net.TLS.Enable = true
-		config.Net.TLS.Config, err = (&security.Credential{
-			CAPath:   ca,
-			CertPath: cert,
-			KeyPath:  key,
-		}).ToTLSConfig()
+		config.Net.TLS.Config, err = newCredential().ToTLSConfig()
 		if err != nil {
 			return nil, errors.Trace(err)
 		}
@@ -219,8 +234,52 @@ func newSaramaConfig() (*sarama.Config, error) {
 	return config, err
 }
 
+func newCredential() *security.Credential {
+	return &security.Credential{
+		CAPath:   ca,
+		CertPath: cert,
+		KeyPath:  key,
+	}
+}
+
+// newConsumer creates a consumer applying the events to the downstream sink.
+func newConsumer(ctx context.Context) (*consumer.Consumer, consumer.Target, error) {
+	tz, err := util.GetTimezone(timezone)
+	if err != nil {
+		return nil, nil, errors.Annotate(err, "can not load timezone")
+	}
+	ctx = util.PutTimezoneInCtx(ctx, tz)
+	errCh := make(chan error, 1)
+	target, err := consumer.NewSinkTarget(ctx, downstreamURIStr, errCh)
+	if err != nil {
+		return nil, nil, errors.Trace(err)
+	}
+	go func() {
+		err := <-errCh
+		if errors.Cause(err) != context.Canceled {
+			log.Panic("error on running sink", zap.Error(err))
+		}
+	}()
+	c, err := consumer.NewConsumer(ctx, &consumer.Config{
+		Protocol:             protocol,
+		Topics:               strings.Split(kafkaTopic, ","),
+		PartitionNum:         kafkaPartitionNum,
+		EnableTiDBExtension:  enableTiDBExtension,
+		SchemaRegistry:       schemaRegistry,
+		Credential:           newCredential(),
+		Timezone:             tz,
+		MaxMessageBytes:      kafkaMaxMessageBytes,
+		MaxBatchSize:         kafkaMaxBatchSize,
+		ClaimCheckStorageURI: claimCheckStorageURI,
+	}, target)
+	if err != nil {
+		return nil, nil, errors.Trace(err)
+	}
+	return c, target, nil
+}
+
 func main() {
-	log.Info("Starting a new TiCDC open protocol consumer")
+	log.Info("Starting a new TiCDC consumer", zap.Stringer("protocol", protocol))
 
 	/**
 	 * Construct a new Sarama configuration.
@@ -234,15 +293,16 @@ func main() {
 	if err != nil {
 		log.Panic("wait topic created failed", zap.Error(err))
 	}
-	/**
-	 * Setup a new Sarama consumer group
-	 */
-	consumer, err := NewConsumer(context.TODO())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	consumer, target, err := newConsumer(ctx)
 	if err != nil {
 		log.Panic("Error creating consumer", zap.Error(err))
 	}
 
-	ctx, cancel := context.WithCancel(context.Background())
+	/**
+	 * Setup a new Sarama consumer group
+	 */
 	client, err := sarama.NewConsumerGroup(kafkaAddrs, kafkaGroupID, config)
 	if err != nil {
 		log.Panic("Error creating consumer group client", zap.Error(err))
@@ -263,18 +323,17 @@ func main() {
 			if ctx.Err() != nil {
 				return
 			}
-			consumer.ready = make(chan bool)
 		}
 	}()
 
 	go func() {
-		if err := consumer.Run(ctx); err != nil {
+		if err := consumer.Run(ctx); err != nil && errors.Cause(err) != context.Canceled {
 			log.Panic("Error running consumer: %v", zap.Error(err))
 		}
 	}()
 
-	<-consumer.ready // Await till the consumer has been set up
-	log.Info("TiCDC open protocol consumer up and running!...")
+	<-consumer.Ready() // Await till the consumer has been set up
+	log.Info("TiCDC consumer up and running!...")
 
 	sigterm := make(chan os.Signal, 1)
 	signal.Notify(sigterm, syscall.SIGINT, syscall.SIGTERM)
@@ -289,362 +348,7 @@ func main() {
 	if err = client.Close(); err != nil {
 		log.Panic("Error closing client", zap.Error(err))
 	}
-}
-
-type partitionSink struct {
-	sink.Sink
-	resolvedTs  uint64
-	partitionNo int
-	tablesMap   sync.Map
-}
-
-// Consumer represents a Sarama consumer group consumer
-type Consumer struct {
-	ready chan bool
-
-	ddlList          []*model.DDLEvent
-	maxDDLReceivedTs uint64
-	ddlListMu        sync.Mutex
-
-	sinks   []*partitionSink
-	sinksMu sync.Mutex
-
-	ddlSink              sink.Sink
-	fakeTableIDGenerator *fakeTableIDGenerator
-
-	globalResolvedTs uint64
-}
-
-// NewConsumer creates a new cdc kafka consumer
-func NewConsumer(ctx context.Context) (*Consumer, error) {
-	// TODO support filter in downstream sink
-	tz, err := util.GetTimezone(timezone)
-	if err != nil {
-		return nil, errors.Annotate(err, "can not load timezone")
-	}
-	ctx = util.PutTimezoneInCtx(ctx, tz)
-	filter, err := cdcfilter.NewFilter(config.GetDefaultReplicaConfig())
-	if err != nil {
-		return nil, errors.Trace(err)
-	}
-	c := new(Consumer)
-	c.fakeTableIDGenerator = &fakeTableIDGenerator{
-		tableIDs: make(map[string]int64),
-	}
-	c.sinks = make([]*partitionSink, kafkaPartitionNum)
-	ctx, cancel := context.WithCancel(ctx)
-	ctx = util.PutRoleInCtx(ctx, util.RoleKafkaConsumer)
-	errCh := make(chan error, 1)
-	opts := map[string]string{}
-	for i := 0; i < int(kafkaPartitionNum); i++ {
-		s, err := sink.New(ctx, "kafka-consumer", downstreamURIStr, filter, config.GetDefaultReplicaConfig(), opts, errCh)
-		if err != nil {
-			cancel()
-			return nil, errors.Trace(err)
-		}
-		c.sinks[i] = &partitionSink{Sink: s, partitionNo: i}
-	}
-	sink, err := sink.New(ctx, "kafka-consumer", downstreamURIStr, filter, config.GetDefaultReplicaConfig(), opts, errCh)
-	if err != nil {
-		cancel()
-		return nil, errors.Trace(err)
-	}
-	go func() {
-		err := <-errCh
-		if errors.Cause(err) != context.Canceled {
-			log.Error("error on running consumer", zap.Error(err))
-		} else {
-			log.Info("consumer exited")
-		}
-		cancel()
-	}()
-	c.ddlSink = sink
-	c.ready = make(chan bool)
-	return c, nil
-}
-
-// Setup is run at the beginning of a new session, before ConsumeClaim
-func (c *Consumer) Setup(sarama.ConsumerGroupSession) error {
-	// Mark the c as ready
-	close(c.ready)
-	return nil
-}
-
-// Cleanup is run at the end of a session, once all ConsumeClaim goroutines have exited
-func (c *Consumer) Cleanup(sarama.ConsumerGroupSession) error {
-	return nil
-}
-
-// ConsumeClaim must start a consumer loop of ConsumerGroupClaim's Messages().
-func (c *Consumer) ConsumeClaim(session sarama.ConsumerGroupSession, claim sarama.ConsumerGroupClaim) error {
-	ctx := context.TODO()
-	partition := claim.Partition()
-	c.sinksMu.Lock()
-	sink := c.sinks[partition]
-	c.sinksMu.Unlock()
-	if sink == nil {
-		panic("sink should initialized")
-	}
-
-	for message := range claim.Messages() {
-		log.Debug("Message claimed", zap.Int32("partition", message.Partition), zap.ByteString("key", message.Key), zap.ByteString("value", message.Value))
-		batchDecoder, err := codec.NewJSONEventBatchDecoder(message.Key, message.Value)
-		if err != nil {
-			return errors.Trace(err)
-		}
-
-		counter := 0
-		for {
-			tp, hasNext, err := batchDecoder.HasNext()
-			if err != nil {
-				log.Panic("decode message key failed", zap.Error(err))
-			}
-			if !hasNext {
-				break
-			}
-
-			counter++
-			// If the message containing only one event exceeds the length limit, CDC will allow it and issue a warning.
-			if len(message.Key)+len(message.Value) > kafkaMaxMessageBytes && counter > 1 {
-				log.Panic("kafka max-messages-bytes exceeded", zap.Int("max-message-bytes", kafkaMaxMessageBytes),
-					zap.Int("receviedBytes", len(message.Key)+len(message.Value)))
-			}
-
-			switch tp {
-			case model.MqMessageTypeDDL:
-				ddl, err := batchDecoder.NextDDLEvent()
-				if err != nil {
-					log.Panic("decode message value failed", zap.ByteString("value", message.Value))
-				}
-				c.appendDDL(ddl)
-			case model.MqMessageTypeRow:
-				row, err := batchDecoder.NextRowChangedEvent()
-				if err != nil {
-					log.Panic("decode message value failed", zap.ByteString("value", message.Value))
-				}
-				globalResolvedTs := atomic.LoadUint64(&c.globalResolvedTs)
-				if row.CommitTs <= globalResolvedTs || row.CommitTs <= sink.resolvedTs {
-					log.Debug("RowChangedEvent fallback row, ignore it",
-						zap.Uint64("commitTs", row.CommitTs),
-						zap.Uint64("globalResolvedTs", globalResolvedTs),
-						zap.Uint64("sinkResolvedTs", sink.resolvedTs),
-						zap.Int32("partition", partition),
-						zap.ByteString("row", message.Key))
-				}
-				// FIXME: hack to set start-ts in row changed event, as start-ts
-				// is not contained in TiCDC open protocol
-				row.StartTs = row.CommitTs
-				var partitionID int64
-				if row.Table.IsPartition {
-					partitionID = row.Table.TableID
-				}
-				row.Table.TableID =
-					c.fakeTableIDGenerator.generateFakeTableID(row.Table.Schema, row.Table.Table, partitionID)
-				err = sink.EmitRowChangedEvents(ctx, row)
-				if err != nil {
-					log.Panic("emit row changed event failed", zap.Error(err))
-				}
-				lastCommitTs, ok := sink.tablesMap.Load(row.Table.TableID)
-				if !ok || lastCommitTs.(uint64) < row.CommitTs {
-					sink.tablesMap.Store(row.Table.TableID, row.CommitTs)
-				}
-			case model.MqMessageTypeResolved:
-				ts, err := batchDecoder.NextResolvedEvent()
-				if err != nil {
-					log.Panic("decode message value failed", zap.ByteString("value", message.Value))
-				}
-				resolvedTs := atomic.LoadUint64(&sink.resolvedTs)
-				// `resolvedTs` should be monotonically increasing, it's allowed to receive redandunt one.
-				if ts < resolvedTs {
-					log.Panic("partition resolved ts fallback",
-						zap.Uint64("ts", ts),
-						zap.Uint64("resolvedTs", resolvedTs),
-						zap.Int32("partition", partition))
-				} else if ts > resolvedTs {
-					log.Debug("update sink resolved ts",
-						zap.Uint64("ts", ts),
-						zap.Int32("partition", partition))
-					atomic.StoreUint64(&sink.resolvedTs, ts)
-				} else {
-					log.Info("redundant sink resolved ts", zap.Uint64("ts", ts), zap.Int32("partition", partition))
-				}
-			}
-			session.MarkMessage(message, "")
-		}
-
-		if counter > kafkaMaxBatchSize {
-			log.Panic("Open Protocol max-batch-size exceeded", zap.Int("max-batch-size", kafkaMaxBatchSize),
-				zap.Int("actual-batch-size", counter))
-		}
-	}
-
-	return nil
-}
-
-func (c *Consumer) appendDDL(ddl *model.DDLEvent) {
-	c.ddlListMu.Lock()
-	defer c.ddlListMu.Unlock()
-	if ddl.CommitTs <= c.maxDDLReceivedTs {
-		return
-	}
-	globalResolvedTs := atomic.LoadUint64(&c.globalResolvedTs)
-	if ddl.CommitTs < globalResolvedTs {
-		log.Panic("unexpected ddl job", zap.Uint64("ddlts", ddl.CommitTs), zap.Uint64("globalResolvedTs", globalResolvedTs))
-	}
-	if ddl.CommitTs == globalResolvedTs {
-		log.Warn("receive redundant ddl job", zap.Uint64("ddlts", ddl.CommitTs), zap.Uint64("globalResolvedTs", globalResolvedTs))
-		return
-	}
-	c.ddlList = append(c.ddlList, ddl)
-	c.maxDDLReceivedTs = ddl.CommitTs
-}
-
-func (c *Consumer) getFrontDDL() *model.DDLEvent {
-	c.ddlListMu.Lock()
-	defer c.ddlListMu.Unlock()
-	if len(c.ddlList) > 0 {
-		return c.ddlList[0]
-	}
-	return nil
-}
-
-func (c *Consumer) popDDL() *model.DDLEvent {
-	c.ddlListMu.Lock()
-	defer c.ddlListMu.Unlock()
-	if len(c.ddlList) > 0 {
-		ddl := c.ddlList[0]
-		c.ddlList = c.ddlList[1:]
-		return ddl
-	}
-	return nil
-}
-
-func (c *Consumer) forEachSink(fn func(sink *partitionSink) error) error {
-	c.sinksMu.Lock()
-	defer c.sinksMu.Unlock()
-	for _, sink := range c.sinks {
-		if err := fn(sink); err != nil {
-			return errors.Trace(err)
-		}
-	}
-	return nil
-}
-
-// Run runs the Consumer
-func (c *Consumer) Run(ctx context.Context) error {
-	var lastGlobalResolvedTs uint64
-	ticker := time.NewTicker(100 * time.Millisecond)
-	defer ticker.Stop()
-	for {
-		select {
-		case <-ctx.Done():
-			return ctx.Err()
-		case <-ticker.C:
-		}
-		// initialize the `globalResolvedTs` as min of all partition's `ResolvedTs`
-		globalResolvedTs := uint64(math.MaxUint64)
-		err := c.forEachSink(func(sink *partitionSink) error {
-			resolvedTs := atomic.LoadUint64(&sink.resolvedTs)
-			if resolvedTs < globalResolvedTs {
-				globalResolvedTs = resolvedTs
-			}
-			return nil
-		})
-		if err != nil {
-			return errors.Trace(err)
-		}
-		// handle ddl
-		todoDDL := c.getFrontDDL()
-		if todoDDL != nil && globalResolvedTs >= todoDDL.CommitTs {
-			// flush DMLs
-			err := c.forEachSink(func(sink *partitionSink) error {
-				return syncFlushRowChangedEvents(ctx, sink, todoDDL.CommitTs)
-			})
-			if err != nil {
-				return errors.Trace(err)
-			}
-
-			// execute ddl
-			err = c.ddlSink.EmitDDLEvent(ctx, todoDDL)
-			if err != nil {
-				return errors.Trace(err)
-			}
-			c.popDDL()
-			continue
-		}
-
-		if todoDDL != nil && todoDDL.CommitTs < globalResolvedTs {
-			globalResolvedTs = todoDDL.CommitTs
-		}
-		if lastGlobalResolvedTs > globalResolvedTs {
-			log.Panic("global ResolvedTs fallback")
-		}
-
-		if globalResolvedTs > lastGlobalResolvedTs {
-			lastGlobalResolvedTs = globalResolvedTs
-			atomic.StoreUint64(&c.globalResolvedTs, globalResolvedTs)
-			log.Info("update globalResolvedTs", zap.Uint64("ts", globalResolvedTs))
-
-			err = c.forEachSink(func(sink *partitionSink) error {
-				return syncFlushRowChangedEvents(ctx, sink, globalResolvedTs)
-			})
-			if err != nil {
-				return errors.Trace(err)
-			}
-		}
-	}
-}
-
-func syncFlushRowChangedEvents(ctx context.Context, sink *partitionSink, resolvedTs uint64) error {
-	for {
-		select {
-		case <-ctx.Done():
-			return ctx.Err()
-		default:
-		}
-		// tables are flushed
-		var (
-			err          error
-			checkpointTs uint64
-		)
-		flushedResolvedTs := true
-		sink.tablesMap.Range(func(key, value interface{}) bool {
-			tableID := key.(int64)
-			checkpointTs, err = sink.FlushRowChangedEvents(ctx, tableID, resolvedTs)
-			if err != nil {
-				return false
-			}
-			if checkpointTs < resolvedTs {
-				flushedResolvedTs = false
-			}
-			return true
-		})
-		if err != nil {
-			return err
-		}
-		if flushedResolvedTs {
-			return nil
-		}
-	}
-}
-
-type fakeTableIDGenerator struct {
-	tableIDs       map[string]int64
-	currentTableID int64
-	mu             sync.Mutex
-}
-
-func (g *fakeTableIDGenerator) generateFakeTableID(schema, table string, partition int64) int64 {
-	g.mu.Lock()
-	defer g.mu.Unlock()
-	key := quotes.QuoteSchema(schema, table)
-	if partition != 0 {
-		key = fmt.Sprintf("%s.`%d`", key, partition)
-	}
-	if tableID, ok := g.tableIDs[key]; ok {
-		return tableID
+	if err = target.Close(context.Background()); err != nil {
+		log.Panic("Error closing sink", zap.Error(err))
 	}
-	g.currentTableID++
-	g.tableIDs[key] = g.currentTableID
-	return g.currentTableID
 }
@@ -255,6 +255,14 @@ var doc = `{
                         "schema": {
                             "$ref": "#/definitions/config.SinkConfig"
                         }
+                    },
+                    {
+                        "description": "rate limit",
+                        "name": "rate_limit",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/config.RateLimitConfig"
+                        }
                     }
                 ],
                 "responses": {
@@ -722,6 +730,12 @@ var doc = `{
         "config.DispatchRule": {
             "type": "object",
             "properties": {
+                "columns": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
                 "dispatcher": {
                     "type": "string"
                 },
@@ -730,6 +744,20 @@ var doc = `{
                     "items": {
                         "type": "string"
                     }
+                },
+                "topic": {
+                    "type": "string"
+                }
+            }
+        },
+        "config.RateLimitConfig": {
+            "type": "object",
+            "properties": {
+                "bytes-per-second": {
+                    "type": "integer"
+                },
+                "rows-per-second": {
+                    "type": "integer"
                 }
             }
         },
@@ -839,6 +867,10 @@ var doc = `{
                     "type": "integer",
                     "default": 16
                 },
+                "rate_limit": {
+                    "description": "RateLimit can be updated while the changefeed is running.",
+                    "$ref": "#/definitions/config.RateLimitConfig"
+                },
                 "sink_config": {
                     "$ref": "#/definitions/config.SinkConfig"
                 },
@@ -948,6 +980,10 @@ var doc = `{
                     "description": "Error code when error happens",
                     "$ref": "#/definitions/model.RunningError"
                 },
+                "memory_consumption": {
+                    "description": "The estimated memory consumption of the events buffered by the processor.",
+                    "type": "integer"
+                },
                 "resolved_ts": {
                     "description": "The event that satisfies CommitTs \u003c= ResolvedTs can be synchronized.",
                     "type": "integer"
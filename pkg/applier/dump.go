@@ -0,0 +1,297 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package applier
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/pingcap/errors"
+	filterV2 "github.com/pingcap/tidb-tools/pkg/table-filter"
+	"github.com/pingcap/tiflow/cdc/model"
+	"github.com/pingcap/tiflow/cdc/redo"
+	"github.com/pingcap/tiflow/cdc/redo/reader"
+	cerror "github.com/pingcap/tiflow/pkg/errors"
+)
+
+// The event types of the dumped events.
+const (
+	DumpEventInsert = "insert"
+	DumpEventUpdate = "update"
+	DumpEventDelete = "delete"
+	DumpEventDDL    = "ddl"
+)
+
+// RedoDumperConfig is the configuration used by a redo log dumper
+type RedoDumperConfig struct {
+	Storage           string
+	Dir               string
+	EncryptionKeyFile string
+	EncryptionKeyEnv  string
+	// StartTs and EndTs are the range (StartTs, EndTs] of the dumped events,
+	// the checkpoint ts and the resolved ts of the meta are used if they're 0.
+	StartTs uint64
+	EndTs   uint64
+	// Tables are the table filter rules, such as "test.*", all tables are
+	// dumped if it's empty.
+	Tables []string
+	// EventTypes are the types of the dumped events, all types are dumped if
+	// it's empty.
+	EventTypes []string
+}
+
+// RedoDumper dumps the events of redo logs as JSON lines
+type RedoDumper struct {
+	cfg *RedoDumperConfig
+
+	filter     filterV2.Filter
+	eventTypes map[string]struct{}
+}
+
+// NewRedoDumper creates a new RedoDumper instance
+func NewRedoDumper(cfg *RedoDumperConfig) (*RedoDumper, error) {
+	rules := cfg.Tables
+	if len(rules) == 0 {
+		rules = []string{"*.*"}
+	}
+	f, err := filterV2.Parse(rules)
+	if err != nil {
+		return nil, cerror.WrapError(cerror.ErrFilterRuleInvalid, err)
+	}
+	d := &RedoDumper{
+		cfg:        cfg,
+		filter:     filterV2.CaseInsensitive(f),
+		eventTypes: make(map[string]struct{}),
+	}
+	for _, tp := range cfg.EventTypes {
+		switch tp {
+		case DumpEventInsert, DumpEventUpdate, DumpEventDelete, DumpEventDDL:
+			d.eventTypes[tp] = struct{}{}
+		default:
+			return nil, cerror.WrapError(cerror.ErrRedoConfigInvalid,
+				fmt.Errorf("invalid event type %s, should be insert, update, delete or ddl", tp))
+		}
+	}
+	return d, nil
+}
+
+// dumpedEvent is a row or DDL event dumped as a JSON line.
+type dumpedEvent struct {
+	Type       string                 `json:"type"`
+	StartTs    uint64                 `json:"start-ts"`
+	CommitTs   uint64                 `json:"commit-ts"`
+	Schema     string                 `json:"schema"`
+	Table      string                 `json:"table,omitempty"`
+	TableID    int64                  `json:"table-id,omitempty"`
+	Query      string                 `json:"query,omitempty"`
+	PreColumns map[string]interface{} `json:"pre-columns,omitempty"`
+	Columns    map[string]interface{} `json:"columns,omitempty"`
+}
+
+func columnValues(cols []*model.Column) map[string]interface{} {
+	if len(cols) == 0 {
+		return nil
+	}
+	values := make(map[string]interface{}, len(cols))
+	for _, col := range cols {
+		if col == nil {
+			continue
+		}
+		if b, ok := col.Value.([]byte); ok {
+			values[col.Name] = string(b)
+			continue
+		}
+		values[col.Name] = col.Value
+	}
+	return values
+}
+
+func (d *RedoDumper) matchType(tp string) bool {
+	if len(d.eventTypes) == 0 {
+		return true
+	}
+	_, ok := d.eventTypes[tp]
+	return ok
+}
+
+// rowEvent returns the dumped event of the row, or nil if it's filtered out.
+func (d *RedoDumper) rowEvent(row *model.RowChangedEvent) *dumpedEvent {
+	tp := DumpEventUpdate
+	if row.IsInsert() {
+		tp = DumpEventInsert
+	} else if row.IsDelete() {
+		tp = DumpEventDelete
+	}
+	if !d.matchType(tp) || !d.filter.MatchTable(row.Table.Schema, row.Table.Table) {
+		return nil
+	}
+	return &dumpedEvent{
+		Type:       tp,
+		StartTs:    row.StartTs,
+		CommitTs:   row.CommitTs,
+		Schema:     row.Table.Schema,
+		Table:      row.Table.Table,
+		TableID:    row.Table.TableID,
+		PreColumns: columnValues(row.PreColumns),
+		Columns:    columnValues(row.Columns),
+	}
+}
+
+// ddlEvent returns the dumped event of the DDL, or nil if it's filtered out.
+// The schema DDLs are matched by the schema.
+func (d *RedoDumper) ddlEvent(ddl *model.DDLEvent) *dumpedEvent {
+	if !d.matchType(DumpEventDDL) {
+		return nil
+	}
+	event := &dumpedEvent{
+		Type:     DumpEventDDL,
+		StartTs:  ddl.StartTs,
+		CommitTs: ddl.CommitTs,
+		Query:    ddl.Query,
+	}
+	if ddl.TableInfo != nil {
+		event.Schema, event.Table, event.TableID = ddl.TableInfo.Schema, ddl.TableInfo.Table, ddl.TableInfo.TableID
+	}
+	if event.Table == "" {
+		if !d.filter.MatchSchema(event.Schema) {
+			return nil
+		}
+	} else if !d.filter.MatchTable(event.Schema, event.Table) {
+		return nil
+	}
+	return event
+}
+
+func (d *RedoDumper) applierConfig() *RedoApplierConfig {
+	return &RedoApplierConfig{
+		Storage:           d.cfg.Storage,
+		Dir:               d.cfg.Dir,
+		EncryptionKeyFile: d.cfg.EncryptionKeyFile,
+		EncryptionKeyEnv:  d.cfg.EncryptionKeyEnv,
+	}
+}
+
+// openReader creates the redo log reader, and sets it up with the ts range.
+// The returned range is empty if there are no events to read.
+func (d *RedoDumper) openReader(ctx context.Context) (rd reader.RedoLogReader, startTs, endTs uint64, err error) {
+	rd, err = createRedoReader(ctx, d.applierConfig())
+	if err != nil {
+		return nil, 0, 0, err
+	}
+	checkpointTs, resolvedTs, err := rd.ReadMeta(ctx)
+	if err != nil {
+		rd.Close() //nolint:errcheck
+		return nil, 0, 0, err
+	}
+	startTs, endTs = checkpointTs, resolvedTs
+	if d.cfg.StartTs != 0 {
+		startTs = d.cfg.StartTs
+	}
+	if d.cfg.EndTs != 0 {
+		endTs = d.cfg.EndTs
+	}
+	if startTs >= endTs {
+		return rd, startTs, endTs, nil
+	}
+	if err := rd.ResetReader(ctx, startTs, endTs); err != nil {
+		rd.Close() //nolint:errcheck
+		return nil, 0, 0, err
+	}
+	return rd, startTs, endTs, nil
+}
+
+// Dump writes the events of the redo logs to w in the order of commit ts,
+// one JSON object per line. The DDLs are written before the rows with the
+// same commit ts.
+func (d *RedoDumper) Dump(ctx context.Context, w io.Writer) error {
+	rd, startTs, endTs, err := d.openReader(ctx)
+	if err != nil {
+		return err
+	}
+	defer rd.Close() //nolint:errcheck
+	if startTs >= endTs {
+		return nil
+	}
+
+	encoder := json.NewEncoder(w)
+	write := func(event *dumpedEvent) error {
+		if event == nil {
+			return nil
+		}
+		return errors.Trace(encoder.Encode(event))
+	}
+
+	var ddls []*model.DDLEvent
+	for {
+		redoDDLs, err := rd.ReadNextDDL(ctx, readBatch)
+		if err != nil {
+			return err
+		}
+		if len(redoDDLs) == 0 {
+			break
+		}
+		for _, redoDDL := range redoDDLs {
+			ddls = append(ddls, redo.LogToDDL(redoDDL))
+		}
+	}
+	for {
+		redoLogs, err := rd.ReadNextLog(ctx, readBatch)
+		if err != nil {
+			return err
+		}
+		if len(redoLogs) == 0 {
+			break
+		}
+		for _, redoLog := range redoLogs {
+			row := redo.LogToRow(redoLog)
+			for len(ddls) > 0 && ddls[0].CommitTs <= row.CommitTs {
+				if err := write(d.ddlEvent(ddls[0])); err != nil {
+					return err
+				}
+				ddls = ddls[1:]
+			}
+			if err := write(d.rowEvent(row)); err != nil {
+				return err
+			}
+		}
+	}
+	for _, ddl := range ddls {
+		if err := write(d.ddlEvent(ddl)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Check reads all the redo log files, and reports the broken files and the
+// gaps between the meta and the files.
+func (d *RedoDumper) Check(ctx context.Context) (*reader.LogFilesReport, error) {
+	// the reader downloads the log files if they're in S3.
+	rd, _, _, err := d.openReader(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer rd.Close() //nolint:errcheck
+	checkpointTs, resolvedTs, err := rd.ReadMeta(ctx)
+	if err != nil {
+		return nil, err
+	}
+	_, readerCfg, err := d.applierConfig().toLogReaderConfig()
+	if err != nil {
+		return nil, err
+	}
+	return reader.CheckLogFiles(readerCfg.Dir, checkpointTs, resolvedTs, readerCfg.EncryptionKey)
+}
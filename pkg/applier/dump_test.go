@@ -0,0 +1,111 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package applier
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	timodel "github.com/pingcap/tidb/parser/model"
+	"github.com/pingcap/tiflow/cdc/model"
+	"github.com/pingcap/tiflow/cdc/redo"
+	"github.com/pingcap/tiflow/cdc/redo/reader"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewRedoDumper(t *testing.T) {
+	_, err := NewRedoDumper(&RedoDumperConfig{EventTypes: []string{"insert", "truncate"}})
+	require.Regexp(t, "CDC:ErrRedoConfigInvalid", err)
+	_, err = NewRedoDumper(&RedoDumperConfig{Tables: []string{"test.t1("}})
+	require.Regexp(t, "CDC:ErrFilterRuleInvalid", err)
+}
+
+func TestDump(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	newReader := func() (reader.RedoLogReader, error) {
+		redoLogCh := make(chan *model.RedoRowChangedEvent, 1024)
+		ddlEventCh := make(chan *model.RedoDDLEvent, 1024)
+		for _, row := range []*model.RowChangedEvent{
+			{
+				StartTs:  1100,
+				CommitTs: 1200,
+				Table:    &model.TableName{Schema: "test", Table: "t1", TableID: 1},
+				Columns:  []*model.Column{{Name: "a", Value: 1}, {Name: "b", Value: []byte("x")}},
+			},
+			{
+				StartTs:    1300,
+				CommitTs:   1400,
+				Table:      &model.TableName{Schema: "test", Table: "t2", TableID: 2},
+				PreColumns: []*model.Column{{Name: "a", Value: 1}},
+			},
+		} {
+			redoLogCh <- redo.RowToRedo(row)
+		}
+		ddlEventCh <- redo.DDLToRedo(&model.DDLEvent{
+			StartTs:   1250,
+			CommitTs:  1300,
+			Query:     "ALTER TABLE test.t1 ADD COLUMN c INT",
+			Type:      timodel.ActionAddColumn,
+			TableInfo: &model.SimpleTableInfo{Schema: "test", Table: "t1", TableID: 1},
+		})
+		close(redoLogCh)
+		close(ddlEventCh)
+		return NewMockReader(1000, 2000, redoLogCh, ddlEventCh), nil
+	}
+	createRedoReaderBak := createRedoReader
+	createRedoReader = func(ctx context.Context, cfg *RedoApplierConfig) (reader.RedoLogReader, error) {
+		return newReader()
+	}
+	defer func() {
+		createRedoReader = createRedoReaderBak
+	}()
+
+	for _, tc := range []struct {
+		cfg      *RedoDumperConfig
+		expected []string
+	}{
+		{
+			cfg: &RedoDumperConfig{},
+			expected: []string{
+				`{"type":"insert","start-ts":1100,"commit-ts":1200,"schema":"test","table":"t1","table-id":1,"columns":{"a":1,"b":"x"}}`,
+				`{"type":"ddl","start-ts":1250,"commit-ts":1300,"schema":"test","table":"t1","table-id":1,"query":"ALTER TABLE test.t1 ADD COLUMN c INT"}`,
+				`{"type":"delete","start-ts":1300,"commit-ts":1400,"schema":"test","table":"t2","table-id":2,"pre-columns":{"a":1}}`,
+			},
+		},
+		{
+			cfg: &RedoDumperConfig{Tables: []string{"test.t1"}, EventTypes: []string{"ddl", "delete"}},
+			expected: []string{
+				`{"type":"ddl","start-ts":1250,"commit-ts":1300,"schema":"test","table":"t1","table-id":1,"query":"ALTER TABLE test.t1 ADD COLUMN c INT"}`,
+			},
+		},
+		{
+			// the range is empty.
+			cfg: &RedoDumperConfig{StartTs: 2000},
+		},
+	} {
+		d, err := NewRedoDumper(tc.cfg)
+		require.Nil(t, err)
+		buf := &bytes.Buffer{}
+		require.Nil(t, d.Dump(ctx, buf))
+		var lines []string
+		if buf.Len() != 0 {
+			lines = strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+		}
+		require.Equal(t, tc.expected, lines)
+	}
+}
@@ -16,11 +16,13 @@ package applier
 import (
 	"context"
 	"net/url"
+	"time"
 
 	"github.com/pingcap/errors"
 	"github.com/pingcap/log"
 	"github.com/pingcap/tiflow/cdc/model"
 	"github.com/pingcap/tiflow/cdc/redo"
+	"github.com/pingcap/tiflow/cdc/redo/common"
 	"github.com/pingcap/tiflow/cdc/redo/reader"
 	"github.com/pingcap/tiflow/cdc/sink"
 	"github.com/pingcap/tiflow/pkg/config"
@@ -35,6 +37,7 @@ const (
 	applierChangefeed = "redo-applier"
 	emitBatch         = sink.DefaultMaxTxnRow
 	readBatch         = sink.DefaultWorkerCount * emitBatch
+	flushInterval     = 100 * time.Millisecond
 )
 
 var errApplyFinished = errors.New("apply finished, can exit safely")
@@ -44,6 +47,19 @@ type RedoApplierConfig struct {
 	SinkURI string
 	Storage string
 	Dir     string
+	// EncryptionKeyFile and EncryptionKeyEnv provide the key to decrypt the
+	// encrypted redo logs.
+	EncryptionKeyFile string
+	EncryptionKeyEnv  string
+	// EndTs is the ts the redo logs are applied to, it can't be larger than
+	// the resolved ts of the meta, which is used if it's 0.
+	EndTs uint64
+	// FilterRules are the table filter rules with the same syntax as the
+	// changefeed filter, only the events of the matched tables are applied.
+	// All the tables are applied if it's empty.
+	FilterRules []string
+	// Routes route the events to the target schemas and tables in the sink.
+	Routes []*config.RouteRule
 }
 
 // RedoApplier implements a redo log applier
@@ -68,13 +84,18 @@ func (rac *RedoApplierConfig) toLogReaderConfig() (string, *reader.LogReaderConf
 	if err != nil {
 		return "", nil, cerror.WrapError(cerror.ErrConsistentStorage, err)
 	}
+	key, err := common.LoadEncryptionKey(rac.EncryptionKeyFile, rac.EncryptionKeyEnv)
+	if err != nil {
+		return "", nil, err
+	}
 	cfg := &reader.LogReaderConfig{
-		Dir:       uri.Path,
-		S3Storage: redo.IsS3StorageEnabled(uri.Scheme),
+		Dir:                uri.Path,
+		UseExternalStorage: redo.IsExternalStorage(uri.Scheme),
+		EncryptionKey:      key,
 	}
-	if cfg.S3Storage {
-		cfg.S3URI = *uri
-		// If use s3 as backend, applier will download redo logs to local dir.
+	if cfg.UseExternalStorage {
+		cfg.ExternalStorageURI = *uri
+		// If use an external storage as backend, applier will download redo logs to local dir.
 		cfg.Dir = rac.Dir
 	}
 	return uri.Scheme, cfg, nil
@@ -96,24 +117,47 @@ func (ra *RedoApplier) consumeLogs(ctx context.Context) error {
 	if err != nil {
 		return err
 	}
-	err = ra.rd.ResetReader(ctx, checkpointTs, resolvedTs)
+	// The redo logs can be applied to any moment between the checkpoint ts
+	// and the resolved ts, the events before the checkpoint ts have been
+	// written to the downstream.
+	endTs := resolvedTs
+	if ra.cfg.EndTs != 0 {
+		if ra.cfg.EndTs < checkpointTs || ra.cfg.EndTs > resolvedTs {
+			return cerror.WrapError(cerror.ErrRedoConfigInvalid,
+				errors.Errorf("end ts %d should be between checkpoint ts %d and resolved ts %d",
+					ra.cfg.EndTs, checkpointTs, resolvedTs))
+		}
+		endTs = ra.cfg.EndTs
+	}
+	err = ra.rd.ResetReader(ctx, checkpointTs, endTs)
 	if err != nil {
 		return err
 	}
-	log.Info("apply redo log starts", zap.Uint64("checkpointTs", checkpointTs), zap.Uint64("resolvedTs", resolvedTs))
+	log.Info("apply redo log starts",
+		zap.Uint64("checkpointTs", checkpointTs),
+		zap.Uint64("resolvedTs", resolvedTs),
+		zap.Uint64("endTs", endTs),
+		zap.Strings("filterRules", ra.cfg.FilterRules))
 
-	// MySQL sink will use the following replication config
+	// The sink will use the following replication config
 	// - EnableOldValue: default true
 	// - ForceReplicate: default false
-	// - filter: default []string{"*.*"}
+	// - filter: the filter rules of the applier, default []string{"*.*"}
+	// - routes: the route rules of the applier
+	// Any sink supported by changefeeds can be used, such as MySQL, Kafka
+	// and blackhole, the protocol of MQ sinks is set by the sink URI.
 	replicaConfig := config.GetDefaultReplicaConfig()
+	if len(ra.cfg.FilterRules) != 0 {
+		replicaConfig.Filter.Rules = ra.cfg.FilterRules
+	}
+	replicaConfig.Sink.Routes = ra.cfg.Routes
 	ft, err := filter.NewFilter(replicaConfig)
 	if err != nil {
 		return err
 	}
-	opts := map[string]string{}
+	opts := map[string]string{sink.OptChangefeedID: applierChangefeed}
 	ctx = util.PutRoleInCtx(ctx, util.RoleRedoLogApplier)
-	s, err := sink.New(ctx, applierChangefeed, ra.cfg.SinkURI, ft, replicaConfig, opts, ra.errCh)
+	s, err := createSink(ctx, applierChangefeed, ra.cfg.SinkURI, ft, replicaConfig, opts, ra.errCh)
 	if err != nil {
 		return err
 	}
@@ -123,15 +167,15 @@ func (ra *RedoApplier) consumeLogs(ctx context.Context) error {
 	}()
 
 	// TODO: split events for large transaction
-	// We use lastSafeResolvedTs and lastResolvedTs to ensure the events in one
-	// transaction are flushed in a single batch.
-	// lastSafeResolvedTs records the max resolved ts of a closed transaction.
-	// Closed transaction means all events of this transaction have been received.
-	lastSafeResolvedTs := checkpointTs - 1
+	// The redo logs are read in the order of commit ts, so all events before
+	// lastResolvedTs have been read once a batch is read, and the events of
+	// lastResolvedTs may be continued in the next batch.
 	// lastResolvedTs records the max resolved ts we have seen from redo logs.
 	lastResolvedTs := checkpointTs
+	// lastCheckpointTs records the last checkpoint ts emitted to the sink.
+	lastCheckpointTs := checkpointTs
 	cachedRows := make([]*model.RowChangedEvent, 0, emitBatch)
-	tableResolvedTsMap := make(map[model.TableID]model.Ts)
+	tables := make(map[model.TableID]model.TableName)
 	for {
 		redoLogs, err := ra.rd.ReadNextLog(ctx, readBatch)
 		if err != nil {
@@ -142,10 +186,6 @@ func (ra *RedoApplier) consumeLogs(ctx context.Context) error {
 		}
 
 		for _, redoLog := range redoLogs {
-			tableID := redoLog.Row.Table.TableID
-			if _, ok := tableResolvedTsMap[redoLog.Row.Table.TableID]; !ok {
-				tableResolvedTsMap[tableID] = lastSafeResolvedTs
-			}
 			if len(cachedRows) >= emitBatch {
 				err := s.EmitRowChangedEvents(ctx, cachedRows...)
 				if err != nil {
@@ -153,30 +193,50 @@ func (ra *RedoApplier) consumeLogs(ctx context.Context) error {
 				}
 				cachedRows = make([]*model.RowChangedEvent, 0, emitBatch)
 			}
-			cachedRows = append(cachedRows, redo.LogToRow(redoLog))
-
-			if redoLog.Row.CommitTs > tableResolvedTsMap[tableID] {
-				tableResolvedTsMap[tableID], lastResolvedTs = lastResolvedTs, redoLog.Row.CommitTs
+			row := redo.LogToRow(redoLog)
+			if row.CommitTs > lastResolvedTs {
+				lastResolvedTs = row.CommitTs
 			}
+			// the tables filtered out are never flushed, so they're left
+			// untouched in the downstream.
+			if ft.ShouldIgnoreDMLEvent(row.StartTs, row.Table.Schema, row.Table.Table) {
+				continue
+			}
+			tables[row.Table.TableID] = *row.Table
+			cachedRows = append(cachedRows, row)
 		}
 
-		for tableID, tableLastResolvedTs := range tableResolvedTsMap {
-			_, err = s.FlushRowChangedEvents(ctx, tableID, tableLastResolvedTs)
-			if err != nil {
-				return err
-			}
+		// The cached rows must be emitted before flushing, otherwise the sinks
+		// which write asynchronously, such as MQ sinks, may receive them after
+		// the resolved ts which covers them.
+		err = s.EmitRowChangedEvents(ctx, cachedRows...)
+		if err != nil {
+			return err
+		}
+		cachedRows = make([]*model.RowChangedEvent, 0, emitBatch)
+		lastCheckpointTs, err = flushTables(ctx, s, tables, lastResolvedTs-1, lastCheckpointTs)
+		if err != nil {
+			return err
 		}
-	}
-	err = s.EmitRowChangedEvents(ctx, cachedRows...)
-	if err != nil {
-		return err
 	}
 
-	for tableID := range tableResolvedTsMap {
-		_, err = s.FlushRowChangedEvents(ctx, tableID, resolvedTs)
+	// The sinks may flush the events asynchronously, so wait until all the
+	// events are flushed.
+	for {
+		lastCheckpointTs, err = flushTables(ctx, s, tables, endTs, lastCheckpointTs)
 		if err != nil {
 			return err
 		}
+		if lastCheckpointTs >= endTs {
+			break
+		}
+		select {
+		case <-ctx.Done():
+			return errors.Trace(ctx.Err())
+		case <-time.After(flushInterval):
+		}
+	}
+	for tableID := range tables {
 		err = s.Barrier(ctx, tableID)
 		if err != nil {
 			return err
@@ -185,6 +245,38 @@ func (ra *RedoApplier) consumeLogs(ctx context.Context) error {
 	return errApplyFinished
 }
 
+// flushTables flushes the events of the tables before resolvedTs, and emits
+// the checkpoint ts to the sink if all the tables have been flushed beyond
+// lastCheckpointTs. It returns the last emitted checkpoint ts.
+func flushTables(
+	ctx context.Context, s sink.Sink, tables map[model.TableID]model.TableName,
+	resolvedTs, lastCheckpointTs model.Ts,
+) (model.Ts, error) {
+	checkpointTs := resolvedTs
+	for tableID := range tables {
+		ts, err := s.FlushRowChangedEvents(ctx, tableID, resolvedTs)
+		if err != nil {
+			return 0, err
+		}
+		if ts < checkpointTs {
+			checkpointTs = ts
+		}
+	}
+	if checkpointTs <= lastCheckpointTs {
+		return lastCheckpointTs, nil
+	}
+	tableNames := make([]model.TableName, 0, len(tables))
+	for _, table := range tables {
+		tableNames = append(tableNames, table)
+	}
+	if err := s.EmitCheckpointTs(ctx, checkpointTs, tableNames); err != nil {
+		return 0, err
+	}
+	return checkpointTs, nil
+}
+
+var createSink = sink.New
+
 var createRedoReader = createRedoReaderImpl
 
 func createRedoReaderImpl(ctx context.Context, cfg *RedoApplierConfig) (reader.RedoLogReader, error) {
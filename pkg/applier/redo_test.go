@@ -25,6 +25,8 @@ import (
 	"github.com/pingcap/tiflow/cdc/redo"
 	"github.com/pingcap/tiflow/cdc/redo/reader"
 	"github.com/pingcap/tiflow/cdc/sink"
+	"github.com/pingcap/tiflow/pkg/config"
+	"github.com/pingcap/tiflow/pkg/filter"
 	"github.com/stretchr/testify/require"
 )
 
@@ -34,6 +36,9 @@ type MockReader struct {
 	resolvedTs   uint64
 	redoLogCh    chan *model.RedoRowChangedEvent
 	ddlEventCh   chan *model.RedoDDLEvent
+
+	// endTs is set by ResetReader, the events beyond it are skipped.
+	endTs uint64
 }
 
 // NewMockReader creates a new MockReader
@@ -53,6 +58,7 @@ func NewMockReader(
 
 // ResetReader implements LogReader.ReadLog
 func (br *MockReader) ResetReader(ctx context.Context, startTs, endTs uint64) error {
+	br.endTs = endTs
 	return nil
 }
 
@@ -67,6 +73,9 @@ func (br *MockReader) ReadNextLog(ctx context.Context, maxNumberOfMessages uint6
 			if !ok {
 				return cached, nil
 			}
+			if br.endTs != 0 && redoLog.Row.CommitTs > br.endTs {
+				continue
+			}
 			cached = append(cached, redoLog)
 			if len(cached) >= int(maxNumberOfMessages) {
 				return cached, nil
@@ -241,3 +250,171 @@ func TestApplyMeetSinkError(t *testing.T) {
 	err = ap.Apply(ctx)
 	require.Regexp(t, "CDC:ErrMySQLConnectionError", err)
 }
+
+// mockSink is a sink which flushes the events asynchronously, it records the
+// events and the checkpoints.
+type mockSink struct {
+	sink.Sink
+	rows []*model.RowChangedEvent
+	// flushed are the resolved ts of the tables flushed by the last call.
+	flushed     map[model.TableID]model.Ts
+	resolved    map[model.TableID]model.Ts
+	checkpoints []model.Ts
+}
+
+func (s *mockSink) EmitRowChangedEvents(ctx context.Context, rows ...*model.RowChangedEvent) error {
+	for _, row := range rows {
+		// the rows must be emitted before the resolved ts which covers them.
+		if row.CommitTs <= s.resolved[row.Table.TableID] {
+			return fmt.Errorf("row %d is emitted after resolved ts %d", row.CommitTs, s.resolved[row.Table.TableID])
+		}
+	}
+	s.rows = append(s.rows, rows...)
+	return nil
+}
+
+func (s *mockSink) FlushRowChangedEvents(ctx context.Context, tableID model.TableID, resolvedTs uint64) (uint64, error) {
+	flushed := s.flushed[tableID]
+	s.flushed[tableID] = s.resolved[tableID]
+	s.resolved[tableID] = resolvedTs
+	return flushed, nil
+}
+
+func (s *mockSink) EmitCheckpointTs(ctx context.Context, ts uint64, tables []model.TableName) error {
+	s.checkpoints = append(s.checkpoints, ts)
+	return nil
+}
+
+func (s *mockSink) Barrier(ctx context.Context, tableID model.TableID) error {
+	return nil
+}
+
+func (s *mockSink) Close(ctx context.Context) error {
+	return nil
+}
+
+func TestApplyDMLsToAsyncSink(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	checkpointTs := uint64(1000)
+	// the resolved ts must cover all the rows, otherwise they're skipped.
+	resolvedTs := checkpointTs + 2*readBatch
+	redoLogCh := make(chan *model.RedoRowChangedEvent, 3*readBatch)
+	ddlEventCh := make(chan *model.RedoDDLEvent, 1024)
+	createMockReader := func(ctx context.Context, cfg *RedoApplierConfig) (reader.RedoLogReader, error) {
+		return NewMockReader(checkpointTs, resolvedTs, redoLogCh, ddlEventCh), nil
+	}
+	ms := &mockSink{
+		flushed:  make(map[model.TableID]model.Ts),
+		resolved: make(map[model.TableID]model.Ts),
+	}
+	createMockSink := func(
+		ctx context.Context, changefeedID model.ChangeFeedID, sinkURIStr string,
+		_ *filter.Filter, _ *config.ReplicaConfig, _ map[string]string, _ chan error,
+	) (sink.Sink, error) {
+		require.Equal(t, "kafka://127.0.0.1:9092/test?protocol=canal-json", sinkURIStr)
+		return ms, nil
+	}
+
+	createRedoReaderBak := createRedoReader
+	createRedoReader = createMockReader
+	createSinkBak := createSink
+	createSink = createMockSink
+	defer func() {
+		createRedoReader = createRedoReaderBak
+		createSink = createSinkBak
+	}()
+
+	for i := 0; i < 3*readBatch; i++ {
+		redoLogCh <- redo.RowToRedo(&model.RowChangedEvent{
+			StartTs:  checkpointTs + uint64(i),
+			CommitTs: checkpointTs + 1 + uint64(i/2),
+			Table:    &model.TableName{Schema: "test", Table: "t1", TableID: int64(i%2 + 1)},
+			Columns:  []*model.Column{{Name: "a", Value: i, Flag: model.HandleKeyFlag}},
+		})
+	}
+	close(redoLogCh)
+	close(ddlEventCh)
+
+	cfg := &RedoApplierConfig{SinkURI: "kafka://127.0.0.1:9092/test?protocol=canal-json"}
+	ap := NewRedoApplier(cfg)
+	err := ap.Apply(ctx)
+	require.Nil(t, err)
+	require.Len(t, ms.rows, 3*readBatch)
+	require.NotEmpty(t, ms.checkpoints)
+	for i := 1; i < len(ms.checkpoints); i++ {
+		require.Greater(t, ms.checkpoints[i], ms.checkpoints[i-1])
+	}
+	require.Equal(t, resolvedTs, ms.checkpoints[len(ms.checkpoints)-1])
+}
+
+func TestApplyPartialDMLs(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	checkpointTs := uint64(1000)
+	resolvedTs := uint64(2000)
+	redoLogCh := make(chan *model.RedoRowChangedEvent, 1024)
+	ddlEventCh := make(chan *model.RedoDDLEvent, 1024)
+	createMockReader := func(ctx context.Context, cfg *RedoApplierConfig) (reader.RedoLogReader, error) {
+		return NewMockReader(checkpointTs, resolvedTs, redoLogCh, ddlEventCh), nil
+	}
+	ms := &mockSink{
+		flushed:  make(map[model.TableID]model.Ts),
+		resolved: make(map[model.TableID]model.Ts),
+	}
+	routes := []*config.RouteRule{{Matcher: []string{"test.t1"}, TargetSchema: "test_bak"}}
+	createMockSink := func(
+		ctx context.Context, changefeedID model.ChangeFeedID, sinkURIStr string,
+		_ *filter.Filter, replicaConfig *config.ReplicaConfig, _ map[string]string, _ chan error,
+	) (sink.Sink, error) {
+		require.Equal(t, []string{"test.t1"}, replicaConfig.Filter.Rules)
+		require.Equal(t, routes, replicaConfig.Sink.Routes)
+		return ms, nil
+	}
+
+	createRedoReaderBak := createRedoReader
+	createRedoReader = createMockReader
+	createSinkBak := createSink
+	createSink = createMockSink
+	defer func() {
+		createRedoReader = createRedoReaderBak
+		createSink = createSinkBak
+	}()
+
+	for i := 0; i < 10; i++ {
+		table := "t1"
+		if i%2 == 1 {
+			table = "t2"
+		}
+		redoLogCh <- redo.RowToRedo(&model.RowChangedEvent{
+			StartTs:  checkpointTs + uint64(i),
+			CommitTs: checkpointTs + 100*uint64(i+1),
+			Table:    &model.TableName{Schema: "test", Table: table, TableID: int64(i%2 + 1)},
+			Columns:  []*model.Column{{Name: "a", Value: i, Flag: model.HandleKeyFlag}},
+		})
+	}
+	close(redoLogCh)
+	close(ddlEventCh)
+
+	cfg := &RedoApplierConfig{
+		SinkURI:     "blackhole://",
+		EndTs:       resolvedTs + 1,
+		FilterRules: []string{"test.t1"},
+		Routes:      routes,
+	}
+	err := NewRedoApplier(cfg).Apply(ctx)
+	require.Regexp(t, "end ts 2001 should be between checkpoint ts 1000 and resolved ts 2000", err)
+
+	// only the rows of test.t1 with commit ts no larger than 1500 are applied.
+	cfg.EndTs = 1500
+	err = NewRedoApplier(cfg).Apply(ctx)
+	require.Nil(t, err)
+	require.Len(t, ms.rows, 3)
+	for _, row := range ms.rows {
+		require.Equal(t, "t1", row.Table.Table)
+		require.LessOrEqual(t, row.CommitTs, cfg.EndTs)
+	}
+	require.Equal(t, cfg.EndTs, ms.checkpoints[len(ms.checkpoints)-1])
+}
@@ -24,6 +24,7 @@ import (
 	"github.com/pingcap/errors"
 	"github.com/pingcap/log"
 	"github.com/pingcap/tiflow/cdc/model"
+	"github.com/pingcap/tiflow/cdc/redo"
 	"github.com/pingcap/tiflow/cdc/sink"
 	cmdcontext "github.com/pingcap/tiflow/pkg/cmd/context"
 	"github.com/pingcap/tiflow/pkg/cmd/factory"
@@ -284,6 +285,12 @@ func (o *createChangefeedOptions) validate(ctx context.Context, cmd *cobra.Comma
 		return err
 	}
 
+	// the storage of redo logs is validated here, otherwise the changefeed
+	// fails after it's created.
+	if err := redo.ValidateConsistentConfig(o.cfg.Consistent); err != nil {
+		return err
+	}
+
 	if err := o.validateStartTs(ctx); err != nil {
 		return err
 	}
@@ -15,6 +15,7 @@ package cli
 
 import (
 	"fmt"
+	"reflect"
 	"strings"
 
 	"github.com/pingcap/tiflow/pkg/etcd"
@@ -22,6 +23,7 @@ import (
 	"github.com/pingcap/errors"
 	"github.com/pingcap/log"
 	"github.com/pingcap/tiflow/cdc/model"
+	"github.com/pingcap/tiflow/cdc/redo"
 	cmdcontext "github.com/pingcap/tiflow/pkg/cmd/context"
 	"github.com/pingcap/tiflow/pkg/cmd/factory"
 	cerror "github.com/pingcap/tiflow/pkg/errors"
@@ -86,9 +88,7 @@ func (o *updateChangefeedOptions) run(cmd *cobra.Command) error {
 	}
 	// Note that the correctness of the logic here depends on the return value of `/capture/owner/changefeed/query` interface.
 	// TODO: Using error codes instead of string containing judgments
-	if err == nil && !strings.Contains(resp, `"state": "stopped"`) {
-		return errors.Errorf("can only update changefeed config when it is stopped\nstatus: %s", resp)
-	}
+	running := err == nil && !strings.Contains(resp, `"state": "stopped"`)
 
 	old, err := o.etcdClient.GetChangeFeedInfo(ctx, o.changefeedID)
 	if err != nil {
@@ -99,6 +99,13 @@ func (o *updateChangefeedOptions) run(cmd *cobra.Command) error {
 	if err != nil {
 		return err
 	}
+	// the storage of redo logs is validated here, otherwise the changefeed
+	// fails after it's resumed.
+	if !reflect.DeepEqual(old.Config.Consistent, newInfo.Config.Consistent) {
+		if err := redo.ValidateConsistentConfig(newInfo.Config.Consistent); err != nil {
+			return err
+		}
+	}
 
 	changelog, err := diff.Diff(old, newInfo)
 	if err != nil {
@@ -108,6 +115,11 @@ func (o *updateChangefeedOptions) run(cmd *cobra.Command) error {
 		cmd.Printf("changefeed config is the same with the old one, do nothing\n")
 		return nil
 	}
+	// The rate limit is applied by the processors at runtime.
+	if running && !isRateLimitOnlyChange(changelog) {
+		return errors.Errorf("can only update changefeed config when it is stopped, "+
+			"except the rate limit\nstatus: %s", resp)
+	}
 	cmd.Printf("Diff of changefeed config:\n")
 	for _, change := range changelog {
 		cmd.Printf("%+v\n", change)
@@ -212,6 +224,17 @@ func (o *updateChangefeedOptions) applyChanges(oldInfo *model.ChangeFeedInfo, cm
 	return newInfo, nil
 }
 
+// isRateLimitOnlyChange returns whether the changes only update the rate
+// limit, which can be updated while the changefeed is running.
+func isRateLimitOnlyChange(changelog diff.Changelog) bool {
+	for _, change := range changelog {
+		if len(change.Path) < 2 || change.Path[0] != "Config" || change.Path[1] != "RateLimit" {
+			return false
+		}
+	}
+	return true
+}
+
 // newCmdPauseChangefeed creates the `cli changefeed update` command.
 func newCmdUpdateChangefeed(f factory.Factory) *cobra.Command {
 	commonChangefeedOptions := newChangefeedCommonOptions()
@@ -21,7 +21,9 @@ import (
 	"github.com/pingcap/check"
 	"github.com/pingcap/log"
 	"github.com/pingcap/tiflow/cdc/model"
+	"github.com/pingcap/tiflow/pkg/config"
 	"github.com/pingcap/tiflow/pkg/util/testleak"
+	"github.com/r3labs/diff"
 )
 
 type changefeedUpdateSuite struct{}
@@ -71,6 +73,24 @@ func (s *changefeedUpdateSuite) TestApplyChanges(c *check.C) {
 	c.Assert(strings.Contains(string(file), "this flag cannot be updated and will be ignored"), check.IsTrue)
 }
 
+func (s *changefeedUpdateSuite) TestIsRateLimitOnlyChange(c *check.C) {
+	defer testleak.AfterTest(c)()
+
+	oldInfo := &model.ChangeFeedInfo{SinkURI: "blackhole://", Config: config.GetDefaultReplicaConfig()}
+	newInfo, err := oldInfo.Clone()
+	c.Assert(err, check.IsNil)
+	newInfo.Config.RateLimit = &config.RateLimitConfig{RowsPerSecond: 1000}
+	changelog, err := diff.Diff(oldInfo, newInfo)
+	c.Assert(err, check.IsNil)
+	c.Assert(changelog, check.Not(check.HasLen), 0)
+	c.Assert(isRateLimitOnlyChange(changelog), check.IsTrue)
+
+	newInfo.SinkURI = "mysql://root@downstream-tidb:4000"
+	changelog, err = diff.Diff(oldInfo, newInfo)
+	c.Assert(err, check.IsNil)
+	c.Assert(isRateLimitOnlyChange(changelog), check.IsFalse)
+}
+
 func initTestLogger(filename string) (func(), error) {
 	logConfig := &log.Config{
 		File: log.FileLogConfig{
@@ -16,13 +16,18 @@ package redo
 import (
 	"github.com/pingcap/tiflow/pkg/applier"
 	cmdcontext "github.com/pingcap/tiflow/pkg/cmd/context"
+	"github.com/pingcap/tiflow/pkg/cmd/util"
+	"github.com/pingcap/tiflow/pkg/config"
 	"github.com/spf13/cobra"
 )
 
 // applyRedoOptions defines flags for the `redo apply` command.
 type applyRedoOptions struct {
 	options
-	sinkURI string
+	sinkURI     string
+	endTs       uint64
+	filterRules []string
+	configFile  string
 }
 
 // newapplyRedoOptions creates new applyRedoOptions for the `redo apply` command.
@@ -33,7 +38,10 @@ func newapplyRedoOptions() *applyRedoOptions {
 // addFlags receives a *cobra.Command reference and binds
 // flags related to template printing to it.
 func (o *applyRedoOptions) addFlags(cmd *cobra.Command) {
-	cmd.Flags().StringVar(&o.sinkURI, "sink-uri", "", "target database sink-uri")
+	cmd.Flags().StringVar(&o.sinkURI, "sink-uri", "", "target sink-uri, any sink supported by changefeeds such as mysql, kafka and blackhole")
+	cmd.Flags().Uint64Var(&o.endTs, "end-ts", 0, "apply the events with commit ts no larger than it, it can't be larger than the resolved ts of the meta, which is used by default")
+	cmd.Flags().StringSliceVar(&o.filterRules, "filter", nil, "table filter rules of the applied events, eg, \"test.t1\", the rules in the config file or all tables by default")
+	cmd.Flags().StringVar(&o.configFile, "config", "", "path of the changefeed configuration file, the filter rules and the sink routes in it are used")
 	// the possible error returned from MarkFlagRequired is `no such flag`
 	cmd.MarkFlagRequired("sink-uri") //nolint:errcheck
 }
@@ -43,9 +51,23 @@ func (o *applyRedoOptions) run(cmd *cobra.Command) error {
 	ctx := cmdcontext.GetDefaultContext()
 
 	cfg := &applier.RedoApplierConfig{
-		Storage: o.storage,
-		SinkURI: o.sinkURI,
-		Dir:     o.dir,
+		Storage:           o.storage,
+		SinkURI:           o.sinkURI,
+		Dir:               o.dir,
+		EncryptionKeyFile: o.encryptionKeyFile,
+		EncryptionKeyEnv:  o.encryptionKeyEnv,
+		EndTs:             o.endTs,
+		FilterRules:       o.filterRules,
+	}
+	if o.configFile != "" {
+		replicaConfig := config.GetDefaultReplicaConfig()
+		if err := util.StrictDecodeFile(o.configFile, "TiCDC changefeed", replicaConfig); err != nil {
+			return err
+		}
+		if len(cfg.FilterRules) == 0 {
+			cfg.FilterRules = replicaConfig.Filter.Rules
+		}
+		cfg.Routes = replicaConfig.Sink.Routes
 	}
 	ap := applier.NewRedoApplier(cfg)
 	err := ap.Apply(ctx)
@@ -0,0 +1,98 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package redo
+
+import (
+	"github.com/pingcap/tiflow/pkg/applier"
+	cmdcontext "github.com/pingcap/tiflow/pkg/cmd/context"
+	"github.com/pingcap/tiflow/pkg/cmd/util"
+	cerror "github.com/pingcap/tiflow/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+// dumpRedoOptions defines flags for the `redo dump` command.
+type dumpRedoOptions struct {
+	options
+	startTs    uint64
+	endTs      uint64
+	tables     []string
+	eventTypes []string
+	check      bool
+}
+
+// newDumpRedoOptions creates new dumpRedoOptions for the `redo dump` command.
+func newDumpRedoOptions() *dumpRedoOptions {
+	return &dumpRedoOptions{}
+}
+
+// addFlags receives a *cobra.Command reference and binds
+// flags related to template printing to it.
+func (o *dumpRedoOptions) addFlags(cmd *cobra.Command) {
+	cmd.Flags().Uint64Var(&o.startTs, "start-ts", 0, "dump the events with commit ts larger than it, the checkpoint ts of the meta by default")
+	cmd.Flags().Uint64Var(&o.endTs, "end-ts", 0, "dump the events with commit ts no larger than it, the resolved ts of the meta by default")
+	cmd.Flags().StringSliceVar(&o.tables, "table", nil, "table filter rules of the dumped events, eg, \"test.*\", all tables by default")
+	cmd.Flags().StringSliceVar(&o.eventTypes, "event-type", nil, "types of the dumped events (insert|update|delete|ddl), all types by default")
+	cmd.Flags().BoolVar(&o.check, "check", false, "check the integrity of the redo log files and the gaps between the meta and the files instead of dumping the events")
+}
+
+// run runs the `redo dump` command.
+func (o *dumpRedoOptions) run(cmd *cobra.Command) error {
+	ctx := cmdcontext.GetDefaultContext()
+
+	cfg := &applier.RedoDumperConfig{
+		Storage:           o.storage,
+		Dir:               o.dir,
+		EncryptionKeyFile: o.encryptionKeyFile,
+		EncryptionKeyEnv:  o.encryptionKeyEnv,
+		StartTs:           o.startTs,
+		EndTs:             o.endTs,
+		Tables:            o.tables,
+		EventTypes:        o.eventTypes,
+	}
+	d, err := applier.NewRedoDumper(cfg)
+	if err != nil {
+		return err
+	}
+	if !o.check {
+		return d.Dump(ctx, cmd.OutOrStdout())
+	}
+
+	report, err := d.Check(ctx)
+	if err != nil {
+		return err
+	}
+	if err := util.JSONPrint(cmd, report); err != nil {
+		return err
+	}
+	if len(report.Issues) != 0 {
+		return cerror.ErrRedoLogFilesBroken.GenWithStackByArgs(len(report.Issues))
+	}
+	return nil
+}
+
+// newCmdDump creates the `redo dump` command.
+func newCmdDump(opt *options) *cobra.Command {
+	o := newDumpRedoOptions()
+	command := &cobra.Command{
+		Use:   "dump",
+		Short: "Dump the events of redo logs as JSON lines",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			o.options = *opt
+			return o.run(cmd)
+		},
+	}
+	o.addFlags(command)
+
+	return command
+}
@@ -24,6 +24,9 @@ type options struct {
 	storage  string
 	dir      string
 	logLevel string
+
+	encryptionKeyFile string
+	encryptionKeyEnv  string
 }
 
 // newOptions creates new options for the `server` command.
@@ -34,9 +37,11 @@ func newOptions() *options {
 // addFlags receives a *cobra.Command reference and binds
 // flags related to template printing to it.
 func (o *options) addFlags(cmd *cobra.Command) {
-	cmd.PersistentFlags().StringVar(&o.storage, "storage", "", "storage of redo log, specify the url where backup redo logs will store, eg, \"s3://bucket/path/prefix\"")
-	cmd.PersistentFlags().StringVar(&o.dir, "tmp-dir", "", "temporary path used to download redo log with S3 backend")
+	cmd.PersistentFlags().StringVar(&o.storage, "storage", "", "storage of redo log, specify the url where backup redo logs will store, eg, \"s3://bucket/path/prefix\", \"gcs://bucket/path/prefix\" or \"local:///path\"")
+	cmd.PersistentFlags().StringVar(&o.dir, "tmp-dir", "", "temporary path used to download redo log with external storage backends, such as s3")
 	cmd.PersistentFlags().StringVar(&o.logLevel, "log-level", "info", "log level (etc: debug|info|warn|error)")
+	cmd.PersistentFlags().StringVar(&o.encryptionKeyFile, "encryption-key-file", "", "file of the hex-encoded key used to decrypt the encrypted redo logs")
+	cmd.PersistentFlags().StringVar(&o.encryptionKeyEnv, "encryption-key-env", "", "environment variable of the hex-encoded key used to decrypt the encrypted redo logs")
 	// the possible error returned from MarkFlagRequired is `no such flag`
 	cmd.MarkFlagRequired("storage") //nolint:errcheck
 }
@@ -63,6 +68,7 @@ func NewCmdRedo() *cobra.Command {
 	// Add subcommands.
 	cmds.AddCommand(newCmdApply(o))
 	cmds.AddCommand(newCmdMeta(o))
+	cmds.AddCommand(newCmdDump(o))
 
 	return cmds
 }
@@ -21,6 +21,7 @@ import (
 	"testing"
 
 	"github.com/pingcap/check"
+	bf "github.com/pingcap/tidb-tools/pkg/binlog-filter"
 	"github.com/pingcap/tiflow/pkg/config"
 	"github.com/pingcap/tiflow/pkg/util/testleak"
 	"github.com/spf13/cobra"
@@ -177,22 +178,50 @@ func (s *utilsSuite) TestAndWriteExampleReplicaTOML(c *check.C) {
 	c.Assert(err, check.IsNil)
 
 	c.Assert(cfg.CaseSensitive, check.IsTrue)
+	c.Assert(cfg.MemoryQuota, check.Equals, uint64(1073741824))
 	c.Assert(cfg.Filter, check.DeepEquals, &config.FilterConfig{
 		IgnoreTxnStartTs: []uint64{1, 2},
 		Rules:            []string{"*.*", "!test.*"},
+		EventFilters: []*config.EventFilterRule{{
+			Matcher:                  []string{"test1.tenant"},
+			IgnoreInsertValueExpr:    "status != 'active'",
+			IgnoreUpdateNewValueExpr: "status != 'active'",
+		}, {
+			Matcher:     []string{"test2.*"},
+			IgnoreEvent: []bf.EventType{bf.DeleteEvent, bf.DropTable},
+			IgnoreSQL:   []string{"^ALTER TABLE .* ADD INDEX"},
+		}},
+	})
+	c.Assert(cfg.Masking, check.DeepEquals, &config.MaskingConfig{
+		Rules: []*config.MaskingRule{{
+			Matcher:   []string{"test1.user"},
+			Columns:   []string{"email", "phone"},
+			Transform: config.MaskingHash,
+			Salt:      "salt",
+		}, {
+			Matcher:   []string{"test1.*"},
+			Columns:   []string{"address"},
+			Transform: config.MaskingTruncate,
+			Length:    8,
+		}},
 	})
 	c.Assert(cfg.Mounter, check.DeepEquals, &config.MounterConfig{
 		WorkerNum: 16,
 	})
+	c.Assert(cfg.RateLimit, check.DeepEquals, &config.RateLimitConfig{})
 	c.Assert(cfg.Sink, check.DeepEquals, &config.SinkConfig{
 		DispatchRules: []*config.DispatchRule{
 			{Dispatcher: "ts", Matcher: []string{"test1.*", "test2.*"}},
-			{Dispatcher: "rowid", Matcher: []string{"test3.*", "test4.*"}},
+			{Dispatcher: "rowid", Matcher: []string{"test3.*", "test4.*"}, TopicRule: "{schema}_{table}"},
 		},
 		ColumnSelectors: []*config.ColumnSelector{
 			{Matcher: []string{"test1.*", "test2.*"}, Columns: []string{"column1", "column2"}},
 			{Matcher: []string{"test3.*", "test4.*"}, Columns: []string{"!a", "column3"}},
 		},
+		Routes: []*config.RouteRule{
+			{Matcher: []string{"test5.*"}, TargetSchema: "c1_{schema}"},
+			{Matcher: []string{"test6.t"}, TargetSchema: "test6", TargetTable: "t_{table}"},
+		},
 		Protocol: "open-protocol",
 	})
 	c.Assert(cfg.Cyclic, check.DeepEquals, &config.CyclicConfig{
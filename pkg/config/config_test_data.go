@@ -167,7 +167,8 @@ const (
           "b"
         ]
       }
-    ]
+    ],
+    "routes": null
   },
   "cyclic-replication": {
     "enable": false,
@@ -215,7 +216,8 @@ const (
           "b"
         ]
       }
-    ]
+    ],
+    "routes": null
   },
   "cyclic-replication": {
     "enable": false,
@@ -19,4 +19,12 @@ type ConsistentConfig struct {
 	MaxLogSize        int64  `toml:"max-log-size" json:"max-log-size"`
 	FlushIntervalInMs int64  `toml:"flush-interval" json:"flush-interval"`
 	Storage           string `toml:"storage" json:"storage"`
+	// Compression is the compression of the redo log records, none, lz4 or zstd.
+	Compression string `toml:"compression" json:"compression,omitempty"`
+	// EncryptionKeyFile and EncryptionKeyEnv are the file and the environment
+	// variable of the captures, which contain the hex-encoded AES key used to
+	// encrypt the redo logs. The redo logs are not encrypted if neither of
+	// them is set.
+	EncryptionKeyFile string `toml:"encryption-key-file" json:"encryption-key-file,omitempty"`
+	EncryptionKeyEnv  string `toml:"encryption-key-env" json:"encryption-key-env,omitempty"`
 }
@@ -14,6 +14,7 @@
 package config
 
 import (
+	bf "github.com/pingcap/tidb-tools/pkg/binlog-filter"
 	filter "github.com/pingcap/tidb-tools/pkg/table-filter"
 	"github.com/pingcap/tidb/parser/model"
 )
@@ -24,4 +25,25 @@ type FilterConfig struct {
 	*filter.MySQLReplicationRules
 	IgnoreTxnStartTs []uint64           `toml:"ignore-txn-start-ts" json:"ignore-txn-start-ts"`
 	DDLAllowlist     []model.ActionType `toml:"ddl-allow-list" json:"ddl-allow-list,omitempty"`
+	EventFilters     []*EventFilterRule `toml:"event-filters" json:"event-filters,omitempty"`
+}
+
+// EventFilterRule filters the events of the tables matched by Matcher.
+// The events are ignored if their types are in IgnoreEvent, such as `delete`
+// and `create index`, or the DDL queries match the regular expressions in
+// IgnoreSQL, such as `^ALTER TABLE .* ADD INDEX`.
+// The DMLs are also ignored if the SQL expressions of their types are
+// evaluated to true on the column values, such as `tenant_status != 'active'`.
+// The insert and update expressions require the old value to be enabled.
+type EventFilterRule struct {
+	Matcher     []string       `toml:"matcher" json:"matcher"`
+	IgnoreEvent []bf.EventType `toml:"ignore-event" json:"ignore-event"`
+	IgnoreSQL   []string       `toml:"ignore-sql" json:"ignore-sql"`
+	// IgnoreUpdateOldValueExpr and IgnoreUpdateNewValueExpr are evaluated on
+	// the old values and the new values of an update, the update is ignored
+	// only if both of them are true, the one not set is treated as true.
+	IgnoreInsertValueExpr    string `toml:"ignore-insert-value-expr" json:"ignore-insert-value-expr"`
+	IgnoreUpdateNewValueExpr string `toml:"ignore-update-new-value-expr" json:"ignore-update-new-value-expr"`
+	IgnoreUpdateOldValueExpr string `toml:"ignore-update-old-value-expr" json:"ignore-update-old-value-expr"`
+	IgnoreDeleteValueExpr    string `toml:"ignore-delete-value-expr" json:"ignore-delete-value-expr"`
 }
@@ -0,0 +1,49 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+// MaskingTransform is the transform applied to the masked columns.
+type MaskingTransform string
+
+const (
+	// MaskingHash replaces the value by the hex encoded SHA-256 of the salt
+	// and the value, the result is truncated to Length if it's set. The
+	// columns must be able to hold the 64 characters, or Length.
+	MaskingHash MaskingTransform = "hash"
+	// MaskingTruncate keeps the first Length characters of the value.
+	MaskingTruncate MaskingTransform = "truncate"
+	// MaskingSetNull replaces the value by NULL, the NOT NULL columns can't
+	// be masked by it.
+	MaskingSetNull MaskingTransform = "set-null"
+	// MaskingRedact replaces the letters by `X` or `x` and the digits by `0`,
+	// the other characters are kept, so the format of the value is preserved.
+	MaskingRedact MaskingTransform = "redact"
+)
+
+// MaskingConfig represents the data masking config for a changefeed,
+// the columns are masked before the rows are sent to the sink.
+type MaskingConfig struct {
+	Rules []*MaskingRule `toml:"rules" json:"rules"`
+}
+
+// MaskingRule applies the transform to the columns of the tables matched by
+// Matcher, the column names are case-insensitive. Only the first rule
+// matching a column is applied.
+type MaskingRule struct {
+	Matcher   []string         `toml:"matcher" json:"matcher"`
+	Columns   []string         `toml:"columns" json:"columns"`
+	Transform MaskingTransform `toml:"transform" json:"transform"`
+	Salt      string           `toml:"salt" json:"salt"`
+	Length    int              `toml:"length" json:"length"`
+}
@@ -36,6 +36,8 @@ const (
 	ProtocolCanalJSON
 	ProtocolCraft
 	ProtocolOpen
+	ProtocolCSV
+	ProtocolDebezium
 )
 
 // FromString converts the protocol from string to Protocol enum type.
@@ -55,6 +57,10 @@ func (p *Protocol) FromString(protocol string) error {
 		*p = ProtocolCraft
 	case "open-protocol":
 		*p = ProtocolOpen
+	case "csv":
+		*p = ProtocolCSV
+	case "debezium":
+		*p = ProtocolDebezium
 	default:
 		return cerror.ErrMQSinkUnknownProtocol.GenWithStackByArgs(protocol)
 	}
@@ -79,6 +85,10 @@ func (p Protocol) String() string {
 		return "craft"
 	case ProtocolOpen:
 		return "open-protocol"
+	case ProtocolCSV:
+		return "csv"
+	case ProtocolDebezium:
+		return "debezium"
 	default:
 		panic("unreachable")
 	}
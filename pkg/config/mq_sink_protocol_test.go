@@ -58,6 +58,14 @@ func TestFromString(t *testing.T) {
 			protocol:             "open-protocol",
 			expectedProtocolEnum: ProtocolOpen,
 		},
+		{
+			protocol:             "csv",
+			expectedProtocolEnum: ProtocolCSV,
+		},
+		{
+			protocol:             "debezium",
+			expectedProtocolEnum: ProtocolDebezium,
+		},
 	}
 
 	for _, tc := range testCases {
@@ -105,6 +113,14 @@ func TestString(t *testing.T) {
 			protocolEnum:     ProtocolOpen,
 			expectedProtocol: "open-protocol",
 		},
+		{
+			protocolEnum:     ProtocolCSV,
+			expectedProtocol: "csv",
+		},
+		{
+			protocolEnum:     ProtocolDebezium,
+			expectedProtocol: "debezium",
+		},
 	}
 
 	for _, tc := range testCases {
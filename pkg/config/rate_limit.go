@@ -0,0 +1,23 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+// RateLimitConfig limits the throughput of the rows written to the sink,
+// zero means no limit. The limits are of the whole changefeed, they're split
+// evenly among the captures replicating it, and can be updated while the
+// changefeed is running.
+type RateLimitConfig struct {
+	RowsPerSecond  uint64 `toml:"rows-per-second" json:"rows-per-second"`
+	BytesPerSecond uint64 `toml:"bytes-per-second" json:"bytes-per-second"`
+}
@@ -60,11 +60,14 @@ type replicaConfig struct {
 	ForceReplicate   bool              `toml:"force-replicate" json:"force-replicate"`
 	CheckGCSafePoint bool              `toml:"check-gc-safe-point" json:"check-gc-safe-point"`
 	Filter           *FilterConfig     `toml:"filter" json:"filter"`
+	Masking          *MaskingConfig    `toml:"masking" json:"masking,omitempty"`
 	Mounter          *MounterConfig    `toml:"mounter" json:"mounter"`
 	Sink             *SinkConfig       `toml:"sink" json:"sink"`
 	Cyclic           *CyclicConfig     `toml:"cyclic-replication" json:"cyclic-replication"`
 	Scheduler        *SchedulerConfig  `toml:"scheduler" json:"scheduler"`
 	Consistent       *ConsistentConfig `toml:"consistent" json:"consistent"`
+	RateLimit        *RateLimitConfig  `toml:"rate-limit" json:"rate-limit,omitempty"`
+	MemoryQuota      uint64            `toml:"memory-quota" json:"memory-quota,omitempty"`
 }
 
 // Marshal returns the json marshal format of a ReplicationConfig
@@ -82,6 +82,8 @@ func TestReplicaConfigOutDated(t *testing.T) {
 		{Matcher: []string{"a.d"}, Dispatcher: "r2"},
 	}
 	require.Equal(t, conf, conf2)
+	// the changefeeds created before the memory quota have no shared limit.
+	require.Zero(t, conf2.MemoryQuota)
 }
 
 func TestReplicaConfigValidate(t *testing.T) {
@@ -29,6 +29,7 @@ var ForceEnableOldValueProtocols = []string{
 	ProtocolCanal.String(),
 	ProtocolCanalJSON.String(),
 	ProtocolMaxwell.String(),
+	ProtocolDebezium.String(),
 }
 
 // SinkConfig represents sink config for a changefeed
@@ -36,19 +37,40 @@ type SinkConfig struct {
 	DispatchRules   []*DispatchRule   `toml:"dispatchers" json:"dispatchers"`
 	Protocol        string            `toml:"protocol" json:"protocol"`
 	ColumnSelectors []*ColumnSelector `toml:"column-selectors" json:"column-selectors"`
+	Routes          []*RouteRule      `toml:"routes" json:"routes"`
 }
 
 // DispatchRule represents partition rule for a table
 type DispatchRule struct {
 	Matcher    []string `toml:"matcher" json:"matcher"`
 	Dispatcher string   `toml:"dispatcher" json:"dispatcher"`
+	// TopicRule is an expression such as `{schema}_{table}`, the events of
+	// the matched tables are sent to the substituted topic. An empty rule
+	// means the events are sent to the topic specified in the sink URI.
+	TopicRule string `toml:"topic" json:"topic"`
+	// Columns are the columns used by the `columns` dispatcher, the rows
+	// with the same values of these columns go to the same partition.
+	Columns []string `toml:"columns" json:"columns"`
 }
 
+// ColumnSelector represents a column selector for a table, only the columns
+// matched by Columns are sent to the downstream.
 type ColumnSelector struct {
 	Matcher []string `toml:"matcher" json:"matcher"`
 	Columns []string `toml:"columns" json:"columns"`
 }
 
+// RouteRule routes the events of the matched tables to the target schema and
+// table in the downstream. The targets are expressions such as `c1_{schema}`,
+// `{schema}` and `{table}` are replaced by the upstream schema and table name.
+// An empty target keeps the upstream name. The DDLs of the schemas are only
+// routed by the rules without a target table.
+type RouteRule struct {
+	Matcher      []string `toml:"matcher" json:"matcher"`
+	TargetSchema string   `toml:"target-schema" json:"target-schema"`
+	TargetTable  string   `toml:"target-table" json:"target-table"`
+}
+
 func (s *SinkConfig) validate(enableOldValue bool) error {
 	if !enableOldValue {
 		for _, protocolStr := range ForceEnableOldValueProtocols {
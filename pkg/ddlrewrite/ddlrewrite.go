@@ -0,0 +1,127 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ddlrewrite
+
+import (
+	"strings"
+
+	"github.com/pingcap/errors"
+	"github.com/pingcap/tidb-tools/pkg/filter"
+	"github.com/pingcap/tidb/parser/ast"
+	"github.com/pingcap/tidb/parser/format"
+	"github.com/pingcap/tidb/parser/model"
+	_ "github.com/pingcap/tidb/types/parser_driver" // for the value expressions in DDLs
+)
+
+type tableNameExtractor struct {
+	currentSchema string
+	names         []*filter.Table
+}
+
+func (e *tableNameExtractor) Enter(in ast.Node) (ast.Node, bool) {
+	if t, ok := in.(*ast.TableName); ok {
+		table := &filter.Table{Schema: t.Schema.O, Name: t.Name.O}
+		if table.Schema == "" {
+			table.Schema = e.currentSchema
+		}
+		e.names = append(e.names, table)
+		return in, true
+	}
+	return in, false
+}
+
+func (e *tableNameExtractor) Leave(in ast.Node) (ast.Node, bool) {
+	return in, true
+}
+
+// FetchTables returns the tables in the DDL in the order they are visited,
+// the tables without schema belong to the current schema. For example,
+// `RENAME TABLE` returns [old1, new1, old2, new2, ...], and `CREATE TABLE
+// LIKE` returns [table, referred table]. The DDLs of schemas return a single
+// table with an empty name.
+func FetchTables(currentSchema string, stmt ast.StmtNode) ([]*filter.Table, error) {
+	if _, ok := stmt.(ast.DDLNode); !ok {
+		return nil, errors.Errorf("%s is not a DDL", stmt.Text())
+	}
+	switch v := stmt.(type) {
+	case *ast.AlterDatabaseStmt:
+		return []*filter.Table{{Schema: v.Name}}, nil
+	case *ast.CreateDatabaseStmt:
+		return []*filter.Table{{Schema: v.Name}}, nil
+	case *ast.DropDatabaseStmt:
+		return []*filter.Table{{Schema: v.Name}}, nil
+	}
+	e := &tableNameExtractor{currentSchema: currentSchema}
+	stmt.Accept(e)
+	return e.names, nil
+}
+
+type tableRenameVisitor struct {
+	targets []*filter.Table
+	i       int
+	hasErr  bool
+}
+
+func (v *tableRenameVisitor) Enter(in ast.Node) (ast.Node, bool) {
+	if v.hasErr {
+		return in, true
+	}
+	if t, ok := in.(*ast.TableName); ok {
+		if v.i >= len(v.targets) {
+			v.hasErr = true
+			return in, true
+		}
+		t.Schema = model.NewCIStr(v.targets[v.i].Schema)
+		t.Name = model.NewCIStr(v.targets[v.i].Name)
+		v.i++
+		return in, true
+	}
+	return in, false
+}
+
+func (v *tableRenameVisitor) Leave(in ast.Node) (ast.Node, bool) {
+	return in, !v.hasErr
+}
+
+// RenameTables replaces the tables in the DDL by the targets, which are in
+// the same order as the result of FetchTables, and returns the restored DDL.
+// The stmt is modified in place.
+func RenameTables(stmt ast.StmtNode, targets []*filter.Table) (string, error) {
+	if _, ok := stmt.(ast.DDLNode); !ok {
+		return "", errors.Errorf("%s is not a DDL", stmt.Text())
+	}
+	switch v := stmt.(type) {
+	case *ast.AlterDatabaseStmt:
+		v.Name = targets[0].Schema
+	case *ast.CreateDatabaseStmt:
+		v.Name = targets[0].Schema
+	case *ast.DropDatabaseStmt:
+		v.Name = targets[0].Schema
+	default:
+		visitor := &tableRenameVisitor{targets: targets}
+		stmt.Accept(visitor)
+		if visitor.hasErr {
+			return "", errors.Errorf("the tables %v don't match the DDL %s", targets, stmt.Text())
+		}
+	}
+	var sb strings.Builder
+	err := stmt.Restore(&format.RestoreCtx{
+		Flags: format.DefaultRestoreFlags | format.RestoreTiDBSpecialComment,
+		In:    &sb,
+	})
+	if err != nil {
+		return "", errors.Trace(err)
+	}
+	return sb.String(), nil
+}
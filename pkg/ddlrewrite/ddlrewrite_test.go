@@ -0,0 +1,76 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ddlrewrite
+
+import (
+	"testing"
+
+	"github.com/pingcap/tidb-tools/pkg/filter"
+	"github.com/pingcap/tidb/parser"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRenameTables(t *testing.T) {
+	t.Parallel()
+
+	p := parser.New()
+	for _, tc := range []struct {
+		query    string
+		tables   []*filter.Table
+		targets  []*filter.Table
+		expected string
+	}{
+		{
+			query:    "create database test",
+			tables:   []*filter.Table{{Schema: "test"}},
+			targets:  []*filter.Table{{Schema: "c1_test"}},
+			expected: "CREATE DATABASE `c1_test`",
+		},
+		{
+			query:    "alter table t add column c int",
+			tables:   []*filter.Table{{Schema: "test", Name: "t"}},
+			targets:  []*filter.Table{{Schema: "c1_test", Name: "t"}},
+			expected: "ALTER TABLE `c1_test`.`t` ADD COLUMN `c` INT",
+		},
+		{
+			query: "rename table t1 to other.t2",
+			tables: []*filter.Table{
+				{Schema: "test", Name: "t1"}, {Schema: "other", Name: "t2"},
+			},
+			targets: []*filter.Table{
+				{Schema: "c1_test", Name: "t1"}, {Schema: "other", Name: "t2"},
+			},
+			expected: "RENAME TABLE `c1_test`.`t1` TO `other`.`t2`",
+		},
+	} {
+		stmt, err := p.ParseOneStmt(tc.query, "", "")
+		require.Nil(t, err)
+		tables, err := FetchTables("test", stmt)
+		require.Nil(t, err)
+		require.Equal(t, tc.tables, tables)
+		query, err := RenameTables(stmt, tc.targets)
+		require.Nil(t, err)
+		require.Equal(t, tc.expected, query)
+	}
+
+	stmt, err := p.ParseOneStmt("select * from t", "", "")
+	require.Nil(t, err)
+	_, err = FetchTables("test", stmt)
+	require.NotNil(t, err)
+
+	stmt, err = p.ParseOneStmt("rename table t1 to t2", "", "")
+	require.Nil(t, err)
+	_, err = RenameTables(stmt, []*filter.Table{{Schema: "test", Name: "t1"}})
+	require.NotNil(t, err)
+}
@@ -59,6 +59,11 @@ var (
 	ErrEncodeFailed      = errors.Normalize("encode failed: %s", errors.RFCCodeText("CDC:ErrEncodeFailed"))
 	ErrDecodeFailed      = errors.Normalize("decode failed: %s", errors.RFCCodeText("CDC:ErrDecodeFailed"))
 	ErrFilterRuleInvalid = errors.Normalize("filter rule is invalid", errors.RFCCodeText("CDC:ErrFilterRuleInvalid"))
+	ErrExprParseFailed   = errors.Normalize("invalid filter expression: %s", errors.RFCCodeText("CDC:ErrExprParseFailed"))
+	ErrExprEvalFailed    = errors.Normalize("failed to evaluate the filter expression %s on the row of %s", errors.RFCCodeText("CDC:ErrExprEvalFailed"))
+	ErrExprColumnUnknown = errors.Normalize("filter expression %s refers to an unknown column of table %s", errors.RFCCodeText("CDC:ErrExprColumnUnknown"))
+	ErrMaskRuleInvalid   = errors.Normalize("masking rule is invalid: %s", errors.RFCCodeText("CDC:ErrMaskRuleInvalid"))
+	ErrMaskingFailed     = errors.Normalize("masking transform %s can't be applied to column %s of table %s", errors.RFCCodeText("CDC:ErrMaskingFailed"))
 
 	// internal errors
 	ErrAdminStopProcessor = errors.Normalize("stop processor by admin command", errors.RFCCodeText("CDC:ErrAdminStopProcessor"))
@@ -78,6 +83,8 @@ var (
 	ErrKafkaInvalidClientID     = errors.Normalize("invalid kafka client ID '%s'", errors.RFCCodeText("CDC:ErrKafkaInvalidClientID"))
 	ErrKafkaInvalidVersion      = errors.Normalize("invalid kafka version", errors.RFCCodeText("CDC:ErrKafkaInvalidVersion"))
 	ErrKafkaInvalidConfig       = errors.Normalize("kafka config invalid", errors.RFCCodeText("CDC:ErrKafkaInvalidConfig"))
+	ErrKafkaTopicExprInvalid    = errors.Normalize("invalid topic expression: %s", errors.RFCCodeText("CDC:ErrKafkaTopicExprInvalid"))
+	ErrKafkaTopicNotFound       = errors.Normalize("kafka topic %s not found", errors.RFCCodeText("CDC:ErrKafkaTopicNotFound"))
 	ErrPulsarNewProducer        = errors.Normalize("new pulsar producer", errors.RFCCodeText("CDC:ErrPulsarNewProducer"))
 	ErrPulsarSendMessage        = errors.Normalize("pulsar send message failed", errors.RFCCodeText("CDC:ErrPulsarSendMessage"))
 	ErrRedoConfigInvalid        = errors.Normalize("redo log config invalid", errors.RFCCodeText("CDC:ErrRedoConfigInvalid"))
@@ -86,7 +93,10 @@ var (
 	ErrRedoFileOp               = errors.Normalize("redo file operation", errors.RFCCodeText("CDC:ErrRedoFileOp"))
 	ErrRedoMetaFileNotFound     = errors.Normalize("no redo meta file found in dir: %s", errors.RFCCodeText("CDC:ErrRedoMetaFileNotFound"))
 	ErrRedoMetaInitialize       = errors.Normalize("initialize meta for redo log", errors.RFCCodeText("CDC:ErrRedoMetaInitialize"))
+	ErrRedoLogFilesBroken       = errors.Normalize("redo log files are broken, %d issues found", errors.RFCCodeText("CDC:ErrRedoLogFilesBroken"))
+	ErrRedoLogCodec             = errors.Normalize("encode or decode redo log record failed", errors.RFCCodeText("CDC:ErrRedoLogCodec"))
 	ErrFileSizeExceed           = errors.Normalize("rawData size %d exceeds maximum file size %d", errors.RFCCodeText("CDC:ErrFileSizeExceed"))
+	ErrRedoStorageInitialize    = errors.Normalize("new external storage for redo log", errors.RFCCodeText("CDC:ErrRedoStorageInitialize"))
 	ErrS3StorageAPI             = errors.Normalize("s3 storage api", errors.RFCCodeText("CDC:ErrS3StorageAPI"))
 	ErrS3StorageInitialize      = errors.Normalize("new s3 storage for redo log", errors.RFCCodeText("CDC:ErrS3StorageInitialize"))
 	ErrPrepareAvroFailed        = errors.Normalize("prepare avro failed", errors.RFCCodeText("CDC:ErrPrepareAvroFailed"))
@@ -104,6 +114,8 @@ var (
 	ErrAvroEncodeFailed         = errors.Normalize("encode to avro native data", errors.RFCCodeText("CDC:ErrAvroEncodeFailed"))
 	ErrAvroEncodeToBinary       = errors.Normalize("encode to binray from native", errors.RFCCodeText("CDC:ErrAvroEncodeToBinary"))
 	ErrAvroSchemaAPIError       = errors.Normalize("schema manager API error", errors.RFCCodeText("CDC:ErrAvroSchemaAPIError"))
+	ErrAvroIncompatibleSchema   = errors.Normalize("schema of %s is incompatible with the latest version in the registry", errors.RFCCodeText("CDC:ErrAvroIncompatibleSchema"))
+	ErrAvroDecodeFailed         = errors.Normalize("avro decode failed", errors.RFCCodeText("CDC:ErrAvroDecodeFailed"))
 	ErrMaxwellEncodeFailed      = errors.Normalize("maxwell encode failed", errors.RFCCodeText("CDC:ErrMaxwellEncodeFailed"))
 	ErrMaxwellDecodeFailed      = errors.Normalize("maxwell decode failed", errors.RFCCodeText("CDC:ErrMaxwellDecodeFailed"))
 	ErrMaxwellInvalidData       = errors.Normalize("maxwell invalid data", errors.RFCCodeText("CDC:ErrMaxwellInvalidData"))
@@ -111,9 +123,27 @@ var (
 	ErrJSONCodecRowTooLarge     = errors.Normalize("json codec single row too large", errors.RFCCodeText("CDC:ErrJSONCodecRowTooLarge"))
 	ErrCanalDecodeFailed        = errors.Normalize("canal decode failed", errors.RFCCodeText("CDC:ErrCanalDecodeFailed"))
 	ErrCanalEncodeFailed        = errors.Normalize("canal encode failed", errors.RFCCodeText("CDC:ErrCanalEncodeFailed"))
+	ErrCSVEncodeFailed          = errors.Normalize("csv encode failed", errors.RFCCodeText("CDC:ErrCSVEncodeFailed"))
+	ErrDebeziumEncodeFailed     = errors.Normalize("debezium encode failed", errors.RFCCodeText("CDC:ErrDebeziumEncodeFailed"))
+	ErrDebeziumDecodeFailed     = errors.Normalize("debezium decode failed", errors.RFCCodeText("CDC:ErrDebeziumDecodeFailed"))
 	ErrOldValueNotEnabled       = errors.Normalize("old value is not enabled", errors.RFCCodeText("CDC:ErrOldValueNotEnabled"))
 	ErrSinkInvalidConfig        = errors.Normalize("sink config invalid", errors.RFCCodeText("CDC:ErrSinkInvalidConfig"))
 	ErrCraftCodecInvalidData    = errors.Normalize("craft codec invalid data", errors.RFCCodeText("CDC:ErrCraftCodecInvalidData"))
+	ErrColumnSelectorInvalid    = errors.Normalize("column selector is invalid", errors.RFCCodeText("CDC:ErrColumnSelectorInvalid"))
+	ErrColumnSelectorFailed     = errors.Normalize("column selector of table %s drops the required column %s", errors.RFCCodeText("CDC:ErrColumnSelectorFailed"))
+	ErrDispatchColumnInvalid    = errors.Normalize("dispatch column %s of table %s is invalid: %s", errors.RFCCodeText("CDC:ErrDispatchColumnInvalid"))
+	ErrRouteRuleInvalid         = errors.Normalize("route rule is invalid", errors.RFCCodeText("CDC:ErrRouteRuleInvalid"))
+	ErrRouteDDLFailed           = errors.Normalize("failed to route the DDL %s", errors.RFCCodeText("CDC:ErrRouteDDLFailed"))
+	ErrStorageInitialize        = errors.Normalize("new external storage for storage sink", errors.RFCCodeText("CDC:ErrStorageInitialize"))
+	ErrExternalStorageAPI       = errors.Normalize("external storage api", errors.RFCCodeText("CDC:ErrExternalStorageAPI"))
+	ErrClaimCheckCorrupted      = errors.Normalize("claim check message %s is corrupted", errors.RFCCodeText("CDC:ErrClaimCheckCorrupted"))
+	ErrClaimCheckUnresolved     = errors.Normalize("claim check message is not resolved, please set %s", errors.RFCCodeText("CDC:ErrClaimCheckUnresolved"))
+
+	// kafka consumer related errors
+	ErrConsumerUnsupportedProtocol = errors.Normalize("protocol %s is not supported by the consumer: %s", errors.RFCCodeText("CDC:ErrConsumerUnsupportedProtocol"))
+	ErrConsumerInvalidMessage      = errors.Normalize("invalid message of partition %d at offset %d", errors.RFCCodeText("CDC:ErrConsumerInvalidMessage"))
+	ErrConsumerResolvedTsFallback  = errors.Normalize("resolved ts of partition %d fallback from %d to %d", errors.RFCCodeText("CDC:ErrConsumerResolvedTsFallback"))
+	ErrConsumerDDLFallback         = errors.Normalize("ddl with commit ts %d is received after the global resolved ts %d", errors.RFCCodeText("CDC:ErrConsumerDDLFallback"))
 
 	// utilities related errors
 	ErrToTLSConfigFailed         = errors.Normalize("generate tls config failed", errors.RFCCodeText("CDC:ErrToTLSConfigFailed"))
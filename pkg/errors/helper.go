@@ -32,10 +32,12 @@ func WrapError(rfcError *errors.Error, err error, args ...interface{}) error {
 
 // ChangeFeedFastFailError is read only.
 // If this type of error occurs in a changefeed, it means that the data it
-// wants to replicate has been or will be GC. So it makes no sense to try to
-// resume the changefeed, and the changefeed should immediately be failed.
+// wants to replicate has been or will be GC, or that the downstream can never
+// accept it, e.g. an incompatible schema change is rejected by the schema
+// registry. So it makes no sense to try to resume the changefeed, and the
+// changefeed should immediately be failed.
 var ChangeFeedFastFailError = []*errors.Error{
-	ErrGCTTLExceeded, ErrSnapshotLostByGC, ErrStartTsBeforeGC,
+	ErrGCTTLExceeded, ErrSnapshotLostByGC, ErrStartTsBeforeGC, ErrAvroIncompatibleSchema,
 }
 
 // ChangefeedFastFailError checks if an error is a ChangefeedFastFailError
@@ -94,6 +94,11 @@ func TestChangefeedFastFailError(t *testing.T) {
 	require.Equal(t, true, ChangefeedFastFailError(err))
 	require.Equal(t, true, ChangefeedFastFailErrorCode(rfcCode))
 
+	err = ErrAvroIncompatibleSchema.FastGenByArgs("test_t1-value")
+	rfcCode, _ = RFCCode(err)
+	require.Equal(t, true, ChangefeedFastFailError(err))
+	require.Equal(t, true, ChangefeedFastFailErrorCode(rfcCode))
+
 	err = ErrToTLSConfigFailed.FastGenByArgs()
 	rfcCode, _ = RFCCode(err)
 	require.Equal(t, false, ChangefeedFastFailError(err))
@@ -0,0 +1,276 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package filter
+
+import (
+	"sync"
+
+	"github.com/pingcap/errors"
+	"github.com/pingcap/log"
+	filterV2 "github.com/pingcap/tidb-tools/pkg/table-filter"
+	"github.com/pingcap/tidb/expression"
+	"github.com/pingcap/tidb/parser"
+	"github.com/pingcap/tidb/planner/core"
+	"github.com/pingcap/tidb/sessionctx"
+	"github.com/pingcap/tidb/table"
+	"github.com/pingcap/tidb/types"
+	_ "github.com/pingcap/tidb/types/parser_driver" // for parser driver
+	"github.com/pingcap/tidb/util/chunk"
+	"github.com/pingcap/tidb/util/mock"
+	"github.com/pingcap/tiflow/cdc/model"
+	"github.com/pingcap/tiflow/pkg/config"
+	cerror "github.com/pingcap/tiflow/pkg/errors"
+	"go.uber.org/zap"
+)
+
+// tableExprs are the expressions of a rule built for a version of a table.
+type tableExprs struct {
+	version   uint64
+	insert    expression.Expression
+	updateOld expression.Expression
+	updateNew expression.Expression
+	delete    expression.Expression
+}
+
+// dmlExprFilterRule ignores the DMLs of the tables matched by the rule if the
+// expressions are evaluated to true on the column values.
+type dmlExprFilterRule struct {
+	mu sync.Mutex
+
+	matcher filterV2.Filter
+	config  *config.EventFilterRule
+	// exprs caches the expressions by the quoted table name, they are rebuilt
+	// once the table info version changes.
+	exprs   map[string]*tableExprs
+	sessCtx sessionctx.Context
+}
+
+// dmlExprFilter ignores the DMLs by the expressions of the event filter rules.
+type dmlExprFilter struct {
+	rules []*dmlExprFilterRule
+}
+
+// verifyExprs checks the syntax of the expressions in the event filter rules,
+// the columns are checked by VerifyTableExprs on the tables.
+// The insert and update expressions require the old value, because the
+// updates without the old value can't be told apart from the inserts.
+func verifyExprs(cfg *config.ReplicaConfig) error {
+	p := parser.New()
+	for _, rule := range cfg.Filter.EventFilters {
+		if !cfg.EnableOldValue && (rule.IgnoreInsertValueExpr != "" ||
+			rule.IgnoreUpdateNewValueExpr != "" || rule.IgnoreUpdateOldValueExpr != "") {
+			return cerror.WrapError(cerror.ErrFilterRuleInvalid, errors.New(
+				"ignore-insert-value-expr and ignore-update-*-value-expr require enable-old-value"))
+		}
+		for _, expr := range exprsOfRule(rule) {
+			if _, err := p.ParseOneStmt("select * from t where "+expr, "", ""); err != nil {
+				return cerror.WrapError(cerror.ErrExprParseFailed, err, expr)
+			}
+		}
+	}
+	return nil
+}
+
+// exprsOfRule returns the expressions set in the rule.
+func exprsOfRule(rule *config.EventFilterRule) []string {
+	exprs := make([]string, 0, 4)
+	for _, expr := range []string{
+		rule.IgnoreInsertValueExpr,
+		rule.IgnoreUpdateNewValueExpr,
+		rule.IgnoreUpdateOldValueExpr,
+		rule.IgnoreDeleteValueExpr,
+	} {
+		if expr != "" {
+			exprs = append(exprs, expr)
+		}
+	}
+	return exprs
+}
+
+func newDMLExprFilter(cfg *config.ReplicaConfig) (*dmlExprFilter, error) {
+	f := &dmlExprFilter{}
+	for _, rule := range cfg.Filter.EventFilters {
+		if len(exprsOfRule(rule)) == 0 {
+			continue
+		}
+		matcher, err := filterV2.Parse(rule.Matcher)
+		if err != nil {
+			return nil, cerror.WrapError(cerror.ErrFilterRuleInvalid, err)
+		}
+		if !cfg.CaseSensitive {
+			matcher = filterV2.CaseInsensitive(matcher)
+		}
+		f.rules = append(f.rules, &dmlExprFilterRule{
+			matcher: matcher,
+			config:  rule,
+			exprs:   make(map[string]*tableExprs),
+			sessCtx: mock.NewContext(),
+		})
+	}
+	return f, nil
+}
+
+// shouldSkipDML returns true if the row should be ignored by any rule.
+func (f *dmlExprFilter) shouldSkipDML(row *model.RowChangedEvent, ti *model.TableInfo) (bool, error) {
+	for _, rule := range f.rules {
+		if !rule.matcher.MatchTable(row.Table.Schema, row.Table.Table) {
+			continue
+		}
+		skip, err := rule.shouldSkipDML(row, ti)
+		if err != nil || skip {
+			return skip, err
+		}
+	}
+	return false, nil
+}
+
+// ShouldIgnoreDMLByExpr returns true if the row is ignored by the expressions
+// of the event filter rules, ti is the table info the row is decoded with.
+func (f *Filter) ShouldIgnoreDMLByExpr(row *model.RowChangedEvent, ti *model.TableInfo) (bool, error) {
+	if f.dmlExprFilter == nil || len(f.dmlExprFilter.rules) == 0 {
+		return false, nil
+	}
+	return f.dmlExprFilter.shouldSkipDML(row, ti)
+}
+
+// VerifyTableExprs checks that the columns referred by the expressions of the
+// event filter rules matching the table exist in the table.
+func (f *Filter) VerifyTableExprs(ti *model.TableInfo) error {
+	if f.dmlExprFilter == nil {
+		return nil
+	}
+	for _, rule := range f.dmlExprFilter.rules {
+		if !rule.matcher.MatchTable(ti.TableName.Schema, ti.TableName.Table) {
+			continue
+		}
+		if err := rule.verifyTable(ti); err != nil {
+			return errors.Trace(err)
+		}
+	}
+	return nil
+}
+
+func (r *dmlExprFilterRule) verifyTable(ti *model.TableInfo) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, expr := range exprsOfRule(r.config) {
+		_, err := expression.ParseSimpleExprWithTableInfo(r.sessCtx, expr, ti.TableInfo)
+		if err == nil {
+			continue
+		}
+		if core.ErrUnknownColumn.Equal(err) {
+			return cerror.WrapError(cerror.ErrExprColumnUnknown, err, expr, ti.TableName.String())
+		}
+		return cerror.WrapError(cerror.ErrExprParseFailed, err, expr)
+	}
+	return nil
+}
+
+func (r *dmlExprFilterRule) shouldSkipDML(row *model.RowChangedEvent, ti *model.TableInfo) (bool, error) {
+	// The expressions and the session context are not thread safe.
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	exprs, err := r.getExprs(ti)
+	if err != nil {
+		return false, errors.Trace(err)
+	}
+	switch {
+	case row.IsInsert():
+		return r.skipByExpr(exprs.insert, row.Columns, ti)
+	case row.IsUpdate():
+		if exprs.updateOld == nil && exprs.updateNew == nil {
+			return false, nil
+		}
+		// The missing one of the two expressions is treated as true.
+		if exprs.updateOld != nil {
+			skip, err := r.skipByExpr(exprs.updateOld, row.PreColumns, ti)
+			if err != nil || !skip {
+				return false, err
+			}
+		}
+		if exprs.updateNew == nil {
+			return true, nil
+		}
+		return r.skipByExpr(exprs.updateNew, row.Columns, ti)
+	case row.IsDelete():
+		return r.skipByExpr(exprs.delete, row.PreColumns, ti)
+	}
+	return false, nil
+}
+
+func (r *dmlExprFilterRule) getExprs(ti *model.TableInfo) (*tableExprs, error) {
+	tableName := ti.TableName.QuoteString()
+	if exprs, ok := r.exprs[tableName]; ok && exprs.version == ti.TableInfoVersion {
+		return exprs, nil
+	}
+	exprs := &tableExprs{version: ti.TableInfoVersion}
+	for _, item := range []struct {
+		expr   string
+		target *expression.Expression
+	}{
+		{r.config.IgnoreInsertValueExpr, &exprs.insert},
+		{r.config.IgnoreUpdateOldValueExpr, &exprs.updateOld},
+		{r.config.IgnoreUpdateNewValueExpr, &exprs.updateNew},
+		{r.config.IgnoreDeleteValueExpr, &exprs.delete},
+	} {
+		if item.expr == "" {
+			continue
+		}
+		e, err := expression.ParseSimpleExprWithTableInfo(r.sessCtx, item.expr, ti.TableInfo)
+		if err != nil {
+			// The rows are not ignored if the expression contains an unknown
+			// column, since the column may be added by a later DDL.
+			if !core.ErrUnknownColumn.Equal(err) {
+				return nil, cerror.WrapError(cerror.ErrExprParseFailed, err, item.expr)
+			}
+			log.Warn("meet unknown column when generating expression, return a FALSE expression instead",
+				zap.String("expression", item.expr),
+				zap.String("table", tableName),
+				zap.Error(err))
+			e = expression.NewZero()
+		}
+		*item.target = e
+	}
+	r.exprs[tableName] = exprs
+	return exprs, nil
+}
+
+// skipByExpr returns true if the expression is evaluated to true on the columns.
+func (r *dmlExprFilterRule) skipByExpr(
+	expr expression.Expression, cols []*model.Column, ti *model.TableInfo,
+) (bool, error) {
+	if expr == nil {
+		return false, nil
+	}
+	// The expressions refer to the columns by the offsets in the table info,
+	// the columns not visible to TiCDC are evaluated as NULL.
+	data := make([]types.Datum, len(ti.Columns))
+	for i, colInfo := range ti.Columns {
+		offset, ok := ti.RowColumnsOffset[colInfo.ID]
+		if !ok || offset >= len(cols) || cols[offset] == nil || cols[offset].Value == nil {
+			data[i].SetNull()
+			continue
+		}
+		d, err := table.CastValue(r.sessCtx, types.NewDatum(cols[offset].Value), colInfo, false, false)
+		if err != nil {
+			return false, cerror.WrapError(cerror.ErrExprEvalFailed, err, expr.String(), ti.TableName.String())
+		}
+		data[i] = d
+	}
+	d, err := expr.Eval(chunk.MutRowFromDatums(data).ToRow())
+	if err != nil {
+		return false, cerror.WrapError(cerror.ErrExprEvalFailed, err, expr.String(), ti.TableName.String())
+	}
+	return d.GetInt64() == 1, nil
+}
@@ -0,0 +1,182 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package filter
+
+import (
+	"testing"
+
+	"github.com/pingcap/tidb/ddl"
+	"github.com/pingcap/tidb/parser"
+	"github.com/pingcap/tidb/parser/ast"
+	timock "github.com/pingcap/tidb/util/mock"
+	"github.com/pingcap/tiflow/cdc/model"
+	"github.com/pingcap/tiflow/pkg/config"
+	"github.com/stretchr/testify/require"
+)
+
+func mockTableInfo(t *testing.T, version uint64, sql string) *model.TableInfo {
+	p := parser.New()
+	node, err := p.ParseOneStmt(sql, "", "")
+	require.Nil(t, err)
+	ti, err := ddl.MockTableInfo(timock.NewContext(), node.(*ast.CreateTableStmt), 1)
+	require.Nil(t, err)
+	return model.WrapTableInfo(1, "test", version, ti)
+}
+
+// mockColumns returns the columns of the row in the order of the table info.
+func mockColumns(ti *model.TableInfo, values map[string]interface{}) []*model.Column {
+	if values == nil {
+		return nil
+	}
+	cols := make([]*model.Column, len(ti.RowColumnsOffset))
+	for _, colInfo := range ti.Columns {
+		cols[ti.RowColumnsOffset[colInfo.ID]] = &model.Column{
+			Name:  colInfo.Name.O,
+			Type:  colInfo.Tp,
+			Value: values[colInfo.Name.O],
+		}
+	}
+	return cols
+}
+
+func TestShouldIgnoreDMLByExpr(t *testing.T) {
+	t.Parallel()
+
+	cfg := config.GetDefaultReplicaConfig()
+	cfg.Filter.EventFilters = []*config.EventFilterRule{{
+		Matcher:                  []string{"test.t"},
+		IgnoreInsertValueExpr:    "status != 'active'",
+		IgnoreUpdateNewValueExpr: "status != 'active'",
+		IgnoreDeleteValueExpr:    "id > 100 and score < 60.5",
+	}, {
+		// the level is unknown until the column is added.
+		Matcher:               []string{"test.*"},
+		IgnoreInsertValueExpr: "level > 3",
+	}}
+	f, err := NewFilter(cfg)
+	require.Nil(t, err)
+
+	ti := mockTableInfo(t, 1,
+		"create table t (id int primary key, status varchar(16), score decimal(10, 2))")
+	other := mockTableInfo(t, 1, "create table t1 (id int primary key, status varchar(16))")
+	newRow := func(ti *model.TableInfo, pre, cur map[string]interface{}) *model.RowChangedEvent {
+		return &model.RowChangedEvent{
+			Table:      &ti.TableName,
+			PreColumns: mockColumns(ti, pre),
+			Columns:    mockColumns(ti, cur),
+		}
+	}
+	active := map[string]interface{}{"id": int64(1), "status": []byte("active"), "score": "90.00"}
+	inactive := map[string]interface{}{"id": int64(101), "status": []byte("inactive"), "score": "50.00"}
+	for _, tc := range []struct {
+		ti     *model.TableInfo
+		pre    map[string]interface{}
+		cur    map[string]interface{}
+		ignore bool
+	}{
+		{ti, nil, active, false},
+		{ti, nil, inactive, true},
+		{ti, inactive, active, false},
+		{ti, active, inactive, true},
+		{ti, active, nil, false},
+		{ti, inactive, nil, true},
+		{other, nil, map[string]interface{}{"id": int64(1), "status": []byte("inactive")}, false},
+	} {
+		ignore, err := f.ShouldIgnoreDMLByExpr(newRow(tc.ti, tc.pre, tc.cur), tc.ti)
+		require.Nil(t, err)
+		require.Equal(t, tc.ignore, ignore, "%v -> %v", tc.pre, tc.cur)
+	}
+
+	// the expressions are rebuilt once the table info is changed.
+	ti = mockTableInfo(t, 2,
+		"create table t (id int primary key, status varchar(16), score decimal(10, 2), level int)")
+	ignore, err := f.ShouldIgnoreDMLByExpr(newRow(ti, nil, map[string]interface{}{
+		"id": int64(1), "status": []byte("active"), "score": "90.00", "level": int64(4),
+	}), ti)
+	require.Nil(t, err)
+	require.True(t, ignore)
+
+	// the filter without event filters ignores nothing.
+	f, err = NewFilter(config.GetDefaultReplicaConfig())
+	require.Nil(t, err)
+	ignore, err = f.ShouldIgnoreDMLByExpr(newRow(ti, nil, inactive), ti)
+	require.Nil(t, err)
+	require.False(t, ignore)
+}
+
+func TestVerifyEventFilters(t *testing.T) {
+	t.Parallel()
+
+	cfg := config.GetDefaultReplicaConfig()
+	cfg.Filter.EventFilters = []*config.EventFilterRule{{
+		Matcher:               []string{"test.t"},
+		IgnoreInsertValueExpr: "status !=",
+	}}
+	_, err := VerifyRules(cfg)
+	require.Regexp(t, ".*ErrExprParseFailed.*", err)
+
+	cfg.Filter.EventFilters[0].IgnoreInsertValueExpr = "status != 'active'"
+	cfg.Filter.EventFilters[0].Matcher = []string{"[test.t"}
+	_, err = VerifyRules(cfg)
+	require.Regexp(t, ".*ErrFilterRuleInvalid.*", err)
+
+	cfg.Filter.EventFilters[0].Matcher = []string{"test.t"}
+	_, err = VerifyRules(cfg)
+	require.Nil(t, err)
+}
+
+func TestVerifyEventFiltersWithoutOldValue(t *testing.T) {
+	t.Parallel()
+
+	cfg := config.GetDefaultReplicaConfig()
+	cfg.EnableOldValue = false
+	cfg.Filter.EventFilters = []*config.EventFilterRule{{
+		Matcher:               []string{"test.t"},
+		IgnoreDeleteValueExpr: "id > 100",
+	}}
+	_, err := VerifyRules(cfg)
+	require.Nil(t, err)
+
+	cfg.Filter.EventFilters[0].IgnoreInsertValueExpr = "status != 'active'"
+	_, err = VerifyRules(cfg)
+	require.Regexp(t, ".*ErrFilterRuleInvalid.*", err)
+
+	cfg.Filter.EventFilters[0].IgnoreInsertValueExpr = ""
+	cfg.Filter.EventFilters[0].IgnoreUpdateNewValueExpr = "status != 'active'"
+	_, err = VerifyRules(cfg)
+	require.Regexp(t, ".*ErrFilterRuleInvalid.*", err)
+}
+
+func TestVerifyTableExprs(t *testing.T) {
+	t.Parallel()
+
+	cfg := config.GetDefaultReplicaConfig()
+	cfg.Filter.EventFilters = []*config.EventFilterRule{{
+		Matcher:               []string{"test.t"},
+		IgnoreInsertValueExpr: "level > 3",
+	}}
+	f, err := NewFilter(cfg)
+	require.Nil(t, err)
+
+	ti := mockTableInfo(t, 1, "create table t (id int primary key, level int)")
+	require.Nil(t, f.VerifyTableExprs(ti))
+
+	ti = mockTableInfo(t, 1, "create table t (id int primary key, status varchar(16))")
+	err = f.VerifyTableExprs(ti)
+	require.Regexp(t, ".*ErrExprColumnUnknown.*", err)
+
+	// The rule doesn't match the table.
+	ti = mockTableInfo(t, 1, "create table t1 (id int primary key)")
+	require.Nil(t, f.VerifyTableExprs(ti))
+}
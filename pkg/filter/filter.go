@@ -14,6 +14,7 @@
 package filter
 
 import (
+	"github.com/pingcap/errors"
 	filterV1 "github.com/pingcap/tidb-tools/pkg/filter"
 	filterV2 "github.com/pingcap/tidb-tools/pkg/table-filter"
 	"github.com/pingcap/tidb/parser/model"
@@ -28,6 +29,8 @@ type Filter struct {
 	ignoreTxnStartTs []uint64
 	ddlAllowlist     []model.ActionType
 	isCyclicEnabled  bool
+	dmlExprFilter    *dmlExprFilter
+	sqlEventFilter   *sqlEventFilter
 }
 
 // VerifyRules checks the filter rules in the configuration
@@ -47,6 +50,17 @@ func VerifyRules(cfg *config.ReplicaConfig) (filterV2.Filter, error) {
 	if err != nil {
 		return nil, cerror.WrapError(cerror.ErrFilterRuleInvalid, err)
 	}
+	for _, rule := range cfg.Filter.EventFilters {
+		if _, err := filterV2.Parse(rule.Matcher); err != nil {
+			return nil, cerror.WrapError(cerror.ErrFilterRuleInvalid, err)
+		}
+	}
+	if err := verifyExprs(cfg); err != nil {
+		return nil, errors.Trace(err)
+	}
+	if _, err := newSQLEventFilter(cfg); err != nil {
+		return nil, errors.Trace(err)
+	}
 
 	return f, nil
 }
@@ -61,11 +75,21 @@ func NewFilter(cfg *config.ReplicaConfig) (*Filter, error) {
 	if !cfg.CaseSensitive {
 		f = filterV2.CaseInsensitive(f)
 	}
+	dmlExprFilter, err := newDMLExprFilter(cfg)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	sqlEventFilter, err := newSQLEventFilter(cfg)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
 	return &Filter{
 		filter:           f,
 		ignoreTxnStartTs: cfg.Filter.IgnoreTxnStartTs,
 		ddlAllowlist:     cfg.Filter.DDLAllowlist,
 		isCyclicEnabled:  cfg.Cyclic.IsEnabled(),
+		dmlExprFilter:    dmlExprFilter,
+		sqlEventFilter:   sqlEventFilter,
 	}, nil
 }
 
@@ -0,0 +1,182 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package filter
+
+import (
+	"strings"
+	"sync"
+
+	"github.com/pingcap/log"
+	bf "github.com/pingcap/tidb-tools/pkg/binlog-filter"
+	filterV2 "github.com/pingcap/tidb-tools/pkg/table-filter"
+	"github.com/pingcap/tidb/parser"
+	timodel "github.com/pingcap/tidb/parser/model"
+	"github.com/pingcap/tiflow/cdc/model"
+	"github.com/pingcap/tiflow/pkg/config"
+	cerror "github.com/pingcap/tiflow/pkg/errors"
+	"go.uber.org/zap"
+)
+
+// The binlog filters of the rules match all the tables, the tables are
+// matched by the matchers of the rules instead, which support the syntax of
+// the table filter.
+const (
+	binlogFilterSchemaPlaceholder = "binlogFilterSchema"
+	binlogFilterTablePlaceholder  = "binlogFilterTable"
+)
+
+// sqlEventRule ignores the events of the tables matched by the rule by their
+// event types and the SQL patterns.
+type sqlEventRule struct {
+	matcher      filterV2.Filter
+	binlogFilter *bf.BinlogEvent
+}
+
+// sqlEventFilter ignores the events by the event types and the SQL patterns
+// of the event filter rules.
+type sqlEventFilter struct {
+	// parser is used to get the event types of the DDLs, it's not thread safe.
+	parserMu sync.Mutex
+	parser   *parser.Parser
+
+	rules []*sqlEventRule
+}
+
+func newSQLEventFilter(cfg *config.ReplicaConfig) (*sqlEventFilter, error) {
+	f := &sqlEventFilter{parser: parser.New()}
+	for _, rule := range cfg.Filter.EventFilters {
+		if len(rule.IgnoreEvent) == 0 && len(rule.IgnoreSQL) == 0 {
+			continue
+		}
+		for _, et := range rule.IgnoreEvent {
+			if err := verifyEventType(et); err != nil {
+				return nil, cerror.WrapError(cerror.ErrFilterRuleInvalid, err)
+			}
+		}
+		matcher, err := filterV2.Parse(rule.Matcher)
+		if err != nil {
+			return nil, cerror.WrapError(cerror.ErrFilterRuleInvalid, err)
+		}
+		if !cfg.CaseSensitive {
+			matcher = filterV2.CaseInsensitive(matcher)
+		}
+		binlogFilter, err := bf.NewBinlogEvent(cfg.CaseSensitive, []*bf.BinlogEventRule{{
+			SchemaPattern: "*",
+			TablePattern:  "*",
+			Events:        rule.IgnoreEvent,
+			SQLPattern:    rule.IgnoreSQL,
+			Action:        bf.Ignore,
+		}})
+		if err != nil {
+			return nil, cerror.WrapError(cerror.ErrFilterRuleInvalid, err)
+		}
+		f.rules = append(f.rules, &sqlEventRule{matcher: matcher, binlogFilter: binlogFilter})
+	}
+	return f, nil
+}
+
+// verifyEventType checks the event type case-insensitively like the binlog
+// filter, the types matching a group of events such as `all dml` are valid too.
+func verifyEventType(et bf.EventType) error {
+	et = bf.EventType(strings.ToLower(string(et)))
+	switch et {
+	case bf.AllEvent, bf.AllDDL, bf.AllDML, bf.NoneEvent, bf.NoneDDL, bf.NoneDML:
+		return nil
+	}
+	_, err := bf.ClassifyEvent(et)
+	return err
+}
+
+// ShouldIgnoreDMLByEventType returns true if the type of the row is ignored
+// by the event filter rules.
+func (f *Filter) ShouldIgnoreDMLByEventType(row *model.RowChangedEvent) (bool, error) {
+	if f.sqlEventFilter == nil || len(f.sqlEventFilter.rules) == 0 {
+		return false, nil
+	}
+	var et bf.EventType
+	switch {
+	case row.IsInsert():
+		et = bf.InsertEvent
+	case row.IsUpdate():
+		et = bf.UpdateEvent
+	case row.IsDelete():
+		et = bf.DeleteEvent
+	default:
+		return false, nil
+	}
+	for _, rule := range f.sqlEventFilter.rules {
+		if !rule.matcher.MatchTable(row.Table.Schema, row.Table.Table) {
+			continue
+		}
+		ignore, err := rule.shouldIgnore(et, "")
+		if err != nil || ignore {
+			return ignore, err
+		}
+	}
+	return false, nil
+}
+
+// ShouldIgnoreDDLByEventType returns true if the DDL is ignored by the event
+// types or the SQL patterns of the event filter rules.
+func (f *Filter) ShouldIgnoreDDLByEventType(ddl *model.DDLEvent) (bool, error) {
+	if f.sqlEventFilter == nil || len(f.sqlEventFilter.rules) == 0 {
+		return false, nil
+	}
+	et := f.sqlEventFilter.ddlEventType(ddl.Query)
+	var schema, table string
+	if ddl.TableInfo != nil {
+		schema, table = ddl.TableInfo.Schema, ddl.TableInfo.Table
+	}
+	for _, rule := range f.sqlEventFilter.rules {
+		var matched bool
+		switch ddl.Type {
+		case timodel.ActionCreateSchema, timodel.ActionDropSchema,
+			timodel.ActionModifySchemaCharsetAndCollate:
+			matched = rule.matcher.MatchSchema(schema)
+		default:
+			matched = rule.matcher.MatchTable(schema, table)
+		}
+		if !matched {
+			continue
+		}
+		ignore, err := rule.shouldIgnore(et, ddl.Query)
+		if err != nil || ignore {
+			return ignore, err
+		}
+	}
+	return false, nil
+}
+
+// ddlEventType returns the event type of the DDL query, the DDLs that can't
+// be parsed are only ignored by the SQL patterns.
+func (f *sqlEventFilter) ddlEventType(query string) bf.EventType {
+	f.parserMu.Lock()
+	defer f.parserMu.Unlock()
+	stmt, err := f.parser.ParseOneStmt(query, "", "")
+	if err != nil {
+		log.Warn("failed to parse the DDL, only the SQL patterns are applied to it",
+			zap.String("query", query), zap.Error(err))
+		return bf.NullEvent
+	}
+	return bf.AstToDDLEvent(stmt)
+}
+
+func (r *sqlEventRule) shouldIgnore(et bf.EventType, query string) (bool, error) {
+	action, err := r.binlogFilter.Filter(
+		binlogFilterSchemaPlaceholder, binlogFilterTablePlaceholder, et, query)
+	if err != nil {
+		return false, cerror.WrapError(cerror.ErrFilterRuleInvalid, err)
+	}
+	return action == bf.Ignore, nil
+}
@@ -0,0 +1,123 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package filter
+
+import (
+	"testing"
+
+	bf "github.com/pingcap/tidb-tools/pkg/binlog-filter"
+	timodel "github.com/pingcap/tidb/parser/model"
+	"github.com/pingcap/tiflow/cdc/model"
+	"github.com/pingcap/tiflow/pkg/config"
+	"github.com/stretchr/testify/require"
+)
+
+func TestShouldIgnoreDMLByEventType(t *testing.T) {
+	t.Parallel()
+
+	cfg := config.GetDefaultReplicaConfig()
+	cfg.Filter.EventFilters = []*config.EventFilterRule{{
+		Matcher:     []string{"archive.*"},
+		IgnoreEvent: []bf.EventType{bf.DeleteEvent},
+	}, {
+		Matcher:     []string{"test.log"},
+		IgnoreEvent: []bf.EventType{"ALL DML"},
+	}}
+	f, err := NewFilter(cfg)
+	require.Nil(t, err)
+
+	cols := []*model.Column{{Name: "id", Value: int64(1)}}
+	for _, tc := range []struct {
+		table   *model.TableName
+		preCols []*model.Column
+		cols    []*model.Column
+		ignore  bool
+	}{
+		{&model.TableName{Schema: "archive", Table: "t"}, nil, cols, false},
+		{&model.TableName{Schema: "archive", Table: "t"}, cols, cols, false},
+		{&model.TableName{Schema: "archive", Table: "t"}, cols, nil, true},
+		{&model.TableName{Schema: "test", Table: "t"}, cols, nil, false},
+		{&model.TableName{Schema: "test", Table: "log"}, nil, cols, true},
+		{&model.TableName{Schema: "test", Table: "log"}, cols, cols, true},
+	} {
+		ignore, err := f.ShouldIgnoreDMLByEventType(&model.RowChangedEvent{
+			Table:      tc.table,
+			PreColumns: tc.preCols,
+			Columns:    tc.cols,
+		})
+		require.Nil(t, err)
+		require.Equal(t, tc.ignore, ignore, tc.table.String())
+	}
+}
+
+func TestShouldIgnoreDDLByEventType(t *testing.T) {
+	t.Parallel()
+
+	cfg := config.GetDefaultReplicaConfig()
+	cfg.Filter.EventFilters = []*config.EventFilterRule{{
+		Matcher:   []string{"test.*"},
+		IgnoreSQL: []string{"^ALTER TABLE .* ADD INDEX"},
+	}, {
+		Matcher:     []string{"archive.*"},
+		IgnoreEvent: []bf.EventType{bf.DropTable, bf.DropDatabase, bf.TruncateTable},
+	}}
+	f, err := NewFilter(cfg)
+	require.Nil(t, err)
+
+	for _, tc := range []struct {
+		schema string
+		table  string
+		tp     timodel.ActionType
+		query  string
+		ignore bool
+	}{
+		{"test", "t", timodel.ActionAddIndex, "ALTER TABLE t ADD INDEX idx(a)", true},
+		{"test", "t", timodel.ActionAddColumn, "ALTER TABLE t ADD COLUMN a INT", false},
+		{"other", "t", timodel.ActionAddIndex, "ALTER TABLE t ADD INDEX idx(a)", false},
+		{"archive", "t", timodel.ActionDropTable, "DROP TABLE t", true},
+		{"archive", "t", timodel.ActionTruncateTable, "TRUNCATE TABLE t", true},
+		{"archive", "t", timodel.ActionCreateTable, "CREATE TABLE t (a INT)", false},
+		{"archive", "", timodel.ActionDropSchema, "DROP DATABASE archive", true},
+		{"test", "", timodel.ActionDropSchema, "DROP DATABASE test", false},
+	} {
+		ignore, err := f.ShouldIgnoreDDLByEventType(&model.DDLEvent{
+			TableInfo: &model.SimpleTableInfo{Schema: tc.schema, Table: tc.table},
+			Type:      tc.tp,
+			Query:     tc.query,
+		})
+		require.Nil(t, err)
+		require.Equal(t, tc.ignore, ignore, tc.query)
+	}
+}
+
+func TestVerifyIgnoreEvents(t *testing.T) {
+	t.Parallel()
+
+	cfg := config.GetDefaultReplicaConfig()
+	cfg.Filter.EventFilters = []*config.EventFilterRule{{
+		Matcher:     []string{"test.*"},
+		IgnoreEvent: []bf.EventType{"delete rows"},
+	}}
+	_, err := VerifyRules(cfg)
+	require.Regexp(t, ".*ErrFilterRuleInvalid.*", err)
+
+	cfg.Filter.EventFilters[0].IgnoreEvent = []bf.EventType{bf.DeleteEvent}
+	cfg.Filter.EventFilters[0].IgnoreSQL = []string{"[ADD"}
+	_, err = VerifyRules(cfg)
+	require.Regexp(t, ".*ErrFilterRuleInvalid.*", err)
+
+	cfg.Filter.EventFilters[0].IgnoreSQL = []string{"^ALTER TABLE .* ADD INDEX"}
+	_, err = VerifyRules(cfg)
+	require.Nil(t, err)
+}
@@ -0,0 +1,389 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package consumer
+
+import (
+	"context"
+	"math"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/Shopify/sarama"
+	"github.com/pingcap/errors"
+	"github.com/pingcap/log"
+	"github.com/pingcap/tiflow/cdc/model"
+	"github.com/pingcap/tiflow/cdc/sink/codec"
+	"github.com/pingcap/tiflow/pkg/config"
+	cerror "github.com/pingcap/tiflow/pkg/errors"
+	"github.com/pingcap/tiflow/pkg/security"
+	"go.uber.org/zap"
+)
+
+const defaultTickInterval = 100 * time.Millisecond
+
+// Config is the configuration of a Consumer
+type Config struct {
+	// Protocol is the protocol of the messages, the protocols which don't send
+	// resolved events are not supported.
+	Protocol config.Protocol
+	// Topics are the topics to consume, the global resolved ts is the minimal
+	// resolved ts of the partitions of all these topics.
+	Topics []string
+	// PartitionNum is the number of partitions of each topic.
+	PartitionNum int32
+	// EnableTiDBExtension should be the same as the one of the changefeed,
+	// the resolved events are sent in canal-json, avro and debezium only if
+	// it's enabled.
+	EnableTiDBExtension bool
+	// SchemaRegistry is the URL of the schema registry used by avro.
+	SchemaRegistry string
+	Credential     *security.Credential
+	// Timezone is used to decode the time values of avro.
+	Timezone *time.Location
+	// MaxMessageBytes and MaxBatchSize are used to check the messages sent by
+	// TiCDC, 0 means no limit.
+	MaxMessageBytes int
+	MaxBatchSize    int
+	// TickInterval is the interval to apply the resolved events.
+	TickInterval time.Duration
+	// ClaimCheckStorageURI is the claim-check-storage-uri of the changefeed,
+	// the claim check messages are resolved from it if it's set.
+	ClaimCheckStorageURI string
+}
+
+// offsetMarker marks the offsets to commit, it's implemented by sarama.ConsumerGroupSession.
+type offsetMarker interface {
+	MarkOffset(topic string, partition int32, offset int64, metadata string)
+}
+
+// Consumer consumes the messages sent by TiCDC, the events of all partitions
+// are applied to the Target in the order of commit ts once the global resolved
+// ts, the minimal resolved ts of all partitions of all topics, passes them.
+// The offset of a message is committed only after all the events in it are
+// applied.
+//
+// Consumer implements the sarama.ConsumerGroupHandler interface, the messages
+// from other clients can be handled by HandleMessage.
+type Consumer struct {
+	cfg        *Config
+	target     Target
+	newDecoder codec.DecoderFactory
+
+	ready     chan struct{}
+	readyOnce sync.Once
+
+	// partitions are ordered by topic and partition id, partitionIndex
+	// indexes them by topic and partition id.
+	partitions     []*partition
+	partitionIndex map[partitionKey]*partition
+
+	ddlList          []*model.DDLEvent
+	maxDDLReceivedTs uint64
+	ddlListMu        sync.Mutex
+
+	// appliedTs is the ts before which all the events are applied to the Target.
+	appliedTs uint64
+
+	marker   offsetMarker
+	markerMu sync.Mutex
+}
+
+// NewConsumer creates a new Consumer
+func NewConsumer(ctx context.Context, cfg *Config, target Target) (*Consumer, error) {
+	if len(cfg.Topics) == 0 {
+		return nil, cerror.ErrKafkaInvalidConfig.GenWithStack("no topic is specified")
+	}
+	if cfg.PartitionNum <= 0 {
+		return nil, cerror.ErrKafkaInvalidPartitionNum.GenWithStackByArgs(cfg.PartitionNum)
+	}
+	newDecoder, err := newDecoderFactory(ctx, cfg)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	if cfg.TickInterval <= 0 {
+		cfg.TickInterval = defaultTickInterval
+	}
+	c := &Consumer{
+		cfg:            cfg,
+		target:         target,
+		newDecoder:     newDecoder,
+		ready:          make(chan struct{}),
+		partitionIndex: make(map[partitionKey]*partition),
+	}
+	for _, topic := range cfg.Topics {
+		for i := int32(0); i < cfg.PartitionNum; i++ {
+			key := partitionKey{topic: topic, id: i}
+			if _, ok := c.partitionIndex[key]; ok {
+				continue
+			}
+			p := newPartition(topic, i)
+			c.partitions = append(c.partitions, p)
+			c.partitionIndex[key] = p
+		}
+	}
+	return c, nil
+}
+
+// Ready returns a channel which is closed once the first session is set up.
+func (c *Consumer) Ready() <-chan struct{} {
+	return c.ready
+}
+
+// Setup is run at the beginning of a new session, before ConsumeClaim
+func (c *Consumer) Setup(session sarama.ConsumerGroupSession) error {
+	c.markerMu.Lock()
+	c.marker = session
+	c.markerMu.Unlock()
+	c.readyOnce.Do(func() { close(c.ready) })
+	return nil
+}
+
+// Cleanup is run at the end of a session, once all ConsumeClaim goroutines have exited
+func (c *Consumer) Cleanup(sarama.ConsumerGroupSession) error {
+	c.markerMu.Lock()
+	c.marker = nil
+	c.markerMu.Unlock()
+	return nil
+}
+
+// ConsumeClaim must start a consumer loop of ConsumerGroupClaim's Messages().
+func (c *Consumer) ConsumeClaim(session sarama.ConsumerGroupSession, claim sarama.ConsumerGroupClaim) error {
+	for message := range claim.Messages() {
+		log.Debug("Message claimed", zap.Int32("partition", message.Partition),
+			zap.ByteString("key", message.Key), zap.ByteString("value", message.Value))
+		err := c.HandleMessage(message.Topic, message.Partition, message.Offset, message.Key, message.Value)
+		if err != nil {
+			return errors.Trace(err)
+		}
+	}
+	return nil
+}
+
+// HandleMessage decodes a message and buffers the events in it, the messages
+// of a partition must be handled in order.
+func (c *Consumer) HandleMessage(topic string, partitionID int32, offset int64, key, value []byte) error {
+	p, ok := c.partitionIndex[partitionKey{topic: topic, id: partitionID}]
+	if !ok {
+		return cerror.ErrConsumerInvalidMessage.GenWithStackByArgs(partitionID, offset)
+	}
+	if !p.checkOffset(offset) {
+		log.Debug("message has been handled, ignore it",
+			zap.String("topic", topic), zap.Int32("partition", partitionID), zap.Int64("offset", offset))
+		return nil
+	}
+	decoder, err := c.newDecoder(key, value)
+	if err != nil {
+		return cerror.WrapError(cerror.ErrConsumerInvalidMessage, err, partitionID, offset)
+	}
+
+	var maxTs uint64
+	counter := 0
+	for {
+		tp, hasNext, err := decoder.HasNext()
+		if err != nil {
+			return cerror.WrapError(cerror.ErrConsumerInvalidMessage, err, partitionID, offset)
+		}
+		if !hasNext {
+			break
+		}
+
+		counter++
+		// If the message containing only one event exceeds the length limit, CDC will allow it and issue a warning.
+		if c.cfg.MaxMessageBytes > 0 && len(key)+len(value) > c.cfg.MaxMessageBytes && counter > 1 {
+			return cerror.WrapError(cerror.ErrConsumerInvalidMessage,
+				errors.Errorf("max-message-bytes %d exceeded, received %d bytes",
+					c.cfg.MaxMessageBytes, len(key)+len(value)),
+				partitionID, offset)
+		}
+
+		var ts uint64
+		switch tp {
+		case model.MqMessageTypeDDL:
+			ddl, err := decoder.NextDDLEvent()
+			if err != nil {
+				return cerror.WrapError(cerror.ErrConsumerInvalidMessage, err, partitionID, offset)
+			}
+			if err := c.appendDDL(ddl); err != nil {
+				return errors.Trace(err)
+			}
+			ts = ddl.CommitTs
+		case model.MqMessageTypeRow:
+			row, err := decoder.NextRowChangedEvent()
+			if err != nil {
+				return cerror.WrapError(cerror.ErrConsumerInvalidMessage, err, partitionID, offset)
+			}
+			// a row without commit ts can't be ordered, it would be taken as
+			// a fallback row and lost silently.
+			if row.CommitTs == 0 {
+				return cerror.WrapError(cerror.ErrConsumerInvalidMessage,
+					errors.Errorf("row of table %s has no commit ts", row.Table),
+					partitionID, offset)
+			}
+			p.appendRow(row)
+			ts = row.CommitTs
+		case model.MqMessageTypeResolved:
+			ts, err = decoder.NextResolvedEvent()
+			if err != nil {
+				return cerror.WrapError(cerror.ErrConsumerInvalidMessage, err, partitionID, offset)
+			}
+			if err := p.updateResolvedTs(ts); err != nil {
+				return errors.Trace(err)
+			}
+		}
+		if ts > maxTs {
+			maxTs = ts
+		}
+	}
+
+	if c.cfg.MaxBatchSize > 0 && counter > c.cfg.MaxBatchSize {
+		return cerror.WrapError(cerror.ErrConsumerInvalidMessage,
+			errors.Errorf("max-batch-size %d exceeded, received %d events", c.cfg.MaxBatchSize, counter),
+			partitionID, offset)
+	}
+	p.addPendingOffset(offset, maxTs)
+	return nil
+}
+
+// appendDDL appends a DDL, which is sent to all partitions and received only once.
+func (c *Consumer) appendDDL(ddl *model.DDLEvent) error {
+	c.ddlListMu.Lock()
+	defer c.ddlListMu.Unlock()
+	if ddl.CommitTs <= c.maxDDLReceivedTs {
+		return nil
+	}
+	appliedTs := atomic.LoadUint64(&c.appliedTs)
+	if ddl.CommitTs < appliedTs {
+		return cerror.ErrConsumerDDLFallback.GenWithStackByArgs(ddl.CommitTs, appliedTs)
+	}
+	if ddl.CommitTs == appliedTs {
+		log.Warn("receive redundant ddl job", zap.Uint64("ddlts", ddl.CommitTs), zap.Uint64("appliedTs", appliedTs))
+		return nil
+	}
+	c.ddlList = append(c.ddlList, ddl)
+	c.maxDDLReceivedTs = ddl.CommitTs
+	return nil
+}
+
+func (c *Consumer) getFrontDDL() *model.DDLEvent {
+	c.ddlListMu.Lock()
+	defer c.ddlListMu.Unlock()
+	if len(c.ddlList) > 0 {
+		return c.ddlList[0]
+	}
+	return nil
+}
+
+func (c *Consumer) popDDL() {
+	c.ddlListMu.Lock()
+	defer c.ddlListMu.Unlock()
+	if len(c.ddlList) > 0 {
+		c.ddlList = c.ddlList[1:]
+	}
+}
+
+// globalResolvedTs returns the minimal resolved ts of all partitions of all topics.
+func (c *Consumer) globalResolvedTs() uint64 {
+	globalResolvedTs := uint64(math.MaxUint64)
+	for _, p := range c.partitions {
+		if resolvedTs := p.getResolvedTs(); resolvedTs < globalResolvedTs {
+			globalResolvedTs = resolvedTs
+		}
+	}
+	return globalResolvedTs
+}
+
+// Run applies the resolved events to the Target periodically until ctx is done.
+func (c *Consumer) Run(ctx context.Context) error {
+	ticker := time.NewTicker(c.cfg.TickInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return errors.Trace(ctx.Err())
+		case <-ticker.C:
+		}
+		if err := c.advance(ctx); err != nil {
+			return errors.Trace(err)
+		}
+	}
+}
+
+// advance applies the events before the global resolved ts, and commits the
+// offsets of the messages whose events are all applied.
+func (c *Consumer) advance(ctx context.Context) error {
+	for {
+		globalResolvedTs := c.globalResolvedTs()
+		todoDDL := c.getFrontDDL()
+		if todoDDL != nil && todoDDL.CommitTs <= globalResolvedTs {
+			// flush DMLs
+			if err := c.applyRows(ctx, todoDDL.CommitTs); err != nil {
+				return errors.Trace(err)
+			}
+			// execute ddl
+			if err := c.target.EmitDDLEvent(ctx, todoDDL); err != nil {
+				return errors.Trace(err)
+			}
+			atomic.StoreUint64(&c.appliedTs, todoDDL.CommitTs)
+			c.popDDL()
+			continue
+		}
+
+		if globalResolvedTs > atomic.LoadUint64(&c.appliedTs) {
+			if err := c.applyRows(ctx, globalResolvedTs); err != nil {
+				return errors.Trace(err)
+			}
+			atomic.StoreUint64(&c.appliedTs, globalResolvedTs)
+			log.Debug("update globalResolvedTs", zap.Uint64("ts", globalResolvedTs))
+		}
+		break
+	}
+	c.commitOffsets()
+	return nil
+}
+
+// applyRows emits the rows whose commit ts are not greater than resolvedTs in
+// the order of commit ts, and flushes them.
+func (c *Consumer) applyRows(ctx context.Context, resolvedTs uint64) error {
+	var rows []*model.RowChangedEvent
+	for _, p := range c.partitions {
+		rows = append(rows, p.takeRows(resolvedTs)...)
+	}
+	// the stable sort keeps the rows with the same commit ts in the order they're received.
+	sort.SliceStable(rows, func(i, j int) bool {
+		return rows[i].CommitTs < rows[j].CommitTs
+	})
+	if len(rows) > 0 {
+		if err := c.target.EmitRowChangedEvents(ctx, rows...); err != nil {
+			return errors.Trace(err)
+		}
+	}
+	return errors.Trace(c.target.FlushRowChangedEvents(ctx, resolvedTs))
+}
+
+func (c *Consumer) commitOffsets() {
+	c.markerMu.Lock()
+	defer c.markerMu.Unlock()
+	if c.marker == nil {
+		return
+	}
+	appliedTs := atomic.LoadUint64(&c.appliedTs)
+	for _, p := range c.partitions {
+		for _, pending := range p.takeCommittableOffsets(appliedTs) {
+			// the committed offset is the offset of the next message to consume.
+			c.marker.MarkOffset(p.topic, p.id, pending.offset+1, "")
+		}
+	}
+}
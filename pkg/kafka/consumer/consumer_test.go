@@ -0,0 +1,292 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package consumer
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	timodel "github.com/pingcap/tidb/parser/model"
+	"github.com/pingcap/tidb/parser/mysql"
+	"github.com/pingcap/tiflow/cdc/model"
+	"github.com/pingcap/tiflow/cdc/sink/codec"
+	"github.com/pingcap/tiflow/pkg/config"
+	cerror "github.com/pingcap/tiflow/pkg/errors"
+	"github.com/stretchr/testify/require"
+)
+
+const testTopic = "test"
+
+// testProducer sends the messages of the open protocol to a topic of a Consumer.
+type testProducer struct {
+	t        *testing.T
+	consumer *Consumer
+	topic    string
+	offsets  map[int32]int64
+}
+
+// newTestProducer creates a Consumer of the topics, the returned producer sends
+// messages to the first topic.
+func newTestProducer(t *testing.T, partitionNum int32, topics ...string) (*testProducer, *[]string) {
+	if len(topics) == 0 {
+		topics = []string{testTopic}
+	}
+	var events []string
+	target := &Callback{
+		OnRows: func(ctx context.Context, rows []*model.RowChangedEvent) error {
+			for _, row := range rows {
+				events = append(events, fmt.Sprintf("row %d %v", row.CommitTs, row.Columns[0].Value))
+			}
+			return nil
+		},
+		OnDDL: func(ctx context.Context, ddl *model.DDLEvent) error {
+			events = append(events, fmt.Sprintf("ddl %d", ddl.CommitTs))
+			return nil
+		},
+		OnResolved: func(ctx context.Context, resolvedTs uint64) error {
+			events = append(events, fmt.Sprintf("resolved %d", resolvedTs))
+			return nil
+		},
+	}
+	c, err := NewConsumer(context.Background(), &Config{
+		Protocol:     config.ProtocolOpen,
+		Topics:       topics,
+		PartitionNum: partitionNum,
+		MaxBatchSize: 4,
+	}, target)
+	require.Nil(t, err)
+	return &testProducer{t: t, consumer: c, topic: topics[0], offsets: make(map[int32]int64)}, &events
+}
+
+// withTopic returns a producer which sends messages to another topic of the Consumer.
+func (p *testProducer) withTopic(topic string) *testProducer {
+	return &testProducer{t: p.t, consumer: p.consumer, topic: topic, offsets: make(map[int32]int64)}
+}
+
+func (p *testProducer) newEncoder() codec.EventBatchEncoder {
+	encoder := codec.NewJSONEventBatchEncoder()
+	require.Nil(p.t, encoder.SetParams(map[string]string{"max-message-bytes": "1048576"}))
+	return encoder
+}
+
+func (p *testProducer) send(partition int32, msg *codec.MQMessage) error {
+	offset := p.offsets[partition]
+	p.offsets[partition]++
+	return p.consumer.HandleMessage(p.topic, partition, offset, msg.Key, msg.Value)
+}
+
+func (p *testProducer) sendRows(partition int32, commitTs ...uint64) error {
+	encoder := p.newEncoder()
+	for _, ts := range commitTs {
+		_, err := encoder.AppendRowChangedEvent(&model.RowChangedEvent{
+			CommitTs: ts,
+			Table:    &model.TableName{Schema: "test", Table: "t"},
+			Columns: []*model.Column{{
+				Name: "id", Type: mysql.TypeLonglong, Value: int64(partition),
+				Flag: model.HandleKeyFlag | model.PrimaryKeyFlag,
+			}},
+		})
+		require.Nil(p.t, err)
+	}
+	msgs := encoder.Build()
+	require.Len(p.t, msgs, 1)
+	return p.send(partition, msgs[0])
+}
+
+func (p *testProducer) sendResolved(partition int32, ts uint64) error {
+	msg, err := p.newEncoder().EncodeCheckpointEvent(ts)
+	require.Nil(p.t, err)
+	return p.send(partition, msg)
+}
+
+func (p *testProducer) sendDDL(partition int32, commitTs uint64) error {
+	msg, err := p.newEncoder().EncodeDDLEvent(&model.DDLEvent{
+		CommitTs:  commitTs,
+		TableInfo: &model.SimpleTableInfo{Schema: "test", Table: "t"},
+		Query:     "alter table t add column a int",
+		Type:      timodel.ActionAddColumn,
+	})
+	require.Nil(p.t, err)
+	return p.send(partition, msg)
+}
+
+type testMarker struct {
+	offsets map[int32]int64
+}
+
+func (m *testMarker) MarkOffset(topic string, partition int32, offset int64, metadata string) {
+	m.offsets[partition] = offset
+}
+
+func TestConsumerOrdering(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	p, events := newTestProducer(t, 2)
+	require.Nil(t, p.sendRows(0, 10))
+	require.Nil(t, p.sendRows(1, 5))
+	require.Nil(t, p.sendResolved(0, 8))
+	require.Nil(t, p.consumer.advance(ctx))
+	// partition 1 is not resolved yet.
+	require.Empty(t, *events)
+
+	require.Nil(t, p.sendResolved(1, 12))
+	require.Nil(t, p.consumer.advance(ctx))
+	require.Equal(t, []string{"row 5 1", "resolved 8"}, *events)
+
+	// the rows which are not greater than the resolved ts of their partitions are ignored.
+	require.Nil(t, p.sendRows(0, 7, 9))
+	require.Nil(t, p.sendRows(1, 11, 13))
+	require.Nil(t, p.sendResolved(0, 20))
+	require.Nil(t, p.consumer.advance(ctx))
+	require.Equal(t, []string{
+		"row 5 1", "resolved 8",
+		"row 9 0", "row 10 0", "resolved 12",
+	}, *events)
+}
+
+func TestConsumerDDL(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	p, events := newTestProducer(t, 2)
+	require.Nil(t, p.sendRows(0, 11))
+	require.Nil(t, p.sendDDL(0, 15))
+	require.Nil(t, p.sendRows(0, 16))
+	require.Nil(t, p.sendDDL(1, 15))
+	require.Nil(t, p.sendRows(1, 18))
+	require.Nil(t, p.sendResolved(0, 20))
+	require.Nil(t, p.sendResolved(1, 20))
+	require.Nil(t, p.consumer.advance(ctx))
+	require.Equal(t, []string{
+		"row 11 0", "resolved 15", "ddl 15",
+		"row 16 0", "row 18 1", "resolved 20",
+	}, *events)
+
+	// a DDL before the applied ts is unexpected.
+	err := p.sendDDL(0, 19)
+	require.True(t, cerror.ErrConsumerDDLFallback.Equal(err), err)
+}
+
+func TestConsumerInvalidMessage(t *testing.T) {
+	t.Parallel()
+
+	p, _ := newTestProducer(t, 2)
+	require.Nil(t, p.sendResolved(0, 20))
+	err := p.sendResolved(0, 10)
+	require.True(t, cerror.ErrConsumerResolvedTsFallback.Equal(err), err)
+
+	err = p.sendRows(1, 1, 2, 3, 4, 5)
+	require.Regexp(t, ".*ErrConsumerInvalidMessage.*max-batch-size 4 exceeded", err)
+
+	// a row without commit ts can't be ordered.
+	err = p.sendRows(1, 0)
+	require.Regexp(t, ".*ErrConsumerInvalidMessage.*has no commit ts", err)
+
+	err = p.consumer.HandleMessage(testTopic, 2, 0, nil, nil)
+	require.True(t, cerror.ErrConsumerInvalidMessage.Equal(err), err)
+	err = p.consumer.HandleMessage("unknown", 0, 0, nil, nil)
+	require.True(t, cerror.ErrConsumerInvalidMessage.Equal(err), err)
+}
+
+func TestConsumerTopics(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	p1, events := newTestProducer(t, 1, "t1", "t2")
+	p2 := p1.withTopic("t2")
+	require.Nil(t, p1.sendRows(0, 10))
+	require.Nil(t, p2.sendRows(0, 12))
+	require.Nil(t, p2.sendResolved(0, 20))
+	require.Nil(t, p1.consumer.advance(ctx))
+	// the partition of t1 is not resolved yet.
+	require.Empty(t, *events)
+
+	// the partitions of the same id in different topics are resolved separately.
+	require.Nil(t, p1.sendResolved(0, 15))
+	require.Nil(t, p1.consumer.advance(ctx))
+	require.Equal(t, []string{"row 10 0", "row 12 0", "resolved 15"}, *events)
+
+	require.Nil(t, p1.sendRows(0, 18))
+	require.Nil(t, p1.sendResolved(0, 25))
+	require.Nil(t, p1.consumer.advance(ctx))
+	require.Equal(t, []string{"row 10 0", "row 12 0", "resolved 15", "row 18 0", "resolved 20"}, *events)
+}
+
+func TestConsumerCommitOffsets(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	p, events := newTestProducer(t, 2)
+	marker := &testMarker{offsets: make(map[int32]int64)}
+	p.consumer.marker = marker
+
+	require.Nil(t, p.sendResolved(0, 5))
+	require.Nil(t, p.sendRows(0, 10))
+	require.Nil(t, p.sendResolved(0, 20))
+	require.Nil(t, p.sendRows(1, 15))
+	require.Nil(t, p.sendResolved(1, 12))
+	require.Nil(t, p.consumer.advance(ctx))
+	require.Equal(t, []string{"row 10 0", "resolved 12"}, *events)
+	// the resolved event of 20 and the row of 15 are not applied yet.
+	require.Equal(t, map[int32]int64{0: 2}, marker.offsets)
+
+	require.Nil(t, p.sendResolved(1, 30))
+	require.Nil(t, p.consumer.advance(ctx))
+	require.Equal(t, map[int32]int64{0: 3, 1: 2}, marker.offsets)
+
+	// the redelivered messages are skipped.
+	p.offsets[1] = 0
+	require.Nil(t, p.sendRows(1, 25))
+	require.Nil(t, p.consumer.advance(ctx))
+	require.Equal(t, []string{"row 10 0", "resolved 12", "row 15 1", "resolved 20"}, *events)
+}
+
+func TestConsumerProtocols(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	for _, cfg := range []*Config{
+		{Protocol: config.ProtocolCanal},
+		{Protocol: config.ProtocolMaxwell},
+		{Protocol: config.ProtocolCSV},
+		{Protocol: config.ProtocolCanalJSON},
+		{Protocol: config.ProtocolDebezium},
+		{Protocol: config.ProtocolAvro},
+		{Protocol: config.ProtocolAvro, EnableTiDBExtension: true},
+	} {
+		cfg.Topics = []string{testTopic}
+		cfg.PartitionNum = 1
+		_, err := NewConsumer(ctx, cfg, &Callback{})
+		require.True(t, cerror.ErrConsumerUnsupportedProtocol.Equal(err), cfg.Protocol.String())
+	}
+
+	for _, cfg := range []*Config{
+		{Protocol: config.ProtocolOpen},
+		{Protocol: config.ProtocolCraft},
+		{Protocol: config.ProtocolCanalJSON, EnableTiDBExtension: true},
+		{Protocol: config.ProtocolDebezium, EnableTiDBExtension: true},
+	} {
+		cfg.Topics = []string{testTopic}
+		cfg.PartitionNum = 1
+		_, err := NewConsumer(ctx, cfg, &Callback{})
+		require.Nil(t, err, cfg.Protocol.String())
+	}
+
+	_, err := NewConsumer(ctx, &Config{Protocol: config.ProtocolOpen, Topics: []string{testTopic}}, &Callback{})
+	require.True(t, cerror.ErrKafkaInvalidPartitionNum.Equal(err), err)
+	_, err = NewConsumer(ctx, &Config{Protocol: config.ProtocolOpen, PartitionNum: 1}, &Callback{})
+	require.True(t, cerror.ErrKafkaInvalidConfig.Equal(err), err)
+}
@@ -0,0 +1,84 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package consumer
+
+import (
+	"context"
+
+	"github.com/pingcap/errors"
+	"github.com/pingcap/tiflow/cdc/sink/codec"
+	"github.com/pingcap/tiflow/pkg/config"
+	cerror "github.com/pingcap/tiflow/pkg/errors"
+)
+
+// newDecoderFactory returns the DecoderFactory of the protocol, the values of
+// the claim check messages are resolved before decoding.
+func newDecoderFactory(ctx context.Context, cfg *Config) (codec.DecoderFactory, error) {
+	factory, err := newProtocolDecoderFactory(ctx, cfg)
+	if err != nil || cfg.ClaimCheckStorageURI == "" {
+		return factory, err
+	}
+	claimCheck, err := codec.NewClaimCheck(ctx, cfg.ClaimCheckStorageURI, "")
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	return claimCheck.NewDecoderFactory(ctx, factory), nil
+}
+
+// newProtocolDecoderFactory returns the DecoderFactory of the protocol, the
+// protocols are required to send resolved events, since the events can't be
+// ordered without them.
+func newProtocolDecoderFactory(ctx context.Context, cfg *Config) (codec.DecoderFactory, error) {
+	switch cfg.Protocol {
+	case config.ProtocolDefault, config.ProtocolOpen:
+		return codec.NewJSONEventBatchDecoder, nil
+	case config.ProtocolCraft:
+		return func(key, value []byte) (codec.EventBatchDecoder, error) {
+			return codec.NewCraftEventBatchDecoder(value)
+		}, nil
+	case config.ProtocolCanalJSON:
+		if !cfg.EnableTiDBExtension {
+			break
+		}
+		return func(key, value []byte) (codec.EventBatchDecoder, error) {
+			return codec.NewCanalJSONEventBatchDecoder(value, true)
+		}, nil
+	case config.ProtocolDebezium:
+		if !cfg.EnableTiDBExtension {
+			break
+		}
+		return codec.NewDebeziumEventBatchDecoder, nil
+	case config.ProtocolAvro:
+		if !cfg.EnableTiDBExtension {
+			break
+		}
+		if cfg.SchemaRegistry == "" {
+			return nil, cerror.ErrConsumerUnsupportedProtocol.GenWithStackByArgs(
+				cfg.Protocol, "the schema registry is required")
+		}
+		schemaManager, err := codec.NewAvroSchemaManager(ctx, cfg.Credential, cfg.SchemaRegistry, "")
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		tz := cfg.Timezone
+		return func(key, value []byte) (codec.EventBatchDecoder, error) {
+			return codec.NewAvroEventBatchDecoder(key, value, schemaManager, tz)
+		}, nil
+	default:
+		return nil, cerror.ErrConsumerUnsupportedProtocol.GenWithStackByArgs(
+			cfg.Protocol, "no resolved event is sent")
+	}
+	return nil, cerror.ErrConsumerUnsupportedProtocol.GenWithStackByArgs(
+		cfg.Protocol, "the resolved events are sent only if enable-tidb-extension is true")
+}
@@ -0,0 +1,140 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package consumer
+
+import (
+	"sync"
+
+	"github.com/pingcap/log"
+	"github.com/pingcap/tiflow/cdc/model"
+	cerror "github.com/pingcap/tiflow/pkg/errors"
+	"go.uber.org/zap"
+)
+
+// pendingOffset is the offset of a handled message, it can be committed
+// after all the events in the message are applied.
+type pendingOffset struct {
+	offset int64
+	// maxTs is the max commit ts or resolved ts of the events in the message.
+	maxTs uint64
+}
+
+// partitionKey identifies a partition, the topics dispatched by tables may
+// have partitions of the same ids.
+type partitionKey struct {
+	topic string
+	id    int32
+}
+
+// partition buffers the rows of a Kafka partition until they're resolved.
+type partition struct {
+	mu sync.Mutex
+
+	topic      string
+	id         int32
+	resolvedTs uint64
+	// rows are kept in the order they're received.
+	rows    []*model.RowChangedEvent
+	pending []pendingOffset
+	// nextOffset is the offset of the next message to handle, the messages
+	// redelivered after a rebalance are skipped.
+	nextOffset int64
+}
+
+func newPartition(topic string, id int32) *partition {
+	return &partition{
+		topic: topic,
+		id:    id,
+	}
+}
+
+// checkOffset returns false if the message has been handled.
+func (p *partition) checkOffset(offset int64) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return offset >= p.nextOffset
+}
+
+func (p *partition) appendRow(row *model.RowChangedEvent) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if row.CommitTs <= p.resolvedTs {
+		log.Debug("RowChangedEvent fallback row, ignore it",
+			zap.Uint64("commitTs", row.CommitTs),
+			zap.Uint64("resolvedTs", p.resolvedTs),
+			zap.String("topic", p.topic),
+			zap.Int32("partition", p.id),
+			zap.Stringer("table", row.Table))
+		return
+	}
+	p.rows = append(p.rows, row)
+}
+
+func (p *partition) updateResolvedTs(ts uint64) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	// `resolvedTs` should be monotonically increasing, it's allowed to receive redundant one.
+	if ts < p.resolvedTs {
+		return cerror.ErrConsumerResolvedTsFallback.GenWithStackByArgs(p.id, p.resolvedTs, ts)
+	}
+	p.resolvedTs = ts
+	return nil
+}
+
+func (p *partition) getResolvedTs() uint64 {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.resolvedTs
+}
+
+func (p *partition) addPendingOffset(offset int64, maxTs uint64) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.pending = append(p.pending, pendingOffset{offset: offset, maxTs: maxTs})
+	p.nextOffset = offset + 1
+}
+
+// takeRows removes and returns the rows whose commit ts are not greater than ts.
+func (p *partition) takeRows(ts uint64) []*model.RowChangedEvent {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	var taken []*model.RowChangedEvent
+	rest := p.rows[:0]
+	for _, row := range p.rows {
+		if row.CommitTs <= ts {
+			taken = append(taken, row)
+		} else {
+			rest = append(rest, row)
+		}
+	}
+	for i := len(rest); i < len(p.rows); i++ {
+		p.rows[i] = nil
+	}
+	p.rows = rest
+	return taken
+}
+
+// takeCommittableOffsets removes and returns the pending offsets in order, whose
+// events are all applied to the appliedTs.
+func (p *partition) takeCommittableOffsets(appliedTs uint64) []pendingOffset {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	i := 0
+	for i < len(p.pending) && p.pending[i].maxTs <= appliedTs {
+		i++
+	}
+	taken := p.pending[:i:i]
+	p.pending = p.pending[i:]
+	return taken
+}
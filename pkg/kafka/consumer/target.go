@@ -0,0 +1,180 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package consumer
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/pingcap/errors"
+	"github.com/pingcap/tiflow/cdc/model"
+	"github.com/pingcap/tiflow/cdc/sink"
+	"github.com/pingcap/tiflow/pkg/config"
+	"github.com/pingcap/tiflow/pkg/filter"
+	"github.com/pingcap/tiflow/pkg/quotes"
+	"github.com/pingcap/tiflow/pkg/util"
+)
+
+const consumerChangefeed = "kafka-consumer"
+
+// Target is where the decoded events are applied to.
+// The events are emitted in the order of their commit ts, and a DDL is emitted
+// only after all the rows before it are flushed.
+type Target interface {
+	// EmitRowChangedEvents emits the rows whose commit ts are not greater than
+	// the resolved ts of the following FlushRowChangedEvents.
+	EmitRowChangedEvents(ctx context.Context, rows ...*model.RowChangedEvent) error
+	// EmitDDLEvent executes the DDL synchronously.
+	EmitDDLEvent(ctx context.Context, ddl *model.DDLEvent) error
+	// FlushRowChangedEvents returns after all the emitted rows are applied.
+	FlushRowChangedEvents(ctx context.Context, resolvedTs uint64) error
+	// Close closes the Target.
+	Close(ctx context.Context) error
+}
+
+// Callback is a Target which calls the functions on events, a nil function
+// ignores the corresponding events.
+type Callback struct {
+	OnRows     func(ctx context.Context, rows []*model.RowChangedEvent) error
+	OnDDL      func(ctx context.Context, ddl *model.DDLEvent) error
+	OnResolved func(ctx context.Context, resolvedTs uint64) error
+}
+
+// EmitRowChangedEvents implements the Target interface
+func (c *Callback) EmitRowChangedEvents(ctx context.Context, rows ...*model.RowChangedEvent) error {
+	if c.OnRows == nil || len(rows) == 0 {
+		return nil
+	}
+	return c.OnRows(ctx, rows)
+}
+
+// EmitDDLEvent implements the Target interface
+func (c *Callback) EmitDDLEvent(ctx context.Context, ddl *model.DDLEvent) error {
+	if c.OnDDL == nil {
+		return nil
+	}
+	return c.OnDDL(ctx, ddl)
+}
+
+// FlushRowChangedEvents implements the Target interface
+func (c *Callback) FlushRowChangedEvents(ctx context.Context, resolvedTs uint64) error {
+	if c.OnResolved == nil {
+		return nil
+	}
+	return c.OnResolved(ctx, resolvedTs)
+}
+
+// Close implements the Target interface
+func (c *Callback) Close(ctx context.Context) error {
+	return nil
+}
+
+// sinkTarget applies the events to a sink.
+type sinkTarget struct {
+	sink sink.Sink
+	// the table IDs are not contained in the messages, fake ones are generated
+	// for the sink to tell the tables apart.
+	tableIDs *fakeTableIDGenerator
+	tables   map[model.TableID]struct{}
+}
+
+// NewSinkTarget creates a Target applying the events to the sink of the given
+// URI, e.g. a MySQL sink. The timezone used by the sink is taken from ctx.
+func NewSinkTarget(ctx context.Context, sinkURI string, errCh chan error) (Target, error) {
+	replicaConfig := config.GetDefaultReplicaConfig()
+	// TODO support filter in downstream sink
+	f, err := filter.NewFilter(replicaConfig)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	ctx = util.PutRoleInCtx(ctx, util.RoleKafkaConsumer)
+	s, err := sink.New(ctx, consumerChangefeed, sinkURI, f, replicaConfig, map[string]string{}, errCh)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	return &sinkTarget{
+		sink:     s,
+		tableIDs: &fakeTableIDGenerator{tableIDs: make(map[string]int64)},
+		tables:   make(map[model.TableID]struct{}),
+	}, nil
+}
+
+// EmitRowChangedEvents implements the Target interface
+func (t *sinkTarget) EmitRowChangedEvents(ctx context.Context, rows ...*model.RowChangedEvent) error {
+	for _, row := range rows {
+		// FIXME: hack to set start-ts in row changed event, as start-ts
+		// is not contained in most of the protocols
+		row.StartTs = row.CommitTs
+		var partitionID int64
+		if row.Table.IsPartition {
+			partitionID = row.Table.TableID
+		}
+		row.Table.TableID = t.tableIDs.generateFakeTableID(row.Table.Schema, row.Table.Table, partitionID)
+		t.tables[row.Table.TableID] = struct{}{}
+	}
+	return errors.Trace(t.sink.EmitRowChangedEvents(ctx, rows...))
+}
+
+// EmitDDLEvent implements the Target interface
+func (t *sinkTarget) EmitDDLEvent(ctx context.Context, ddl *model.DDLEvent) error {
+	return errors.Trace(t.sink.EmitDDLEvent(ctx, ddl))
+}
+
+// FlushRowChangedEvents implements the Target interface
+func (t *sinkTarget) FlushRowChangedEvents(ctx context.Context, resolvedTs uint64) error {
+	for tableID := range t.tables {
+		for {
+			checkpointTs, err := t.sink.FlushRowChangedEvents(ctx, tableID, resolvedTs)
+			if err != nil {
+				return errors.Trace(err)
+			}
+			if checkpointTs >= resolvedTs {
+				break
+			}
+			select {
+			case <-ctx.Done():
+				return errors.Trace(ctx.Err())
+			default:
+			}
+		}
+	}
+	return nil
+}
+
+// Close implements the Target interface
+func (t *sinkTarget) Close(ctx context.Context) error {
+	return errors.Trace(t.sink.Close(ctx))
+}
+
+type fakeTableIDGenerator struct {
+	tableIDs       map[string]int64
+	currentTableID int64
+	mu             sync.Mutex
+}
+
+func (g *fakeTableIDGenerator) generateFakeTableID(schema, table string, partition int64) int64 {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	key := quotes.QuoteSchema(schema, table)
+	if partition != 0 {
+		key = fmt.Sprintf("%s.`%d`", key, partition)
+	}
+	if tableID, ok := g.tableIDs[key]; ok {
+		return tableID
+	}
+	g.currentTableID++
+	g.tableIDs[key] = g.currentTableID
+	return g.currentTableID
+}
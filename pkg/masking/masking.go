@@ -0,0 +1,297 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package masking
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/pingcap/errors"
+	filter "github.com/pingcap/tidb-tools/pkg/table-filter"
+	timodel "github.com/pingcap/tidb/parser/model"
+	"github.com/pingcap/tidb/parser/mysql"
+	"github.com/pingcap/tidb/parser/types"
+	"github.com/pingcap/tiflow/cdc/model"
+	"github.com/pingcap/tiflow/pkg/config"
+	cerror "github.com/pingcap/tiflow/pkg/errors"
+)
+
+// hashLength is the length of the hex encoded SHA-256 hash.
+const hashLength = sha256.Size * 2
+
+type rule struct {
+	matcher filter.Filter
+	// columns are the lower case names of the masked columns.
+	columns map[string]struct{}
+	config  *config.MaskingRule
+}
+
+// Masker masks the columns of the row changed events, according to the
+// masking rules in the replica config.
+type Masker struct {
+	rules []*rule
+}
+
+// New creates a Masker, the tables which are not matched by any rule are
+// not masked.
+func New(cfg *config.ReplicaConfig) (*Masker, error) {
+	m := &Masker{}
+	if cfg.Masking == nil {
+		return m, nil
+	}
+	for _, r := range cfg.Masking.Rules {
+		switch r.Transform {
+		case config.MaskingHash, config.MaskingSetNull, config.MaskingRedact:
+		case config.MaskingTruncate:
+			if r.Length <= 0 {
+				return nil, cerror.ErrMaskRuleInvalid.GenWithStackByArgs(
+					"the length of the truncate transform must be positive")
+			}
+		default:
+			return nil, cerror.ErrMaskRuleInvalid.GenWithStackByArgs(
+				"unknown transform " + string(r.Transform))
+		}
+		if len(r.Columns) == 0 {
+			return nil, cerror.ErrMaskRuleInvalid.GenWithStackByArgs("no column is specified")
+		}
+		matcher, err := filter.Parse(r.Matcher)
+		if err != nil {
+			return nil, cerror.WrapError(cerror.ErrMaskRuleInvalid, err, err.Error())
+		}
+		if !cfg.CaseSensitive {
+			matcher = filter.CaseInsensitive(matcher)
+		}
+		columns := make(map[string]struct{}, len(r.Columns))
+		for _, column := range r.Columns {
+			columns[strings.ToLower(strings.TrimSpace(column))] = struct{}{}
+		}
+		m.rules = append(m.rules, &rule{matcher: matcher, columns: columns, config: r})
+	}
+	return m, nil
+}
+
+// match returns the rules matching the table.
+func (m *Masker) match(schema, table string) []*rule {
+	var rules []*rule
+	for _, r := range m.rules {
+		if r.matcher.MatchTable(schema, table) {
+			rules = append(rules, r)
+		}
+	}
+	return rules
+}
+
+// transformOf returns the first rule masking the column, or nil if the
+// column is not masked.
+func transformOf(rules []*rule, column string) *config.MaskingRule {
+	column = strings.ToLower(column)
+	for _, r := range rules {
+		if _, ok := r.columns[column]; ok {
+			return r.config
+		}
+	}
+	return nil
+}
+
+// verifyColumn checks whether the transform can be applied to the column.
+// Only the hash transform without truncation keeps the values of the handle
+// keys unique, and the transforms except set-null only work on the strings.
+func verifyColumn(r *config.MaskingRule, tp byte, flag model.ColumnFlagType) bool {
+	if flag.IsHandleKey() && (r.Transform != config.MaskingHash || r.Length > 0) {
+		return false
+	}
+	if r.Transform == config.MaskingSetNull {
+		return true
+	}
+	switch tp {
+	case mysql.TypeString, mysql.TypeVarString, mysql.TypeVarchar,
+		mysql.TypeTinyBlob, mysql.TypeMediumBlob, mysql.TypeLongBlob, mysql.TypeBlob:
+		return true
+	}
+	return false
+}
+
+// VerifyTable checks whether the transforms can be applied to the columns
+// of the table, it's called before the changefeed is created.
+func (m *Masker) VerifyTable(tableInfo *model.TableInfo) error {
+	rules := m.match(tableInfo.TableName.Schema, tableInfo.TableName.Table)
+	if len(rules) == 0 {
+		return nil
+	}
+	for _, colInfo := range tableInfo.Columns {
+		if !model.IsColCDCVisible(colInfo) {
+			continue
+		}
+		r := transformOf(rules, colInfo.Name.O)
+		if r == nil {
+			continue
+		}
+		if !verifyColumn(r, colInfo.Tp, tableInfo.ColumnsFlag[colInfo.ID]) ||
+			!verifyColumnInfo(r, colInfo) {
+			return cerror.ErrMaskingFailed.GenWithStackByArgs(
+				r.Transform, colInfo.Name.O, tableInfo.TableName.String())
+		}
+	}
+	return nil
+}
+
+// verifyColumnInfo checks whether the masked values fit in the column in the
+// downstream, which has the same definition as the upstream one. The set-null
+// transform can't be applied to the not null columns, and the hash must not be
+// longer than the column. The truncate and the redact transforms never make
+// the values longer.
+func verifyColumnInfo(r *config.MaskingRule, colInfo *timodel.ColumnInfo) bool {
+	switch r.Transform {
+	case config.MaskingSetNull:
+		return !mysql.HasNotNullFlag(colInfo.Flag)
+	case config.MaskingHash:
+		length := hashLength
+		if r.Length > 0 && r.Length < length {
+			length = r.Length
+		}
+		return colInfo.Flen == types.UnspecifiedLength || colInfo.Flen >= length
+	}
+	return true
+}
+
+// Apply masks the columns of the row in place, it's called before the row
+// is shared with the others. The old and the new values are masked in the
+// same way, so the changes of the handle keys are still detected.
+func (m *Masker) Apply(row *model.RowChangedEvent) error {
+	if m == nil || len(m.rules) == 0 {
+		return nil
+	}
+	rules := m.match(row.Table.Schema, row.Table.Table)
+	if len(rules) == 0 {
+		return nil
+	}
+	for _, cols := range [][]*model.Column{row.PreColumns, row.Columns} {
+		for _, col := range cols {
+			if col == nil {
+				continue
+			}
+			r := transformOf(rules, col.Name)
+			if r == nil {
+				continue
+			}
+			if !verifyColumn(r, col.Type, col.Flag) {
+				return cerror.ErrMaskingFailed.GenWithStackByArgs(
+					r.Transform, col.Name, row.Table.String())
+			}
+			if err := maskColumn(r, col, row.Table); err != nil {
+				return errors.Trace(err)
+			}
+		}
+	}
+	return nil
+}
+
+func maskColumn(r *config.MaskingRule, col *model.Column, table *model.TableName) error {
+	if col.Value == nil {
+		return nil
+	}
+	if r.Transform == config.MaskingSetNull {
+		col.Value = nil
+		return nil
+	}
+	var value []byte
+	switch v := col.Value.(type) {
+	case []byte:
+		value = v
+	case string:
+		value = []byte(v)
+	default:
+		return cerror.ErrMaskingFailed.GenWithStackByArgs(r.Transform, col.Name, table.String())
+	}
+	binary := col.Flag.IsBinary()
+	switch r.Transform {
+	case config.MaskingHash:
+		h := sha256.New()
+		h.Write([]byte(r.Salt))
+		h.Write(value)
+		value = []byte(hex.EncodeToString(h.Sum(nil)))
+		if r.Length > 0 && len(value) > r.Length {
+			value = value[:r.Length]
+		}
+	case config.MaskingTruncate:
+		value = truncate(value, r.Length, binary)
+	case config.MaskingRedact:
+		value = redact(value, binary)
+	}
+	col.Value = value
+	return nil
+}
+
+// truncate keeps the first n characters of the value, or the first n bytes
+// of the binary value.
+func truncate(value []byte, n int, binary bool) []byte {
+	if binary {
+		if len(value) > n {
+			return value[:n]
+		}
+		return value
+	}
+	for i := range string(value) {
+		if n == 0 {
+			return value[:i]
+		}
+		n--
+	}
+	return value
+}
+
+// redact replaces the letters and the digits in the value, the other
+// characters are kept. The result has the same number of characters as the
+// value, and is never longer than it in bytes.
+func redact(value []byte, binary bool) []byte {
+	redactRune := func(r rune) rune {
+		switch {
+		case unicode.IsUpper(r):
+			return 'X'
+		case unicode.IsLetter(r):
+			return 'x'
+		case unicode.IsDigit(r):
+			return '0'
+		}
+		return r
+	}
+	if binary {
+		result := make([]byte, len(value))
+		for i, b := range value {
+			if b < utf8.RuneSelf {
+				b = byte(redactRune(rune(b)))
+			}
+			result[i] = b
+		}
+		return result
+	}
+	result := make([]byte, 0, len(value))
+	buf := make([]byte, utf8.UTFMax)
+	for len(value) > 0 {
+		r, size := utf8.DecodeRune(value)
+		if r == utf8.RuneError && size <= 1 {
+			// keep the invalid byte, instead of replacing it with the
+			// 3-byte replacement character.
+			result = append(result, value[0])
+		} else {
+			n := utf8.EncodeRune(buf, redactRune(r))
+			result = append(result, buf[:n]...)
+		}
+		value = value[size:]
+	}
+	return result
+}
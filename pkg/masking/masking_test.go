@@ -0,0 +1,187 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package masking
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"testing"
+
+	"github.com/pingcap/tidb/ddl"
+	"github.com/pingcap/tidb/parser"
+	"github.com/pingcap/tidb/parser/ast"
+	"github.com/pingcap/tidb/parser/mysql"
+	timock "github.com/pingcap/tidb/util/mock"
+	"github.com/pingcap/tiflow/cdc/model"
+	"github.com/pingcap/tiflow/pkg/config"
+	cerror "github.com/pingcap/tiflow/pkg/errors"
+	"github.com/stretchr/testify/require"
+)
+
+func newReplicaConfig(rules ...*config.MaskingRule) *config.ReplicaConfig {
+	cfg := config.GetDefaultReplicaConfig()
+	cfg.Masking = &config.MaskingConfig{Rules: rules}
+	return cfg
+}
+
+func TestNewMasker(t *testing.T) {
+	t.Parallel()
+
+	for _, r := range []*config.MaskingRule{
+		{Matcher: []string{"test.*"}, Columns: []string{"a"}, Transform: "encrypt"},
+		{Matcher: []string{"test.*"}, Columns: []string{"a"}, Transform: config.MaskingTruncate},
+		{Matcher: []string{"test.*"}, Transform: config.MaskingHash},
+		{Matcher: []string{"[test.*"}, Columns: []string{"a"}, Transform: config.MaskingHash},
+	} {
+		_, err := New(newReplicaConfig(r))
+		require.Regexp(t, ".*ErrMaskRuleInvalid.*", err)
+	}
+
+	m, err := New(config.GetDefaultReplicaConfig())
+	require.Nil(t, err)
+	row := &model.RowChangedEvent{
+		Table:   &model.TableName{Schema: "test", Table: "t"},
+		Columns: []*model.Column{{Name: "a", Type: mysql.TypeVarchar, Value: []byte("a")}},
+	}
+	require.Nil(t, m.Apply(row))
+	require.Equal(t, []byte("a"), row.Columns[0].Value)
+}
+
+func TestApply(t *testing.T) {
+	t.Parallel()
+
+	m, err := New(newReplicaConfig(
+		&config.MaskingRule{
+			Matcher: []string{"test.user"}, Columns: []string{"ID", "email"},
+			Transform: config.MaskingHash, Salt: "salt",
+		},
+		&config.MaskingRule{
+			Matcher: []string{"test.*"}, Columns: []string{"email", "name"},
+			Transform: config.MaskingTruncate, Length: 2,
+		},
+		&config.MaskingRule{
+			Matcher: []string{"test.*"}, Columns: []string{"phone"}, Transform: config.MaskingRedact,
+		},
+		&config.MaskingRule{
+			Matcher: []string{"test.*"}, Columns: []string{"age"}, Transform: config.MaskingSetNull,
+		},
+	))
+	require.Nil(t, err)
+
+	hash := func(v string) []byte {
+		sum := sha256.Sum256([]byte("salt" + v))
+		return []byte(hex.EncodeToString(sum[:]))
+	}
+	newColumns := func(id, email string) []*model.Column {
+		return []*model.Column{
+			{Name: "id", Type: mysql.TypeVarchar, Flag: model.HandleKeyFlag | model.PrimaryKeyFlag, Value: []byte(id)},
+			{Name: "email", Type: mysql.TypeVarchar, Value: []byte(email)},
+			{Name: "name", Type: mysql.TypeVarchar, Value: []byte("测试名字")},
+			{Name: "phone", Type: mysql.TypeVarchar, Value: []byte("+86 Tel-138")},
+			{Name: "age", Type: mysql.TypeLong, Value: int64(20)},
+			{Name: "other", Type: mysql.TypeVarchar, Value: []byte("other")},
+		}
+	}
+	row := &model.RowChangedEvent{
+		Table:      &model.TableName{Schema: "test", Table: "user"},
+		PreColumns: newColumns("1", "a@b.com"),
+		Columns:    newColumns("2", "a@b.com"),
+	}
+	require.Nil(t, m.Apply(row))
+	for i, cols := range [][]*model.Column{row.PreColumns, row.Columns} {
+		require.Equal(t, hash([]string{"1", "2"}[i]), cols[0].Value)
+		require.Equal(t, hash("a@b.com"), cols[1].Value)
+		require.Equal(t, []byte("测试"), cols[2].Value)
+		require.Equal(t, []byte("+00 Xxx-000"), cols[3].Value)
+		require.Nil(t, cols[4].Value)
+		require.Equal(t, []byte("other"), cols[5].Value)
+	}
+
+	// the transforms which break the uniqueness of the handle keys are refused.
+	row = &model.RowChangedEvent{
+		Table: &model.TableName{Schema: "test", Table: "t"},
+		Columns: []*model.Column{
+			{Name: "name", Type: mysql.TypeVarchar, Flag: model.HandleKeyFlag, Value: []byte("name")},
+		},
+	}
+	err = m.Apply(row)
+	require.True(t, cerror.ErrMaskingFailed.Equal(err), err)
+
+	// only set-null can be applied to the columns which are not strings.
+	row = &model.RowChangedEvent{
+		Table:   &model.TableName{Schema: "test", Table: "t"},
+		Columns: []*model.Column{{Name: "phone", Type: mysql.TypeLonglong, Value: int64(138)}},
+	}
+	err = m.Apply(row)
+	require.True(t, cerror.ErrMaskingFailed.Equal(err), err)
+}
+
+func mockTableInfo(t *testing.T, sql string) *model.TableInfo {
+	node, err := parser.New().ParseOneStmt(sql, "", "")
+	require.Nil(t, err)
+	ti, err := ddl.MockTableInfo(timock.NewContext(), node.(*ast.CreateTableStmt), 1)
+	require.Nil(t, err)
+	return model.WrapTableInfo(1, "test", 1, ti)
+}
+
+func TestVerifyTable(t *testing.T) {
+	t.Parallel()
+
+	m, err := New(newReplicaConfig(
+		&config.MaskingRule{
+			Matcher: []string{"test.t"}, Columns: []string{"a"}, Transform: config.MaskingHash,
+		},
+		&config.MaskingRule{
+			Matcher: []string{"test.t"}, Columns: []string{"b"}, Transform: config.MaskingHash, Length: 16,
+		},
+		&config.MaskingRule{
+			Matcher: []string{"test.t"}, Columns: []string{"c"}, Transform: config.MaskingSetNull,
+		},
+		&config.MaskingRule{
+			Matcher: []string{"test.t"}, Columns: []string{"d"}, Transform: config.MaskingRedact,
+		},
+	))
+	require.Nil(t, err)
+
+	for _, tc := range []struct {
+		sql string
+		ok  bool
+	}{
+		{sql: "create table t (id int primary key, a varchar(64), b char(16), c int, d varchar(4))", ok: true},
+		{sql: "create table t (id int primary key, a text, b varchar(32), c int null, d text)", ok: true},
+		// the hash doesn't fit in the columns.
+		{sql: "create table t (id int primary key, a varchar(32))"},
+		{sql: "create table t (id int primary key, b varchar(8))"},
+		// the not null column can't be set to null.
+		{sql: "create table t (id int primary key, c int not null)"},
+		// the table is not matched.
+		{sql: "create table t1 (id int primary key, a varchar(8), c int not null)", ok: true},
+	} {
+		err := m.VerifyTable(mockTableInfo(t, tc.sql))
+		if tc.ok {
+			require.Nil(t, err, tc.sql)
+		} else {
+			require.True(t, cerror.ErrMaskingFailed.Equal(err), tc.sql)
+		}
+	}
+}
+
+func TestRedact(t *testing.T) {
+	t.Parallel()
+
+	require.Equal(t, []byte("Xx-00 xx"), redact([]byte("Ab-12 测试"), false))
+	// the invalid bytes are kept, the result is not longer than the value.
+	require.Equal(t, []byte("x\xff0"), redact([]byte("a\xff1"), false))
+	require.Equal(t, []byte("Xx\xe6\xb5"), redact([]byte("Ab\xe6\xb5"), true))
+}
@@ -118,7 +118,107 @@ func GenDeleteSQL(changes ...*RowChange) (string, []interface{}) {
 	return buf.String(), args
 }
 
-// TODO: support GenUpdateSQL(changes ...*RowChange) using UPDATE SET CASE WHEN
+// GenUpdateSQL generates the UPDATE SQL and its arguments by CASE WHEN, e.g.
+// UPDATE `db`.`tb` SET `c`=CASE WHEN `c`=? THEN ? WHEN `c`=? THEN ? END, ...
+// WHERE `c` IN (?,?).
+// Input `changes` should have same target table and same columns for WHERE
+// (typically same PK/NOT NULL UK), and should not update the identity values,
+// otherwise the behaviour is undefined.
+func GenUpdateSQL(changes ...*RowChange) (string, []interface{}) {
+	if len(changes) == 0 {
+		log.L().DPanic("row changes is empty")
+		return "", nil
+	}
+
+	first := changes[0]
+
+	var buf strings.Builder
+	buf.Grow(1024)
+	buf.WriteString("UPDATE ")
+	buf.WriteString(first.targetTable.QuoteString())
+	buf.WriteString(" SET ")
+
+	// whereStmt is `c` for the single column identity, or ROW(`c1`,`c2`) for
+	// the multiple columns one.
+	whereColumns, _ := first.whereColumnsAndValues()
+	var whereStmt, whenStmt, inHolder string
+	if len(whereColumns) == 1 {
+		whereStmt = quotes.QuoteName(whereColumns[0])
+		whenStmt = whereStmt + "=?"
+		inHolder = "?"
+	} else {
+		quoted := make([]string, 0, len(whereColumns))
+		for _, column := range whereColumns {
+			quoted = append(quoted, quotes.QuoteName(column))
+		}
+		whereStmt = "ROW(" + strings.Join(quoted, ",") + ")"
+		inHolder = "ROW" + valuesHolder(len(whereColumns))
+		whenStmt = whereStmt + "=" + inHolder
+	}
+
+	var skipColIdx []int
+	columnNum := 0
+	for i, col := range first.sourceTableInfo.Columns {
+		if isGenerated(first.targetTableInfo.Columns, col.Name) {
+			skipColIdx = append(skipColIdx, i)
+			continue
+		}
+
+		if columnNum != 0 {
+			buf.WriteString(", ")
+		}
+		columnNum++
+		buf.WriteString(quotes.QuoteName(col.Name.O) + "=CASE")
+		for range changes {
+			buf.WriteString(" WHEN " + whenStmt + " THEN ?")
+		}
+		buf.WriteString(" END")
+	}
+
+	buf.WriteString(" WHERE " + whereStmt + " IN (")
+	for i := range changes {
+		if i > 0 {
+			buf.WriteString(",")
+		}
+		buf.WriteString(inHolder)
+	}
+	buf.WriteString(")")
+
+	// the arguments of each CASE are the WHERE values and the new value of
+	// each change, followed by the WHERE values of all changes for IN.
+	argsPerCol := make([][]interface{}, columnNum)
+	whereArgs := make([]interface{}, 0, len(changes)*len(whereColumns))
+	for _, change := range changes {
+		_, whereValues := change.whereColumnsAndValues()
+		if len(whereValues) != len(whereColumns) {
+			log.L().DPanic("len(whereValues) != len(whereColumns)",
+				zap.Int("len(whereValues)", len(whereValues)),
+				zap.Int("len(whereColumns)", len(whereColumns)),
+				zap.Any("whereValues", whereValues),
+				zap.Stringer("sourceTable", change.sourceTable))
+			return "", nil
+		}
+		whereArgs = append(whereArgs, whereValues...)
+
+		i := 0 // used as index of skipColIdx
+		col := 0
+		for j, val := range change.postValues {
+			if i < len(skipColIdx) && skipColIdx[i] == j {
+				i++
+				continue
+			}
+			argsPerCol[col] = append(argsPerCol[col], whereValues...)
+			argsPerCol[col] = append(argsPerCol[col], val)
+			col++
+		}
+	}
+	args := make([]interface{}, 0, columnNum*len(changes)*(len(whereColumns)+1)+len(whereArgs))
+	for _, colArgs := range argsPerCol {
+		args = append(args, colArgs...)
+	}
+	args = append(args, whereArgs...)
+	return buf.String(), args
+}
 
 // GenInsertSQL generates the INSERT SQL and its arguments.
 // Input `changes` should have same target table and same modifiable columns,
@@ -66,3 +66,43 @@ func TestGenInsertMultiValue(t *testing.T) {
 	require.Equal(t, "INSERT INTO `db`.`tb` (`c`,`c2`) VALUES (?,?),(?,?) ON DUPLICATE KEY UPDATE `c`=VALUES(`c`),`c2`=VALUES(`c2`)", sql)
 	require.Equal(t, []interface{}{1, 2, 3, 4}, args)
 }
+
+func TestGenUpdateMultiValue(t *testing.T) {
+	t.Parallel()
+
+	source1 := &cdcmodel.TableName{Schema: "db", Table: "tb1"}
+	source2 := &cdcmodel.TableName{Schema: "db", Table: "tb2"}
+	target := &cdcmodel.TableName{Schema: "db", Table: "tb"}
+
+	sourceTI1 := mockTableInfo(t, "CREATE TABLE tb1 (gen INT AS (c+1), c INT PRIMARY KEY, c2 INT)")
+	sourceTI2 := mockTableInfo(t, "CREATE TABLE tb2 (gen INT AS (c+1), c INT PRIMARY KEY, c2 INT)")
+	targetTI := mockTableInfo(t, "CREATE TABLE tb (gen INT AS (c+1), c INT PRIMARY KEY, c2 INT)")
+
+	change1 := NewRowChange(source1, target, []interface{}{2, 1, 2}, []interface{}{2, 1, 20}, sourceTI1, targetTI, nil)
+	change2 := NewRowChange(source2, target, []interface{}{4, 3, 4}, []interface{}{4, 3, 40}, sourceTI2, targetTI, nil)
+
+	sql, args := GenUpdateSQL(change1, change2)
+	require.Equal(t, "UPDATE `db`.`tb` SET "+
+		"`c`=CASE WHEN `c`=? THEN ? WHEN `c`=? THEN ? END, "+
+		"`c2`=CASE WHEN `c`=? THEN ? WHEN `c`=? THEN ? END "+
+		"WHERE `c` IN (?,?)", sql)
+	require.Equal(t, []interface{}{1, 1, 3, 3, 1, 20, 3, 40, 1, 3}, args)
+
+	// multiple columns identity
+	sourceTI := mockTableInfo(t, "CREATE TABLE tb (c INT, c2 INT, c3 INT, PRIMARY KEY (c, c2))")
+	change1 = NewRowChange(target, nil, []interface{}{1, 2, 3}, []interface{}{1, 2, 30}, sourceTI, nil, nil)
+	change2 = NewRowChange(target, nil, []interface{}{4, 5, 6}, []interface{}{4, 5, 60}, sourceTI, nil, nil)
+
+	sql, args = GenUpdateSQL(change1, change2)
+	require.Equal(t, "UPDATE `db`.`tb` SET "+
+		"`c`=CASE WHEN ROW(`c`,`c2`)=ROW(?,?) THEN ? WHEN ROW(`c`,`c2`)=ROW(?,?) THEN ? END, "+
+		"`c2`=CASE WHEN ROW(`c`,`c2`)=ROW(?,?) THEN ? WHEN ROW(`c`,`c2`)=ROW(?,?) THEN ? END, "+
+		"`c3`=CASE WHEN ROW(`c`,`c2`)=ROW(?,?) THEN ? WHEN ROW(`c`,`c2`)=ROW(?,?) THEN ? END "+
+		"WHERE ROW(`c`,`c2`) IN (ROW(?,?),ROW(?,?))", sql)
+	require.Equal(t, []interface{}{
+		1, 2, 1, 4, 5, 4,
+		1, 2, 2, 4, 5, 5,
+		1, 2, 30, 4, 5, 60,
+		1, 2, 4, 5,
+	}, args)
+}
@@ -14,6 +14,7 @@
 package sqlmodel
 
 import (
+	"bytes"
 	"fmt"
 	"strings"
 
@@ -70,13 +71,24 @@ func (r *RowChange) IsIdentityUpdated() bool {
 		return true
 	}
 	for i := range pre {
-		if pre[i] != post[i] {
+		if !identityValueEqual(pre[i], post[i]) {
 			return true
 		}
 	}
 	return false
 }
 
+// identityValueEqual compares two values of the identity, the values of
+// []byte, which are not comparable by `==`, are compared by the contents.
+func identityValueEqual(lhs, rhs interface{}) bool {
+	lhsBytes, lhsOK := lhs.([]byte)
+	rhsBytes, rhsOK := rhs.([]byte)
+	if lhsOK || rhsOK {
+		return lhsOK && rhsOK && bytes.Equal(lhsBytes, rhsBytes)
+	}
+	return lhs == rhs
+}
+
 // genKey gens key by values e.g. "a.1.b".
 func genKey(values []interface{}) string {
 	builder := new(strings.Builder)
@@ -128,3 +128,15 @@ func (s *dpanicSuite) TestReduce() {
 		change2.Reduce(change1)
 	})
 }
+
+func TestIsIdentityUpdatedBytes(t *testing.T) {
+	t.Parallel()
+
+	source := &cdcmodel.TableName{Schema: "db", Table: "tb1"}
+	sourceTI := mockTableInfo(t, "CREATE TABLE tb1 (c VARCHAR(10) PRIMARY KEY, c2 INT)")
+
+	change := NewRowChange(source, nil, []interface{}{[]byte("a"), 2}, []interface{}{[]byte("a"), 4}, sourceTI, nil, nil)
+	require.False(t, change.IsIdentityUpdated())
+	change = NewRowChange(source, nil, []interface{}{[]byte("a"), 2}, []interface{}{[]byte("b"), 4}, sourceTI, nil, nil)
+	require.True(t, change.IsIdentityUpdated())
+}
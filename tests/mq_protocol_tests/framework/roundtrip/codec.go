@@ -0,0 +1,186 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package roundtrip
+
+import (
+	"context"
+	"encoding/base64"
+	"time"
+
+	"github.com/pingcap/errors"
+	"github.com/pingcap/tiflow/cdc/model"
+	"github.com/pingcap/tiflow/cdc/sink/codec"
+	"github.com/pingcap/tiflow/pkg/config"
+	"github.com/pingcap/tiflow/pkg/security"
+	"github.com/tikv/client-go/v2/oracle"
+)
+
+// Codec describes how the messages of a protocol are decoded, and what is lost
+// in the round trip.
+type Codec struct {
+	Protocol config.Protocol
+	// Opts are passed to the encoder builder.
+	Opts map[string]string
+	// NewDecoder creates a decoder for an encoded message.
+	NewDecoder func(msg *codec.MQMessage) (codec.EventBatchDecoder, error)
+	// Expect returns the row expected to be decoded from the given row,
+	// the row is expected to be decoded as it is if it's nil.
+	Expect func(row *model.RowChangedEvent) *model.RowChangedEvent
+	// ExpectDDL is the same as Expect for the DDL events.
+	ExpectDDL func(ddl *model.DDLEvent) *model.DDLEvent
+}
+
+func (c *Codec) expect(row *model.RowChangedEvent) *model.RowChangedEvent {
+	if c.Expect == nil {
+		return row
+	}
+	return c.Expect(copyRow(row))
+}
+
+func (c *Codec) expectDDL(ddl *model.DDLEvent) *model.DDLEvent {
+	if c.ExpectDDL == nil {
+		return ddl
+	}
+	cloned := *ddl
+	return c.ExpectDDL(&cloned)
+}
+
+// Codecs returns the codecs of all the protocols which can be decoded, the Avro
+// schemas are registered in the schema registry of the given URL.
+func Codecs(ctx context.Context, registryURL string) ([]*Codec, error) {
+	avroSchemaManager, err := codec.NewAvroSchemaManager(ctx, &security.Credential{}, registryURL, "")
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	newOpts := func(kvs ...string) map[string]string {
+		opts := map[string]string{"max-message-bytes": "1048576"}
+		for i := 0; i+1 < len(kvs); i += 2 {
+			opts[kvs[i]] = kvs[i+1]
+		}
+		return opts
+	}
+
+	return []*Codec{
+		{
+			Protocol: config.ProtocolOpen,
+			Opts:     newOpts(),
+			NewDecoder: func(msg *codec.MQMessage) (codec.EventBatchDecoder, error) {
+				return codec.NewJSONEventBatchDecoder(msg.Key, msg.Value)
+			},
+		},
+		{
+			Protocol: config.ProtocolCraft,
+			Opts:     newOpts(),
+			NewDecoder: func(msg *codec.MQMessage) (codec.EventBatchDecoder, error) {
+				return codec.NewCraftEventBatchDecoder(msg.Value)
+			},
+		},
+		{
+			Protocol: config.ProtocolCanal,
+			Opts:     newOpts(),
+			NewDecoder: func(msg *codec.MQMessage) (codec.EventBatchDecoder, error) {
+				return codec.NewCanalEventBatchDecoder(msg.Value)
+			},
+			Expect: func(row *model.RowChangedEvent) *model.RowChangedEvent {
+				row.CommitTs = millisecondTs(row.CommitTs)
+				return row
+			},
+			ExpectDDL: func(ddl *model.DDLEvent) *model.DDLEvent {
+				ddl.CommitTs = millisecondTs(ddl.CommitTs)
+				return ddl
+			},
+		},
+		{
+			Protocol: config.ProtocolCanalJSON,
+			Opts:     newOpts("enable-tidb-extension", "true"),
+			NewDecoder: func(msg *codec.MQMessage) (codec.EventBatchDecoder, error) {
+				return codec.NewCanalJSONEventBatchDecoder(msg.Value, true)
+			},
+			Expect: func(row *model.RowChangedEvent) *model.RowChangedEvent {
+				// the deleted values are in the data of canal-json.
+				if row.IsDelete() {
+					row.Columns, row.PreColumns = row.PreColumns, nil
+				}
+				return row
+			},
+		},
+		{
+			Protocol: config.ProtocolMaxwell,
+			Opts:     newOpts(),
+			NewDecoder: func(msg *codec.MQMessage) (codec.EventBatchDecoder, error) {
+				return codec.NewMaxwellEventBatchDecoder(msg.Key, msg.Value)
+			},
+			Expect: func(row *model.RowChangedEvent) *model.RowChangedEvent {
+				physical := oracle.ExtractPhysical(row.CommitTs)
+				row.CommitTs = oracle.ComposeTS(physical-physical%1000, 0)
+				// the binary values are marshaled by encoding/json.
+				for _, cols := range [][]*model.Column{row.PreColumns, row.Columns} {
+					for _, col := range cols {
+						if v, ok := col.Value.([]byte); ok && col.Flag.IsBinary() {
+							col.Value = base64.StdEncoding.EncodeToString(v)
+						}
+					}
+				}
+				return row
+			},
+		},
+		{
+			Protocol: config.ProtocolAvro,
+			Opts:     newOpts("registry", registryURL, "enable-tidb-extension", "true"),
+			NewDecoder: func(msg *codec.MQMessage) (codec.EventBatchDecoder, error) {
+				return codec.NewAvroEventBatchDecoder(msg.Key, msg.Value, avroSchemaManager, time.UTC)
+			},
+			Expect: func(row *model.RowChangedEvent) *model.RowChangedEvent {
+				// only the new values are sent, and a deletion is sent by the key.
+				if row.IsDelete() {
+					row.PreColumns = row.HandleKeyColumns()
+				} else {
+					row.PreColumns = nil
+				}
+				return row
+			},
+		},
+		{
+			Protocol: config.ProtocolDebezium,
+			Opts:     newOpts("enable-tidb-extension", "true"),
+			NewDecoder: func(msg *codec.MQMessage) (codec.EventBatchDecoder, error) {
+				return codec.NewDebeziumEventBatchDecoder(msg.Key, msg.Value)
+			},
+		},
+	}, nil
+}
+
+// millisecondTs drops the logical part of the ts, since canal keeps the physical
+// time in milliseconds only.
+func millisecondTs(ts uint64) uint64 {
+	return ts >> 18 << 18
+}
+
+func copyRow(row *model.RowChangedEvent) *model.RowChangedEvent {
+	copyColumns := func(cols []*model.Column) []*model.Column {
+		if cols == nil {
+			return nil
+		}
+		result := make([]*model.Column, len(cols))
+		for i, col := range cols {
+			cloned := *col
+			result[i] = &cloned
+		}
+		return result
+	}
+	cloned := *row
+	cloned.PreColumns = copyColumns(row.PreColumns)
+	cloned.Columns = copyColumns(row.Columns)
+	return &cloned
+}
@@ -0,0 +1,146 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package roundtrip
+
+import (
+	"fmt"
+	"math/rand"
+	"time"
+
+	timodel "github.com/pingcap/tidb/parser/model"
+	"github.com/pingcap/tidb/parser/mysql"
+	"github.com/pingcap/tidb/types"
+	"github.com/pingcap/tiflow/cdc/model"
+	"github.com/tikv/client-go/v2/oracle"
+)
+
+// Generator generates random insertions, updates and deletions of a table.
+// The values are limited to those all the protocols can carry, e.g. there
+// are no fractional seconds since Avro keeps milliseconds only.
+type Generator struct {
+	rand     *rand.Rand
+	table    *model.TableName
+	commitTs uint64
+	nextID   int64
+	rows     map[int64][]*model.Column
+	ids      []int64
+}
+
+// NewGenerator creates a Generator, the same seed generates the same events.
+func NewGenerator(seed int64, schema, table string) *Generator {
+	return &Generator{
+		rand:     rand.New(rand.NewSource(seed)),
+		table:    &model.TableName{Schema: schema, Table: table},
+		commitTs: oracle.ComposeTS(time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC).UnixNano()/int64(time.Millisecond), 0),
+		nextID:   1,
+		rows:     make(map[int64][]*model.Column),
+	}
+}
+
+// Rows generates n row changed events in the order of the commit ts.
+func (g *Generator) Rows(n int) []*model.RowChangedEvent {
+	rows := make([]*model.RowChangedEvent, 0, n)
+	for i := 0; i < n; i++ {
+		rows = append(rows, g.nextRow())
+	}
+	return rows
+}
+
+// DDL generates a DDL event of the table.
+func (g *Generator) DDL() *model.DDLEvent {
+	return &model.DDLEvent{
+		CommitTs: g.nextCommitTs(),
+		TableInfo: &model.SimpleTableInfo{
+			Schema: g.table.Schema,
+			Table:  g.table.Table,
+		},
+		Query: fmt.Sprintf("alter table %s add column c_%d int", g.table.Table, g.rand.Intn(1000)),
+		Type:  timodel.ActionAddColumn,
+	}
+}
+
+func (g *Generator) nextCommitTs() uint64 {
+	// the logical part is kept to check the protocols losing it.
+	physical := oracle.ExtractPhysical(g.commitTs) + 1 + int64(g.rand.Intn(3000))
+	g.commitTs = oracle.ComposeTS(physical, int64(g.rand.Intn(10)))
+	return g.commitTs
+}
+
+func (g *Generator) nextRow() *model.RowChangedEvent {
+	row := &model.RowChangedEvent{
+		CommitTs: g.nextCommitTs(),
+		Table:    g.table,
+	}
+	// insert more than update or delete to keep some rows in the table.
+	switch op := g.rand.Intn(4); {
+	case op < 2 || len(g.ids) == 0:
+		id := g.nextID
+		g.nextID++
+		row.Columns = g.newColumns(id)
+		g.rows[id] = row.Columns
+		g.ids = append(g.ids, id)
+	case op == 2:
+		id := g.ids[g.rand.Intn(len(g.ids))]
+		row.PreColumns = g.rows[id]
+		row.Columns = g.newColumns(id)
+		g.rows[id] = row.Columns
+	default:
+		i := g.rand.Intn(len(g.ids))
+		id := g.ids[i]
+		row.PreColumns = g.rows[id]
+		delete(g.rows, id)
+		g.ids = append(g.ids[:i], g.ids[i+1:]...)
+	}
+	return row
+}
+
+func (g *Generator) newColumns(id int64) []*model.Column {
+	return []*model.Column{
+		{Name: "id", Type: mysql.TypeLonglong, Flag: model.HandleKeyFlag | model.PrimaryKeyFlag, Value: id},
+		{Name: "c_int", Type: mysql.TypeLong, Flag: model.NullableFlag, Value: int64(g.rand.Int31()) - g.rand.Int63n(1<<31)},
+		{Name: "c_uint", Type: mysql.TypeLonglong, Flag: model.UnsignedFlag | model.NullableFlag, Value: uint64(g.rand.Uint32())},
+		{Name: "c_double", Type: mysql.TypeDouble, Flag: model.NullableFlag, Value: float64(g.rand.Intn(1000000)) / 100},
+		{Name: "c_decimal", Type: mysql.TypeNewDecimal, Flag: model.NullableFlag, Value: fmt.Sprintf("%d.%02d", g.rand.Intn(1000000), g.rand.Intn(100))},
+		{Name: "c_varchar", Type: mysql.TypeVarchar, Flag: model.NullableFlag, Value: g.randomString()},
+		{Name: "c_blob", Type: mysql.TypeBlob, Flag: model.BinaryFlag | model.NullableFlag, Value: g.randomBytes()},
+		{Name: "c_datetime", Type: mysql.TypeDatetime, Flag: model.NullableFlag, Value: g.randomTime().Format(types.TimeFormat)},
+		{Name: "c_date", Type: mysql.TypeDate, Flag: model.NullableFlag, Value: g.randomTime().Format(types.DateFormat)},
+		{Name: "c_timestamp", Type: mysql.TypeTimestamp, Flag: model.NullableFlag, Value: g.randomTime().Format(types.TimeFormat)},
+	}
+}
+
+const letters = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
+
+func (g *Generator) randomString() interface{} {
+	// some values are NULL.
+	if g.rand.Intn(5) == 0 {
+		return nil
+	}
+	b := make([]byte, g.rand.Intn(32))
+	for i := range b {
+		b[i] = letters[g.rand.Intn(len(letters))]
+	}
+	return b
+}
+
+func (g *Generator) randomBytes() []byte {
+	b := make([]byte, g.rand.Intn(32))
+	g.rand.Read(b)
+	return b
+}
+
+func (g *Generator) randomTime() time.Time {
+	// between 2000-01-01 and 2030-01-01.
+	return time.Unix(946684800+g.rand.Int63n(946771200), 0).UTC()
+}
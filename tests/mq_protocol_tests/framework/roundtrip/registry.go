@@ -0,0 +1,98 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package roundtrip
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// schemaRegistry is an in-memory schema registry which supports the APIs used
+// by the Avro encoder and decoder only, the compatibility is not checked.
+type schemaRegistry struct {
+	mu      sync.Mutex
+	ids     map[string]int
+	schemas []string
+}
+
+// NewSchemaRegistry starts an in-memory schema registry, the caller should close
+// the server after using.
+func NewSchemaRegistry() *httptest.Server {
+	return httptest.NewServer(&schemaRegistry{ids: make(map[string]int)})
+}
+
+type schemaRegistryMessage struct {
+	ID     int    `json:"id,omitempty"`
+	Schema string `json:"schema,omitempty"`
+}
+
+func (r *schemaRegistry) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	path := req.URL.Path
+	switch {
+	case req.Method == http.MethodGet && (path == "" || path == "/"):
+		// used for testing the connectivity.
+		_, _ = w.Write([]byte("{}"))
+	case req.Method == http.MethodPost && strings.HasPrefix(path, "/subjects/") && strings.HasSuffix(path, "/versions"):
+		body, err := io.ReadAll(req.Body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		var msg schemaRegistryMessage
+		if err := json.Unmarshal(body, &msg); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		r.mu.Lock()
+		id, ok := r.ids[msg.Schema]
+		if !ok {
+			r.schemas = append(r.schemas, msg.Schema)
+			id = len(r.schemas)
+			r.ids[msg.Schema] = id
+		}
+		r.mu.Unlock()
+		writeJSON(w, &schemaRegistryMessage{ID: id})
+	case req.Method == http.MethodGet && strings.HasPrefix(path, "/schemas/ids/"):
+		id, err := strconv.Atoi(strings.TrimPrefix(path, "/schemas/ids/"))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		if id <= 0 || id > len(r.schemas) {
+			http.NotFound(w, req)
+			return
+		}
+		writeJSON(w, &schemaRegistryMessage{Schema: r.schemas[id-1]})
+	default:
+		// a missing subject means there is no schema to check the compatibility with.
+		http.NotFound(w, req)
+	}
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write(data)
+}
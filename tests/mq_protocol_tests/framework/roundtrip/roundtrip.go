@@ -0,0 +1,216 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package roundtrip checks that the events encoded by an MQ protocol are decoded
+// into the same events, except for the known losses of the protocol.
+package roundtrip
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strconv"
+	"time"
+
+	"github.com/pingcap/errors"
+	"github.com/pingcap/tiflow/cdc/model"
+	"github.com/pingcap/tiflow/cdc/sink/codec"
+	"github.com/pingcap/tiflow/pkg/security"
+	"github.com/pingcap/tiflow/pkg/util"
+)
+
+// Check encodes the rows, the DDL and a checkpoint event with the codec, then
+// decodes the messages and compares the decoded events with the expected ones.
+func Check(ctx context.Context, c *Codec, rows []*model.RowChangedEvent, ddl *model.DDLEvent) error {
+	ctx = util.PutTimezoneInCtx(ctx, time.UTC)
+	builder, err := codec.NewEventBatchEncoderBuilder(c.Protocol, &security.Credential{}, c.Opts)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	encoder, err := builder.Build(ctx)
+	if err != nil {
+		return errors.Trace(err)
+	}
+
+	var resolvedTs uint64
+	for _, row := range rows {
+		if _, err := encoder.AppendRowChangedEvent(row); err != nil {
+			return errors.Trace(err)
+		}
+		if row.CommitTs > resolvedTs {
+			resolvedTs = row.CommitTs
+		}
+	}
+	if _, err := encoder.AppendResolvedEvent(resolvedTs); err != nil {
+		return errors.Trace(err)
+	}
+	events := new(decodedEvents)
+	for _, msg := range encoder.Build() {
+		if err := events.decode(c, msg); err != nil {
+			return errors.Trace(err)
+		}
+	}
+	if len(events.rows) != len(rows) {
+		return errors.Errorf("%s: expect %d rows, got %d", c.Protocol, len(rows), len(events.rows))
+	}
+	for i, row := range rows {
+		expected := digestRow(c.expect(row))
+		actual := digestRow(events.rows[i])
+		if !reflect.DeepEqual(expected, actual) {
+			return errors.Errorf("%s: row %d mismatch, expect %+v, got %+v", c.Protocol, i, expected, actual)
+		}
+	}
+
+	msg, err := encoder.EncodeDDLEvent(ddl)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	if msg != nil {
+		events := new(decodedEvents)
+		if err := events.decode(c, msg); err != nil {
+			return errors.Trace(err)
+		}
+		if len(events.ddls) != 1 {
+			return errors.Errorf("%s: expect one DDL, got %d", c.Protocol, len(events.ddls))
+		}
+		expected := digestDDL(c.expectDDL(ddl))
+		actual := digestDDL(events.ddls[0])
+		if expected != actual {
+			return errors.Errorf("%s: DDL mismatch, expect %+v, got %+v", c.Protocol, expected, actual)
+		}
+	}
+
+	// the protocols without the checkpoint events return nil messages.
+	msg, err = encoder.EncodeCheckpointEvent(resolvedTs)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	if msg != nil {
+		events := new(decodedEvents)
+		if err := events.decode(c, msg); err != nil {
+			return errors.Trace(err)
+		}
+		if len(events.resolved) != 1 || events.resolved[0] != resolvedTs {
+			return errors.Errorf("%s: expect resolved ts %d, got %v", c.Protocol, resolvedTs, events.resolved)
+		}
+	}
+	return nil
+}
+
+type decodedEvents struct {
+	rows     []*model.RowChangedEvent
+	ddls     []*model.DDLEvent
+	resolved []uint64
+}
+
+func (e *decodedEvents) decode(c *Codec, msg *codec.MQMessage) error {
+	decoder, err := c.NewDecoder(msg)
+	if err != nil {
+		return errors.Trace(err)
+	}
+	for {
+		tp, hasNext, err := decoder.HasNext()
+		if err != nil {
+			return errors.Trace(err)
+		}
+		if !hasNext {
+			return nil
+		}
+		switch tp {
+		case model.MqMessageTypeRow:
+			row, err := decoder.NextRowChangedEvent()
+			if err != nil {
+				return errors.Trace(err)
+			}
+			e.rows = append(e.rows, row)
+		case model.MqMessageTypeDDL:
+			ddl, err := decoder.NextDDLEvent()
+			if err != nil {
+				return errors.Trace(err)
+			}
+			e.ddls = append(e.ddls, ddl)
+		case model.MqMessageTypeResolved:
+			ts, err := decoder.NextResolvedEvent()
+			if err != nil {
+				return errors.Trace(err)
+			}
+			e.resolved = append(e.resolved, ts)
+		default:
+			return errors.Errorf("%s: unknown message type %d", c.Protocol, tp)
+		}
+	}
+}
+
+// rowDigest is the comparable form of a row, the values are compared as strings
+// since the decoders of different protocols restore the values in different types.
+type rowDigest struct {
+	Schema     string
+	Table      string
+	CommitTs   uint64
+	PreColumns map[string]string
+	Columns    map[string]string
+}
+
+func digestRow(row *model.RowChangedEvent) rowDigest {
+	return rowDigest{
+		Schema:     row.Table.Schema,
+		Table:      row.Table.Table,
+		CommitTs:   row.CommitTs,
+		PreColumns: digestColumns(row.PreColumns),
+		Columns:    digestColumns(row.Columns),
+	}
+}
+
+func digestColumns(cols []*model.Column) map[string]string {
+	if len(cols) == 0 {
+		return nil
+	}
+	result := make(map[string]string, len(cols))
+	for _, col := range cols {
+		result[col.Name] = formatValue(col.Value)
+	}
+	return result
+}
+
+func formatValue(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return "NULL"
+	case []byte:
+		return string(v)
+	case string:
+		return v
+	case float32:
+		return strconv.FormatFloat(float64(v), 'f', -1, 32)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+type ddlDigest struct {
+	Schema   string
+	Table    string
+	CommitTs uint64
+	Query    string
+}
+
+func digestDDL(ddl *model.DDLEvent) ddlDigest {
+	return ddlDigest{
+		Schema:   ddl.TableInfo.Schema,
+		Table:    ddl.TableInfo.Table,
+		CommitTs: ddl.CommitTs,
+		Query:    ddl.Query,
+	}
+}
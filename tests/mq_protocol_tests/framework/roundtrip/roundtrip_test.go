@@ -0,0 +1,74 @@
+// Copyright 2022 PingCAP, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package roundtrip
+
+import (
+	"context"
+	"testing"
+
+	"github.com/pingcap/tiflow/cdc/model"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	registry := NewSchemaRegistry()
+	defer registry.Close()
+
+	codecs, err := Codecs(ctx, registry.URL)
+	require.NoError(t, err)
+	for _, c := range codecs {
+		g := NewGenerator(42, "test", "roundtrip")
+		require.NoError(t, Check(ctx, c, g.Rows(200), g.DDL()), c.Protocol.String())
+	}
+}
+
+func TestGenerator(t *testing.T) {
+	rows := NewGenerator(1, "test", "t1").Rows(100)
+	require.Equal(t, rows, NewGenerator(1, "test", "t1").Rows(100))
+
+	ids := make(map[int64]struct{})
+	for i, row := range rows {
+		if i > 0 {
+			require.Greater(t, row.CommitTs, rows[i-1].CommitTs)
+		}
+		switch {
+		case row.IsDelete():
+			id := row.PreColumns[0].Value.(int64)
+			require.Contains(t, ids, id)
+			delete(ids, id)
+		case len(row.PreColumns) != 0:
+			require.Contains(t, ids, row.PreColumns[0].Value.(int64))
+		default:
+			ids[row.Columns[0].Value.(int64)] = struct{}{}
+		}
+	}
+}
+
+func TestCheckMismatch(t *testing.T) {
+	ctx := context.Background()
+	registry := NewSchemaRegistry()
+	defer registry.Close()
+
+	codecs, err := Codecs(ctx, registry.URL)
+	require.NoError(t, err)
+	// the open protocol keeps the commit ts, so the wrong expectation fails.
+	c := codecs[0]
+	c.Expect = func(row *model.RowChangedEvent) *model.RowChangedEvent {
+		row.CommitTs++
+		return row
+	}
+	g := NewGenerator(1, "test", "t1")
+	require.Error(t, Check(ctx, c, g.Rows(10), g.DDL()))
+}
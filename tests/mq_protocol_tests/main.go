@@ -14,7 +14,9 @@
 package main
 
 import (
+	"context"
 	"flag"
+	"time"
 
 	"github.com/pingcap/log"
 	"github.com/pingcap/tiflow/tests/mq_protocol_tests/cases"
@@ -22,12 +24,13 @@ import (
 	"github.com/pingcap/tiflow/tests/mq_protocol_tests/framework/avro"
 	"github.com/pingcap/tiflow/tests/mq_protocol_tests/framework/canal"
 	"github.com/pingcap/tiflow/tests/mq_protocol_tests/framework/mysql"
+	"github.com/pingcap/tiflow/tests/mq_protocol_tests/framework/roundtrip"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
 )
 
 var (
-	testProtocol      = flag.String("protocol", "avro", "the protocol we want to test: avro or canal")
+	testProtocol      = flag.String("protocol", "avro", "the protocol we want to test: avro, canal or roundtrip")
 	dockerComposeFile = flag.String("docker-compose-file", "", "the path of the Docker-compose yml file")
 )
 
@@ -127,6 +130,28 @@ func testMySQLWithCheckingOldValue() {
 	runTests(testCases, env)
 }
 
+// testRoundTrip checks the encoders and decoders of all the protocols, it
+// requires neither Kafka nor TiDB.
+func testRoundTrip() {
+	ctx := context.Background()
+	registry := roundtrip.NewSchemaRegistry()
+	defer registry.Close()
+
+	codecs, err := roundtrip.Codecs(ctx, registry.URL)
+	if err != nil {
+		log.Fatal("create codecs failed", zap.Error(err))
+	}
+	seed := time.Now().UnixNano()
+	for _, c := range codecs {
+		g := roundtrip.NewGenerator(seed, "test", "roundtrip")
+		if err := roundtrip.Check(ctx, c, g.Rows(10000), g.DDL()); err != nil {
+			log.Fatal("round trip check failed",
+				zap.String("protocol", c.Protocol.String()), zap.Int64("seed", seed), zap.Error(err))
+		}
+		log.Info("round trip check passed", zap.String("protocol", c.Protocol.String()))
+	}
+}
+
 func runTests(cases []framework.Task, env framework.Environment) {
 	log.SetLevel(zapcore.DebugLevel)
 
@@ -155,6 +180,8 @@ func main() {
 		testMySQL()
 	} else if *testProtocol == "simple-mysql-checking-old-value" {
 		testMySQLWithCheckingOldValue()
+	} else if *testProtocol == "roundtrip" {
+		testRoundTrip()
 	} else {
 		log.Fatal("Unknown sink protocol", zap.String("protocol", *testProtocol))
 	}